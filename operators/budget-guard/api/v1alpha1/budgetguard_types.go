@@ -5,6 +5,7 @@ import (
 )
 
 // BudgetGuardSpec defines the desired state of BudgetGuard
+// +kubebuilder:validation:XValidation:rule="self.scope != 'namespace' || has(self.__namespace__)",message="namespace is required when scope is namespace"
 type BudgetGuardSpec struct {
 	// Budget is the cost limit (in USD or resource units)
 	Budget BudgetLimit `json:"budget"`
@@ -28,15 +29,51 @@ type BudgetGuardSpec struct {
 	// Default: http://opencost.opencost.svc.cluster.local:9003
 	OpenCostEndpoint string `json:"openCostEndpoint,omitempty"`
 
+	// CostProvider configures TLS and authentication for the cost provider API
+	CostProvider *CostProviderSpec `json:"costProvider,omitempty"`
+
 	// RefreshIntervalSeconds is how often to check budget status (in seconds)
 	// Default: 300 (5 minutes)
+	// +kubebuilder:validation:Minimum=60
 	// +kubebuilder:default=300
 	RefreshIntervalSeconds int32 `json:"refreshIntervalSeconds,omitempty"`
 }
 
+// CostProviderSpec configures authenticated and TLS access to the cost provider API
+type CostProviderSpec struct {
+	// Type is the cost provider: "opencost" or "kubecost"
+	// Default: opencost
+	// +kubebuilder:validation:Enum=opencost;kubecost
+	// +kubebuilder:default=opencost
+	Type string `json:"type,omitempty"`
+
+	// CABundle is a PEM encoded CA bundle used to verify the provider's TLS certificate
+	CABundle string `json:"caBundle,omitempty"`
+
+	// InsecureSkipVerify disables TLS certificate verification (not recommended)
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+
+	// AuthSecretRef references a Secret holding credentials for the provider.
+	// Recognized keys: "token" (bearer token), "username" and "password" (basic auth),
+	// "apiKey" (Kubecost API key), "ca.crt" (CA bundle, overrides caBundle),
+	// and "tls.crt" and "tls.key" (client certificate for mutual TLS).
+	// The Secret must be in the operator's namespace.
+	AuthSecretRef *SecretReference `json:"authSecretRef,omitempty"`
+}
+
+// SecretReference references a Secret
+type SecretReference struct {
+	// Name of the Secret
+	Name string `json:"name"`
+
+	// Namespace of the Secret (optional); it must be the operator's namespace
+	Namespace string `json:"namespace,omitempty"`
+}
+
 // BudgetLimit defines the budget limit
 type BudgetLimit struct {
 	// Amount is the budget amount
+	// +kubebuilder:validation:Minimum=0
 	Amount float64 `json:"amount"`
 
 	// Currency is the currency unit (USD, EUR, etc.) or resource unit (CPU-hours, Memory-GB-hours)
@@ -47,19 +84,46 @@ type BudgetLimit struct {
 
 // ActionsOnExceedSpec defines actions to take when budget is exceeded
 type ActionsOnExceedSpec struct {
-	// ThrottleScaling prevents new scaling operations when budget is exceeded
+	// ThrottleScaling caps the maxReplicas of HorizontalPodAutoscalers in scope at their current
+	// replicas when budget is exceeded. They are restored once spend is back under budget, the
+	// action is turned off or the BudgetGuard is deleted.
 	ThrottleScaling bool `json:"throttleScaling,omitempty"`
 
-	// EvictLowPriorityWorkloads evicts pods with low priority when budget is exceeded
+	// EvictLowPriorityWorkloads evicts pods with low priority when budget is exceeded, lowest
+	// priority first, through the Eviction API so PodDisruptionBudgets are respected
 	EvictLowPriorityWorkloads bool `json:"evictLowPriorityWorkloads,omitempty"`
 
+	// Eviction tunes which pods evictLowPriorityWorkloads evicts and how many at a time
+	Eviction EvictionSpec `json:"eviction,omitempty"`
+
 	// Notify sends notifications when budget is exceeded
 	Notify NotifySpec `json:"notify,omitempty"`
 
-	// BlockNewResources prevents creation of new resources when budget is exceeded
+	// BlockNewResources rejects new Deployments, StatefulSets and Jobs in the budget's namespaces
+	// when budget is exceeded. Requires the operator's webhook (--enable-webhooks); resources
+	// annotated aiops.prophet.io/budget-bypass=true are let through if their creator is one of the
+	// operator's --budget-bypass-users or --budget-bypass-groups.
 	BlockNewResources bool `json:"blockNewResources,omitempty"`
 }
 
+// EvictionSpec defines which pods are evicted when budget is exceeded
+type EvictionSpec struct {
+	// PriorityThreshold is the priority below which pods are evicted. A pod's priority is
+	// its spec.priority, or the value of its PriorityClass, or of the global default one.
+	// Default: 1000
+	// +kubebuilder:default=1000
+	PriorityThreshold *int32 `json:"priorityThreshold,omitempty"`
+
+	// MaxEvictionsPerCycle caps how many pods are evicted each time the budget is checked
+	// Default: 10
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=10
+	MaxEvictionsPerCycle int32 `json:"maxEvictionsPerCycle,omitempty"`
+
+	// ProtectedNamespaces are never evicted from, in addition to the operator's protected namespaces
+	ProtectedNamespaces []string `json:"protectedNamespaces,omitempty"`
+}
+
 // NotifySpec defines notification settings
 type NotifySpec struct {
 	// Enabled enables notifications
@@ -70,6 +134,50 @@ type NotifySpec struct {
 
 	// EmailRecipients is a list of email addresses to notify
 	EmailRecipients []string `json:"emailRecipients,omitempty"`
+
+	// Channels are additional notification destinations
+	Channels []NotificationChannel `json:"channels,omitempty"`
+
+	// Template is a Go text/template for the notification text, rendered against the BudgetGuard
+	// (e.g., "{{ .Name }} is over budget"). If empty, a default message is used.
+	Template string `json:"template,omitempty"`
+}
+
+// NotificationChannel is a notification destination
+type NotificationChannel struct {
+	// Type is the channel type: "webhook", "slack", "teams", "pagerduty", "opsgenie", or "email"
+	// +kubebuilder:validation:Enum=webhook;slack;teams;pagerduty;opsgenie;email
+	Type string `json:"type"`
+
+	// URL is the webhook URL (webhook, slack, teams) or an API endpoint override (pagerduty, opsgenie)
+	URL string `json:"url,omitempty"`
+
+	// SecretRef references a Secret holding channel credentials.
+	// Recognized keys: "url" (overrides url), "token" (webhook bearer token, PagerDuty routing key
+	// or Opsgenie API key), "username" and "password" (SMTP authentication)
+	SecretRef *SecretReference `json:"secretRef,omitempty"`
+
+	// SMTP configures the mail server (required if type is "email")
+	SMTP *SMTPSpec `json:"smtp,omitempty"`
+}
+
+// SMTPSpec configures an SMTP mail server
+type SMTPSpec struct {
+	// Host is the SMTP server host
+	Host string `json:"host"`
+
+	// Port is the SMTP server port
+	// Default: 587
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=587
+	Port int32 `json:"port,omitempty"`
+
+	// From is the sender address
+	From string `json:"from"`
+
+	// To is the list of recipients (defaults to emailRecipients)
+	To []string `json:"to,omitempty"`
 }
 
 // BudgetGuardStatus defines the observed state of BudgetGuard
@@ -95,7 +203,12 @@ type BudgetGuardStatus struct {
 	// ActionsTaken is a list of actions that have been taken due to budget exceed
 	ActionsTaken []string `json:"actionsTaken,omitempty"`
 
-	// Conditions represent the latest available observations
+	// ObservedGeneration is the most recent generation the operator has reconciled
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations, including the standard Ready, Progressing and Degraded conditions
+	// +listType=map
+	// +listMapKey=type
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 
 	// ErrorMessage contains any error message from the last refresh
@@ -0,0 +1,55 @@
+package v1alpha1_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/budget-guard/api/v1alpha1"
+	"github.com/prophet-aiops/budget-guard/internal/builders"
+)
+
+// TestValidationRules checks the CRD's CEL validation rules against the API server
+func TestValidationRules(t *testing.T) {
+	c := testEnv.Require(t)
+
+	tests := map[string]struct {
+		modify func(*aiopsv1alpha1.BudgetGuardSpec)
+		error  string
+	}{
+		"namespace": {
+			modify: func(*aiopsv1alpha1.BudgetGuardSpec) {},
+		},
+		"cluster": {
+			modify: func(spec *aiopsv1alpha1.BudgetGuardSpec) {
+				spec.Scope = "cluster"
+				spec.Namespace = ""
+			},
+		},
+		"namespace scope without namespace": {
+			modify: func(spec *aiopsv1alpha1.BudgetGuardSpec) { spec.Namespace = "" },
+			error:  "namespace is required when scope is namespace",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			budgetGuard := builders.BudgetGuard("shop", func(budgetGuard *aiopsv1alpha1.BudgetGuard) {
+				budgetGuard.Name = ""
+				budgetGuard.GenerateName = "monthly-"
+				tt.modify(&budgetGuard.Spec)
+			})
+			err := c.Create(context.Background(), budgetGuard)
+			if err == nil {
+				t.Cleanup(func() { _ = c.Delete(context.Background(), budgetGuard) })
+			}
+			switch {
+			case tt.error == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.error != "" && err == nil:
+				t.Errorf("expected %q", tt.error)
+			case tt.error != "" && !strings.Contains(err.Error(), tt.error):
+				t.Errorf("expected %q, got %v", tt.error, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package v1alpha1_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/budget-guard/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/testenv"
+)
+
+// testEnv is the API server the CRD validation tests run against; nil without KUBEBUILDER_ASSETS
+var testEnv *testenv.Environment
+
+func TestMain(m *testing.M) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(aiopsv1alpha1.AddToScheme(scheme))
+
+	var err error
+	testEnv, err = testenv.Start(scheme, filepath.Join("..", "..", "config", "crd", "bases"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to start the test environment: %v\n", err)
+		os.Exit(1)
+	}
+	code := m.Run()
+	if err := testEnv.Stop(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to stop the test environment: %v\n", err)
+	}
+	os.Exit(code)
+}
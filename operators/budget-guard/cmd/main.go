@@ -3,13 +3,16 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
 
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -17,6 +20,7 @@ import (
 
 	aiopsv1alpha1 "github.com/prophet-aiops/budget-guard/api/v1alpha1"
 	"github.com/prophet-aiops/budget-guard/controllers"
+	"github.com/prophet-aiops/pkg/httpclient"
 )
 
 var (
@@ -33,11 +37,29 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var watchNamespaces string
+	var extraProtectedNamespaces string
+	var guardrailsConfigMap string
+	var enableWebhooks bool
+	var bypassUsers string
+	var bypassGroups string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", os.Getenv("WATCH_NAMESPACE"),
+		"Comma-separated namespaces to watch, defaulting to $WATCH_NAMESPACE. Leave empty to watch all namespaces.")
+	flag.StringVar(&extraProtectedNamespaces, "protected-namespaces", "",
+		"Comma-separated namespaces the operator must never act in, in addition to its own namespace and kube-system, kube-public and kube-node-lease.")
+	flag.StringVar(&guardrailsConfigMap, "guardrails-configmap", "prophet-operators/prophet-guardrails",
+		"The namespace/name of the ConfigMap holding guardrail policies. Set to an empty string to disable guardrails.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Serve the webhook that blocks new resources while a BudgetGuard with blockNewResources is exceeded. Requires serving certificates in /tmp/k8s-webhook-server/serving-certs.")
+	flag.StringVar(&bypassUsers, "budget-bypass-users", "",
+		"Comma-separated users, including service accounts as system:serviceaccount:<namespace>:<name>, who may create resources annotated aiops.prophet.io/budget-bypass=true while their budget is exceeded.")
+	flag.StringVar(&bypassGroups, "budget-bypass-groups", "",
+		"Comma-separated groups whose members may create resources annotated aiops.prophet.io/budget-bypass=true while their budget is exceeded.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -46,6 +68,25 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	var guardrails types.NamespacedName
+	if guardrailsConfigMap != "" {
+		namespace, name, ok := strings.Cut(guardrailsConfigMap, "/")
+		if !ok || namespace == "" || name == "" {
+			setupLog.Info("invalid --guardrails-configmap, expected namespace/name", "value", guardrailsConfigMap)
+			os.Exit(1)
+		}
+		guardrails = types.NamespacedName{Namespace: namespace, Name: name}
+	}
+
+	// Fail fast on bad PROPHET_HTTP_* settings rather than on the first outbound request
+	if _, err := httpclient.New(httpclient.Config{}); err != nil {
+		setupLog.Error(err, "invalid outbound HTTP settings")
+		os.Exit(1)
+	}
+
+	// Credentials Secrets of the cluster-scoped BudgetGuards are only read from the operator's namespace
+	namespace := operatorNamespace()
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
 		Metrics: metricsserver.Options{
@@ -55,6 +96,7 @@ func main() {
 			Port: 9443,
 		}),
 		HealthProbeBindAddress: probeAddr,
+		Cache:                  cacheOptions(watchNamespaces, guardrails.Namespace, namespace),
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "budget-guard.prophet.io",
 	})
@@ -63,14 +105,29 @@ func main() {
 		os.Exit(1)
 	}
 
+	protected := protectedNamespaces(extraProtectedNamespaces)
 	if err = (&controllers.BudgetGuardReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-		Log:    ctrl.Log.WithName("controllers").WithName("BudgetGuard"),
+		Client:              mgr.GetClient(),
+		Scheme:              mgr.GetScheme(),
+		Log:                 ctrl.Log.WithName("controllers").WithName("BudgetGuard"),
+		Guardrails:          guardrails,
+		ProtectedNamespaces: protected,
+		SecretNamespace:     namespace,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "BudgetGuard")
 		os.Exit(1)
 	}
+	if enableWebhooks {
+		if err = (&controllers.ResourceBlocker{
+			Client:              mgr.GetClient(),
+			ProtectedNamespaces: protected,
+			BypassUsers:         splitList(bypassUsers),
+			BypassGroups:        splitList(bypassGroups),
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "BudgetGuard")
+			os.Exit(1)
+		}
+	}
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
@@ -87,3 +144,60 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// cacheOptions restricts the manager's cache to the comma-separated watch namespaces.
+// Extra namespaces the operator reads from are added in namespace-scoped mode.
+// With no watch namespaces every namespace is watched.
+func cacheOptions(watchNamespaces string, extra ...string) cache.Options {
+	namespaces := map[string]cache.Config{}
+	for _, namespace := range strings.Split(watchNamespaces, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces[namespace] = cache.Config{}
+		}
+	}
+	if len(namespaces) == 0 {
+		return cache.Options{}
+	}
+	for _, namespace := range extra {
+		if namespace != "" {
+			namespaces[namespace] = cache.Config{}
+		}
+	}
+	return cache.Options{DefaultNamespaces: namespaces}
+}
+
+// protectedNamespaces returns the operator's own namespace and the comma-separated extra namespaces
+func protectedNamespaces(extra string) []string {
+	var namespaces []string
+	if namespace := operatorNamespace(); namespace != "" {
+		namespaces = append(namespaces, namespace)
+	}
+	for _, namespace := range strings.Split(extra, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// operatorNamespace returns the namespace the operator runs in, or "" if it can't be determined
+func operatorNamespace() string {
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace
+	}
+	if data, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	return ""
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
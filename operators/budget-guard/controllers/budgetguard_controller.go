@@ -2,22 +2,26 @@ package controllers
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	aiopsv1alpha1 "github.com/prophet-aiops/budget-guard/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/conditions"
+	"github.com/prophet-aiops/pkg/costprovider"
+	"github.com/prophet-aiops/pkg/notifier"
+	"github.com/prophet-aiops/pkg/prophetconfig"
+	"github.com/prophet-aiops/pkg/status"
 )
 
 // BudgetGuardReconciler reconciles a BudgetGuard object
@@ -25,13 +29,31 @@ type BudgetGuardReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	Log    logr.Logger
+
+	// Guardrails is the ConfigMap holding guardrail policies checked before pods are evicted.
+	// Leave empty to disable guardrails.
+	Guardrails types.NamespacedName
+
+	// ProtectedNamespaces are never acted on, in addition to the system namespaces.
+	// They should include the operator's own namespace.
+	ProtectedNamespaces []string
+
+	// SecretNamespace is the namespace credentials Secrets are read from, normally the operator's
+	// own. BudgetGuards are cluster-scoped, so they can't name Secrets in arbitrary namespaces.
+	SecretNamespace string
 }
 
 //+kubebuilder:rbac:groups=aiops.prophet.io,resources=budgetguards,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=aiops.prophet.io,resources=budgetguards/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=aiops.prophet.io,resources=budgetguards/finalizers,verbs=update
+//+kubebuilder:rbac:groups=aiops.prophet.io,resources=prophetconfigs,verbs=get
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;update;patch
-//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;delete;evict
+//+kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=pods/eviction,verbs=create
+//+kubebuilder:rbac:groups=scheduling.k8s.io,resources=priorityclasses,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop
@@ -42,19 +64,38 @@ func (r *BudgetGuardReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	if err := r.Get(ctx, req.NamespacedName, &budgetGuard); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	// Deleted BudgetGuards give back the scaling they throttled before they go
+	if !budgetGuard.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.restoreScaling(ctx, &budgetGuard)
+	}
+	// Status changes are patched against the BudgetGuard as read
+	base := budgetGuard.DeepCopy()
 
 	logger.Info("Reconciling BudgetGuard", "name", req.Name, "scope", budgetGuard.Spec.Scope)
 
+	// Cluster-wide defaults; settings on the BudgetGuard take precedence
+	defaults, err := prophetconfig.Get(ctx, r.Client)
+	if err != nil {
+		logger.Error(err, "Failed to read ProphetConfig, using built-in defaults")
+	}
+
 	// Fetch cost data from OpenCost/Kubecost
-	currentSpend, err := r.fetchCostData(ctx, &budgetGuard)
+	currentSpend, err := r.fetchCostData(ctx, &budgetGuard, defaults)
 	if err != nil {
 		logger.Error(err, "Failed to fetch cost data")
 		budgetGuard.Status.ErrorMessage = err.Error()
-		if err := r.Status().Update(ctx, &budgetGuard); err != nil {
+		budgetGuard.Status.ObservedGeneration = budgetGuard.Generation
+		conditions.Apply(&budgetGuard.Status.Conditions, budgetGuard.Generation, conditions.Summary{
+			Degraded: true,
+			Reason:   "CostDataUnavailable",
+			Message:  err.Error(),
+		})
+		if err := status.Patch(ctx, r.Client, &budgetGuard, base); err != nil {
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{RequeueAfter: 1 * time.Minute}, nil
 	}
+	budgetGuard.Status.ErrorMessage = ""
 
 	// Update status
 	now := metav1.Now()
@@ -81,7 +122,7 @@ func (r *BudgetGuardReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	// Take actions if budget is exceeded
 	if exceeded {
 		actionsTaken := []string{}
-		if err := r.enforceBudget(ctx, &budgetGuard, &actionsTaken); err != nil {
+		if err := r.enforceBudget(ctx, &budgetGuard, defaults, &actionsTaken); err != nil {
 			logger.Error(err, "Failed to enforce budget")
 			budgetGuard.Status.ErrorMessage = err.Error()
 		} else {
@@ -89,25 +130,42 @@ func (r *BudgetGuardReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 	} else {
 		budgetGuard.Status.ActionsTaken = []string{}
+		if err := r.restoreScaling(ctx, &budgetGuard); err != nil {
+			logger.Error(err, "Failed to restore throttled scaling")
+			budgetGuard.Status.ErrorMessage = err.Error()
+		}
 	}
 
 	// Update conditions
-	condition := metav1.Condition{
-		Type:               "BudgetStatus",
-		Status:             metav1.ConditionTrue,
-		Reason:             "WithinBudget",
-		Message:            fmt.Sprintf("Current spend: %.2f %s (%.1f%% of budget)", currentSpend, budgetGuard.Spec.Budget.Currency, budgetGuard.Status.PercentageUsed),
-		LastTransitionTime: now,
-	}
+	message := fmt.Sprintf("Current spend: %.2f %s (%.1f%% of budget)", currentSpend, budgetGuard.Spec.Budget.Currency, budgetGuard.Status.PercentageUsed)
 	if exceeded {
-		condition.Status = metav1.ConditionFalse
-		condition.Reason = "BudgetExceeded"
-		condition.Message = fmt.Sprintf("Budget exceeded! Current spend: %.2f %s (%.1f%% of budget)", currentSpend, budgetGuard.Spec.Budget.Currency, budgetGuard.Status.PercentageUsed)
+		exceededMessage := fmt.Sprintf("Budget exceeded! Current spend: %.2f %s (%.1f%% of budget)", currentSpend, budgetGuard.Spec.Budget.Currency, budgetGuard.Status.PercentageUsed)
+		conditions.Set(&budgetGuard.Status.Conditions, budgetGuard.Generation, "BudgetStatus", false, "BudgetExceeded", exceededMessage)
+		conditions.Set(&budgetGuard.Status.Conditions, budgetGuard.Generation, conditions.Triggered, true, "BudgetExceeded", exceededMessage)
+	} else {
+		conditions.Set(&budgetGuard.Status.Conditions, budgetGuard.Generation, "BudgetStatus", true, "WithinBudget", message)
+		conditions.Set(&budgetGuard.Status.Conditions, budgetGuard.Generation, conditions.Triggered, false, "WithinBudget", message)
+	}
+	switch {
+	case !exceeded:
+		conditions.Set(&budgetGuard.Status.Conditions, budgetGuard.Generation, conditions.Remediating, false, "WithinBudget", "No enforcement needed")
+	case budgetGuard.Status.ErrorMessage != "":
+		conditions.Set(&budgetGuard.Status.Conditions, budgetGuard.Generation, conditions.Remediating, false, "EnforcementFailed", budgetGuard.Status.ErrorMessage)
+	case len(budgetGuard.Status.ActionsTaken) == 0:
+		conditions.Set(&budgetGuard.Status.Conditions, budgetGuard.Generation, conditions.Remediating, false, "NoActionsConfigured", "Budget exceeded, no enforcement actions are configured")
+	default:
+		conditions.Set(&budgetGuard.Status.Conditions, budgetGuard.Generation, conditions.Remediating, true, "EnforcingBudget",
+			fmt.Sprintf("Actions taken: %s", strings.Join(budgetGuard.Status.ActionsTaken, ", ")))
 	}
-	budgetGuard.Status.Conditions = []metav1.Condition{condition}
+	summary := conditions.Summary{Message: message}
+	if budgetGuard.Status.ErrorMessage != "" {
+		summary = conditions.Summary{Degraded: true, Reason: "EnforcementFailed", Message: budgetGuard.Status.ErrorMessage}
+	}
+	budgetGuard.Status.ObservedGeneration = budgetGuard.Generation
+	conditions.Apply(&budgetGuard.Status.Conditions, budgetGuard.Generation, summary)
 
 	// Update status
-	if err := r.Status().Update(ctx, &budgetGuard); err != nil {
+	if err := status.Patch(ctx, r.Client, &budgetGuard, base); err != nil {
 		return ctrl.Result{}, err
 	}
 
@@ -120,92 +178,108 @@ func (r *BudgetGuardReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 }
 
 // fetchCostData fetches cost data from OpenCost/Kubecost API
-func (r *BudgetGuardReconciler) fetchCostData(ctx context.Context, budgetGuard *aiopsv1alpha1.BudgetGuard) (float64, error) {
-	endpoint := budgetGuard.Spec.OpenCostEndpoint
-	if endpoint == "" {
-		endpoint = "http://opencost.opencost.svc.cluster.local:9003"
-	}
-
+func (r *BudgetGuardReconciler) fetchCostData(ctx context.Context, budgetGuard *aiopsv1alpha1.BudgetGuard, defaults prophetconfig.Spec) (float64, error) {
 	// Build query based on scope
-	var url string
+	params := url.Values{"window": []string{"7d"}}
 	switch budgetGuard.Spec.Scope {
 	case "namespace":
 		if budgetGuard.Spec.Namespace == "" {
 			return 0, fmt.Errorf("namespace is required for namespace-scoped budget")
 		}
-		url = fmt.Sprintf("%s/allocation?window=7d&aggregate=namespace&namespace=%s", endpoint, budgetGuard.Spec.Namespace)
+		params.Set("aggregate", "namespace")
+		params.Set("namespace", budgetGuard.Spec.Namespace)
 	case "cluster":
-		url = fmt.Sprintf("%s/allocation?window=7d&aggregate=cluster", endpoint)
+		params.Set("aggregate", "cluster")
 	default:
 		return 0, fmt.Errorf("unsupported scope: %s", budgetGuard.Spec.Scope)
 	}
 
-	// Make HTTP request
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	config, err := r.costProviderConfig(ctx, budgetGuard, defaults)
+	if err != nil {
+		return 0, err
+	}
+	provider, err := costprovider.NewClient(config)
 	if err != nil {
 		return 0, err
 	}
 
-	resp, err := client.Do(req)
+	costs, err := provider.Allocation(ctx, params)
 	if err != nil {
-		// If OpenCost is not available, return a mock value for testing
-		// In production, this should be an error
-		return 0, fmt.Errorf("failed to fetch cost data (OpenCost may not be deployed): %w", err)
+		return 0, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return 0, fmt.Errorf("OpenCost API returned status %d: %s", resp.StatusCode, string(body))
+	return costprovider.Total(costs), nil
+}
+
+// costProviderConfig builds the cost provider client configuration, reading
+// credentials from the referenced Secret if one is set
+func (r *BudgetGuardReconciler) costProviderConfig(ctx context.Context, budgetGuard *aiopsv1alpha1.BudgetGuard, defaults prophetconfig.Spec) (costprovider.Config, error) {
+	config := costprovider.Config{Endpoint: budgetGuard.Spec.OpenCostEndpoint}
+	if config.Endpoint == "" {
+		config.Endpoint = defaults.CostProvider.Endpoint
 	}
 
-	// Parse response (simplified - OpenCost returns complex JSON)
-	var data map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return 0, err
+	provider := budgetGuard.Spec.CostProvider
+	if provider == nil {
+		return config, nil
 	}
+	config.CABundle = []byte(provider.CABundle)
+	config.InsecureSkipVerify = provider.InsecureSkipVerify
 
-	// Extract total cost (simplified parsing)
-	// In production, properly parse OpenCost's allocation response
-	totalCost := 0.0
-	if allocations, ok := data["data"].(map[string]interface{}); ok {
-		for _, allocation := range allocations {
-			if alloc, ok := allocation.(map[string]interface{}); ok {
-				if cost, ok := alloc["totalCost"].(float64); ok {
-					totalCost += cost
-				}
-			}
+	if ref := provider.AuthSecretRef; ref != nil {
+		namespace, err := r.secretNamespace(ref)
+		if err != nil {
+			return config, fmt.Errorf("cost provider secret: %w", err)
+		}
+		var secret corev1.Secret
+		if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, &secret); err != nil {
+			return config, fmt.Errorf("failed to get cost provider secret %s/%s: %w", namespace, ref.Name, err)
+		}
+		config.BearerToken = string(secret.Data["token"])
+		config.Username = string(secret.Data["username"])
+		config.Password = string(secret.Data["password"])
+		config.APIKey = string(secret.Data["apiKey"])
+		if ca, ok := secret.Data["ca.crt"]; ok {
+			config.CABundle = ca
 		}
+		config.ClientCert = secret.Data["tls.crt"]
+		config.ClientKey = secret.Data["tls.key"]
 	}
 
-	return totalCost, nil
+	return config, nil
 }
 
 // enforceBudget enforces budget limits by taking configured actions
-func (r *BudgetGuardReconciler) enforceBudget(ctx context.Context, budgetGuard *aiopsv1alpha1.BudgetGuard, actionsTaken *[]string) error {
+func (r *BudgetGuardReconciler) enforceBudget(ctx context.Context, budgetGuard *aiopsv1alpha1.BudgetGuard, defaults prophetconfig.Spec, actionsTaken *[]string) error {
 	logger := log.FromContext(ctx)
 	actions := budgetGuard.Spec.ActionsOnExceed
 
 	// Throttle scaling
 	if actions.ThrottleScaling {
-		// In production, this would patch HPA/PredictiveScale to set min/max replicas
-		// For now, just log
-		logger.Info("Throttling scaling due to budget exceed")
+		if err := r.throttleScaling(ctx, budgetGuard, defaults); err != nil {
+			return err
+		}
 		*actionsTaken = append(*actionsTaken, "throttle-scaling")
+	} else if err := r.restoreScaling(ctx, budgetGuard); err != nil {
+		return err
 	}
 
 	// Evict low priority workloads
 	if actions.EvictLowPriorityWorkloads {
-		if err := r.evictLowPriorityPods(ctx, budgetGuard); err != nil {
+		if err := r.evictLowPriorityPods(ctx, budgetGuard, defaults); err != nil {
 			return err
 		}
 		*actionsTaken = append(*actionsTaken, "evict-low-priority-workloads")
 	}
 
+	// New resources are blocked by the webhook while the budget is exceeded
+	if actions.BlockNewResources {
+		*actionsTaken = append(*actionsTaken, "block-new-resources")
+	}
+
 	// Send notifications
 	if actions.Notify.Enabled {
-		if err := r.sendNotification(ctx, budgetGuard); err != nil {
+		if err := r.sendNotification(ctx, budgetGuard, defaults); err != nil {
 			logger.Error(err, "Failed to send notification")
 		} else {
 			*actionsTaken = append(*actionsTaken, "notify")
@@ -215,59 +289,35 @@ func (r *BudgetGuardReconciler) enforceBudget(ctx context.Context, budgetGuard *
 	return nil
 }
 
-// evictLowPriorityPods evicts pods with low priority classes
-func (r *BudgetGuardReconciler) evictLowPriorityPods(ctx context.Context, budgetGuard *aiopsv1alpha1.BudgetGuard) error {
-	logger := log.FromContext(ctx)
+// sendNotification sends budget exceeded notifications
+func (r *BudgetGuardReconciler) sendNotification(ctx context.Context, budgetGuard *aiopsv1alpha1.BudgetGuard, defaults prophetconfig.Spec) error {
+	notify := budgetGuard.Spec.ActionsOnExceed.Notify
+	message := fmt.Sprintf("Budget exceeded! Current spend: %.2f %s (%.1f%% of budget)",
+		budgetGuard.Status.CurrentSpend, budgetGuard.Spec.Budget.Currency, budgetGuard.Status.PercentageUsed)
 
-	// Get pods in scope
-	var pods corev1.PodList
-	opts := []client.ListOption{}
-	if budgetGuard.Spec.Scope == "namespace" && budgetGuard.Spec.Namespace != "" {
-		opts = append(opts, client.InNamespace(budgetGuard.Spec.Namespace))
-	}
+	r.recordEvent(ctx, budgetGuard, "Warning", "BudgetExceeded", message)
 
-	if err := r.List(ctx, &pods, opts...); err != nil {
+	channels, err := r.notificationChannels(ctx, notify, defaults)
+	if err != nil {
 		return err
 	}
-
-	// Evict pods with low priority (priorityClassName < 1000 or no priority class)
-	evictedCount := 0
-	for _, pod := range pods.Items {
-		// Check priority (simplified - in production, check PriorityClass resource)
-		priority := int32(0)
-		if pod.Spec.PriorityClassName != "" {
-			// In production, fetch PriorityClass to get actual priority value
-			// For now, assume pods without explicit priority are low priority
-		}
-
-		if priority < 1000 || pod.Spec.PriorityClassName == "" {
-			logger.Info("Evicting low priority pod due to budget exceed", "pod", pod.Name, "namespace", pod.Namespace)
-			if err := r.Delete(ctx, &pod); err != nil {
-				logger.Error(err, "Failed to evict pod", "pod", pod.Name)
-			} else {
-				evictedCount++
-			}
-		}
-	}
-
-	logger.Info("Evicted pods due to budget exceed", "count", evictedCount)
-	return nil
-}
-
-// sendNotification sends budget exceed notifications
-func (r *BudgetGuardReconciler) sendNotification(ctx context.Context, budgetGuard *aiopsv1alpha1.BudgetGuard) error {
-	notify := budgetGuard.Spec.ActionsOnExceed.Notify
-
-	// Send webhook notification
-	if notify.WebhookURL != "" {
-		// In production, send HTTP POST to webhook URL
-		// For now, just create a Kubernetes event
-		r.recordEvent(ctx, budgetGuard, "Warning", "BudgetExceeded",
-			fmt.Sprintf("Budget exceeded! Current spend: %.2f %s (%.1f%% of budget)",
-				budgetGuard.Status.CurrentSpend, budgetGuard.Spec.Budget.Currency, budgetGuard.Status.PercentageUsed))
+	text, err := notificationText(notify, budgetGuard, message)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return notifications.Send(ctx, channels, notifier.Message{
+		Title:    fmt.Sprintf("BudgetGuard %s exceeded", budgetGuard.Name),
+		Text:     text,
+		Severity: "critical",
+		Source:   "budget-guard",
+		Key:      fmt.Sprintf("budgetguard/%s", budgetGuard.Name),
+		Fields: map[string]string{
+			"scope":  budgetGuard.Spec.Scope,
+			"spend":  fmt.Sprintf("%.2f %s", budgetGuard.Status.CurrentSpend, budgetGuard.Spec.Budget.Currency),
+			"budget": fmt.Sprintf("%.2f %s", budgetGuard.Spec.Budget.Amount, budgetGuard.Spec.Budget.Currency),
+		},
+	})
 }
 
 // recordEvent records a Kubernetes event
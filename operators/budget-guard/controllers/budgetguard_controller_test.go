@@ -0,0 +1,135 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/budget-guard/api/v1alpha1"
+	"github.com/prophet-aiops/budget-guard/internal/builders"
+	"github.com/prophet-aiops/pkg/conditions"
+	"github.com/prophet-aiops/pkg/testenv"
+)
+
+// throttlingBudgetGuard creates a BudgetGuard throttling the namespace's HPAs, with an HPA to
+// throttle, against a fake OpenCost reporting spend
+func throttlingBudgetGuard(t *testing.T, c client.Client, spend float64) (*aiopsv1alpha1.BudgetGuard, *autoscalingv2.HorizontalPodAutoscaler, *testenv.OpenCost) {
+	t.Helper()
+	ctx := context.Background()
+	namespace := testEnv.Namespace(t)
+	openCost := testenv.NewOpenCost(t, map[string]float64{namespace: spend})
+
+	hpa := testenv.HorizontalPodAutoscaler(namespace, "web", 2, 10)
+	if err := c.Create(ctx, hpa); err != nil {
+		t.Fatal(err)
+	}
+	budgetGuard := builders.BudgetGuard(namespace, func(budgetGuard *aiopsv1alpha1.BudgetGuard) {
+		budgetGuard.Spec.OpenCostEndpoint = openCost.URL
+		budgetGuard.Spec.ActionsOnExceed.ThrottleScaling = true
+	})
+	if err := c.Create(ctx, budgetGuard); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		// Drop the finalizer a failed test may have left behind
+		_ = c.Patch(ctx, budgetGuard, client.RawPatch(types.MergePatchType, []byte(`{"metadata":{"finalizers":null}}`)))
+		_ = c.Delete(ctx, budgetGuard)
+	})
+	return budgetGuard, hpa, openCost
+}
+
+// TestReconcileThrottleScaling runs a BudgetGuard through exceeding its budget, throttling the HPAs
+// in scope, and restoring them once spend is back under budget
+func TestReconcileThrottleScaling(t *testing.T) {
+	c := testEnv.Require(t)
+	ctx := context.Background()
+	budgetGuard, hpa, openCost := throttlingBudgetGuard(t, c, 150)
+
+	r := &BudgetGuardReconciler{Client: c, Scheme: c.Scheme()}
+	reconcile := func() (*aiopsv1alpha1.BudgetGuard, *autoscalingv2.HorizontalPodAutoscaler) {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(budgetGuard)}); err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+		var gotBudgetGuard aiopsv1alpha1.BudgetGuard
+		if err := c.Get(ctx, client.ObjectKeyFromObject(budgetGuard), &gotBudgetGuard); err != nil {
+			t.Fatal(err)
+		}
+		var gotHPA autoscalingv2.HorizontalPodAutoscaler
+		if err := c.Get(ctx, client.ObjectKeyFromObject(hpa), &gotHPA); err != nil {
+			t.Fatal(err)
+		}
+		return &gotBudgetGuard, &gotHPA
+	}
+
+	got, gotHPA := reconcile()
+	if !got.Status.Exceeded || got.Status.CurrentSpend != 150 || got.Status.PercentageUsed != 150 {
+		t.Errorf("expected the budget to be exceeded, got status %+v", got.Status)
+	}
+	if len(got.Status.ActionsTaken) != 1 || got.Status.ActionsTaken[0] != "throttle-scaling" {
+		t.Errorf("got actions %v, want [throttle-scaling]", got.Status.ActionsTaken)
+	}
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, conditions.Remediating) {
+		t.Errorf("expected the %s condition to be true, got %+v", conditions.Remediating, got.Status.Conditions)
+	}
+	if !controllerutil.ContainsFinalizer(got, scalingFinalizer) {
+		t.Errorf("expected the %s finalizer, got %v", scalingFinalizer, got.Finalizers)
+	}
+	// No replicas are running, so the HPA is capped at its minimum
+	if gotHPA.Spec.MaxReplicas != 2 || gotHPA.Annotations[ThrottledByAnnotation] != budgetGuard.Name || gotHPA.Annotations[OriginalMaxReplicasAnnotation] != "10" {
+		t.Errorf("expected the HPA to be throttled to 2, got maxReplicas %d, annotations %v", gotHPA.Spec.MaxReplicas, gotHPA.Annotations)
+	}
+
+	openCost.SetCosts(map[string]float64{budgetGuard.Spec.Namespace: 50})
+	got, gotHPA = reconcile()
+	if got.Status.Exceeded || len(got.Status.ActionsTaken) != 0 {
+		t.Errorf("expected the budget to be back within limits, got status %+v", got.Status)
+	}
+	if meta.IsStatusConditionTrue(got.Status.Conditions, conditions.Triggered) {
+		t.Errorf("expected the %s condition to be false, got %+v", conditions.Triggered, got.Status.Conditions)
+	}
+	if controllerutil.ContainsFinalizer(got, scalingFinalizer) {
+		t.Errorf("expected the %s finalizer to be removed, got %v", scalingFinalizer, got.Finalizers)
+	}
+	if _, ok := gotHPA.Annotations[ThrottledByAnnotation]; ok || gotHPA.Spec.MaxReplicas != 10 {
+		t.Errorf("expected the HPA to be restored to 10, got maxReplicas %d, annotations %v", gotHPA.Spec.MaxReplicas, gotHPA.Annotations)
+	}
+}
+
+// TestReconcileDeletedBudgetGuard checks that a deleted BudgetGuard restores the HPAs it throttled
+// before it goes
+func TestReconcileDeletedBudgetGuard(t *testing.T) {
+	c := testEnv.Require(t)
+	ctx := context.Background()
+	budgetGuard, hpa, _ := throttlingBudgetGuard(t, c, 150)
+
+	r := &BudgetGuardReconciler{Client: c, Scheme: c.Scheme()}
+	req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(budgetGuard)}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	if err := c.Delete(ctx, budgetGuard); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+
+	var gotHPA autoscalingv2.HorizontalPodAutoscaler
+	if err := c.Get(ctx, client.ObjectKeyFromObject(hpa), &gotHPA); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := gotHPA.Annotations[ThrottledByAnnotation]; ok || gotHPA.Spec.MaxReplicas != 10 {
+		t.Errorf("expected the HPA to be restored to 10, got maxReplicas %d, annotations %v", gotHPA.Spec.MaxReplicas, gotHPA.Annotations)
+	}
+	if err := c.Get(ctx, req.NamespacedName, &aiopsv1alpha1.BudgetGuard{}); !apierrors.IsNotFound(err) {
+		t.Errorf("expected the BudgetGuard to be gone once its finalizer was removed, got %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/budget-guard/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/policy"
+	"github.com/prophet-aiops/pkg/prophetconfig"
+)
+
+const (
+	// defaultPriorityThreshold matches the value below which pods were always considered low priority
+	defaultPriorityThreshold = 1000
+
+	defaultMaxEvictionsPerCycle = 10
+)
+
+// evictLowPriorityPods evicts the pods in scope whose priority is below the threshold, lowest
+// priority first and at most maxEvictionsPerCycle of them. Evictions go through the Eviction API,
+// so pods whose PodDisruptionBudget allows no disruption are left running.
+func (r *BudgetGuardReconciler) evictLowPriorityPods(ctx context.Context, budgetGuard *aiopsv1alpha1.BudgetGuard, defaults prophetconfig.Spec) error {
+	logger := log.FromContext(ctx)
+	eviction := budgetGuard.Spec.ActionsOnExceed.Eviction
+	threshold := int32(defaultPriorityThreshold)
+	if eviction.PriorityThreshold != nil {
+		threshold = *eviction.PriorityThreshold
+	}
+	maxEvictions := int(eviction.MaxEvictionsPerCycle)
+	if maxEvictions <= 0 {
+		maxEvictions = defaultMaxEvictionsPerCycle
+	}
+
+	priorityOf, err := r.priorities(ctx)
+	if err != nil {
+		return err
+	}
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, scopeOptions(budgetGuard)...); err != nil {
+		return err
+	}
+
+	type candidate struct {
+		pod      *corev1.Pod
+		priority int32
+	}
+	var candidates []candidate
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !evictable(pod) || slices.Contains(eviction.ProtectedNamespaces, pod.Namespace) {
+			continue
+		}
+		if priority := priorityOf(pod); priority < threshold {
+			candidates = append(candidates, candidate{pod: pod, priority: priority})
+		}
+	}
+	// Lowest priority first; among equals the youngest pods, which lose the least work
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].priority != candidates[j].priority {
+			return candidates[i].priority < candidates[j].priority
+		}
+		return candidates[j].pod.CreationTimestamp.Before(&candidates[i].pod.CreationTimestamp)
+	})
+
+	evictedCount := 0
+	deniedCount := 0
+	blockedCount := 0
+	var lastDenied policy.Decision
+	for _, candidate := range candidates {
+		if evictedCount >= maxEvictions {
+			break
+		}
+		pod := candidate.pod
+		decision := r.checkGuardrails(ctx, defaults, policy.Action{
+			Type:      "EvictPod",
+			Kind:      "Pod",
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			Labels:    pod.Labels,
+			Reason:    fmt.Sprintf("BudgetGuard %s exceeded", budgetGuard.Name),
+		})
+		if !decision.Allowed {
+			deniedCount++
+			lastDenied = decision
+			continue
+		}
+
+		logger.Info("Evicting low priority pod due to budget exceed", "pod", pod.Name, "namespace", pod.Namespace, "priority", candidate.priority)
+		err := r.SubResource("eviction").Create(ctx, pod, &policyv1.Eviction{
+			ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		})
+		switch {
+		case err == nil:
+			evictedCount++
+		case apierrors.IsNotFound(err):
+			// Already gone
+		case apierrors.IsTooManyRequests(err):
+			// The pod's PodDisruptionBudget doesn't allow another disruption right now
+			blockedCount++
+		default:
+			logger.Error(err, "Failed to evict pod", "pod", pod.Name)
+		}
+	}
+
+	logger.Info("Evicted pods due to budget exceed", "count", evictedCount, "candidates", len(candidates),
+		"blockedByPDB", blockedCount, "denied", deniedCount)
+	if evictedCount > 0 || blockedCount > 0 {
+		r.recordEvent(ctx, budgetGuard, "Warning", "PodsEvicted",
+			fmt.Sprintf("Evicted %d of %d pod(s) with priority below %d; %d blocked by PodDisruptionBudgets", evictedCount, len(candidates), threshold, blockedCount))
+	}
+	if deniedCount > 0 {
+		r.recordEvent(ctx, budgetGuard, "Warning", "GuardrailDenied",
+			fmt.Sprintf("%d pod eviction(s) denied by guardrail policies: %s", deniedCount, lastDenied.Message))
+	}
+	return nil
+}
+
+// priorities returns a function giving a pod's priority: its spec.priority as set on admission,
+// otherwise the value of its PriorityClass or of the global default PriorityClass, otherwise 0
+func (r *BudgetGuardReconciler) priorities(ctx context.Context) (func(*corev1.Pod) int32, error) {
+	var classes schedulingv1.PriorityClassList
+	if err := r.List(ctx, &classes); err != nil {
+		return nil, err
+	}
+	values := make(map[string]int32, len(classes.Items))
+	globalDefault := int32(0)
+	for _, class := range classes.Items {
+		values[class.Name] = class.Value
+		if class.GlobalDefault {
+			globalDefault = class.Value
+		}
+	}
+	return func(pod *corev1.Pod) int32 {
+		if pod.Spec.Priority != nil {
+			return *pod.Spec.Priority
+		}
+		if value, ok := values[pod.Spec.PriorityClassName]; ok {
+			return value
+		}
+		return globalDefault
+	}, nil
+}
+
+// evictable reports whether evicting the pod can free anything: it's still running or pending, and
+// isn't a static pod or a DaemonSet pod, which would come straight back on the same node
+func evictable(pod *corev1.Pod) bool {
+	if pod.DeletionTimestamp != nil || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return false
+	}
+	if _, mirror := pod.Annotations[corev1.MirrorPodAnnotationKey]; mirror {
+		return false
+	}
+	if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "DaemonSet" {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/budget-guard/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/policy"
+	"github.com/prophet-aiops/pkg/prophetconfig"
+	"github.com/prophet-aiops/pkg/testenv"
+)
+
+// newPod returns a running pod with the given priority, created age ago
+func newPod(namespace, name string, priority int32, age time.Duration) *corev1.Pod {
+	pod := testenv.Pod(namespace, name, nil)
+	pod.CreationTimestamp = metav1.NewTime(time.Now().Add(-age))
+	pod.Spec.Priority = &priority
+	pod.Status.Phase = corev1.PodRunning
+	return pod
+}
+
+func TestEvictLowPriorityPods(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(aiopsv1alpha1.AddToScheme(scheme))
+
+	older := newPod("batch", "report-old", 10, time.Hour)
+	younger := newPod("batch", "report-new", 10, time.Minute)
+	preemptible := newPod("batch", "preemptible", 0, time.Hour)
+	preemptible.Spec.Priority = nil
+	preemptible.Spec.PriorityClassName = "preemptible"
+	unclassified := newPod("batch", "unclassified", 0, time.Hour)
+	unclassified.Spec.Priority = nil
+	important := newPod("batch", "important", 2000, time.Hour)
+	daemon := newPod("batch", "agent", 0, time.Hour)
+	daemon.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Name: "agent", Namespace: "batch"}}, appsv1.SchemeGroupVersion.WithKind("DaemonSet"))}
+	mirror := newPod("batch", "static", 0, time.Hour)
+	mirror.Annotations = map[string]string{corev1.MirrorPodAnnotationKey: "node-1"}
+	finished := newPod("batch", "finished", 0, time.Hour)
+	finished.Status.Phase = corev1.PodSucceeded
+	labeled := newPod("batch", "labeled", 0, time.Hour)
+	labeled.Labels = map[string]string{policy.ProtectedLabel: "true"}
+	system := newPod("kube-system", "coredns", 0, time.Hour)
+	operator := newPod("prophet-system", "budget-guard", 0, time.Hour)
+	reports := newPod("reports", "nightly", 0, time.Hour)
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: "preemptible"}, Value: -10},
+		&schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: "standard"}, Value: 500, GlobalDefault: true},
+		older, younger, preemptible, unclassified, important, daemon, mirror, finished, labeled, system, operator, reports,
+	).Build()
+	r := &BudgetGuardReconciler{Client: c, Scheme: scheme, ProtectedNamespaces: []string{"prophet-system"}}
+
+	maxEvictions := int32(2)
+	budgetGuard := &aiopsv1alpha1.BudgetGuard{
+		ObjectMeta: metav1.ObjectMeta{Name: "monthly"},
+		Spec: aiopsv1alpha1.BudgetGuardSpec{
+			Scope: "cluster",
+			ActionsOnExceed: aiopsv1alpha1.ActionsOnExceedSpec{
+				EvictLowPriorityWorkloads: true,
+				Eviction: aiopsv1alpha1.EvictionSpec{
+					MaxEvictionsPerCycle: maxEvictions,
+					ProtectedNamespaces:  []string{"reports"},
+				},
+			},
+		},
+	}
+
+	// The lowest priorities go first, the youngest first among equals
+	if err := r.evictLowPriorityPods(context.Background(), budgetGuard, prophetconfig.Spec{}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"agent", "budget-guard", "coredns", "finished", "important", "labeled", "nightly", "report-old", "static", "unclassified"}
+	assertPods(t, c, want)
+
+	if err := r.evictLowPriorityPods(context.Background(), budgetGuard, prophetconfig.Spec{}); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"agent", "budget-guard", "coredns", "finished", "important", "labeled", "nightly", "static"}
+	assertPods(t, c, want)
+
+	var events corev1.EventList
+	if err := c.List(context.Background(), &events); err != nil {
+		t.Fatal(err)
+	}
+	reasons := map[string]int{}
+	for _, event := range events.Items {
+		reasons[event.Reason]++
+	}
+	if reasons["PodsEvicted"] != 2 || reasons["GuardrailDenied"] != 2 {
+		t.Errorf("expected 2 PodsEvicted and 2 GuardrailDenied events, got %v", reasons)
+	}
+}
+
+func TestEvictLowPriorityPodsThreshold(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(aiopsv1alpha1.AddToScheme(scheme))
+
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newPod("shop", "web", 100, time.Hour),
+		newPod("shop", "batch", 49, time.Hour),
+		newPod("other", "batch", 0, time.Hour),
+	).Build()
+	r := &BudgetGuardReconciler{Client: c, Scheme: scheme}
+
+	threshold := int32(50)
+	budgetGuard := &aiopsv1alpha1.BudgetGuard{
+		ObjectMeta: metav1.ObjectMeta{Name: "shop"},
+		Spec: aiopsv1alpha1.BudgetGuardSpec{
+			Scope:     "namespace",
+			Namespace: "shop",
+			ActionsOnExceed: aiopsv1alpha1.ActionsOnExceedSpec{
+				EvictLowPriorityWorkloads: true,
+				Eviction:                  aiopsv1alpha1.EvictionSpec{PriorityThreshold: &threshold},
+			},
+		},
+	}
+	if err := r.evictLowPriorityPods(context.Background(), budgetGuard, prophetconfig.Spec{}); err != nil {
+		t.Fatal(err)
+	}
+	assertPods(t, c, []string{"batch", "web"})
+	var pod corev1.Pod
+	if err := c.Get(context.Background(), client.ObjectKey{Namespace: "shop", Name: "batch"}, &pod); err == nil {
+		t.Error("expected shop/batch to be evicted")
+	}
+}
+
+// assertPods checks the names of the pods left, in all namespaces
+func assertPods(t *testing.T, c client.Client, want []string) {
+	t.Helper()
+	var pods corev1.PodList
+	if err := c.List(context.Background(), &pods); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, pod := range pods.Items {
+		got = append(got, pod.Name)
+	}
+	sort.Strings(got)
+	if len(got) != len(want) {
+		t.Fatalf("got pods %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got pods %v, want %v", got, want)
+		}
+	}
+}
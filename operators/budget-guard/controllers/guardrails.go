@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/prophet-aiops/pkg/policy"
+	"github.com/prophet-aiops/pkg/prophetconfig"
+)
+
+// guardrails is shared by all reconciles so compiled policies are reused until the ConfigMap changes
+var guardrails = policy.NewGuard()
+
+// checkGuardrails evaluates the action against the protected targets and the guardrail policies.
+// If the policies can't be read the action is denied.
+func (r *BudgetGuardReconciler) checkGuardrails(ctx context.Context, defaults prophetconfig.Spec, action policy.Action) policy.Decision {
+	logger := log.FromContext(ctx)
+	action.Operator = "budget-guard"
+
+	if decision := policy.Protected(action, defaults.Protected(r.ProtectedNamespaces)); !decision.Allowed {
+		logger.Info("Action denied, target is protected", "action", action.Type, "namespace", action.Namespace, "name", action.Name, "rule", decision.Rule)
+		return decision
+	}
+
+	decision, err := guardrails.Check(ctx, r.Client, r.Guardrails, action)
+	if err != nil {
+		logger.Error(err, "Failed to read guardrail policies, denying action", "action", action.Type, "name", action.Name)
+		return policy.Decision{Message: "guardrail policies could not be read: " + err.Error()}
+	}
+	if !decision.Allowed {
+		logger.Info("Action denied by guardrail policy", "action", action.Type, "namespace", action.Namespace, "name", action.Name, "rule", decision.Rule)
+	}
+	return decision
+}
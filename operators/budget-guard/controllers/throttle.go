@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/budget-guard/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/policy"
+	"github.com/prophet-aiops/pkg/prophetconfig"
+)
+
+const (
+	// ThrottledByAnnotation names the BudgetGuard that throttled an HPA
+	ThrottledByAnnotation = "aiops.prophet.io/budget-throttled-by"
+
+	// OriginalMaxReplicasAnnotation records an HPA's maxReplicas from before it was throttled
+	OriginalMaxReplicasAnnotation = "aiops.prophet.io/budget-original-max-replicas"
+
+	// scalingFinalizer keeps a BudgetGuard around until the scaling it throttled is restored
+	scalingFinalizer = "aiops.prophet.io/restore-scaling"
+)
+
+// throttleScaling caps the HPAs in the budget's scope at their current replicas. The finalizer is
+// added first so a deleted BudgetGuard still restores them.
+func (r *BudgetGuardReconciler) throttleScaling(ctx context.Context, budgetGuard *aiopsv1alpha1.BudgetGuard, defaults prophetconfig.Spec) error {
+	if err := r.setScalingFinalizer(ctx, budgetGuard, true); err != nil {
+		return err
+	}
+
+	hpas, err := r.throttleHPAs(ctx, budgetGuard, defaults)
+	if err != nil {
+		return err
+	}
+	if hpas > 0 {
+		r.recordEvent(ctx, budgetGuard, "Warning", "ScalingThrottled",
+			fmt.Sprintf("Capped %d HorizontalPodAutoscaler(s) at their current replicas", hpas))
+	}
+	return nil
+}
+
+// throttleHPAs sets maxReplicas of the HPAs in scope to their current replicas, recording the
+// original value. HPAs throttled already, by this or another BudgetGuard, are left as they are.
+func (r *BudgetGuardReconciler) throttleHPAs(ctx context.Context, budgetGuard *aiopsv1alpha1.BudgetGuard, defaults prophetconfig.Spec) (int, error) {
+	logger := log.FromContext(ctx)
+
+	var hpas autoscalingv2.HorizontalPodAutoscalerList
+	if err := r.List(ctx, &hpas, scopeOptions(budgetGuard)...); err != nil {
+		return 0, err
+	}
+
+	throttled := 0
+	deniedCount := 0
+	var lastDenied policy.Decision
+	var errs []error
+	for i := range hpas.Items {
+		hpa := &hpas.Items[i]
+		if _, ok := hpa.Annotations[ThrottledByAnnotation]; ok {
+			continue
+		}
+		limit := max(hpa.Status.CurrentReplicas, 1)
+		if hpa.Spec.MinReplicas != nil {
+			limit = max(limit, *hpa.Spec.MinReplicas)
+		}
+		if limit >= hpa.Spec.MaxReplicas {
+			continue
+		}
+
+		decision := r.checkGuardrails(ctx, defaults, policy.Action{
+			Type:      "ThrottleScaling",
+			Kind:      "HorizontalPodAutoscaler",
+			Name:      hpa.Name,
+			Namespace: hpa.Namespace,
+			Labels:    hpa.Labels,
+			Reason:    fmt.Sprintf("BudgetGuard %s exceeded", budgetGuard.Name),
+		})
+		if !decision.Allowed {
+			deniedCount++
+			lastDenied = decision
+			continue
+		}
+
+		patch := client.MergeFrom(hpa.DeepCopy())
+		if hpa.Annotations == nil {
+			hpa.Annotations = map[string]string{}
+		}
+		hpa.Annotations[ThrottledByAnnotation] = budgetGuard.Name
+		hpa.Annotations[OriginalMaxReplicasAnnotation] = strconv.Itoa(int(hpa.Spec.MaxReplicas))
+		hpa.Spec.MaxReplicas = limit
+		if err := r.Patch(ctx, hpa, patch); err != nil {
+			errs = append(errs, fmt.Errorf("failed to throttle HPA %s/%s: %w", hpa.Namespace, hpa.Name, err))
+			continue
+		}
+		logger.Info("Throttled HPA due to budget exceed", "hpa", hpa.Name, "namespace", hpa.Namespace, "maxReplicas", limit)
+		throttled++
+	}
+
+	if deniedCount > 0 {
+		r.recordEvent(ctx, budgetGuard, "Warning", "GuardrailDenied",
+			fmt.Sprintf("%d HPA throttle(s) denied by guardrail policies: %s", deniedCount, lastDenied.Message))
+	}
+	return throttled, errors.Join(errs...)
+}
+
+// restoreScaling undoes the BudgetGuard's throttling wherever it was applied, also outside its
+// current scope, and drops the finalizer once everything is restored
+func (r *BudgetGuardReconciler) restoreScaling(ctx context.Context, budgetGuard *aiopsv1alpha1.BudgetGuard) error {
+	if !controllerutil.ContainsFinalizer(budgetGuard, scalingFinalizer) {
+		return nil
+	}
+	logger := log.FromContext(ctx)
+
+	var hpas autoscalingv2.HorizontalPodAutoscalerList
+	if err := r.List(ctx, &hpas); err != nil {
+		return err
+	}
+	restored := 0
+	var errs []error
+	for i := range hpas.Items {
+		hpa := &hpas.Items[i]
+		if hpa.Annotations[ThrottledByAnnotation] != budgetGuard.Name {
+			continue
+		}
+		patch := client.MergeFrom(hpa.DeepCopy())
+		if original, err := strconv.Atoi(hpa.Annotations[OriginalMaxReplicasAnnotation]); err == nil && original > 0 {
+			hpa.Spec.MaxReplicas = int32(original)
+		}
+		delete(hpa.Annotations, ThrottledByAnnotation)
+		delete(hpa.Annotations, OriginalMaxReplicasAnnotation)
+		if err := r.Patch(ctx, hpa, patch); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore HPA %s/%s: %w", hpa.Namespace, hpa.Name, err))
+			continue
+		}
+		logger.Info("Restored throttled HPA", "hpa", hpa.Name, "namespace", hpa.Namespace, "maxReplicas", hpa.Spec.MaxReplicas)
+		restored++
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	if restored > 0 {
+		r.recordEvent(ctx, budgetGuard, "Normal", "ScalingRestored",
+			fmt.Sprintf("Restored %d throttled HorizontalPodAutoscaler(s)", restored))
+	}
+	return r.setScalingFinalizer(ctx, budgetGuard, false)
+}
+
+// setScalingFinalizer adds or removes the finalizer. It patches a copy, so the status changes
+// being made to the BudgetGuard aren't replaced by the stored ones.
+func (r *BudgetGuardReconciler) setScalingFinalizer(ctx context.Context, budgetGuard *aiopsv1alpha1.BudgetGuard, present bool) error {
+	obj := budgetGuard.DeepCopy()
+	patch := client.MergeFrom(budgetGuard.DeepCopy())
+	var changed bool
+	if present {
+		changed = controllerutil.AddFinalizer(obj, scalingFinalizer)
+	} else {
+		changed = controllerutil.RemoveFinalizer(obj, scalingFinalizer)
+	}
+	if !changed {
+		return nil
+	}
+	if err := r.Patch(ctx, obj, patch); err != nil {
+		return err
+	}
+	budgetGuard.Finalizers = obj.Finalizers
+	return nil
+}
+
+// scopeOptions lists resources in the BudgetGuard's namespace, or in all namespaces for cluster scope
+func scopeOptions(budgetGuard *aiopsv1alpha1.BudgetGuard) []client.ListOption {
+	if budgetGuard.Spec.Scope == "namespace" && budgetGuard.Spec.Namespace != "" {
+		return []client.ListOption{client.InNamespace(budgetGuard.Spec.Namespace)}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/budget-guard/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/policy"
+	"github.com/prophet-aiops/pkg/prophetconfig"
+)
+
+// WebhookPath is where the resource blocking webhook is served
+const WebhookPath = "/validate-budget-guard"
+
+// BypassAnnotation lets a resource be created in an over-budget namespace in an emergency, if
+// the user creating it is allowed to bypass budgets
+const BypassAnnotation = "aiops.prophet.io/budget-bypass"
+
+//+kubebuilder:webhook:path=/validate-budget-guard,mutating=false,failurePolicy=ignore,sideEffects=None,groups=apps;batch,resources=deployments;statefulsets;jobs,verbs=create,versions=v1,name=vbudgetguard.aiops.prophet.io,admissionReviewVersions=v1
+
+// ResourceBlocker is a validating admission webhook that rejects new Deployments, StatefulSets
+// and Jobs in namespaces whose BudgetGuard is exceeded and has blockNewResources set
+type ResourceBlocker struct {
+	Client client.Client
+
+	// ProtectedNamespaces are never blocked, in addition to the system namespaces.
+	// They should include the operator's own namespace.
+	ProtectedNamespaces []string
+
+	// BypassUsers and BypassGroups may create resources annotated with BypassAnnotation while the
+	// budget is exceeded. Service accounts are users named system:serviceaccount:<namespace>:<name>.
+	// With neither set the annotation is ignored.
+	BypassUsers  []string
+	BypassGroups []string
+}
+
+// SetupWebhookWithManager registers the webhook with the manager's webhook server
+func (w *ResourceBlocker) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(WebhookPath, &webhook.Admission{Handler: w})
+	return nil
+}
+
+// Handle denies the creation if a BudgetGuard covering the namespace blocks new resources
+func (w *ResourceBlocker) Handle(ctx context.Context, req admission.Request) admission.Response {
+	logger := log.FromContext(ctx)
+	if req.Operation != admissionv1.Create {
+		return admission.Allowed("")
+	}
+
+	var budgetGuards aiopsv1alpha1.BudgetGuardList
+	if err := w.Client.List(ctx, &budgetGuards); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	var blocking *aiopsv1alpha1.BudgetGuard
+	for i := range budgetGuards.Items {
+		if blocks(&budgetGuards.Items[i], req.Namespace) {
+			blocking = &budgetGuards.Items[i]
+			break
+		}
+	}
+	if blocking == nil {
+		return admission.Allowed("")
+	}
+
+	var obj metav1.PartialObjectMetadata
+	if err := json.Unmarshal(req.Object.Raw, &obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	name := obj.Name
+	if name == "" {
+		name = obj.GenerateName
+	}
+	kind := req.Kind.Kind
+
+	// Protected namespaces are never blocked. The protected label isn't honored here: whoever
+	// creates the object sets its labels, so it would let anyone past the budget.
+	defaults, err := prophetconfig.Get(ctx, w.Client)
+	if err != nil {
+		logger.Error(err, "Failed to read ProphetConfig, using built-in defaults")
+	}
+	if decision := policy.Protected(policy.Action{
+		Type:      "BlockResource",
+		Kind:      kind,
+		Name:      name,
+		Namespace: req.Namespace,
+	}, defaults.Protected(w.ProtectedNamespaces)); !decision.Allowed {
+		return admission.Allowed("")
+	}
+
+	if obj.Annotations[BypassAnnotation] == "true" {
+		if w.canBypass(req.UserInfo) {
+			logger.Info("Budget block bypassed", "budgetGuard", blocking.Name, "kind", kind, "namespace", req.Namespace, "name", name, "user", req.UserInfo.Username)
+			return admission.Allowed("").WithWarnings(fmt.Sprintf("BudgetGuard %s is exceeded; created anyway because of the %s annotation", blocking.Name, BypassAnnotation))
+		}
+		logger.Info("Budget bypass refused", "budgetGuard", blocking.Name, "kind", kind, "namespace", req.Namespace, "name", name, "user", req.UserInfo.Username)
+		return admission.Denied(fmt.Sprintf("BudgetGuard %s is exceeded, so new %s are blocked in namespace %s, and %s may not bypass budgets with the %s annotation",
+			blocking.Name, req.Resource.Resource, req.Namespace, req.UserInfo.Username, BypassAnnotation))
+	}
+
+	logger.Info("Blocked new resource, budget exceeded", "budgetGuard", blocking.Name, "kind", kind, "namespace", req.Namespace, "name", name, "user", req.UserInfo.Username)
+	return admission.Denied(fmt.Sprintf("BudgetGuard %s is exceeded (%.2f of %.2f %s spent), so new %s are blocked in namespace %s. "+
+		"Raise the budget, or have a user allowed to bypass budgets annotate the %s with %s=true to create it anyway in an emergency",
+		blocking.Name, blocking.Status.CurrentSpend, blocking.Spec.Budget.Amount, blocking.Spec.Budget.Currency,
+		req.Resource.Resource, req.Namespace, strings.ToLower(kind), BypassAnnotation))
+}
+
+// canBypass reports whether the user is allowed to bypass budgets
+func (w *ResourceBlocker) canBypass(user authenticationv1.UserInfo) bool {
+	if slices.Contains(w.BypassUsers, user.Username) {
+		return true
+	}
+	for _, group := range user.Groups {
+		if slices.Contains(w.BypassGroups, group) {
+			return true
+		}
+	}
+	return false
+}
+
+// blocks reports whether the BudgetGuard blocks new resources in the namespace
+func blocks(budgetGuard *aiopsv1alpha1.BudgetGuard, namespace string) bool {
+	if !budgetGuard.Spec.ActionsOnExceed.BlockNewResources || !budgetGuard.Status.Exceeded {
+		return false
+	}
+	return budgetGuard.Spec.Scope == "cluster" || budgetGuard.Spec.Namespace == namespace
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/budget-guard/api/v1alpha1"
+	"github.com/prophet-aiops/budget-guard/internal/builders"
+	"github.com/prophet-aiops/pkg/policy"
+	"github.com/prophet-aiops/pkg/testenv"
+)
+
+// createDeployment returns the admission request creating a Deployment in namespace
+func createDeployment(t *testing.T, namespace string, labels, annotations map[string]string, user authenticationv1.UserInfo) admission.Request {
+	t.Helper()
+	deployment := testenv.Deployment(namespace, "web")
+	deployment.Labels = labels
+	deployment.Annotations = annotations
+	raw, err := json.Marshal(deployment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Kind:      metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+		Resource:  metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+		Namespace: namespace,
+		UserInfo:  user,
+		Object:    runtime.RawExtension{Raw: raw},
+	}}
+}
+
+func TestResourceBlocker(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(aiopsv1alpha1.AddToScheme(scheme))
+
+	blockNewResources := func(budgetGuard *aiopsv1alpha1.BudgetGuard) {
+		budgetGuard.Spec.ActionsOnExceed.BlockNewResources = true
+	}
+	exceeded := builders.BudgetGuard("shop", blockNewResources, func(budgetGuard *aiopsv1alpha1.BudgetGuard) {
+		budgetGuard.Status.Exceeded = true
+	})
+	within := builders.BudgetGuard("blog", blockNewResources)
+	blocker := &ResourceBlocker{
+		Client:       fake.NewClientBuilder().WithScheme(scheme).WithObjects(exceeded, within).Build(),
+		BypassUsers:  []string{"system:serviceaccount:ops:breakglass"},
+		BypassGroups: []string{"sre"},
+	}
+
+	bypass := map[string]string{BypassAnnotation: "true"}
+	developer := authenticationv1.UserInfo{Username: "dev@example.com", Groups: []string{"developers"}}
+	tests := map[string]struct {
+		req     admission.Request
+		allowed bool
+	}{
+		"exceeded budget": {
+			req: createDeployment(t, "shop", nil, nil, developer),
+		},
+		"budget within limits": {
+			req:     createDeployment(t, "blog", nil, nil, developer),
+			allowed: true,
+		},
+		"no budget": {
+			req:     createDeployment(t, "docs", nil, nil, developer),
+			allowed: true,
+		},
+		"bypass by an unlisted user": {
+			req: createDeployment(t, "shop", nil, bypass, developer),
+		},
+		"bypass by a listed user": {
+			req:     createDeployment(t, "shop", nil, bypass, authenticationv1.UserInfo{Username: "system:serviceaccount:ops:breakglass"}),
+			allowed: true,
+		},
+		"bypass by a listed group": {
+			req:     createDeployment(t, "shop", nil, bypass, authenticationv1.UserInfo{Username: "oncall@example.com", Groups: []string{"sre"}}),
+			allowed: true,
+		},
+		"labeled protected by its creator": {
+			req: createDeployment(t, "shop", map[string]string{policy.ProtectedLabel: "true"}, nil, developer),
+		},
+		"listed user without the annotation": {
+			req: createDeployment(t, "shop", nil, nil, authenticationv1.UserInfo{Username: "oncall@example.com", Groups: []string{"sre"}}),
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			response := blocker.Handle(context.Background(), tt.req)
+			if response.Allowed != tt.allowed {
+				t.Errorf("got allowed %v, want %v: %v", response.Allowed, tt.allowed, response.Result)
+			}
+		})
+	}
+}
+
+func TestResourceBlockerWithoutBypassUsers(t *testing.T) {
+	blocker := &ResourceBlocker{}
+	if blocker.canBypass(authenticationv1.UserInfo{Username: "system:admin", Groups: []string{"system:masters"}}) {
+		t.Error("expected nobody to bypass budgets when no users or groups are configured")
+	}
+}
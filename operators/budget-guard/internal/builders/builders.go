@@ -0,0 +1,27 @@
+// Package builders builds BudgetGuards for tests. Builders return valid objects that options
+// change.
+package builders
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/budget-guard/api/v1alpha1"
+)
+
+// BudgetGuard returns a BudgetGuard, named after namespace, of a 100 USD monthly budget for the
+// namespace with no actions on exceed
+func BudgetGuard(namespace string, options ...func(*aiopsv1alpha1.BudgetGuard)) *aiopsv1alpha1.BudgetGuard {
+	budgetGuard := &aiopsv1alpha1.BudgetGuard{
+		ObjectMeta: metav1.ObjectMeta{Name: namespace},
+		Spec: aiopsv1alpha1.BudgetGuardSpec{
+			Budget:    aiopsv1alpha1.BudgetLimit{Amount: 100, Currency: "USD"},
+			Scope:     "namespace",
+			Namespace: namespace,
+			Period:    "monthly",
+		},
+	}
+	for _, option := range options {
+		option(budgetGuard)
+	}
+	return budgetGuard
+}
@@ -4,13 +4,27 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// AcknowledgedByAnnotation acknowledges a triggered alert, naming the user who accepted it.
+	// Notifications are suppressed while the acknowledgement is active. The operator removes the
+	// acknowledgement annotations once the alert is back within its threshold.
+	AcknowledgedByAnnotation = "aiops.prophet.io/acknowledged-by"
+
+	// AcknowledgedUntilAnnotation is an optional RFC3339 expiry for the acknowledgement.
+	// Without it the acknowledgement lasts until the alert is back within its threshold.
+	AcknowledgedUntilAnnotation = "aiops.prophet.io/acknowledged-until"
+)
+
 // CostAlertSpec defines the desired state of CostAlert
+// +kubebuilder:validation:XValidation:rule="self.scope != 'workload' || has(self.workloadRef)",message="workloadRef is required when scope is workload"
+// +kubebuilder:validation:XValidation:rule="self.scope != 'namespace' || has(self.__namespace__)",message="namespace is required when scope is namespace"
+// +kubebuilder:validation:XValidation:rule="self.scope != 'label' || has(self.label)",message="label is required when scope is label"
 type CostAlertSpec struct {
 	// Threshold defines the cost threshold that triggers an alert
 	Threshold ThresholdSpec `json:"threshold"`
 
-	// Scope defines the scope of the alert: "workload", "namespace", or "cluster"
-	// +kubebuilder:validation:Enum=workload;namespace;cluster
+	// Scope defines the scope of the alert: "workload", "namespace", "cluster", or "label"
+	// +kubebuilder:validation:Enum=workload;namespace;cluster;label
 	Scope string `json:"scope"`
 
 	// WorkloadRef references a specific workload (required if scope is "workload")
@@ -19,6 +33,9 @@ type CostAlertSpec struct {
 	// Namespace is the namespace to monitor (required if scope is "namespace")
 	Namespace string `json:"namespace,omitempty"`
 
+	// Label selects the allocation label to aggregate by (required if scope is "label")
+	Label *LabelScope `json:"label,omitempty"`
+
 	// Period is the time period for cost calculation: "hourly", "daily", "weekly", "monthly"
 	// +kubebuilder:validation:Enum=hourly;daily;weekly;monthly
 	// +kubebuilder:default=daily
@@ -34,21 +51,44 @@ type CostAlertSpec struct {
 	// Default: http://opencost.opencost.svc.cluster.local:9003
 	OpenCostEndpoint string `json:"openCostEndpoint,omitempty"`
 
+	// CostProvider configures TLS and authentication for the cost provider API
+	CostProvider *CostProviderSpec `json:"costProvider,omitempty"`
+
 	// CheckIntervalSeconds is how often to check costs (in seconds)
 	// Default: 3600 (1 hour)
+	// +kubebuilder:validation:Minimum=60
 	// +kubebuilder:default=3600
 	CheckIntervalSeconds int32 `json:"checkIntervalSeconds,omitempty"`
+
+	// SilenceWindows are time ranges during which notifications are suppressed.
+	// Costs are still checked and status is still updated while silenced.
+	SilenceWindows []SilenceWindow `json:"silenceWindows,omitempty"`
+}
+
+// SilenceWindow is a time range during which notifications are suppressed
+type SilenceWindow struct {
+	// Start is when the silence begins
+	Start metav1.Time `json:"start"`
+
+	// End is when the silence ends
+	End metav1.Time `json:"end"`
+
+	// Reason describes why the alert is silenced (e.g., "planned load test")
+	Reason string `json:"reason,omitempty"`
 }
 
 // ThresholdSpec defines the cost threshold
+// +kubebuilder:validation:XValidation:rule="!has(self.minSamples) || !has(self.historySize) || self.minSamples <= self.historySize",message="minSamples must not exceed historySize"
 type ThresholdSpec struct {
-	// Type is the threshold type: "percentage_increase" or "absolute"
-	// +kubebuilder:validation:Enum=percentage_increase;absolute
+	// Type is the threshold type: "percentage_increase", "absolute", or "anomaly"
+	// +kubebuilder:validation:Enum=percentage_increase;absolute;anomaly
 	Type string `json:"type"`
 
 	// Value is the threshold value
 	// For percentage_increase: percentage increase (e.g., 50 means 50% increase)
 	// For absolute: absolute cost amount (e.g., 100.50 means $100.50)
+	// For anomaly: anomaly score (e.g., 3 means 3 standard deviations from the mean)
+	// +kubebuilder:validation:Minimum=0
 	Value float64 `json:"value"`
 
 	// Currency is the currency unit (USD, EUR, etc.)
@@ -59,6 +99,26 @@ type ThresholdSpec struct {
 	// BaselinePeriod is the period to compare against for percentage_increase
 	// Default: previous period (e.g., previous day for daily, previous month for monthly)
 	BaselinePeriod string `json:"baselinePeriod,omitempty"`
+
+	// AnomalyMethod is the statistical method used when type is "anomaly": "zscore" or "mad"
+	// zscore compares against the mean and standard deviation of the history,
+	// mad compares against the median absolute deviation and is more robust to outliers
+	// Default: zscore
+	// +kubebuilder:validation:Enum=zscore;mad
+	// +kubebuilder:default=zscore
+	AnomalyMethod string `json:"anomalyMethod,omitempty"`
+
+	// HistorySize is the number of cost samples kept for anomaly detection
+	// Default: 24
+	// +kubebuilder:validation:Minimum=2
+	// +kubebuilder:default=24
+	HistorySize int32 `json:"historySize,omitempty"`
+
+	// MinSamples is the number of samples required before anomalies are flagged
+	// Default: 6
+	// +kubebuilder:validation:Minimum=2
+	// +kubebuilder:default=6
+	MinSamples int32 `json:"minSamples,omitempty"`
 }
 
 // WorkloadRef references a Kubernetes workload
@@ -76,6 +136,48 @@ type WorkloadRef struct {
 	Namespace string `json:"namespace"`
 }
 
+// LabelScope aggregates cost by a label key/value (e.g., team=payments)
+type LabelScope struct {
+	// Key is the label key allocations are aggregated by (e.g., "team")
+	// +kubebuilder:validation:MinLength=1
+	Key string `json:"key"`
+
+	// Value is the label value to alert on (e.g., "payments")
+	// If empty, the total across all values of the label is used
+	Value string `json:"value,omitempty"`
+}
+
+// CostProviderSpec configures authenticated and TLS access to the cost provider API
+type CostProviderSpec struct {
+	// Type is the cost provider: "opencost" or "kubecost"
+	// Default: opencost
+	// +kubebuilder:validation:Enum=opencost;kubecost
+	// +kubebuilder:default=opencost
+	Type string `json:"type,omitempty"`
+
+	// CABundle is a PEM encoded CA bundle used to verify the provider's TLS certificate
+	CABundle string `json:"caBundle,omitempty"`
+
+	// InsecureSkipVerify disables TLS certificate verification (not recommended)
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+
+	// AuthSecretRef references a Secret holding credentials for the provider.
+	// Recognized keys: "token" (bearer token), "username" and "password" (basic auth),
+	// "apiKey" (Kubecost API key), "ca.crt" (CA bundle, overrides caBundle),
+	// and "tls.crt" and "tls.key" (client certificate for mutual TLS).
+	// The Secret must be in the CostAlert's namespace.
+	AuthSecretRef *SecretReference `json:"authSecretRef,omitempty"`
+}
+
+// SecretReference references a Secret
+type SecretReference struct {
+	// Name of the Secret
+	Name string `json:"name"`
+
+	// Namespace of the Secret (optional); it must be the CostAlert namespace
+	Namespace string `json:"namespace,omitempty"`
+}
+
 // AlertRuleRef references a PrometheusRule
 type AlertRuleRef struct {
 	// Name of the PrometheusRule
@@ -95,6 +197,50 @@ type NotifySpec struct {
 
 	// EmailRecipients is a list of email addresses to notify
 	EmailRecipients []string `json:"emailRecipients,omitempty"`
+
+	// Channels are additional notification destinations
+	Channels []NotificationChannel `json:"channels,omitempty"`
+
+	// Template is a Go text/template for the notification text, rendered against the CostAlert
+	// (e.g., "{{ .Name }} is over budget"). If empty, a default message is used.
+	Template string `json:"template,omitempty"`
+}
+
+// NotificationChannel is a notification destination
+type NotificationChannel struct {
+	// Type is the channel type: "webhook", "slack", "teams", "pagerduty", "opsgenie", or "email"
+	// +kubebuilder:validation:Enum=webhook;slack;teams;pagerduty;opsgenie;email
+	Type string `json:"type"`
+
+	// URL is the webhook URL (webhook, slack, teams) or an API endpoint override (pagerduty, opsgenie)
+	URL string `json:"url,omitempty"`
+
+	// SecretRef references a Secret holding channel credentials.
+	// Recognized keys: "url" (overrides url), "token" (webhook bearer token, PagerDuty routing key
+	// or Opsgenie API key), "username" and "password" (SMTP authentication)
+	SecretRef *SecretReference `json:"secretRef,omitempty"`
+
+	// SMTP configures the mail server (required if type is "email")
+	SMTP *SMTPSpec `json:"smtp,omitempty"`
+}
+
+// SMTPSpec configures an SMTP mail server
+type SMTPSpec struct {
+	// Host is the SMTP server host
+	Host string `json:"host"`
+
+	// Port is the SMTP server port
+	// Default: 587
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=587
+	Port int32 `json:"port,omitempty"`
+
+	// From is the sender address
+	From string `json:"from"`
+
+	// To is the list of recipients (defaults to emailRecipients)
+	To []string `json:"to,omitempty"`
 }
 
 // CostAlertStatus defines the observed state of CostAlert
@@ -108,7 +254,8 @@ type CostAlertStatus struct {
 	// PreviousCost is the previous period's cost (for percentage_increase comparison)
 	PreviousCost float64 `json:"previousCost,omitempty"`
 
-	// ThresholdValue is the threshold value that triggered the alert
+	// ThresholdValue is the threshold value that triggered the alert. For percentage_increase it
+	// is the observed increase; for anomaly it is the configured score, see AnomalyScore.
 	ThresholdValue float64 `json:"thresholdValue,omitempty"`
 
 	// LastTriggeredTime is when the alert was last triggered
@@ -120,11 +267,48 @@ type CostAlertStatus struct {
 	// LastCheckTime is when the cost was last checked
 	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
 
-	// Conditions represent the latest available observations
+	// ObservedGeneration is the most recent generation the operator has reconciled
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations, including the standard Ready, Progressing and Degraded conditions
+	// +listType=map
+	// +listMapKey=type
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 
 	// ErrorMessage contains any error message from the last check
 	ErrorMessage string `json:"errorMessage,omitempty"`
+
+	// CostHistory is the rolling window of cost samples used for anomaly detection. Anomalous
+	// costs are recorded capped at the threshold, so they don't skew the baseline.
+	CostHistory []CostSample `json:"costHistory,omitempty"`
+
+	// AnomalyScore is the anomaly score of the current cost against CostHistory: a z-score, or a
+	// MAD-based score with anomalyMethod "mad". Only set for the anomaly threshold type.
+	AnomalyScore float64 `json:"anomalyScore,omitempty"`
+
+	// Silenced indicates notifications are currently suppressed by a silence window or acknowledgement
+	Silenced bool `json:"silenced,omitempty"`
+
+	// Acknowledgement is the active acknowledgement, if any
+	Acknowledgement *Acknowledgement `json:"acknowledgement,omitempty"`
+}
+
+// Acknowledgement records who accepted a triggered alert and until when
+type Acknowledgement struct {
+	// By is the user who acknowledged the alert
+	By string `json:"by"`
+
+	// Until is when the acknowledgement expires (unset means until the alert resolves)
+	Until *metav1.Time `json:"until,omitempty"`
+}
+
+// CostSample is a single cost observation
+type CostSample struct {
+	// Timestamp is when the cost was observed
+	Timestamp metav1.Time `json:"timestamp"`
+
+	// Cost is the observed cost for the period
+	Cost float64 `json:"cost"`
 }
 
 //+kubebuilder:object:root=true
@@ -132,7 +316,9 @@ type CostAlertStatus struct {
 //+kubebuilder:printcolumn:name="Scope",type="string",JSONPath=".spec.scope"
 //+kubebuilder:printcolumn:name="Threshold",type="string",JSONPath=".spec.threshold.type + ': ' + .spec.threshold.value"
 //+kubebuilder:printcolumn:name="Current Cost",type="number",JSONPath=".status.currentCost"
+//+kubebuilder:printcolumn:name="Anomaly Score",type="number",JSONPath=".status.anomalyScore",priority=1
 //+kubebuilder:printcolumn:name="Triggered",type="boolean",JSONPath=".status.triggered"
+//+kubebuilder:printcolumn:name="Silenced",type="boolean",JSONPath=".status.silenced"
 //+kubebuilder:printcolumn:name="Last Triggered",type="date",JSONPath=".status.lastTriggeredTime"
 //+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
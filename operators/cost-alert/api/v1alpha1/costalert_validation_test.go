@@ -0,0 +1,82 @@
+package v1alpha1_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/cost-alert/api/v1alpha1"
+	"github.com/prophet-aiops/cost-alert/internal/builders"
+)
+
+// TestValidationRules checks the CRD's CEL validation rules against the API server
+func TestValidationRules(t *testing.T) {
+	c := testEnv.Require(t)
+	namespace := testEnv.Namespace(t)
+	anomaly := func(minSamples, historySize int32) aiopsv1alpha1.ThresholdSpec {
+		return aiopsv1alpha1.ThresholdSpec{Type: "anomaly", Value: 3, MinSamples: minSamples, HistorySize: historySize}
+	}
+
+	tests := map[string]struct {
+		modify func(*aiopsv1alpha1.CostAlertSpec)
+		error  string
+	}{
+		"namespace": {
+			modify: func(*aiopsv1alpha1.CostAlertSpec) {},
+		},
+		"cluster": {
+			modify: func(spec *aiopsv1alpha1.CostAlertSpec) {
+				spec.Scope = "cluster"
+				spec.Namespace = ""
+			},
+		},
+		"namespace scope without namespace": {
+			modify: func(spec *aiopsv1alpha1.CostAlertSpec) { spec.Namespace = "" },
+			error:  "namespace is required when scope is namespace",
+		},
+		"workload": {
+			modify: func(spec *aiopsv1alpha1.CostAlertSpec) {
+				spec.Scope = "workload"
+				spec.WorkloadRef = &aiopsv1alpha1.WorkloadRef{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Namespace: "shop"}
+			},
+		},
+		"workload scope without workloadRef": {
+			modify: func(spec *aiopsv1alpha1.CostAlertSpec) { spec.Scope = "workload" },
+			error:  "workloadRef is required when scope is workload",
+		},
+		"label scope without label": {
+			modify: func(spec *aiopsv1alpha1.CostAlertSpec) { spec.Scope = "label" },
+			error:  "label is required when scope is label",
+		},
+		"anomaly": {
+			modify: func(spec *aiopsv1alpha1.CostAlertSpec) { spec.Threshold = anomaly(12, 48) },
+		},
+		"minSamples above historySize": {
+			modify: func(spec *aiopsv1alpha1.CostAlertSpec) { spec.Threshold = anomaly(12, 6) },
+			error:  "minSamples must not exceed historySize",
+		},
+		"minSamples above the default historySize": {
+			modify: func(spec *aiopsv1alpha1.CostAlertSpec) { spec.Threshold = anomaly(30, 0) },
+			error:  "minSamples must not exceed historySize",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			costAlert := builders.CostAlert(namespace, func(costAlert *aiopsv1alpha1.CostAlert) {
+				costAlert.Name = ""
+				costAlert.GenerateName = "daily-"
+				tt.modify(&costAlert.Spec)
+			})
+
+			err := c.Create(context.Background(), costAlert)
+			switch {
+			case tt.error == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.error != "" && err == nil:
+				t.Errorf("expected %q", tt.error)
+			case tt.error != "" && !strings.Contains(err.Error(), tt.error):
+				t.Errorf("expected %q, got %v", tt.error, err)
+			}
+		})
+	}
+}
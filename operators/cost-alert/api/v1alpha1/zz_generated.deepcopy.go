@@ -9,6 +9,25 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Acknowledgement) DeepCopyInto(out *Acknowledgement) {
+	*out = *in
+	if in.Until != nil {
+		in, out := &in.Until, &out.Until
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Acknowledgement.
+func (in *Acknowledgement) DeepCopy() *Acknowledgement {
+	if in == nil {
+		return nil
+	}
+	out := new(Acknowledgement)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AlertRuleRef) DeepCopyInto(out *AlertRuleRef) {
 	*out = *in
@@ -92,12 +111,29 @@ func (in *CostAlertSpec) DeepCopyInto(out *CostAlertSpec) {
 		*out = new(WorkloadRef)
 		**out = **in
 	}
+	if in.Label != nil {
+		in, out := &in.Label, &out.Label
+		*out = new(LabelScope)
+		**out = **in
+	}
 	in.Notify.DeepCopyInto(&out.Notify)
 	if in.AlertRuleRef != nil {
 		in, out := &in.AlertRuleRef, &out.AlertRuleRef
 		*out = new(AlertRuleRef)
 		**out = **in
 	}
+	if in.CostProvider != nil {
+		in, out := &in.CostProvider, &out.CostProvider
+		*out = new(CostProviderSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SilenceWindows != nil {
+		in, out := &in.SilenceWindows, &out.SilenceWindows
+		*out = make([]SilenceWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CostAlertSpec.
@@ -128,6 +164,18 @@ func (in *CostAlertStatus) DeepCopyInto(out *CostAlertStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.CostHistory != nil {
+		in, out := &in.CostHistory, &out.CostHistory
+		*out = make([]CostSample, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Acknowledgement != nil {
+		in, out := &in.Acknowledgement, &out.Acknowledgement
+		*out = new(Acknowledgement)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CostAlertStatus.
@@ -140,6 +188,82 @@ func (in *CostAlertStatus) DeepCopy() *CostAlertStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CostProviderSpec) DeepCopyInto(out *CostProviderSpec) {
+	*out = *in
+	if in.AuthSecretRef != nil {
+		in, out := &in.AuthSecretRef, &out.AuthSecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CostProviderSpec.
+func (in *CostProviderSpec) DeepCopy() *CostProviderSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CostProviderSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CostSample) DeepCopyInto(out *CostSample) {
+	*out = *in
+	in.Timestamp.DeepCopyInto(&out.Timestamp)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CostSample.
+func (in *CostSample) DeepCopy() *CostSample {
+	if in == nil {
+		return nil
+	}
+	out := new(CostSample)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LabelScope) DeepCopyInto(out *LabelScope) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LabelScope.
+func (in *LabelScope) DeepCopy() *LabelScope {
+	if in == nil {
+		return nil
+	}
+	out := new(LabelScope)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NotificationChannel) DeepCopyInto(out *NotificationChannel) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(SecretReference)
+		**out = **in
+	}
+	if in.SMTP != nil {
+		in, out := &in.SMTP, &out.SMTP
+		*out = new(SMTPSpec)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotificationChannel.
+func (in *NotificationChannel) DeepCopy() *NotificationChannel {
+	if in == nil {
+		return nil
+	}
+	out := new(NotificationChannel)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NotifySpec) DeepCopyInto(out *NotifySpec) {
 	*out = *in
@@ -148,6 +272,13 @@ func (in *NotifySpec) DeepCopyInto(out *NotifySpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Channels != nil {
+		in, out := &in.Channels, &out.Channels
+		*out = make([]NotificationChannel, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NotifySpec.
@@ -160,6 +291,58 @@ func (in *NotifySpec) DeepCopy() *NotifySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SMTPSpec) DeepCopyInto(out *SMTPSpec) {
+	*out = *in
+	if in.To != nil {
+		in, out := &in.To, &out.To
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SMTPSpec.
+func (in *SMTPSpec) DeepCopy() *SMTPSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SMTPSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecretReference) DeepCopyInto(out *SecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretReference.
+func (in *SecretReference) DeepCopy() *SecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SilenceWindow) DeepCopyInto(out *SilenceWindow) {
+	*out = *in
+	in.Start.DeepCopyInto(&out.Start)
+	in.End.DeepCopyInto(&out.End)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SilenceWindow.
+func (in *SilenceWindow) DeepCopy() *SilenceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(SilenceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ThresholdSpec) DeepCopyInto(out *ThresholdSpec) {
 	*out = *in
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
 
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
@@ -10,6 +11,7 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -17,6 +19,7 @@ import (
 
 	aiopsv1alpha1 "github.com/prophet-aiops/cost-alert/api/v1alpha1"
 	"github.com/prophet-aiops/cost-alert/controllers"
+	"github.com/prophet-aiops/pkg/httpclient"
 )
 
 var (
@@ -33,11 +36,14 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var watchNamespaces string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", os.Getenv("WATCH_NAMESPACE"),
+		"Comma-separated namespaces to watch, defaulting to $WATCH_NAMESPACE. Leave empty to watch all namespaces.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -46,6 +52,12 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	// Fail fast on bad PROPHET_HTTP_* settings rather than on the first outbound request
+	if _, err := httpclient.New(httpclient.Config{}); err != nil {
+		setupLog.Error(err, "invalid outbound HTTP settings")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
 		Metrics: metricsserver.Options{
@@ -55,6 +67,7 @@ func main() {
 			Port: 9443,
 		}),
 		HealthProbeBindAddress: probeAddr,
+		Cache:                  cacheOptions(watchNamespaces),
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "cost-alert.prophet.io",
 	})
@@ -87,3 +100,24 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// cacheOptions restricts the manager's cache to the comma-separated watch namespaces.
+// Extra namespaces the operator reads from are added in namespace-scoped mode.
+// With no watch namespaces every namespace is watched.
+func cacheOptions(watchNamespaces string, extra ...string) cache.Options {
+	namespaces := map[string]cache.Config{}
+	for _, namespace := range strings.Split(watchNamespaces, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces[namespace] = cache.Config{}
+		}
+	}
+	if len(namespaces) == 0 {
+		return cache.Options{}
+	}
+	for _, namespace := range extra {
+		if namespace != "" {
+			namespaces[namespace] = cache.Config{}
+		}
+	}
+	return cache.Options{DefaultNamespaces: namespaces}
+}
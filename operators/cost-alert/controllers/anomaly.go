@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/cost-alert/api/v1alpha1"
+)
+
+// madScale converts a median absolute deviation into a standard deviation estimate
+// for normally distributed data, so both methods use comparable thresholds
+const madScale = 1.4826
+
+// anomalyScore scores the current cost against the recorded history.
+// It returns false when there are not enough samples to judge.
+func anomalyScore(history []aiopsv1alpha1.CostSample, current float64, method string, minSamples int32) (float64, bool) {
+	center, spread, ok := baseline(history, method, minSamples)
+	if !ok {
+		return 0, false
+	}
+	return (current - center) / spread, true
+}
+
+// cappedCost returns the cost to record in the history. Costs more than limit spreads from the
+// baseline are capped there, so a spike doesn't inflate the baseline later costs are scored
+// against, while a lasting change still moves it.
+func cappedCost(history []aiopsv1alpha1.CostSample, cost float64, method string, minSamples int32, limit float64) float64 {
+	center, spread, ok := baseline(history, method, minSamples)
+	if !ok || limit <= 0 {
+		return cost
+	}
+	return math.Min(math.Max(cost, center-limit*spread), center+limit*spread)
+}
+
+// baseline returns the center and spread of the history: the mean and standard deviation for
+// zscore, the median and scaled median absolute deviation for mad. It returns false when there
+// are not enough samples to judge.
+func baseline(history []aiopsv1alpha1.CostSample, method string, minSamples int32) (float64, float64, bool) {
+	if minSamples < 2 {
+		minSamples = 6
+	}
+	if int32(len(history)) < minSamples {
+		return 0, 0, false
+	}
+
+	values := make([]float64, 0, len(history))
+	for _, sample := range history {
+		values = append(values, sample.Cost)
+	}
+
+	switch method {
+	case "mad":
+		median := medianOf(values)
+		deviations := make([]float64, 0, len(values))
+		for _, v := range values {
+			deviations = append(deviations, math.Abs(v-median))
+		}
+		return median, math.Max(medianOf(deviations)*madScale, minSpread(median)), true
+
+	default:
+		mean := 0.0
+		for _, v := range values {
+			mean += v
+		}
+		mean /= float64(len(values))
+
+		variance := 0.0
+		for _, v := range values {
+			variance += (v - mean) * (v - mean)
+		}
+		return mean, math.Max(math.Sqrt(variance/float64(len(values))), minSpread(mean)), true
+	}
+}
+
+// minSpread is the smallest spread used when scoring, so that a perfectly flat
+// history doesn't turn every small change into an infinite score
+func minSpread(center float64) float64 {
+	return math.Max(math.Abs(center)*0.01, 0.01)
+}
+
+// appendCostSample records a new sample, trimming the history to historySize.
+// Samples closer together than minInterval are dropped so that reconciles
+// triggered by status updates don't flood the window.
+func appendCostSample(history []aiopsv1alpha1.CostSample, cost float64, now metav1.Time, historySize int32, minInterval time.Duration) []aiopsv1alpha1.CostSample {
+	if historySize < 2 {
+		historySize = 24
+	}
+	if n := len(history); n > 0 && now.Sub(history[n-1].Timestamp.Time) < minInterval {
+		return history
+	}
+
+	history = append(history, aiopsv1alpha1.CostSample{Timestamp: now, Cost: cost})
+	if extra := len(history) - int(historySize); extra > 0 {
+		history = history[extra:]
+	}
+	return history
+}
+
+// medianOf returns the median of values without modifying the input
+func medianOf(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n == 0 {
+		return 0
+	}
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/cost-alert/api/v1alpha1"
+)
+
+// costHistory returns hourly samples with the given costs
+func costHistory(costs ...float64) []aiopsv1alpha1.CostSample {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	history := make([]aiopsv1alpha1.CostSample, 0, len(costs))
+	for i, cost := range costs {
+		history = append(history, aiopsv1alpha1.CostSample{Timestamp: metav1.NewTime(start.Add(time.Duration(i) * time.Hour)), Cost: cost})
+	}
+	return history
+}
+
+func TestAnomalyScore(t *testing.T) {
+	history := costHistory(10, 12, 8, 10, 12, 8)
+	tests := map[string]struct {
+		history    []aiopsv1alpha1.CostSample
+		current    float64
+		method     string
+		minSamples int32
+		want       float64
+		ok         bool
+	}{
+		"zscore": {history: history, current: 14, method: "zscore", want: 4 / math.Sqrt(8.0/3), ok: true},
+		"mad":    {history: history, current: 14, method: "mad", want: 4 / (2 * madScale), ok: true},
+		"below the mean": {
+			history: history, current: 6, method: "zscore", want: -4 / math.Sqrt(8.0/3), ok: true,
+		},
+		"too few samples": {history: history[:5], current: 100},
+		"minSamples":      {history: history[:3], current: 14, minSamples: 3, want: 4 / math.Sqrt(8.0/3), ok: true},
+		"flat history": {
+			history: costHistory(100, 100, 100, 100, 100, 100), current: 101, method: "mad", want: 1, ok: true,
+		},
+		"flat zero history": {
+			history: costHistory(0, 0, 0, 0, 0, 0), current: 0.01, want: 1, ok: true,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			score, ok := anomalyScore(tt.history, tt.current, tt.method, tt.minSamples)
+			if ok != tt.ok {
+				t.Fatalf("got ok %v, want %v", ok, tt.ok)
+			}
+			if math.Abs(score-tt.want) > 1e-9 {
+				t.Errorf("got score %v, want %v", score, tt.want)
+			}
+		})
+	}
+}
+
+func TestCappedCost(t *testing.T) {
+	// Mean 10, standard deviation 2
+	history := costHistory(8, 12, 8, 12, 8, 12)
+	tests := map[string]struct {
+		history []aiopsv1alpha1.CostSample
+		cost    float64
+		limit   float64
+		want    float64
+	}{
+		"within the limit": {history: history, cost: 14, limit: 3, want: 14},
+		"spike":            {history: history, cost: 100, limit: 3, want: 16},
+		"drop":             {history: history, cost: 0, limit: 3, want: 4},
+		"too few samples":  {history: history[:5], cost: 100, limit: 3, want: 100},
+		"no limit":         {history: history, cost: 100, want: 100},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := cappedCost(tt.history, tt.cost, "zscore", 0, tt.limit); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCappedSpikeKeepsBaseline checks that a spike recorded in the history doesn't hide the next one
+func TestCappedSpikeKeepsBaseline(t *testing.T) {
+	history := costHistory(8, 12, 8, 12, 8, 12)
+	last := history[len(history)-1].Timestamp
+	history = appendCostSample(history, cappedCost(history, 100, "zscore", 0, 3), metav1.NewTime(last.Add(time.Hour)), 24, time.Hour)
+
+	if score, _ := anomalyScore(history, 100, "zscore", 0); score < 3 {
+		t.Errorf("expected a second spike to still score above 3, got %v", score)
+	}
+}
+
+func TestAppendCostSample(t *testing.T) {
+	history := costHistory(1, 2, 3)
+	last := history[len(history)-1].Timestamp
+
+	if got := appendCostSample(history, 4, metav1.NewTime(last.Add(time.Minute)), 3, time.Hour); len(got) != 3 || got[2].Cost != 3 {
+		t.Errorf("expected a sample within minInterval to be dropped, got %+v", got)
+	}
+
+	got := appendCostSample(history, 4, metav1.NewTime(last.Add(time.Hour)), 3, time.Hour)
+	if len(got) != 3 || got[0].Cost != 2 || got[2].Cost != 4 {
+		t.Errorf("expected the oldest sample to be trimmed, got %+v", got)
+	}
+
+	if got := appendCostSample(nil, 1, last, 0, time.Hour); len(got) != 1 {
+		t.Errorf("expected the first sample to be recorded, got %+v", got)
+	}
+}
+
+func TestMedianOf(t *testing.T) {
+	values := []float64{5, 1, 4, 2}
+	if got := medianOf(values); got != 3 {
+		t.Errorf("got median %v, want 3", got)
+	}
+	if values[0] != 5 || values[1] != 1 {
+		t.Errorf("medianOf modified its input: %v", values)
+	}
+	if got := medianOf([]float64{3, 1, 2}); got != 2 {
+		t.Errorf("got median %v, want 2", got)
+	}
+	if got := medianOf(nil); got != 0 {
+		t.Errorf("got median %v of no values, want 0", got)
+	}
+}
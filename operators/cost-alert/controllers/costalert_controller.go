@@ -2,21 +2,26 @@ package controllers
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	aiopsv1alpha1 "github.com/prophet-aiops/cost-alert/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/conditions"
+	"github.com/prophet-aiops/pkg/costprovider"
+	"github.com/prophet-aiops/pkg/notifier"
+	"github.com/prophet-aiops/pkg/prophetconfig"
+	"github.com/prophet-aiops/pkg/status"
 )
 
 // CostAlertReconciler reconciles a CostAlert object
@@ -29,6 +34,8 @@ type CostAlertReconciler struct {
 //+kubebuilder:rbac:groups=aiops.prophet.io,resources=costalerts,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=aiops.prophet.io,resources=costalerts/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=aiops.prophet.io,resources=costalerts/finalizers,verbs=update
+//+kubebuilder:rbac:groups=aiops.prophet.io,resources=prophetconfigs,verbs=get
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop
@@ -37,21 +44,39 @@ func (r *CostAlertReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	var costAlert aiopsv1alpha1.CostAlert
 	if err := r.Get(ctx, req.NamespacedName, &costAlert); err != nil {
+		if apierrors.IsNotFound(err) {
+			deleteMetrics(req.Namespace, req.Name)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	// Status changes are patched against the CostAlert as read
+	base := costAlert.DeepCopy()
 
 	logger.Info("Reconciling CostAlert", "name", req.Name, "scope", costAlert.Spec.Scope)
 
+	// Cluster-wide defaults; settings on the CostAlert take precedence
+	defaults, err := prophetconfig.Get(ctx, r.Client)
+	if err != nil {
+		logger.Error(err, "Failed to read ProphetConfig, using built-in defaults")
+	}
+
 	// Fetch current cost
-	currentCost, err := r.fetchCostData(ctx, &costAlert)
+	currentCost, err := r.fetchCostData(ctx, &costAlert, defaults)
 	if err != nil {
 		logger.Error(err, "Failed to fetch cost data")
 		costAlert.Status.ErrorMessage = err.Error()
-		if err := r.Status().Update(ctx, &costAlert); err != nil {
+		costAlert.Status.ObservedGeneration = costAlert.Generation
+		conditions.Apply(&costAlert.Status.Conditions, costAlert.Generation, conditions.Summary{
+			Degraded: true,
+			Reason:   "CostDataUnavailable",
+			Message:  err.Error(),
+		})
+		if err := status.Patch(ctx, r.Client, &costAlert, base); err != nil {
 			return ctrl.Result{}, err
 		}
 		return ctrl.Result{RequeueAfter: 1 * time.Minute}, nil
 	}
+	costAlert.Status.ErrorMessage = ""
 
 	now := metav1.Now()
 	costAlert.Status.LastCheckTime = &now
@@ -79,7 +104,41 @@ func (r *CostAlertReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		if currentCost >= thresholdValue {
 			triggered = true
 		}
+
+	case "anomaly":
+		// Score against history before recording the current sample
+		threshold := costAlert.Spec.Threshold
+		score, ok := anomalyScore(costAlert.Status.CostHistory, currentCost, threshold.AnomalyMethod, threshold.MinSamples)
+		costAlert.Status.AnomalyScore = score
+		if ok && score >= thresholdValue {
+			triggered = true
+		}
+		sample := cappedCost(costAlert.Status.CostHistory, currentCost, threshold.AnomalyMethod, threshold.MinSamples, thresholdValue)
+		costAlert.Status.CostHistory = appendCostSample(costAlert.Status.CostHistory, sample, now,
+			threshold.HistorySize, checkInterval(&costAlert))
+	}
+
+	// Check acknowledgement and silence windows
+	ack, err := acknowledgement(&costAlert)
+	if err != nil {
+		logger.Error(err, "Ignoring invalid acknowledgement")
+	}
+	if !triggered && costAlert.Annotations[aiopsv1alpha1.AcknowledgedByAnnotation] != "" {
+		// Acknowledgements don't carry over to the next trigger
+		if err := r.clearAcknowledgement(ctx, &costAlert); err != nil {
+			logger.Error(err, "Failed to clear acknowledgement")
+		} else {
+			logger.Info("Cleared acknowledgement, cost is back within threshold", "by", costAlert.Annotations[aiopsv1alpha1.AcknowledgedByAnnotation])
+			ack = nil
+		}
 	}
+	silence := silenceReason(&costAlert, ack, now)
+	if ack != nil && ack.Until != nil && !now.Before(ack.Until) {
+		// Expired acknowledgements are not reported
+		ack = nil
+	}
+	costAlert.Status.Acknowledgement = ack
+	costAlert.Status.Silenced = silence != ""
 
 	// Update triggered status
 	if triggered && !costAlert.Status.Triggered {
@@ -89,132 +148,215 @@ func (r *CostAlertReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		costAlert.Status.TriggerCount++
 		costAlert.Status.ThresholdValue = thresholdValue
 
-		// Send notifications
-		if err := r.sendAlert(ctx, &costAlert); err != nil {
+		// Send notifications unless silenced
+		if silence != "" {
+			logger.Info("Suppressing cost alert notification", "reason", silence)
+			r.recordEvent(ctx, &costAlert, "Normal", "NotificationSuppressed",
+				fmt.Sprintf("Cost threshold exceeded but notification suppressed (%s)", silence))
+		} else if err := r.sendAlert(ctx, &costAlert, defaults); err != nil {
 			logger.Error(err, "Failed to send alert")
 		}
 	} else if !triggered {
+		if costAlert.Status.Triggered && silence == "" {
+			message := fmt.Sprintf("Cost back within threshold. Current: %.2f %s", currentCost, costAlert.Spec.Threshold.Currency)
+			if err := r.notify(ctx, &costAlert, defaults, message, true); err != nil {
+				logger.Error(err, "Failed to send resolved notification")
+			}
+		}
 		costAlert.Status.Triggered = false
 	}
 
 	// Update conditions
-	condition := metav1.Condition{
-		Type:               "AlertStatus",
-		Status:             metav1.ConditionFalse,
-		Reason:             "WithinThreshold",
-		Message:            fmt.Sprintf("Current cost: %.2f %s", currentCost, costAlert.Spec.Threshold.Currency),
-		LastTransitionTime: now,
-	}
+	message := fmt.Sprintf("Current cost: %.2f %s", currentCost, costAlert.Spec.Threshold.Currency)
 	if triggered {
-		condition.Status = metav1.ConditionTrue
-		condition.Reason = "ThresholdExceeded"
-		condition.Message = fmt.Sprintf("Cost threshold exceeded! Current: %.2f %s, Threshold: %.2f", currentCost, costAlert.Spec.Threshold.Currency, thresholdValue)
+		exceededMessage := exceededMessage(&costAlert, thresholdValue)
+		conditions.Set(&costAlert.Status.Conditions, costAlert.Generation, "AlertStatus", true, "ThresholdExceeded", exceededMessage)
+		conditions.Set(&costAlert.Status.Conditions, costAlert.Generation, conditions.Triggered, true, "ThresholdExceeded", exceededMessage)
+	} else {
+		conditions.Set(&costAlert.Status.Conditions, costAlert.Generation, "AlertStatus", false, "WithinThreshold", message)
+		conditions.Set(&costAlert.Status.Conditions, costAlert.Generation, conditions.Triggered, false, "WithinThreshold", message)
 	}
-	costAlert.Status.Conditions = []metav1.Condition{condition}
+	switch {
+	case ack != nil && silence != "":
+		conditions.Set(&costAlert.Status.Conditions, costAlert.Generation, "Silenced", true, "Acknowledged", fmt.Sprintf("Notifications suppressed: %s", silence))
+	case silence != "":
+		conditions.Set(&costAlert.Status.Conditions, costAlert.Generation, "Silenced", true, "Silenced", fmt.Sprintf("Notifications suppressed: %s", silence))
+	default:
+		conditions.Set(&costAlert.Status.Conditions, costAlert.Generation, "Silenced", false, "NotSilenced", "Notifications are enabled")
+	}
+	costAlert.Status.ObservedGeneration = costAlert.Generation
+	conditions.Apply(&costAlert.Status.Conditions, costAlert.Generation, conditions.Summary{Message: message})
 
 	// Update status
-	if err := r.Status().Update(ctx, &costAlert); err != nil {
+	if err := status.Patch(ctx, r.Client, &costAlert, base); err != nil {
 		return ctrl.Result{}, err
 	}
+	recordMetrics(&costAlert)
 
 	// Requeue after check interval
-	checkInterval := time.Duration(costAlert.Spec.CheckIntervalSeconds) * time.Second
-	if checkInterval == 0 {
-		checkInterval = 1 * time.Hour
-	}
-	return ctrl.Result{RequeueAfter: checkInterval}, nil
+	return ctrl.Result{RequeueAfter: checkInterval(&costAlert)}, nil
 }
 
-// fetchCostData fetches cost data from OpenCost/Kubecost API
-func (r *CostAlertReconciler) fetchCostData(ctx context.Context, costAlert *aiopsv1alpha1.CostAlert) (float64, error) {
-	endpoint := costAlert.Spec.OpenCostEndpoint
-	if endpoint == "" {
-		endpoint = "http://opencost.opencost.svc.cluster.local:9003"
+// checkInterval returns how often costs are checked for the alert
+func checkInterval(costAlert *aiopsv1alpha1.CostAlert) time.Duration {
+	interval := time.Duration(costAlert.Spec.CheckIntervalSeconds) * time.Second
+	if interval == 0 {
+		interval = 1 * time.Hour
 	}
+	return interval
+}
 
+// fetchCostData fetches cost data from OpenCost/Kubecost API
+func (r *CostAlertReconciler) fetchCostData(ctx context.Context, costAlert *aiopsv1alpha1.CostAlert, defaults prophetconfig.Spec) (float64, error) {
 	// Build query based on scope
-	var url string
+	params := url.Values{"window": []string{"1d"}}
 	switch costAlert.Spec.Scope {
 	case "workload":
 		if costAlert.Spec.WorkloadRef == nil {
 			return 0, fmt.Errorf("workloadRef is required for workload-scoped alert")
 		}
 		ref := costAlert.Spec.WorkloadRef
-		url = fmt.Sprintf("%s/allocation?window=1d&aggregate=controller&controller=%s&namespace=%s",
-			endpoint, ref.Name, ref.Namespace)
+		params.Set("aggregate", "controller")
+		params.Set("controller", ref.Name)
+		params.Set("namespace", ref.Namespace)
 	case "namespace":
 		if costAlert.Spec.Namespace == "" {
 			return 0, fmt.Errorf("namespace is required for namespace-scoped alert")
 		}
-		url = fmt.Sprintf("%s/allocation?window=1d&aggregate=namespace&namespace=%s",
-			endpoint, costAlert.Spec.Namespace)
+		params.Set("aggregate", "namespace")
+		params.Set("namespace", costAlert.Spec.Namespace)
 	case "cluster":
-		url = fmt.Sprintf("%s/allocation?window=1d&aggregate=cluster", endpoint)
+		params.Set("aggregate", "cluster")
+	case "label":
+		if costAlert.Spec.Label == nil || costAlert.Spec.Label.Key == "" {
+			return 0, fmt.Errorf("label.key is required for label-scoped alert")
+		}
+		params.Set("aggregate", "label:"+costAlert.Spec.Label.Key)
 	default:
 		return 0, fmt.Errorf("unsupported scope: %s", costAlert.Spec.Scope)
 	}
 
-	// Make HTTP request
-	client := &http.Client{Timeout: 10 * time.Second}
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	config, err := r.costProviderConfig(ctx, costAlert, defaults)
+	if err != nil {
+		return 0, err
+	}
+	provider, err := costprovider.NewClient(config)
 	if err != nil {
 		return 0, err
 	}
 
-	resp, err := client.Do(req)
+	costs, err := provider.Allocation(ctx, params)
 	if err != nil {
-		// If OpenCost is not available, return a mock value for testing
-		return 0, fmt.Errorf("failed to fetch cost data (OpenCost may not be deployed): %w", err)
+		return 0, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return 0, fmt.Errorf("OpenCost API returned status %d: %s", resp.StatusCode, string(body))
+	// Keep only the requested label value for label scope
+	if costAlert.Spec.Scope == "label" {
+		delete(costs, "__unallocated__")
+		if value := costAlert.Spec.Label.Value; value != "" {
+			return costs[value], nil
+		}
 	}
 
-	// Parse response (simplified)
-	var data map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return 0, err
+	return costprovider.Total(costs), nil
+}
+
+// costProviderConfig builds the cost provider client configuration, reading
+// credentials from the referenced Secret if one is set
+func (r *CostAlertReconciler) costProviderConfig(ctx context.Context, costAlert *aiopsv1alpha1.CostAlert, defaults prophetconfig.Spec) (costprovider.Config, error) {
+	config := costprovider.Config{Endpoint: costAlert.Spec.OpenCostEndpoint}
+	if config.Endpoint == "" {
+		config.Endpoint = defaults.CostProvider.Endpoint
 	}
 
-	// Extract total cost
-	totalCost := 0.0
-	if allocations, ok := data["data"].(map[string]interface{}); ok {
-		for _, allocation := range allocations {
-			if alloc, ok := allocation.(map[string]interface{}); ok {
-				if cost, ok := alloc["totalCost"].(float64); ok {
-					totalCost += cost
-				}
-			}
+	provider := costAlert.Spec.CostProvider
+	if provider == nil {
+		return config, nil
+	}
+	config.CABundle = []byte(provider.CABundle)
+	config.InsecureSkipVerify = provider.InsecureSkipVerify
+
+	if provider.AuthSecretRef != nil {
+		// Secrets are only read from the CostAlert's own namespace, so creating a CostAlert
+		// doesn't give access to credentials its author couldn't read anyway
+		namespace := costAlert.Namespace
+		if ref := provider.AuthSecretRef.Namespace; ref != "" && ref != namespace {
+			return config, fmt.Errorf("cost provider secret %s/%s must be in the CostAlert's namespace %s", ref, provider.AuthSecretRef.Name, namespace)
+		}
+		var secret corev1.Secret
+		if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: provider.AuthSecretRef.Name}, &secret); err != nil {
+			return config, fmt.Errorf("failed to get cost provider secret %s/%s: %w", namespace, provider.AuthSecretRef.Name, err)
+		}
+		config.BearerToken = string(secret.Data["token"])
+		config.Username = string(secret.Data["username"])
+		config.Password = string(secret.Data["password"])
+		config.APIKey = string(secret.Data["apiKey"])
+		if ca, ok := secret.Data["ca.crt"]; ok {
+			config.CABundle = ca
 		}
+		config.ClientCert = secret.Data["tls.crt"]
+		config.ClientKey = secret.Data["tls.key"]
 	}
 
-	return totalCost, nil
+	return config, nil
+}
+
+// exceededMessage describes an exceeded threshold. Anomaly alerts also report the score, which
+// is compared against the threshold in place of the cost.
+func exceededMessage(costAlert *aiopsv1alpha1.CostAlert, thresholdValue float64) string {
+	message := fmt.Sprintf("Cost threshold exceeded! Current: %.2f %s, Threshold: %.2f",
+		costAlert.Status.CurrentCost, costAlert.Spec.Threshold.Currency, thresholdValue)
+	if costAlert.Spec.Threshold.Type == "anomaly" {
+		message += fmt.Sprintf(", Anomaly score: %.2f", costAlert.Status.AnomalyScore)
+	}
+	return message
 }
 
 // sendAlert sends cost alert notifications
-func (r *CostAlertReconciler) sendAlert(ctx context.Context, costAlert *aiopsv1alpha1.CostAlert) error {
+func (r *CostAlertReconciler) sendAlert(ctx context.Context, costAlert *aiopsv1alpha1.CostAlert, defaults prophetconfig.Spec) error {
 	logger := log.FromContext(ctx)
-
-	// Send webhook notification
-	if costAlert.Spec.Notify.WebhookURL != "" {
-		// In production, send HTTP POST to webhook URL
-		logger.Info("Sending cost alert webhook", "url", costAlert.Spec.Notify.WebhookURL)
-	}
+	message := exceededMessage(costAlert, costAlert.Status.ThresholdValue)
 
 	// Create Kubernetes event
-	r.recordEvent(ctx, costAlert, "Warning", "CostThresholdExceeded",
-		fmt.Sprintf("Cost threshold exceeded! Current: %.2f %s, Threshold: %.2f",
-			costAlert.Status.CurrentCost, costAlert.Spec.Threshold.Currency, costAlert.Status.ThresholdValue))
+	r.recordEvent(ctx, costAlert, "Warning", "CostThresholdExceeded", message)
 
 	// In production, also trigger PrometheusRule if AlertRuleRef is set
 	if costAlert.Spec.AlertRuleRef != nil {
 		logger.Info("Cost alert would trigger PrometheusRule", "name", costAlert.Spec.AlertRuleRef.Name)
 	}
 
-	return nil
+	return r.notify(ctx, costAlert, defaults, message, false)
+}
+
+// notify sends a notification to the configured channels
+func (r *CostAlertReconciler) notify(ctx context.Context, costAlert *aiopsv1alpha1.CostAlert, defaults prophetconfig.Spec, message string, resolved bool) error {
+	notify := costAlert.Spec.Notify
+	if !notify.Enabled {
+		return nil
+	}
+
+	channels, err := r.notificationChannels(ctx, notify, defaults, costAlert.Namespace)
+	if err != nil {
+		return err
+	}
+	text, err := notificationText(notify, costAlert, message)
+	if err != nil {
+		return err
+	}
+
+	return notifications.Send(ctx, channels, notifier.Message{
+		Title:    fmt.Sprintf("CostAlert %s/%s", costAlert.Namespace, costAlert.Name),
+		Text:     text,
+		Severity: "warning",
+		Source:   "cost-alert",
+		Key:      fmt.Sprintf("costalert/%s/%s", costAlert.Namespace, costAlert.Name),
+		Resolved: resolved,
+		Fields: map[string]string{
+			"scope":       costAlert.Spec.Scope,
+			"currentCost": fmt.Sprintf("%.2f %s", costAlert.Status.CurrentCost, costAlert.Spec.Threshold.Currency),
+			"threshold":   fmt.Sprintf("%s %.2f", costAlert.Spec.Threshold.Type, costAlert.Spec.Threshold.Value),
+		},
+	})
 }
 
 // recordEvent records a Kubernetes event
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/cost-alert/api/v1alpha1"
+	"github.com/prophet-aiops/cost-alert/internal/builders"
+	"github.com/prophet-aiops/pkg/conditions"
+	"github.com/prophet-aiops/pkg/testenv"
+)
+
+// TestReconcileThreshold runs a CostAlert through trigger, notification and resolution against a
+// fake OpenCost
+func TestReconcileThreshold(t *testing.T) {
+	c := testEnv.Require(t)
+	ctx := context.Background()
+	namespace := testEnv.Namespace(t)
+	openCost := testenv.NewOpenCost(t, map[string]float64{namespace: 150})
+	webhook := testenv.NewWebhook(t)
+
+	costAlert := builders.CostAlert(namespace, builders.Webhook(webhook.URL), func(costAlert *aiopsv1alpha1.CostAlert) {
+		costAlert.Spec.OpenCostEndpoint = openCost.URL
+	})
+	if err := c.Create(ctx, costAlert); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &CostAlertReconciler{Client: c, Scheme: c.Scheme()}
+	key := types.NamespacedName{Namespace: namespace, Name: costAlert.Name}
+	reconcile := func() *aiopsv1alpha1.CostAlert {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+		var got aiopsv1alpha1.CostAlert
+		if err := c.Get(ctx, key, &got); err != nil {
+			t.Fatal(err)
+		}
+		return &got
+	}
+
+	got := reconcile()
+	if !got.Status.Triggered || got.Status.CurrentCost != 150 || got.Status.TriggerCount != 1 || got.Status.ThresholdValue != 100 {
+		t.Errorf("expected the alert to trigger, got status %+v", got.Status)
+	}
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, conditions.Triggered) {
+		t.Errorf("expected the %s condition to be true, got %+v", conditions.Triggered, got.Status.Conditions)
+	}
+	if requests := openCost.Requests(); len(requests) != 1 || requests[0].URL.Query().Get("namespace") != namespace {
+		t.Errorf("expected a namespace allocation query, got %v", requests)
+	}
+	requests := webhook.Requests()
+	if len(requests) != 1 || !strings.Contains(string(requests[0].Body), "Cost threshold exceeded") {
+		t.Fatalf("expected an alert notification, got %d requests", len(requests))
+	}
+
+	openCost.SetCosts(map[string]float64{namespace: 50})
+	got = reconcile()
+	if got.Status.Triggered || got.Status.CurrentCost != 50 || got.Status.TriggerCount != 1 {
+		t.Errorf("expected the alert to resolve, got status %+v", got.Status)
+	}
+	if meta.IsStatusConditionTrue(got.Status.Conditions, conditions.Triggered) {
+		t.Errorf("expected the %s condition to be false, got %+v", conditions.Triggered, got.Status.Conditions)
+	}
+	if requests := webhook.Requests(); len(requests) != 2 || !strings.Contains(string(requests[1].Body), "back within threshold") {
+		t.Errorf("expected a resolved notification, got %d requests", len(requests))
+	}
+}
+
+// TestReconcileCostDataUnavailable checks that an unreachable cost provider degrades the CostAlert
+func TestReconcileCostDataUnavailable(t *testing.T) {
+	c := testEnv.Require(t)
+	ctx := context.Background()
+	namespace := testEnv.Namespace(t)
+	openCost := testenv.NewOpenCost(t, nil)
+	openCost.Close()
+
+	costAlert := builders.CostAlert(namespace, func(costAlert *aiopsv1alpha1.CostAlert) {
+		costAlert.Spec.OpenCostEndpoint = openCost.URL
+	})
+	if err := c.Create(ctx, costAlert); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &CostAlertReconciler{Client: c, Scheme: c.Scheme()}
+	key := types.NamespacedName{Namespace: namespace, Name: costAlert.Name}
+	result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+	if err != nil || result.RequeueAfter == 0 {
+		t.Fatalf("expected a requeue without an error, got %+v, %v", result, err)
+	}
+	var got aiopsv1alpha1.CostAlert
+	if err := c.Get(ctx, key, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.ErrorMessage == "" || !meta.IsStatusConditionTrue(got.Status.Conditions, conditions.Degraded) {
+		t.Errorf("expected the CostAlert to be degraded, got status %+v", got.Status)
+	}
+}
+
+// TestReconcileAcknowledgementClearedOnResolve checks that an acknowledgement without an expiry
+// silences its trigger only, not the next one
+func TestReconcileAcknowledgementClearedOnResolve(t *testing.T) {
+	c := testEnv.Require(t)
+	ctx := context.Background()
+	namespace := testEnv.Namespace(t)
+	openCost := testenv.NewOpenCost(t, map[string]float64{namespace: 150})
+	webhook := testenv.NewWebhook(t)
+
+	costAlert := builders.CostAlert(namespace, builders.Webhook(webhook.URL), func(costAlert *aiopsv1alpha1.CostAlert) {
+		costAlert.Spec.OpenCostEndpoint = openCost.URL
+	})
+	if err := c.Create(ctx, costAlert); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &CostAlertReconciler{Client: c, Scheme: c.Scheme()}
+	key := types.NamespacedName{Namespace: namespace, Name: costAlert.Name}
+	reconcile := func() *aiopsv1alpha1.CostAlert {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+		var got aiopsv1alpha1.CostAlert
+		if err := c.Get(ctx, key, &got); err != nil {
+			t.Fatal(err)
+		}
+		return &got
+	}
+
+	got := reconcile()
+	got.Annotations = map[string]string{aiopsv1alpha1.AcknowledgedByAnnotation: "oncall@example.com"}
+	if err := c.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	got = reconcile()
+	if !got.Status.Silenced || got.Status.Acknowledgement == nil {
+		t.Errorf("expected the acknowledgement to silence the alert, got status %+v", got.Status)
+	}
+
+	openCost.SetCosts(map[string]float64{namespace: 50})
+	got = reconcile()
+	if _, ok := got.Annotations[aiopsv1alpha1.AcknowledgedByAnnotation]; ok || got.Status.Acknowledgement != nil || got.Status.Silenced {
+		t.Errorf("expected the acknowledgement to be cleared once resolved, got annotations %v, status %+v", got.Annotations, got.Status)
+	}
+	if requests := webhook.Requests(); len(requests) != 2 || !strings.Contains(string(requests[1].Body), "back within threshold") {
+		t.Errorf("expected a resolved notification, got %d requests", len(requests))
+	}
+
+	openCost.SetCosts(map[string]float64{namespace: 150})
+	got = reconcile()
+	if !got.Status.Triggered || got.Status.TriggerCount != 2 || got.Status.Silenced {
+		t.Errorf("expected the alert to trigger again unsilenced, got status %+v", got.Status)
+	}
+	if meta.IsStatusConditionTrue(got.Status.Conditions, "Silenced") {
+		t.Errorf("expected the Silenced condition to be false, got %+v", got.Status.Conditions)
+	}
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/cost-alert/api/v1alpha1"
+)
+
+var (
+	currentCostGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prophet_costalert_current_cost",
+		Help: "Current cost for the CostAlert period",
+	}, []string{"namespace", "name", "scope", "currency"})
+
+	previousCostGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prophet_costalert_previous_cost",
+		Help: "Previous period cost used as the percentage_increase baseline",
+	}, []string{"namespace", "name", "scope", "currency"})
+
+	thresholdGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prophet_costalert_threshold",
+		Help: "Configured threshold value of the CostAlert",
+	}, []string{"namespace", "name", "scope", "type"})
+
+	triggeredGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prophet_costalert_triggered",
+		Help: "Whether the CostAlert is triggered (1) or not (0)",
+	}, []string{"namespace", "name", "scope"})
+)
+
+func init() {
+	// Registered with the controller-runtime registry so they are served on the manager's metrics endpoint
+	metrics.Registry.MustRegister(currentCostGauge, previousCostGauge, thresholdGauge, triggeredGauge)
+}
+
+// recordMetrics updates the exported gauges from the CostAlert status
+func recordMetrics(costAlert *aiopsv1alpha1.CostAlert) {
+	// Drop old series first so spec changes (scope, currency, type) don't leave stale ones behind
+	deleteMetrics(costAlert.Namespace, costAlert.Name)
+
+	spec := costAlert.Spec
+	currentCostGauge.WithLabelValues(costAlert.Namespace, costAlert.Name, spec.Scope, spec.Threshold.Currency).
+		Set(costAlert.Status.CurrentCost)
+	previousCostGauge.WithLabelValues(costAlert.Namespace, costAlert.Name, spec.Scope, spec.Threshold.Currency).
+		Set(costAlert.Status.PreviousCost)
+	thresholdGauge.WithLabelValues(costAlert.Namespace, costAlert.Name, spec.Scope, spec.Threshold.Type).
+		Set(spec.Threshold.Value)
+
+	triggered := 0.0
+	if costAlert.Status.Triggered {
+		triggered = 1
+	}
+	triggeredGauge.WithLabelValues(costAlert.Namespace, costAlert.Name, spec.Scope).Set(triggered)
+}
+
+// deleteMetrics removes all series for a CostAlert
+func deleteMetrics(namespace, name string) {
+	labels := prometheus.Labels{"namespace": namespace, "name": name}
+	currentCostGauge.DeletePartialMatch(labels)
+	previousCostGauge.DeletePartialMatch(labels)
+	thresholdGauge.DeletePartialMatch(labels)
+	triggeredGauge.DeletePartialMatch(labels)
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/cost-alert/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/notifier"
+	"github.com/prophet-aiops/pkg/prophetconfig"
+)
+
+// notifications is shared by all reconciles so rate limiting spans the whole controller
+var notifications = notifier.New(notifier.Options{})
+
+// notificationChannels resolves the notify spec into channels, reading credentials from Secrets.
+// Without any channels the ProphetConfig default webhook is used.
+func (r *CostAlertReconciler) notificationChannels(ctx context.Context, notify aiopsv1alpha1.NotifySpec, defaults prophetconfig.Spec, namespace string) ([]notifier.Channel, error) {
+	channels := []notifier.Channel{}
+	if notify.WebhookURL != "" {
+		channels = append(channels, notifier.Channel{Type: "webhook", URL: notify.WebhookURL})
+	}
+
+	for _, spec := range notify.Channels {
+		channel := notifier.Channel{Type: spec.Type, URL: spec.URL, To: notify.EmailRecipients}
+		if spec.SMTP != nil {
+			channel.SMTPHost = spec.SMTP.Host
+			channel.SMTPPort = spec.SMTP.Port
+			channel.From = spec.SMTP.From
+			if len(spec.SMTP.To) > 0 {
+				channel.To = spec.SMTP.To
+			}
+		}
+
+		if ref := spec.SecretRef; ref != nil {
+			// Channel credentials are only read from the CostAlert's own namespace
+			if ref.Namespace != "" && ref.Namespace != namespace {
+				return nil, fmt.Errorf("notification secret %s/%s must be in the CostAlert's namespace %s", ref.Namespace, ref.Name, namespace)
+			}
+			var secret corev1.Secret
+			if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, &secret); err != nil {
+				return nil, fmt.Errorf("failed to get notification secret %s/%s: %w", namespace, ref.Name, err)
+			}
+			if url, ok := secret.Data["url"]; ok {
+				channel.URL = string(url)
+			}
+			channel.Token = string(secret.Data["token"])
+			channel.Username = string(secret.Data["username"])
+			channel.Password = string(secret.Data["password"])
+		}
+
+		channels = append(channels, channel)
+	}
+	if len(channels) == 0 && defaults.Notify.WebhookURL != "" {
+		channels = append(channels, notifier.Channel{Type: "webhook", URL: defaults.Notify.WebhookURL})
+	}
+	return channels, nil
+}
+
+// notificationText renders the notify template against obj, or returns text if no template is set
+func notificationText(notify aiopsv1alpha1.NotifySpec, obj interface{}, text string) (string, error) {
+	if notify.Template == "" {
+		return text, nil
+	}
+	return notifier.Render(notify.Template, obj)
+}
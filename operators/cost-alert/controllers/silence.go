@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/cost-alert/api/v1alpha1"
+)
+
+// acknowledgement reads the acknowledgement annotations from the CostAlert.
+// It returns nil when the alert is not acknowledged.
+func acknowledgement(costAlert *aiopsv1alpha1.CostAlert) (*aiopsv1alpha1.Acknowledgement, error) {
+	by := costAlert.Annotations[aiopsv1alpha1.AcknowledgedByAnnotation]
+	if by == "" {
+		return nil, nil
+	}
+
+	ack := &aiopsv1alpha1.Acknowledgement{By: by}
+	if value := costAlert.Annotations[aiopsv1alpha1.AcknowledgedUntilAnnotation]; value != "" {
+		until, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation %q: %w", aiopsv1alpha1.AcknowledgedUntilAnnotation, value, err)
+		}
+		ack.Until = &metav1.Time{Time: until}
+	}
+	return ack, nil
+}
+
+// clearAcknowledgement removes the acknowledgement annotations. An acknowledgement covers a single
+// trigger, so it's cleared once the alert is back within its threshold.
+func (r *CostAlertReconciler) clearAcknowledgement(ctx context.Context, costAlert *aiopsv1alpha1.CostAlert) error {
+	// Patch a copy, so the status changes made so far aren't overwritten by the response
+	acknowledged := costAlert.DeepCopy()
+	patch := client.MergeFrom(acknowledged.DeepCopy())
+	delete(acknowledged.Annotations, aiopsv1alpha1.AcknowledgedByAnnotation)
+	delete(acknowledged.Annotations, aiopsv1alpha1.AcknowledgedUntilAnnotation)
+	return r.Patch(ctx, acknowledged, patch)
+}
+
+// silenceReason returns why notifications are suppressed at now, or "" if they are not
+func silenceReason(costAlert *aiopsv1alpha1.CostAlert, ack *aiopsv1alpha1.Acknowledgement, now metav1.Time) string {
+	if ack != nil && (ack.Until == nil || now.Before(ack.Until)) {
+		return fmt.Sprintf("acknowledged by %s", ack.By)
+	}
+
+	for _, window := range costAlert.Spec.SilenceWindows {
+		if !now.Before(&window.Start) && now.Before(&window.End) {
+			if window.Reason != "" {
+				return fmt.Sprintf("silence window: %s", window.Reason)
+			}
+			return "silence window"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,34 @@
+// Package builders builds CostAlerts for tests. Builders return valid objects that options change.
+package builders
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/cost-alert/api/v1alpha1"
+)
+
+// CostAlert returns a CostAlert named daily alerting when the namespace's cost reaches 100 USD
+func CostAlert(namespace string, options ...func(*aiopsv1alpha1.CostAlert)) *aiopsv1alpha1.CostAlert {
+	costAlert := &aiopsv1alpha1.CostAlert{
+		ObjectMeta: metav1.ObjectMeta{Name: "daily", Namespace: namespace},
+		Spec: aiopsv1alpha1.CostAlertSpec{
+			Scope:     "namespace",
+			Namespace: namespace,
+			Threshold: aiopsv1alpha1.ThresholdSpec{Type: "absolute", Value: 100, Currency: "USD"},
+		},
+	}
+	for _, option := range options {
+		option(costAlert)
+	}
+	return costAlert
+}
+
+// Webhook notifies the webhook at url
+func Webhook(url string) func(*aiopsv1alpha1.CostAlert) {
+	return func(costAlert *aiopsv1alpha1.CostAlert) {
+		costAlert.Spec.Notify = aiopsv1alpha1.NotifySpec{
+			Enabled:  true,
+			Channels: []aiopsv1alpha1.NotificationChannel{{Type: "webhook", URL: url}},
+		}
+	}
+}
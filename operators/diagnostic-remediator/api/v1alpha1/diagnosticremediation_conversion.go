@@ -0,0 +1,4 @@
+package v1alpha1
+
+// Hub marks v1alpha1 as the version DiagnosticRemediations are stored and converted through
+func (*DiagnosticRemediation) Hub() {}
@@ -9,34 +9,130 @@ type DiagnosticRemediationSpec struct {
 	// Target workload to diagnose and remediate
 	Target TargetSpec `json:"target"`
 
+	// ClusterRef diagnoses a workload in a remote cluster (optional, defaults to the local cluster)
+	ClusterRef *ClusterRef `json:"clusterRef,omitempty"`
+
 	// Diagnostic checks to perform
 	Diagnostics DiagnosticChecks `json:"diagnostics"`
 
 	// Remediation actions to take when issues are found
 	Remediation RemediationActions `json:"remediation"`
 
+	// Recommendations computes CPU and memory request recommendations from observed usage (optional)
+	Recommendations *RecommendationSpec `json:"recommendations,omitempty"`
+
 	// Auto-fix enabled (default: true)
 	AutoFix bool `json:"autoFix,omitempty"`
 
+	// Mode: Audit only reports issues, DryRun also records the remediations it would make
+	// in status.plan without applying them, Enforce applies them.
+	// Defaults to Enforce when autoFix is set and Audit otherwise.
+	// +kubebuilder:validation:Enum=Audit;DryRun;Enforce
+	// +optional
+	Mode string `json:"mode,omitempty"`
+
 	// Cooldown period in seconds before allowing another remediation
+	// Default: 300 (5 minutes)
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=300
 	CooldownSeconds int32 `json:"cooldownSeconds,omitempty"`
+
+	// MaintenanceWindows hold off remediation, and optionally diagnosis, while one is open
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+}
+
+// MaintenanceWindow is a recurring or one-off period, such as a deploy window or planned downtime,
+// in which remediation is held off
+// +kubebuilder:validation:XValidation:rule="[has(self.schedule), has(self.recurrence), has(self.start)].exists_one(x, x)",message="exactly one of schedule, recurrence and start must be set"
+// +kubebuilder:validation:XValidation:rule="has(self.start) == has(self.end)",message="start and end must be set together"
+// +kubebuilder:validation:XValidation:rule="has(self.start) || has(self.durationMinutes)",message="durationMinutes is required with schedule and recurrence"
+type MaintenanceWindow struct {
+	// Name identifies the window in status and events
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Schedule is a cron expression for when the window opens, e.g. "0 2 * * 6" for Saturdays at 02:00
+	Schedule string `json:"schedule,omitempty"`
+
+	// Recurrence is an RFC 5545 recurrence rule for when the window opens, e.g. "FREQ=WEEKLY;BYDAY=SA;BYHOUR=2".
+	// FREQ DAILY, WEEKLY and MONTHLY with BYDAY, BYMONTHDAY, BYHOUR and BYMINUTE are supported.
+	Recurrence string `json:"recurrence,omitempty"`
+
+	// DurationMinutes the window stays open each time it opens
+	// +kubebuilder:validation:Minimum=1
+	DurationMinutes int32 `json:"durationMinutes,omitempty"`
+
+	// TimeZone of schedule and recurrence, as an IANA name such as "Europe/Berlin". Default: UTC
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Start and End bound a one-off window instead
+	Start *metav1.Time `json:"start,omitempty"`
+	End   *metav1.Time `json:"end,omitempty"`
+
+	// SuppressDetections also pauses checks during the window, so no detections are recorded.
+	// By default checks still run and only remediation is held off.
+	SuppressDetections bool `json:"suppressDetections,omitempty"`
+}
+
+// MaintenanceWindowStatus is the maintenance window in effect
+type MaintenanceWindowStatus struct {
+	// Name of the window
+	Name string `json:"name"`
+
+	// Until is when the window closes
+	Until metav1.Time `json:"until"`
 }
 
-// TargetSpec defines the target workload
+// TargetSpec defines the target workload, or the workloads a selector matches
+// +kubebuilder:validation:XValidation:rule="has(self.name) != has(self.selector)",message="exactly one of name and selector must be set"
+// +kubebuilder:validation:XValidation:rule="!has(self.allNamespaces) || !self.allNamespaces || has(self.selector)",message="allNamespaces requires selector"
 type TargetSpec struct {
-	// Namespace
-	Namespace string `json:"namespace"`
+	// Namespace (optional, defaults to the DiagnosticRemediation namespace)
+	Namespace string `json:"namespace,omitempty"`
 
 	// Resource type: Deployment, StatefulSet, DaemonSet
+	// +kubebuilder:validation:Enum=Deployment;StatefulSet;DaemonSet
 	Kind string `json:"kind"`
 
 	// Resource name
-	Name string `json:"name"`
+	Name string `json:"name,omitempty"`
+
+	// Selector diagnoses every workload of the kind whose labels match, instead of a single named one
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
 
-	// Label selector (alternative to name)
+	// AllNamespaces matches the selector in all namespaces instead of the target namespace
+	AllNamespaces bool `json:"allNamespaces,omitempty"`
+
+	// Labels further narrows the pods checked; pods are matched to the target through owner references
 	Labels map[string]string `json:"labels,omitempty"`
 }
 
+// ClusterRef references a remote cluster registered with a kubeconfig Secret
+type ClusterRef struct {
+	// Name of the cluster, recorded in status and events.
+	// Without a secretRef the kubeconfig is read from the Cluster API Secret "<name>-kubeconfig"
+	// in the DiagnosticRemediation namespace.
+	Name string `json:"name"`
+
+	// SecretRef references a Secret holding the cluster's kubeconfig (optional). The kubeconfig must
+	// embed its credentials: exec and auth provider plugins and file paths are refused.
+	SecretRef *KubeconfigSecretReference `json:"secretRef,omitempty"`
+}
+
+// KubeconfigSecretReference references a Secret holding a kubeconfig
+type KubeconfigSecretReference struct {
+	// Name of the Secret
+	Name string `json:"name"`
+
+	// Namespace of the Secret (optional); it must be the DiagnosticRemediation namespace
+	Namespace string `json:"namespace,omitempty"`
+
+	// Key holding the kubeconfig
+	// Default: kubeconfig
+	// +kubebuilder:default=kubeconfig
+	Key string `json:"key,omitempty"`
+}
+
 // DiagnosticChecks defines what to check
 type DiagnosticChecks struct {
 	// Check resource limits/requests
@@ -63,8 +159,70 @@ type DiagnosticChecks struct {
 	// Check network policies
 	NetworkPolicies bool `json:"networkPolicies,omitempty"`
 
-	// Custom diagnostic script
+	// Check that the Services and Ingresses routing to the target select its pods, have ready
+	// endpoints and reference existing Services and ports
+	Routing bool `json:"routing,omitempty"`
+
+	// Check for OOM-killed containers and containers using most of their memory limit.
+	// Memory usage is read from metrics-server; without it only OOM kills are reported.
+	Memory bool `json:"memory,omitempty"`
+
+	// MemoryPressurePercent is the share of its memory limit above which a container is under memory pressure
+	// Default: 90
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default=90
+	MemoryPressurePercent int32 `json:"memoryPressurePercent,omitempty"`
+
+	// Custom diagnostic script, run with "sh -c" in a Job in the target namespace.
+	// TARGET_KIND, TARGET_NAME and TARGET_NAMESPACE are set in its environment.
+	// A non-zero exit code is reported as an issue with the script's output.
 	CustomScript string `json:"customScript,omitempty"`
+
+	// ScriptJob configures the Job running customScript (optional)
+	ScriptJob *ScriptJobSpec `json:"scriptJob,omitempty"`
+}
+
+// ScriptJobSpec configures the Job running a custom diagnostic script
+type ScriptJobSpec struct {
+	// Image running the script; it must provide sh
+	// Default: busybox:1.36
+	// +kubebuilder:default="busybox:1.36"
+	Image string `json:"image,omitempty"`
+
+	// Deprecated: ServiceAccountName is ignored. Scripts run without a service account token.
+	ServiceAccountName string `json:"serviceAccountName,omitempty"`
+
+	// TimeoutSeconds after which the script is stopped and reported as timed out
+	// Default: 300
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=300
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+}
+
+// RecommendationSpec configures resource request recommendations
+type RecommendationSpec struct {
+	// Source of usage data: MetricsServer samples usage on every diagnosis and keeps the samples
+	// in the operator's memory, Prometheus queries the usage history.
+	// Default: MetricsServer
+	// +kubebuilder:validation:Enum=MetricsServer;Prometheus
+	// +kubebuilder:default=MetricsServer
+	Source string `json:"source,omitempty"`
+
+	// PrometheusURL is the base URL of the Prometheus API, required with the Prometheus source
+	PrometheusURL string `json:"prometheusUrl,omitempty"`
+
+	// WindowSeconds of usage the recommendations are based on
+	// Default: 86400 (1 day)
+	// +kubebuilder:validation:Minimum=300
+	// +kubebuilder:default=86400
+	WindowSeconds int32 `json:"windowSeconds,omitempty"`
+
+	// MinSamples a container needs before it gets a recommendation from the MetricsServer source
+	// Default: 30
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=30
+	MinSamples int32 `json:"minSamples,omitempty"`
 }
 
 // ServiceDependency defines a service that must be available
@@ -76,9 +234,13 @@ type ServiceDependency struct {
 	Namespace string `json:"namespace,omitempty"`
 
 	// Port to check
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
 	Port int32 `json:"port"`
 
 	// Protocol: TCP, HTTP, HTTPS
+	// Default: TCP
+	// +kubebuilder:validation:Enum=TCP;HTTP;HTTPS
 	Protocol string `json:"protocol,omitempty"`
 
 	// HTTP path to check (for HTTP/HTTPS)
@@ -96,6 +258,15 @@ type RemediationActions struct {
 	// Fix image pull policy
 	FixImagePullPolicy bool `json:"fixImagePullPolicy,omitempty"`
 
+	// Raise the memory limits of containers that were OOM killed or are under memory pressure
+	FixMemoryLimits bool `json:"fixMemoryLimits,omitempty"`
+
+	// MemoryLimitIncrease configures how fixMemoryLimits raises memory limits
+	MemoryLimitIncrease MemoryLimitIncrease `json:"memoryLimitIncrease,omitempty"`
+
+	// Set container requests to the recommendations in status.recommendations
+	ApplyRecommendations bool `json:"applyRecommendations,omitempty"`
+
 	// Scale up if resources insufficient
 	ScaleUp bool `json:"scaleUp,omitempty"`
 
@@ -112,27 +283,48 @@ type RemediationActions struct {
 	RequiredEnvVars []EnvVarSpec `json:"requiredEnvVars,omitempty"`
 
 	// Default image pull policy
+	// Default: IfNotPresent
+	// +kubebuilder:validation:Enum=Always;IfNotPresent;Never
 	DefaultImagePullPolicy string `json:"defaultImagePullPolicy,omitempty"`
 }
 
+// MemoryLimitIncrease defines how memory limits are raised
+type MemoryLimitIncrease struct {
+	// Percent added to the current memory limit
+	// Default: 25
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=400
+	// +kubebuilder:default=25
+	Percent int32 `json:"percent,omitempty"`
+
+	// Max is the highest memory limit set; limits at the cap aren't raised further (optional)
+	// +kubebuilder:validation:Pattern=`^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$`
+	Max string `json:"max,omitempty"`
+}
+
 // ResourceSpec defines resource limits and requests
 type ResourceSpec struct {
 	// CPU request
+	// +kubebuilder:validation:Pattern=`^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$`
 	CPURequest string `json:"cpuRequest,omitempty"`
 
 	// CPU limit
+	// +kubebuilder:validation:Pattern=`^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$`
 	CPULimit string `json:"cpuLimit,omitempty"`
 
 	// Memory request
+	// +kubebuilder:validation:Pattern=`^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$`
 	MemoryRequest string `json:"memoryRequest,omitempty"`
 
 	// Memory limit
+	// +kubebuilder:validation:Pattern=`^(\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))(([KMGTPE]i)|[numkMGTPE]|([eE](\+|-)?(([0-9]+(\.[0-9]*)?)|(\.[0-9]+))))?$`
 	MemoryLimit string `json:"memoryLimit,omitempty"`
 }
 
 // EnvVarSpec defines an environment variable
 type EnvVarSpec struct {
 	// Variable name
+	// +kubebuilder:validation:Pattern=`^[-._a-zA-Z][-._a-zA-Z0-9]*$`
 	Name string `json:"name"`
 
 	// Variable value (or valueFrom)
@@ -168,9 +360,15 @@ type DiagnosticRemediationStatus struct {
 	// Phase: Pending, Diagnosing, IssuesFound, Remediating, Resolved, Failed
 	Phase string `json:"phase,omitempty"`
 
+	// Remote cluster the target runs in (empty for the local cluster)
+	Cluster string `json:"cluster,omitempty"`
+
 	// Last diagnostic time
 	LastDiagnosed *metav1.Time `json:"lastDiagnosed,omitempty"`
 
+	// MaintenanceWindow is the maintenance window in effect, if any
+	MaintenanceWindow *MaintenanceWindowStatus `json:"maintenanceWindow,omitempty"`
+
 	// Last remediation time
 	LastRemediated *metav1.Time `json:"lastRemediated,omitempty"`
 
@@ -183,8 +381,28 @@ type DiagnosticRemediationStatus struct {
 	// Remediation count
 	RemediationCount int32 `json:"remediationCount,omitempty"`
 
+	// Consecutive diagnoses in which each container, by name, was under memory pressure
+	MemoryPressure map[string]int32 `json:"memoryPressure,omitempty"`
+
+	// Recommended requests per container, from the p95 of observed usage
+	Recommendations []ContainerRecommendation `json:"recommendations,omitempty"`
+
+	// Remediations the last DryRun reconcile would have made
+	Plan *RemediationPlan `json:"plan,omitempty"`
+
+	// Per-workload status when the target is a selector
+	Targets []TargetStatus `json:"targets,omitempty"`
+
 	// Error message if failed
 	ErrorMessage string `json:"errorMessage,omitempty"`
+
+	// Most recent generation reconciled
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Standard Ready, Progressing and Degraded conditions
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // DiagnosticIssue represents a found issue
@@ -198,9 +416,30 @@ type DiagnosticIssue struct {
 	// Description
 	Description string `json:"description"`
 
-	// Affected resource
+	// Affected resource, for display
 	Resource string `json:"resource,omitempty"`
 
+	// TargetKind is the kind of the affected object, e.g. Deployment, Pod, ConfigMap or Service
+	TargetKind string `json:"targetKind,omitempty"`
+
+	// TargetName is the name of the affected object
+	TargetName string `json:"targetName,omitempty"`
+
+	// TargetNamespace is the namespace of the affected object
+	TargetNamespace string `json:"targetNamespace,omitempty"`
+
+	// ContainerName is the affected container, if the issue is about one
+	ContainerName string `json:"containerName,omitempty"`
+
+	// Key is the missing environment variable, or the ConfigMap or Secret key, if the issue is about one
+	Key string `json:"key,omitempty"`
+
+	// Revision is the ReplicaSet or controller revision an affected pod runs
+	Revision string `json:"revision,omitempty"`
+
+	// Workload the issue was found on, as namespace/name, when the target is a selector
+	Workload string `json:"workload,omitempty"`
+
 	// Suggested fix
 	SuggestedFix string `json:"suggestedFix,omitempty"`
 }
@@ -221,11 +460,92 @@ type RemediationAction struct {
 
 	// Error message if failed
 	ErrorMessage string `json:"errorMessage,omitempty"`
+
+	// Workload the action was taken on, as namespace/name, when the target is a selector
+	Workload string `json:"workload,omitempty"`
+
+	// ID of the workload update the action was part of; the actions of one update share it.
+	// Set the prophet.aiops.io/undo annotation to it to revert the update.
+	ID string `json:"id,omitempty"`
+
+	// Patch is the strategic merge patch the workload update applied, as JSON
+	Patch string `json:"patch,omitempty"`
+
+	// RevertPatch is the strategic merge patch that restores the fields the update changed, as JSON
+	RevertPatch string `json:"revertPatch,omitempty"`
+
+	// RevertedAt is when the update was undone
+	RevertedAt *metav1.Time `json:"revertedAt,omitempty"`
+}
+
+// TargetStatus is the status of one workload matched by the target selector
+type TargetStatus struct {
+	// Namespace of the workload
+	Namespace string `json:"namespace"`
+
+	// Name of the workload
+	Name string `json:"name"`
+
+	// Phase: IssuesFound, Remediating, Resolved
+	Phase string `json:"phase,omitempty"`
+
+	// Number of issues found on the workload
+	IssueCount int32 `json:"issueCount,omitempty"`
+
+	// Remediating is true while the operator is still working to fix the workload
+	Remediating bool `json:"remediating,omitempty"`
+
+	// Reason of the workload's Remediating condition
+	Reason string `json:"reason,omitempty"`
+
+	// Message of the workload's Remediating condition
+	Message string `json:"message,omitempty"`
+
+	// Last remediation time
+	LastRemediated *metav1.Time `json:"lastRemediated,omitempty"`
+
+	// Consecutive diagnoses in which each container, by name, was under memory pressure
+	MemoryPressure map[string]int32 `json:"memoryPressure,omitempty"`
+
+	// Recommended requests per container
+	Recommendations []ContainerRecommendation `json:"recommendations,omitempty"`
+
+	// Remediations the last DryRun reconcile would have made on the workload
+	Plan *RemediationPlan `json:"plan,omitempty"`
+}
+
+// ContainerRecommendation holds the recommended requests of a container
+type ContainerRecommendation struct {
+	// Container name
+	Container string `json:"container"`
+
+	// CPURequest is the p95 of the container's CPU usage
+	CPURequest string `json:"cpuRequest,omitempty"`
+
+	// MemoryRequest is the p95 of the container's memory usage
+	MemoryRequest string `json:"memoryRequest,omitempty"`
+
+	// Samples the recommendation is based on (MetricsServer source)
+	Samples int32 `json:"samples,omitempty"`
+}
+
+// RemediationPlan describes the remediations a DryRun reconcile would have made
+type RemediationPlan struct {
+	// When the plan was computed
+	Time metav1.Time `json:"time"`
+
+	// Actions that would have been taken
+	Actions []RemediationAction `json:"actions,omitempty"`
+
+	// WorkloadPatch is the strategic merge patch, as YAML, that would have been applied to the workload
+	WorkloadPatch string `json:"workloadPatch,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:storageversion
 //+kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+//+kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".status.cluster",priority=1
 //+kubebuilder:printcolumn:name="Issues",type="integer",JSONPath=".status.issues[*]"
 //+kubebuilder:printcolumn:name="Remediations",type="integer",JSONPath=".status.remediationCount"
 //+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
@@ -0,0 +1,101 @@
+package v1alpha1_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+	"github.com/prophet-aiops/diagnostic-remediator/internal/builders"
+)
+
+// TestValidationRules checks the CRD's CEL validation rules against the API server
+func TestValidationRules(t *testing.T) {
+	c := testEnv.Require(t)
+	namespace := testEnv.Namespace(t)
+	start := metav1.NewTime(time.Now().Truncate(time.Second))
+	end := metav1.NewTime(start.Add(time.Hour))
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "web"}}
+
+	tests := map[string]struct {
+		modify func(*aiopsv1alpha1.DiagnosticRemediationSpec)
+		error  string
+	}{
+		"valid": {
+			modify: func(*aiopsv1alpha1.DiagnosticRemediationSpec) {},
+		},
+		"selector": {
+			modify: func(spec *aiopsv1alpha1.DiagnosticRemediationSpec) {
+				spec.Target.Name = ""
+				spec.Target.Selector = selector
+			},
+		},
+		"name and selector": {
+			modify: func(spec *aiopsv1alpha1.DiagnosticRemediationSpec) { spec.Target.Selector = selector },
+			error:  "exactly one of name and selector must be set",
+		},
+		"neither name nor selector": {
+			modify: func(spec *aiopsv1alpha1.DiagnosticRemediationSpec) { spec.Target.Name = "" },
+			error:  "exactly one of name and selector must be set",
+		},
+		"all namespaces with selector": {
+			modify: func(spec *aiopsv1alpha1.DiagnosticRemediationSpec) {
+				spec.Target = aiopsv1alpha1.TargetSpec{Kind: "Deployment", Selector: selector, AllNamespaces: true}
+			},
+		},
+		"all namespaces with name": {
+			modify: func(spec *aiopsv1alpha1.DiagnosticRemediationSpec) { spec.Target.AllNamespaces = true },
+			error:  "allNamespaces requires selector",
+		},
+		"recurring maintenance window": {
+			modify: func(spec *aiopsv1alpha1.DiagnosticRemediationSpec) {
+				spec.MaintenanceWindows = []aiopsv1alpha1.MaintenanceWindow{{Name: "weekly", Recurrence: "FREQ=WEEKLY;BYDAY=SA;BYHOUR=2", DurationMinutes: 120}}
+			},
+		},
+		"one-off maintenance window": {
+			modify: func(spec *aiopsv1alpha1.DiagnosticRemediationSpec) {
+				spec.MaintenanceWindows = []aiopsv1alpha1.MaintenanceWindow{{Name: "upgrade", Start: &start, End: &end}}
+			},
+		},
+		"maintenance window with schedule and recurrence": {
+			modify: func(spec *aiopsv1alpha1.DiagnosticRemediationSpec) {
+				spec.MaintenanceWindows = []aiopsv1alpha1.MaintenanceWindow{{Name: "nightly", Schedule: "0 2 * * *", Recurrence: "FREQ=DAILY", DurationMinutes: 60}}
+			},
+			error: "exactly one of schedule, recurrence and start must be set",
+		},
+		"maintenance window without end": {
+			modify: func(spec *aiopsv1alpha1.DiagnosticRemediationSpec) {
+				spec.MaintenanceWindows = []aiopsv1alpha1.MaintenanceWindow{{Name: "upgrade", Start: &start}}
+			},
+			error: "start and end must be set together",
+		},
+		"schedule without duration": {
+			modify: func(spec *aiopsv1alpha1.DiagnosticRemediationSpec) {
+				spec.MaintenanceWindows = []aiopsv1alpha1.MaintenanceWindow{{Name: "nightly", Schedule: "0 2 * * *"}}
+			},
+			error: "durationMinutes is required with schedule and recurrence",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dr := builders.DiagnosticRemediation(namespace, func(dr *aiopsv1alpha1.DiagnosticRemediation) {
+				dr.Name = ""
+				dr.GenerateName = "web-"
+				tt.modify(&dr.Spec)
+			})
+
+			err := c.Create(context.Background(), dr)
+			switch {
+			case tt.error == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.error != "" && err == nil:
+				t.Errorf("expected %q", tt.error)
+			case tt.error != "" && !strings.Contains(err.Error(), tt.error):
+				t.Errorf("expected %q, got %v", tt.error, err)
+			}
+		})
+	}
+}
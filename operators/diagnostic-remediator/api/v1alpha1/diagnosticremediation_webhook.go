@@ -0,0 +1,116 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhookWithManager registers the DiagnosticRemediation defaulting and conversion webhooks.
+// v1beta1 must be registered in the manager's scheme.
+func (r *DiagnosticRemediation) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&diagnosticRemediationDefaulter{}).
+		Complete()
+}
+
+// SetDefaults fills unset fields with their defaults. The defaulting webhook persists them;
+// the controller applies them again in memory so DiagnosticRemediations created without the
+// webhook behave the same.
+func (r *DiagnosticRemediation) SetDefaults() {
+	spec := &r.Spec
+	if spec.Target.Namespace == "" {
+		spec.Target.Namespace = r.Namespace
+	}
+	if ref := spec.ClusterRef; ref != nil && ref.SecretRef != nil {
+		if ref.SecretRef.Namespace == "" {
+			ref.SecretRef.Namespace = r.Namespace
+		}
+		if ref.SecretRef.Key == "" {
+			ref.SecretRef.Key = "kubeconfig"
+		}
+	}
+	for i := range spec.Diagnostics.ServiceDependencies {
+		dep := &spec.Diagnostics.ServiceDependencies[i]
+		if dep.Namespace == "" {
+			dep.Namespace = spec.Target.Namespace
+		}
+		if dep.Protocol == "" {
+			dep.Protocol = "TCP"
+		}
+	}
+	if spec.Diagnostics.CustomScript != "" {
+		if spec.Diagnostics.ScriptJob == nil {
+			spec.Diagnostics.ScriptJob = &ScriptJobSpec{}
+		}
+		if spec.Diagnostics.ScriptJob.Image == "" {
+			spec.Diagnostics.ScriptJob.Image = "busybox:1.36"
+		}
+		if spec.Diagnostics.ScriptJob.TimeoutSeconds == 0 {
+			spec.Diagnostics.ScriptJob.TimeoutSeconds = 300
+		}
+	}
+	if spec.Diagnostics.MemoryPressurePercent == 0 {
+		spec.Diagnostics.MemoryPressurePercent = 90
+	}
+	if spec.Remediation.MemoryLimitIncrease.Percent == 0 {
+		spec.Remediation.MemoryLimitIncrease.Percent = 25
+	}
+	if rec := spec.Recommendations; rec != nil {
+		if rec.Source == "" {
+			rec.Source = "MetricsServer"
+		}
+		if rec.WindowSeconds == 0 {
+			rec.WindowSeconds = 86400
+		}
+		if rec.MinSamples == 0 {
+			rec.MinSamples = 30
+		}
+	}
+	if spec.Remediation.DefaultImagePullPolicy == "" {
+		spec.Remediation.DefaultImagePullPolicy = "IfNotPresent"
+	}
+	if spec.CooldownSeconds == 0 {
+		spec.CooldownSeconds = 300
+	}
+}
+
+// Remediation modes
+const (
+	ModeAudit   = "Audit"
+	ModeDryRun  = "DryRun"
+	ModeEnforce = "Enforce"
+)
+
+// RemediationMode returns spec.mode, or the mode autoFix implies when it is unset. The mode
+// isn't defaulted by SetDefaults so that toggling autoFix keeps working.
+func (r *DiagnosticRemediation) RemediationMode() string {
+	if r.Spec.Mode != "" {
+		return r.Spec.Mode
+	}
+	if r.Spec.AutoFix {
+		return ModeEnforce
+	}
+	return ModeAudit
+}
+
+//+kubebuilder:webhook:path=/mutate-aiops-prophet-io-v1alpha1-diagnosticremediation,mutating=true,failurePolicy=ignore,sideEffects=None,groups=aiops.prophet.io,resources=diagnosticremediations,verbs=create;update,versions=v1alpha1,name=mdiagnosticremediation.aiops.prophet.io,admissionReviewVersions=v1
+
+// diagnosticRemediationDefaulter fills in defaults so they are visible on the stored DiagnosticRemediation
+type diagnosticRemediationDefaulter struct{}
+
+var _ admission.CustomDefaulter = &diagnosticRemediationDefaulter{}
+
+// Default implements admission.CustomDefaulter
+func (d *diagnosticRemediationDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	dr, ok := obj.(*DiagnosticRemediation)
+	if !ok {
+		return fmt.Errorf("expected a DiagnosticRemediation but got %T", obj)
+	}
+	dr.SetDefaults()
+	return nil
+}
@@ -5,9 +5,30 @@
 package v1alpha1
 
 import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterRef) DeepCopyInto(out *ClusterRef) {
+	*out = *in
+	if in.SecretRef != nil {
+		in, out := &in.SecretRef, &out.SecretRef
+		*out = new(KubeconfigSecretReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterRef.
+func (in *ClusterRef) DeepCopy() *ClusterRef {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ConfigMapKeySelector) DeepCopyInto(out *ConfigMapKeySelector) {
 	*out = *in
@@ -23,6 +44,21 @@ func (in *ConfigMapKeySelector) DeepCopy() *ConfigMapKeySelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ContainerRecommendation) DeepCopyInto(out *ContainerRecommendation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ContainerRecommendation.
+func (in *ContainerRecommendation) DeepCopy() *ContainerRecommendation {
+	if in == nil {
+		return nil
+	}
+	out := new(ContainerRecommendation)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DiagnosticChecks) DeepCopyInto(out *DiagnosticChecks) {
 	*out = *in
@@ -31,6 +67,11 @@ func (in *DiagnosticChecks) DeepCopyInto(out *DiagnosticChecks) {
 		*out = make([]ServiceDependency, len(*in))
 		copy(*out, *in)
 	}
+	if in.ScriptJob != nil {
+		in, out := &in.ScriptJob, &out.ScriptJob
+		*out = new(ScriptJobSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiagnosticChecks.
@@ -121,8 +162,25 @@ func (in *DiagnosticRemediationList) DeepCopyObject() runtime.Object {
 func (in *DiagnosticRemediationSpec) DeepCopyInto(out *DiagnosticRemediationSpec) {
 	*out = *in
 	in.Target.DeepCopyInto(&out.Target)
+	if in.ClusterRef != nil {
+		in, out := &in.ClusterRef, &out.ClusterRef
+		*out = new(ClusterRef)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Diagnostics.DeepCopyInto(&out.Diagnostics)
 	in.Remediation.DeepCopyInto(&out.Remediation)
+	if in.Recommendations != nil {
+		in, out := &in.Recommendations, &out.Recommendations
+		*out = new(RecommendationSpec)
+		**out = **in
+	}
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiagnosticRemediationSpec.
@@ -142,6 +200,11 @@ func (in *DiagnosticRemediationStatus) DeepCopyInto(out *DiagnosticRemediationSt
 		in, out := &in.LastDiagnosed, &out.LastDiagnosed
 		*out = (*in).DeepCopy()
 	}
+	if in.MaintenanceWindow != nil {
+		in, out := &in.MaintenanceWindow, &out.MaintenanceWindow
+		*out = new(MaintenanceWindowStatus)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.LastRemediated != nil {
 		in, out := &in.LastRemediated, &out.LastRemediated
 		*out = (*in).DeepCopy()
@@ -158,6 +221,37 @@ func (in *DiagnosticRemediationStatus) DeepCopyInto(out *DiagnosticRemediationSt
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.MemoryPressure != nil {
+		in, out := &in.MemoryPressure, &out.MemoryPressure
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Recommendations != nil {
+		in, out := &in.Recommendations, &out.Recommendations
+		*out = make([]ContainerRecommendation, len(*in))
+		copy(*out, *in)
+	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = new(RemediationPlan)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]TargetStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiagnosticRemediationStatus.
@@ -215,10 +309,98 @@ func (in *EnvVarSpec) DeepCopy() *EnvVarSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KubeconfigSecretReference) DeepCopyInto(out *KubeconfigSecretReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KubeconfigSecretReference.
+func (in *KubeconfigSecretReference) DeepCopy() *KubeconfigSecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(KubeconfigSecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	if in.Start != nil {
+		in, out := &in.Start, &out.Start
+		*out = (*in).DeepCopy()
+	}
+	if in.End != nil {
+		in, out := &in.End, &out.End
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindowStatus) DeepCopyInto(out *MaintenanceWindowStatus) {
+	*out = *in
+	in.Until.DeepCopyInto(&out.Until)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindowStatus.
+func (in *MaintenanceWindowStatus) DeepCopy() *MaintenanceWindowStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindowStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MemoryLimitIncrease) DeepCopyInto(out *MemoryLimitIncrease) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MemoryLimitIncrease.
+func (in *MemoryLimitIncrease) DeepCopy() *MemoryLimitIncrease {
+	if in == nil {
+		return nil
+	}
+	out := new(MemoryLimitIncrease)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RecommendationSpec) DeepCopyInto(out *RecommendationSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RecommendationSpec.
+func (in *RecommendationSpec) DeepCopy() *RecommendationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RecommendationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RemediationAction) DeepCopyInto(out *RemediationAction) {
 	*out = *in
 	in.Timestamp.DeepCopyInto(&out.Timestamp)
+	if in.RevertedAt != nil {
+		in, out := &in.RevertedAt, &out.RevertedAt
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemediationAction.
@@ -234,6 +416,7 @@ func (in *RemediationAction) DeepCopy() *RemediationAction {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RemediationActions) DeepCopyInto(out *RemediationActions) {
 	*out = *in
+	out.MemoryLimitIncrease = in.MemoryLimitIncrease
 	out.DefaultResources = in.DefaultResources
 	if in.RequiredEnvVars != nil {
 		in, out := &in.RequiredEnvVars, &out.RequiredEnvVars
@@ -254,6 +437,29 @@ func (in *RemediationActions) DeepCopy() *RemediationActions {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RemediationPlan) DeepCopyInto(out *RemediationPlan) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+	if in.Actions != nil {
+		in, out := &in.Actions, &out.Actions
+		*out = make([]RemediationAction, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemediationPlan.
+func (in *RemediationPlan) DeepCopy() *RemediationPlan {
+	if in == nil {
+		return nil
+	}
+	out := new(RemediationPlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceSpec) DeepCopyInto(out *ResourceSpec) {
 	*out = *in
@@ -269,6 +475,21 @@ func (in *ResourceSpec) DeepCopy() *ResourceSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ScriptJobSpec) DeepCopyInto(out *ScriptJobSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ScriptJobSpec.
+func (in *ScriptJobSpec) DeepCopy() *ScriptJobSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ScriptJobSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SecretKeySelector) DeepCopyInto(out *SecretKeySelector) {
 	*out = *in
@@ -302,6 +523,11 @@ func (in *ServiceDependency) DeepCopy() *ServiceDependency {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TargetSpec) DeepCopyInto(out *TargetSpec) {
 	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Labels != nil {
 		in, out := &in.Labels, &out.Labels
 		*out = make(map[string]string, len(*in))
@@ -320,3 +546,39 @@ func (in *TargetSpec) DeepCopy() *TargetSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetStatus) DeepCopyInto(out *TargetStatus) {
+	*out = *in
+	if in.LastRemediated != nil {
+		in, out := &in.LastRemediated, &out.LastRemediated
+		*out = (*in).DeepCopy()
+	}
+	if in.MemoryPressure != nil {
+		in, out := &in.MemoryPressure, &out.MemoryPressure
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Recommendations != nil {
+		in, out := &in.Recommendations, &out.Recommendations
+		*out = make([]ContainerRecommendation, len(*in))
+		copy(*out, *in)
+	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = new(RemediationPlan)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetStatus.
+func (in *TargetStatus) DeepCopy() *TargetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TargetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
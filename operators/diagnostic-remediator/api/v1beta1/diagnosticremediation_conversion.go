@@ -0,0 +1,65 @@
+package v1beta1
+
+import (
+	"encoding/json"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+)
+
+// ConvertTo converts this DiagnosticRemediation to the v1alpha1 hub version
+func (src *DiagnosticRemediation) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.DiagnosticRemediation)
+	dst.ObjectMeta = src.ObjectMeta
+	if err := convert(src.Spec, &dst.Spec); err != nil {
+		return err
+	}
+	if err := convert(src.Status, &dst.Status); err != nil {
+		return err
+	}
+
+	// An unset namespace stays unset; it defaults to the DiagnosticRemediation namespace in both versions
+	target := src.Spec.TargetRef
+	dst.Spec.Target = v1alpha1.TargetSpec{
+		Namespace:     target.Namespace,
+		Kind:          target.Kind,
+		Name:          target.Name,
+		Labels:        target.MatchLabels,
+		Selector:      target.Selector,
+		AllNamespaces: target.AllNamespaces,
+	}
+	return nil
+}
+
+// ConvertFrom converts the v1alpha1 hub version to this DiagnosticRemediation
+func (dst *DiagnosticRemediation) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.DiagnosticRemediation)
+	dst.ObjectMeta = src.ObjectMeta
+	if err := convert(src.Spec, &dst.Spec); err != nil {
+		return err
+	}
+	if err := convert(src.Status, &dst.Status); err != nil {
+		return err
+	}
+
+	target := src.Spec.Target
+	dst.Spec.TargetRef = TargetRef{
+		Kind:          target.Kind,
+		Name:          target.Name,
+		Namespace:     target.Namespace,
+		MatchLabels:   target.Labels,
+		Selector:      target.Selector,
+		AllNamespaces: target.AllNamespaces,
+	}
+	return nil
+}
+
+// convert copies fields between versions by JSON name; fields that only exist on one side are dropped
+func convert(in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,70 @@
+package v1beta1
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+)
+
+func TestConvertFromRoundTrip(t *testing.T) {
+	tests := map[string]v1alpha1.TargetSpec{
+		"named":             {Namespace: "shop", Kind: "Deployment", Name: "web", Labels: map[string]string{"tier": "frontend"}},
+		"namespace unset":   {Kind: "StatefulSet", Name: "db"},
+		"selector":          {Kind: "Deployment", Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+		"all namespaces":    {Kind: "DaemonSet", Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "agent"}}, AllNamespaces: true},
+		"other target kind": {Namespace: "batch", Kind: "StatefulSet", Name: "worker"},
+	}
+	for name, target := range tests {
+		t.Run(name, func(t *testing.T) {
+			original := &v1alpha1.DiagnosticRemediation{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+				Spec: v1alpha1.DiagnosticRemediationSpec{
+					Target:          target,
+					AutoFix:         true,
+					CooldownSeconds: 300,
+				},
+				Status: v1alpha1.DiagnosticRemediationStatus{Phase: "Resolved", Cluster: "edge"},
+			}
+
+			var beta DiagnosticRemediation
+			if err := beta.ConvertFrom(original.DeepCopy()); err != nil {
+				t.Fatalf("ConvertFrom: %v", err)
+			}
+			if beta.Spec.TargetRef.Kind != target.Kind || beta.Spec.TargetRef.Name != target.Name ||
+				!reflect.DeepEqual(beta.Spec.TargetRef.MatchLabels, target.Labels) {
+				t.Errorf("target not converted: %+v", beta.Spec.TargetRef)
+			}
+			var back v1alpha1.DiagnosticRemediation
+			if err := beta.ConvertTo(&back); err != nil {
+				t.Fatalf("ConvertTo: %v", err)
+			}
+			if !reflect.DeepEqual(&back, original) {
+				t.Errorf("round trip changed the DiagnosticRemediation:\n got %+v\nwant %+v", back, *original)
+			}
+		})
+	}
+}
+
+func TestConvertToRoundTrip(t *testing.T) {
+	original := &DiagnosticRemediation{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec: DiagnosticRemediationSpec{
+			TargetRef: TargetRef{Kind: "Deployment", Name: "web", MatchLabels: map[string]string{"tier": "frontend"}},
+			Mode:      "DryRun",
+		},
+	}
+	var hub v1alpha1.DiagnosticRemediation
+	if err := original.DeepCopy().ConvertTo(&hub); err != nil {
+		t.Fatalf("ConvertTo: %v", err)
+	}
+	var back DiagnosticRemediation
+	if err := back.ConvertFrom(&hub); err != nil {
+		t.Fatalf("ConvertFrom: %v", err)
+	}
+	if !reflect.DeepEqual(&back, original) {
+		t.Errorf("round trip changed the DiagnosticRemediation:\n got %+v\nwant %+v", back, *original)
+	}
+}
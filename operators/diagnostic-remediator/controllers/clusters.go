@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/clusters"
+	"github.com/prophet-aiops/pkg/prophetconfig"
+)
+
+// remoteClusters is shared by all reconciles so each remote cluster gets one client
+var remoteClusters = clusters.NewRegistry()
+
+// forCluster returns a reconciler for a single reconcile, whose client talks to the cluster the
+// target runs in. Guardrail policies are still read from the cluster the operator runs in.
+func (r *DiagnosticRemediationReconciler) forCluster(ctx context.Context, dr *aiopsv1alpha1.DiagnosticRemediation, defaults prophetconfig.Spec) (*DiagnosticRemediationReconciler, error) {
+	target := *r
+	target.defaults = defaults
+
+	ref := dr.Spec.ClusterRef
+	if ref == nil {
+		return &target, nil
+	}
+
+	// Default to the Secret Cluster API writes for each workload cluster
+	clusterRef := clusters.Ref{
+		Cluster: ref.Name,
+		Secret:  types.NamespacedName{Namespace: dr.Namespace, Name: ref.Name + "-kubeconfig"},
+		Key:     clusters.ClusterAPIKey,
+	}
+	if secretRef := ref.SecretRef; secretRef != nil {
+		// Kubeconfigs are only read from the DiagnosticRemediation's own namespace
+		if secretRef.Namespace != "" && secretRef.Namespace != dr.Namespace {
+			return nil, fmt.Errorf("kubeconfig secret %s/%s for cluster %s must be in the DiagnosticRemediation's namespace %s",
+				secretRef.Namespace, secretRef.Name, ref.Name, dr.Namespace)
+		}
+		clusterRef.Secret = types.NamespacedName{Namespace: dr.Namespace, Name: secretRef.Name}
+		clusterRef.Key = secretRef.Key
+	}
+
+	remote, err := remoteClusters.Client(ctx, r.Client, r.Scheme, clusterRef, clusters.Options{
+		QPS:   r.RemoteClusterQPS,
+		Burst: r.RemoteClusterBurst,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	target.Client = remote
+	target.hub = r.Client
+	return &target, nil
+}
+
+// hubClient returns the client for the cluster the operator runs in
+func (r *DiagnosticRemediationReconciler) hubClient() client.Client {
+	if r.hub != nil {
+		return r.hub
+	}
+	return r.Client
+}
+
+// clusterName returns the remote cluster name, or an empty string for the local cluster
+func clusterName(dr *aiopsv1alpha1.DiagnosticRemediation) string {
+	if dr.Spec.ClusterRef == nil {
+		return ""
+	}
+	return dr.Spec.ClusterRef.Name
+}
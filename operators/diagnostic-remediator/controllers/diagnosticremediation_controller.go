@@ -4,39 +4,74 @@ import (
 	"context"
 	"fmt"
 	"net"
-	"net/http"
 	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/conditions"
+	"github.com/prophet-aiops/pkg/httpclient"
+	"github.com/prophet-aiops/pkg/policy"
+	"github.com/prophet-aiops/pkg/prophetconfig"
+	"github.com/prophet-aiops/pkg/status"
 )
 
 // DiagnosticRemediationReconciler reconciles a DiagnosticRemediation object
 type DiagnosticRemediationReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// Guardrails is the ConfigMap holding guardrail policies checked before each mutation.
+	// Leave empty to disable guardrails.
+	Guardrails types.NamespacedName
+
+	// ProtectedNamespaces are never acted on, in addition to the system namespaces.
+	// They should include the operator's own namespace.
+	ProtectedNamespaces []string
+
+	// RemoteClusterQPS and RemoteClusterBurst rate limit the clients used for remote clusters
+	RemoteClusterQPS   float32
+	RemoteClusterBurst int
+
+	// hub is the local cluster client when Client targets a remote cluster
+	hub client.Client
+
+	// defaults holds the cluster-wide ProphetConfig defaults for the current reconcile
+	defaults prophetconfig.Spec
 }
 
 //+kubebuilder:rbac:groups=aiops.prophet.io,resources=diagnosticremediations,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=aiops.prophet.io,resources=diagnosticremediations/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=aiops.prophet.io,resources=prophetconfigs,verbs=get
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;update;patch
 //+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;update;patch
 //+kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch
 //+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=discovery.k8s.io,resources=endpointslices,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups=metrics.k8s.io,resources=pods,verbs=get;list
 //+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile performs diagnostic checks and remediation
@@ -45,33 +80,116 @@ func (r *DiagnosticRemediationReconciler) Reconcile(ctx context.Context, req ctr
 
 	var dr aiopsv1alpha1.DiagnosticRemediation
 	if err := r.Get(ctx, req.NamespacedName, &dr); err != nil {
+		if apierrors.IsNotFound(err) {
+			usageSamples.forget(req.NamespacedName)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	// Apply defaults in case the defaulting webhook isn't installed
+	dr.SetDefaults()
+	// Status is patched, so spec changes made while diagnosing don't conflict
+	base := dr.DeepCopy()
 
 	logger.Info("Reconciling DiagnosticRemediation", "name", req.Name, "phase", dr.Status.Phase)
 
+	// Cluster-wide defaults; settings on the DiagnosticRemediation take precedence
+	defaults, err := prophetconfig.Get(ctx, r.Client)
+	if err != nil {
+		logger.Error(err, "Failed to read ProphetConfig, using built-in defaults")
+	}
+
+	// Diagnose and remediate the target in the cluster it runs in; status stays on the local object
+	dr.Status.Cluster = clusterName(&dr)
+	target, err := r.forCluster(ctx, &dr, defaults)
+	if err != nil {
+		logger.Error(err, "Failed to get client for remote cluster", "cluster", dr.Status.Cluster)
+		dr.Status.Phase = "Failed"
+		dr.Status.ErrorMessage = err.Error()
+		setConditions(&dr)
+		if err := status.Patch(ctx, r.Client, &dr, base); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: 1 * time.Minute}, nil
+	}
+	dr.Status.ErrorMessage = ""
+
+	// Revert a workload update on request before diagnosing again
+	if _, ok := dr.Annotations[undoAnnotation]; ok {
+		target.undoRemediation(ctx, &dr, logger)
+	}
+
+	// An open maintenance window holds off remediation, and diagnosis if it suppresses detections
+	window, windowEnd, err := openMaintenanceWindow(dr.Spec.MaintenanceWindows, time.Now())
+	if err != nil {
+		logger.Error(err, "Ignoring invalid maintenance windows")
+		dr.Status.ErrorMessage = err.Error()
+	}
+	dr.Status.MaintenanceWindow = nil
+	if window != nil {
+		dr.Status.MaintenanceWindow = &aiopsv1alpha1.MaintenanceWindowStatus{Name: window.Name, Until: metav1.NewTime(windowEnd)}
+		if window.SuppressDetections {
+			logger.Info("Diagnosis paused by maintenance window", "window", window.Name, "until", windowEnd)
+			setRemediating(&dr, false, "MaintenanceWindow",
+				fmt.Sprintf("Diagnosis and remediation paused by maintenance window %s until %s", window.Name, windowEnd.Format(time.RFC3339)))
+			if err := status.Patch(ctx, r.Client, &dr, base); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: time.Until(windowEnd)}, nil
+		}
+	}
+
 	// Update phase to Diagnosing
 	dr.Status.Phase = "Diagnosing"
 	now := metav1.Now()
 	dr.Status.LastDiagnosed = &now
 
+	var requeue time.Duration
+	if dr.Spec.Target.Selector != nil {
+		requeue = target.reconcileSelector(ctx, &dr, defaults, logger)
+	} else {
+		dr.Status.Targets = nil
+		requeue = target.reconcileTarget(ctx, &dr, defaults, logger)
+	}
+
+	setConditions(&dr)
+	if err := status.Patch(ctx, r.Client, &dr, base); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: requeue}, nil
+}
+
+// reconcileTarget diagnoses and remediates a single target workload, updating dr's status, and
+// returns when to reconcile again
+func (r *DiagnosticRemediationReconciler) reconcileTarget(ctx context.Context, dr *aiopsv1alpha1.DiagnosticRemediation, defaults prophetconfig.Spec, logger logr.Logger) time.Duration {
 	// Perform diagnostics
-	issues := r.runDiagnostics(ctx, &dr, logger)
+	issues := r.runDiagnostics(ctx, dr, logger)
 	dr.Status.Issues = issues
 
+	// A plan only describes the current issues of a DryRun DiagnosticRemediation
+	if dr.RemediationMode() != aiopsv1alpha1.ModeDryRun || len(issues) == 0 {
+		dr.Status.Plan = nil
+	}
+
 	if len(issues) > 0 {
 		dr.Status.Phase = "IssuesFound"
 		logger.Info("Issues found", "count", len(issues))
 
+		// Changes wait for the maintenance window to close; plans and audits don't change anything
+		if window := dr.Status.MaintenanceWindow; window != nil && dr.RemediationMode() == aiopsv1alpha1.ModeEnforce {
+			setRemediating(dr, true, "MaintenanceWindow",
+				fmt.Sprintf("Remediation held off by maintenance window %s until %s", window.Name, window.Until.Format(time.RFC3339)))
+			return min(time.Until(window.Until.Time), 1*time.Minute)
+		}
+
 		// Check cooldown
 		if dr.Status.LastRemediated != nil {
-			cooldown := time.Duration(dr.Spec.CooldownSeconds) * time.Second
+			cooldown := defaults.Cooldown(dr.Spec.CooldownSeconds)
 			if time.Since(dr.Status.LastRemediated.Time) < cooldown {
 				logger.Info("In cooldown period, skipping remediation", "remaining", cooldown-time.Since(dr.Status.LastRemediated.Time))
-				if err := r.Status().Update(ctx, &dr); err != nil {
-					return ctrl.Result{}, err
-				}
-				return ctrl.Result{RequeueAfter: cooldown - time.Since(dr.Status.LastRemediated.Time)}, nil
+				setRemediating(dr, true, "CooldownActive",
+					fmt.Sprintf("Next remediation allowed in %s", (cooldown-time.Since(dr.Status.LastRemediated.Time)).Round(time.Second)))
+				return cooldown - time.Since(dr.Status.LastRemediated.Time)
 			}
 		}
 
@@ -103,16 +221,15 @@ func (r *DiagnosticRemediationReconciler) Reconcile(ctx context.Context, req ctr
 				"max", maxRemediationsPerHour,
 				"nextWindow", oneHourAgo.Add(1*time.Hour))
 			dr.Status.Phase = "IssuesFound" // Keep in IssuesFound, don't fail
-			if err := r.Status().Update(ctx, &dr); err != nil {
-				return ctrl.Result{}, err
-			}
-			return ctrl.Result{RequeueAfter: time.Until(oneHourAgo.Add(1 * time.Hour))}, nil
+			setRemediating(dr, false, "RateLimited", fmt.Sprintf("Reached %d remediations per hour", maxRemediationsPerHour))
+			return time.Until(oneHourAgo.Add(1 * time.Hour))
 		}
 
-		// Perform remediation if auto-fix enabled
-		if dr.Spec.AutoFix {
+		// Act on the issues according to the mode
+		switch dr.RemediationMode() {
+		case aiopsv1alpha1.ModeEnforce:
 			dr.Status.Phase = "Remediating"
-			remediations := r.performRemediation(ctx, &dr, issues, logger)
+			remediations := r.performRemediation(ctx, dr, issues, nil, logger)
 			dr.Status.Remediations = append(dr.Status.Remediations, remediations...)
 			dr.Status.RemediationCount += int32(len(remediations))
 
@@ -127,22 +244,64 @@ func (r *DiagnosticRemediationReconciler) Reconcile(ctx context.Context, req ctr
 
 			if allSucceeded && len(remediations) > 0 {
 				dr.Status.Phase = "Resolved"
-				now = metav1.Now()
+				now := metav1.Now()
 				dr.Status.LastRemediated = &now
+				setRemediating(dr, false, "Remediated", fmt.Sprintf("Applied %d fixes", len(remediations)))
 			} else if len(remediations) > 0 {
 				dr.Status.Phase = "IssuesFound" // Some fixes failed, keep trying
+				setRemediating(dr, true, "RemediationIncomplete", "Some fixes failed, retrying on the next reconcile")
+			} else {
+				setRemediating(dr, false, "NoAutomaticFix", "No automatic fix applies to the issues found")
+			}
+		case aiopsv1alpha1.ModeDryRun:
+			plan := &aiopsv1alpha1.RemediationPlan{Time: metav1.Now()}
+			r.performRemediation(ctx, dr, issues, plan, logger)
+			if planChanged(dr.Status.Plan, plan) {
+				r.recordEvent(ctx, dr, corev1.EventTypeNormal, "DryRun", planSummary(plan))
+			}
+			dr.Status.Plan = plan
+			setRemediating(dr, false, "DryRun", planSummary(plan))
+		default:
+			if dr.Spec.Mode == "" {
+				setRemediating(dr, false, "AutoFixDisabled", "autoFix is disabled")
+			} else {
+				setRemediating(dr, false, "AuditMode", "mode is Audit, issues are only reported")
 			}
 		}
 	} else {
 		dr.Status.Phase = "Resolved"
 		logger.Info("No issues found")
+		setRemediating(dr, false, "NoIssues", "No remediation needed")
 	}
 
-	if err := r.Status().Update(ctx, &dr); err != nil {
-		return ctrl.Result{}, err
+	return 1 * time.Minute
+}
+
+// setConditions derives the standard conditions from the phase
+func setConditions(dr *aiopsv1alpha1.DiagnosticRemediation) {
+	summary := conditions.Summary{Reason: dr.Status.Phase}
+	switch dr.Status.Phase {
+	case "Diagnosing", "Remediating":
+		summary.Progressing = true
+		summary.Message = fmt.Sprintf("%s %s", dr.Status.Phase, describeTarget(dr))
+	case "IssuesFound":
+		summary.Degraded = true
+		summary.Message = fmt.Sprintf("%d issues found", len(dr.Status.Issues))
+	case "Failed":
+		summary.Degraded = true
+		summary.Message = dr.Status.ErrorMessage
+	default:
+		summary.Message = "No outstanding issues"
 	}
+	conditions.Set(&dr.Status.Conditions, dr.Generation, conditions.Triggered, len(dr.Status.Issues) > 0, summary.Reason,
+		fmt.Sprintf("%d issues found", len(dr.Status.Issues)))
+	dr.Status.ObservedGeneration = dr.Generation
+	conditions.Apply(&dr.Status.Conditions, dr.Generation, summary)
+}
 
-	return ctrl.Result{RequeueAfter: 1 * time.Minute}, nil
+// setRemediating records whether the operator is still working to fix the target
+func setRemediating(dr *aiopsv1alpha1.DiagnosticRemediation, status bool, reason, message string) {
+	conditions.Set(&dr.Status.Conditions, dr.Generation, conditions.Remediating, status, reason, message)
 }
 
 // runDiagnostics performs all diagnostic checks
@@ -154,9 +313,12 @@ func (r *DiagnosticRemediationReconciler) runDiagnostics(ctx context.Context, dr
 	if err != nil {
 		logger.Error(err, "Failed to get target workload")
 		issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
-			Type:        "WorkloadNotFound",
-			Severity:    "Critical",
-			Description: fmt.Sprintf("Failed to find target workload: %v", err),
+			Type:            "WorkloadNotFound",
+			Severity:        "Critical",
+			Description:     fmt.Sprintf("Failed to find target workload: %v", err),
+			TargetKind:      dr.Spec.Target.Kind,
+			TargetName:      dr.Spec.Target.Name,
+			TargetNamespace: dr.Spec.Target.Namespace,
 		})
 		return issues
 	}
@@ -183,7 +345,36 @@ func (r *DiagnosticRemediationReconciler) runDiagnostics(ctx context.Context, dr
 
 	// Check image pull policy
 	if dr.Spec.Diagnostics.ImagePull {
-		issues = append(issues, r.checkImagePullPolicy(ctx, workload)...)
+		issues = append(issues, r.checkImagePullPolicy(ctx, workload, dr)...)
+	}
+
+	// Check network policies
+	if dr.Spec.Diagnostics.NetworkPolicies {
+		issues = append(issues, r.checkNetworkPolicies(ctx, workload, dr, logger)...)
+	}
+
+	// Check the Services and Ingresses routing to the target
+	if dr.Spec.Diagnostics.Routing {
+		issues = append(issues, r.checkRouting(ctx, workload, dr, logger)...)
+	}
+
+	// Check for OOM kills and memory pressure
+	if dr.Spec.Diagnostics.Memory {
+		issues = append(issues, r.checkMemory(ctx, dr, logger)...)
+	} else {
+		dr.Status.MemoryPressure = nil
+	}
+
+	// Recommend requests from observed usage
+	if dr.Spec.Recommendations != nil {
+		issues = append(issues, r.checkRecommendations(ctx, workload, dr, logger)...)
+	} else {
+		dr.Status.Recommendations = nil
+	}
+
+	// Run the custom diagnostic script
+	if dr.Spec.Diagnostics.CustomScript != "" {
+		issues = append(issues, r.checkCustomScript(ctx, workload, dr, logger)...)
 	}
 
 	// Check pod health (CrashLoopBackOff, high restart counts, stuck states)
@@ -238,20 +429,28 @@ func (r *DiagnosticRemediationReconciler) checkResources(ctx context.Context, wo
 	for i, container := range containers {
 		if container.Resources.Requests == nil || len(container.Resources.Requests) == 0 {
 			issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
-				Type:         "MissingResources",
-				Severity:     "Warning",
-				Description:  fmt.Sprintf("Container %s has no resource requests", container.Name),
-				Resource:     fmt.Sprintf("%s/%s/container[%d]", dr.Spec.Target.Kind, dr.Spec.Target.Name, i),
-				SuggestedFix: "Add resource requests for CPU and memory",
+				Type:            "MissingResources",
+				Severity:        "Warning",
+				Description:     fmt.Sprintf("Container %s has no resource requests", container.Name),
+				Resource:        fmt.Sprintf("%s/%s/container[%d]", dr.Spec.Target.Kind, dr.Spec.Target.Name, i),
+				SuggestedFix:    "Add resource requests for CPU and memory",
+				TargetKind:      dr.Spec.Target.Kind,
+				TargetName:      workload.GetName(),
+				TargetNamespace: workload.GetNamespace(),
+				ContainerName:   container.Name,
 			})
 		}
 		if container.Resources.Limits == nil || len(container.Resources.Limits) == 0 {
 			issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
-				Type:         "MissingResourceLimits",
-				Severity:     "Warning",
-				Description:  fmt.Sprintf("Container %s has no resource limits", container.Name),
-				Resource:     fmt.Sprintf("%s/%s/container[%d]", dr.Spec.Target.Kind, dr.Spec.Target.Name, i),
-				SuggestedFix: "Add resource limits for CPU and memory",
+				Type:            "MissingResourceLimits",
+				Severity:        "Warning",
+				Description:     fmt.Sprintf("Container %s has no resource limits", container.Name),
+				Resource:        fmt.Sprintf("%s/%s/container[%d]", dr.Spec.Target.Kind, dr.Spec.Target.Name, i),
+				SuggestedFix:    "Add resource limits for CPU and memory",
+				TargetKind:      dr.Spec.Target.Kind,
+				TargetName:      workload.GetName(),
+				TargetNamespace: workload.GetNamespace(),
+				ContainerName:   container.Name,
 			})
 		}
 	}
@@ -291,11 +490,16 @@ func (r *DiagnosticRemediationReconciler) checkEnvironment(ctx context.Context,
 		for varName := range requiredVars {
 			if !existingVars[varName] {
 				issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
-					Type:         "MissingEnvVar",
-					Severity:     "Critical",
-					Description:  fmt.Sprintf("Container %s missing required environment variable: %s", container.Name, varName),
-					Resource:     fmt.Sprintf("%s/%s/container[%d]", dr.Spec.Target.Kind, dr.Spec.Target.Name, i),
-					SuggestedFix: fmt.Sprintf("Add environment variable %s", varName),
+					Type:            "MissingEnvVar",
+					Severity:        "Critical",
+					Description:     fmt.Sprintf("Container %s missing required environment variable: %s", container.Name, varName),
+					Resource:        fmt.Sprintf("%s/%s/container[%d]", dr.Spec.Target.Kind, dr.Spec.Target.Name, i),
+					SuggestedFix:    fmt.Sprintf("Add environment variable %s", varName),
+					TargetKind:      dr.Spec.Target.Kind,
+					TargetName:      workload.GetName(),
+					TargetNamespace: workload.GetNamespace(),
+					ContainerName:   container.Name,
+					Key:             varName,
 				})
 			}
 		}
@@ -329,11 +533,15 @@ func (r *DiagnosticRemediationReconciler) checkConfigReferences(ctx context.Cont
 				cm := &corev1.ConfigMap{}
 				if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: envFrom.ConfigMapRef.Name}, cm); err != nil {
 					issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
-						Type:         "MissingConfigMap",
-						Severity:     "Critical",
-						Description:  fmt.Sprintf("Container %s references non-existent ConfigMap: %s", container.Name, envFrom.ConfigMapRef.Name),
-						Resource:     fmt.Sprintf("%s/%s/container[%d]", dr.Spec.Target.Kind, dr.Spec.Target.Name, i),
-						SuggestedFix: fmt.Sprintf("Create ConfigMap %s in namespace %s", envFrom.ConfigMapRef.Name, namespace),
+						Type:            "MissingConfigMap",
+						Severity:        "Critical",
+						Description:     fmt.Sprintf("Container %s references non-existent ConfigMap: %s", container.Name, envFrom.ConfigMapRef.Name),
+						Resource:        fmt.Sprintf("%s/%s/container[%d]", dr.Spec.Target.Kind, dr.Spec.Target.Name, i),
+						SuggestedFix:    fmt.Sprintf("Create ConfigMap %s in namespace %s", envFrom.ConfigMapRef.Name, namespace),
+						TargetKind:      "ConfigMap",
+						TargetName:      envFrom.ConfigMapRef.Name,
+						TargetNamespace: namespace,
+						ContainerName:   container.Name,
 					})
 				}
 			}
@@ -341,11 +549,15 @@ func (r *DiagnosticRemediationReconciler) checkConfigReferences(ctx context.Cont
 				secret := &corev1.Secret{}
 				if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: envFrom.SecretRef.Name}, secret); err != nil {
 					issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
-						Type:         "MissingSecret",
-						Severity:     "Critical",
-						Description:  fmt.Sprintf("Container %s references non-existent Secret: %s", container.Name, envFrom.SecretRef.Name),
-						Resource:     fmt.Sprintf("%s/%s/container[%d]", dr.Spec.Target.Kind, dr.Spec.Target.Name, i),
-						SuggestedFix: fmt.Sprintf("Create Secret %s in namespace %s", envFrom.SecretRef.Name, namespace),
+						Type:            "MissingSecret",
+						Severity:        "Critical",
+						Description:     fmt.Sprintf("Container %s references non-existent Secret: %s", container.Name, envFrom.SecretRef.Name),
+						Resource:        fmt.Sprintf("%s/%s/container[%d]", dr.Spec.Target.Kind, dr.Spec.Target.Name, i),
+						SuggestedFix:    fmt.Sprintf("Create Secret %s in namespace %s", envFrom.SecretRef.Name, namespace),
+						TargetKind:      "Secret",
+						TargetName:      envFrom.SecretRef.Name,
+						TargetNamespace: namespace,
+						ContainerName:   container.Name,
 					})
 				}
 			}
@@ -358,11 +570,16 @@ func (r *DiagnosticRemediationReconciler) checkConfigReferences(ctx context.Cont
 					cm := &corev1.ConfigMap{}
 					if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: env.ValueFrom.ConfigMapKeyRef.Name}, cm); err != nil {
 						issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
-							Type:         "MissingConfigMap",
-							Severity:     "Critical",
-							Description:  fmt.Sprintf("Container %s references non-existent ConfigMap key: %s/%s", container.Name, env.ValueFrom.ConfigMapKeyRef.Name, env.ValueFrom.ConfigMapKeyRef.Key),
-							Resource:     fmt.Sprintf("%s/%s/container[%d]", dr.Spec.Target.Kind, dr.Spec.Target.Name, i),
-							SuggestedFix: fmt.Sprintf("Create ConfigMap %s with key %s", env.ValueFrom.ConfigMapKeyRef.Name, env.ValueFrom.ConfigMapKeyRef.Key),
+							Type:            "MissingConfigMap",
+							Severity:        "Critical",
+							Description:     fmt.Sprintf("Container %s references non-existent ConfigMap key: %s/%s", container.Name, env.ValueFrom.ConfigMapKeyRef.Name, env.ValueFrom.ConfigMapKeyRef.Key),
+							Resource:        fmt.Sprintf("%s/%s/container[%d]", dr.Spec.Target.Kind, dr.Spec.Target.Name, i),
+							SuggestedFix:    fmt.Sprintf("Create ConfigMap %s with key %s", env.ValueFrom.ConfigMapKeyRef.Name, env.ValueFrom.ConfigMapKeyRef.Key),
+							TargetKind:      "ConfigMap",
+							TargetName:      env.ValueFrom.ConfigMapKeyRef.Name,
+							TargetNamespace: namespace,
+							ContainerName:   container.Name,
+							Key:             env.ValueFrom.ConfigMapKeyRef.Key,
 						})
 					}
 				}
@@ -370,11 +587,16 @@ func (r *DiagnosticRemediationReconciler) checkConfigReferences(ctx context.Cont
 					secret := &corev1.Secret{}
 					if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: env.ValueFrom.SecretKeyRef.Name}, secret); err != nil {
 						issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
-							Type:         "MissingSecret",
-							Severity:     "Critical",
-							Description:  fmt.Sprintf("Container %s references non-existent Secret key: %s/%s", container.Name, env.ValueFrom.SecretKeyRef.Name, env.ValueFrom.SecretKeyRef.Key),
-							Resource:     fmt.Sprintf("%s/%s/container[%d]", dr.Spec.Target.Kind, dr.Spec.Target.Name, i),
-							SuggestedFix: fmt.Sprintf("Create Secret %s with key %s", env.ValueFrom.SecretKeyRef.Name, env.ValueFrom.SecretKeyRef.Key),
+							Type:            "MissingSecret",
+							Severity:        "Critical",
+							Description:     fmt.Sprintf("Container %s references non-existent Secret key: %s/%s", container.Name, env.ValueFrom.SecretKeyRef.Name, env.ValueFrom.SecretKeyRef.Key),
+							Resource:        fmt.Sprintf("%s/%s/container[%d]", dr.Spec.Target.Kind, dr.Spec.Target.Name, i),
+							SuggestedFix:    fmt.Sprintf("Create Secret %s with key %s", env.ValueFrom.SecretKeyRef.Name, env.ValueFrom.SecretKeyRef.Key),
+							TargetKind:      "Secret",
+							TargetName:      env.ValueFrom.SecretKeyRef.Name,
+							TargetNamespace: namespace,
+							ContainerName:   container.Name,
+							Key:             env.ValueFrom.SecretKeyRef.Key,
 						})
 					}
 				}
@@ -391,19 +613,19 @@ func (r *DiagnosticRemediationReconciler) checkServiceDependencies(ctx context.C
 
 	for _, dep := range dr.Spec.Diagnostics.ServiceDependencies {
 		namespace := dep.Namespace
-		if namespace == "" {
-			namespace = dr.Spec.Target.Namespace
-		}
 
 		// Check if service exists
 		svc := &corev1.Service{}
 		if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: dep.Name}, svc); err != nil {
 			issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
-				Type:         "ServiceUnavailable",
-				Severity:     "Critical",
-				Description:  fmt.Sprintf("Service dependency %s/%s not found", namespace, dep.Name),
-				Resource:     fmt.Sprintf("Service/%s", dep.Name),
-				SuggestedFix: fmt.Sprintf("Create Service %s in namespace %s", dep.Name, namespace),
+				Type:            "ServiceUnavailable",
+				Severity:        "Critical",
+				Description:     fmt.Sprintf("Service dependency %s/%s not found", namespace, dep.Name),
+				Resource:        fmt.Sprintf("Service/%s", dep.Name),
+				SuggestedFix:    fmt.Sprintf("Create Service %s in namespace %s", dep.Name, namespace),
+				TargetKind:      "Service",
+				TargetName:      dep.Name,
+				TargetNamespace: namespace,
 			})
 			continue
 		}
@@ -413,22 +635,28 @@ func (r *DiagnosticRemediationReconciler) checkServiceDependencies(ctx context.C
 			url := fmt.Sprintf("%s://%s.%s.svc.cluster.local:%d%s", strings.ToLower(dep.Protocol), dep.Name, namespace, dep.Port, dep.Path)
 			if !r.checkHTTPEndpoint(url) {
 				issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
-					Type:         "ServiceUnreachable",
-					Severity:     "Warning",
-					Description:  fmt.Sprintf("Service %s/%s endpoint not reachable: %s", namespace, dep.Name, url),
-					Resource:     fmt.Sprintf("Service/%s", dep.Name),
-					SuggestedFix: "Check service endpoints and pod readiness",
+					Type:            "ServiceUnreachable",
+					Severity:        "Warning",
+					Description:     fmt.Sprintf("Service %s/%s endpoint not reachable: %s", namespace, dep.Name, url),
+					Resource:        fmt.Sprintf("Service/%s", dep.Name),
+					SuggestedFix:    "Check service endpoints and pod readiness",
+					TargetKind:      "Service",
+					TargetName:      dep.Name,
+					TargetNamespace: namespace,
 				})
 			}
-		} else if dep.Protocol == "TCP" || dep.Protocol == "" {
+		} else if dep.Protocol == "TCP" {
 			address := fmt.Sprintf("%s.%s.svc.cluster.local:%d", dep.Name, namespace, dep.Port)
 			if !r.checkTCPEndpoint(address) {
 				issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
-					Type:         "ServiceUnreachable",
-					Severity:     "Warning",
-					Description:  fmt.Sprintf("Service %s/%s TCP port %d not reachable", namespace, dep.Name, dep.Port),
-					Resource:     fmt.Sprintf("Service/%s", dep.Name),
-					SuggestedFix: "Check service endpoints and pod readiness",
+					Type:            "ServiceUnreachable",
+					Severity:        "Warning",
+					Description:     fmt.Sprintf("Service %s/%s TCP port %d not reachable", namespace, dep.Name, dep.Port),
+					Resource:        fmt.Sprintf("Service/%s", dep.Name),
+					SuggestedFix:    "Check service endpoints and pod readiness",
+					TargetKind:      "Service",
+					TargetName:      dep.Name,
+					TargetNamespace: namespace,
 				})
 			}
 		}
@@ -438,7 +666,7 @@ func (r *DiagnosticRemediationReconciler) checkServiceDependencies(ctx context.C
 }
 
 // checkImagePullPolicy checks if image pull policy is set appropriately
-func (r *DiagnosticRemediationReconciler) checkImagePullPolicy(ctx context.Context, workload client.Object) []aiopsv1alpha1.DiagnosticIssue {
+func (r *DiagnosticRemediationReconciler) checkImagePullPolicy(ctx context.Context, workload client.Object, dr *aiopsv1alpha1.DiagnosticRemediation) []aiopsv1alpha1.DiagnosticIssue {
 	var issues []aiopsv1alpha1.DiagnosticIssue
 
 	var containers []corev1.Container
@@ -456,11 +684,15 @@ func (r *DiagnosticRemediationReconciler) checkImagePullPolicy(ctx context.Conte
 			// Check if image tag is "latest" - should use PullAlways or specific tag
 			if strings.Contains(container.Image, ":latest") || !strings.Contains(container.Image, ":") {
 				issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
-					Type:         "ImagePullPolicy",
-					Severity:     "Warning",
-					Description:  fmt.Sprintf("Container %s uses 'latest' tag without explicit pull policy", container.Name),
-					Resource:     fmt.Sprintf("container[%d]", i),
-					SuggestedFix: "Use specific image tags or set ImagePullPolicy: Always",
+					Type:            "ImagePullPolicy",
+					Severity:        "Warning",
+					Description:     fmt.Sprintf("Container %s uses 'latest' tag without explicit pull policy", container.Name),
+					Resource:        fmt.Sprintf("container[%d]", i),
+					SuggestedFix:    "Use specific image tags or set ImagePullPolicy: Always",
+					TargetKind:      dr.Spec.Target.Kind,
+					TargetName:      workload.GetName(),
+					TargetNamespace: workload.GetNamespace(),
+					ContainerName:   container.Name,
 				})
 			}
 		}
@@ -469,8 +701,9 @@ func (r *DiagnosticRemediationReconciler) checkImagePullPolicy(ctx context.Conte
 	return issues
 }
 
-// performRemediation applies fixes based on found issues
-func (r *DiagnosticRemediationReconciler) performRemediation(ctx context.Context, dr *aiopsv1alpha1.DiagnosticRemediation, issues []aiopsv1alpha1.DiagnosticIssue, logger logr.Logger) []aiopsv1alpha1.RemediationAction {
+// performRemediation applies fixes based on found issues. With a plan, nothing is changed:
+// the fixes are recorded in the plan together with the patch the workload update would apply.
+func (r *DiagnosticRemediationReconciler) performRemediation(ctx context.Context, dr *aiopsv1alpha1.DiagnosticRemediation, issues []aiopsv1alpha1.DiagnosticIssue, plan *aiopsv1alpha1.RemediationPlan, logger logr.Logger) []aiopsv1alpha1.RemediationAction {
 	var remediations []aiopsv1alpha1.RemediationAction
 
 	workload, err := r.getTargetWorkload(ctx, dr)
@@ -478,11 +711,29 @@ func (r *DiagnosticRemediationReconciler) performRemediation(ctx context.Context
 		logger.Error(err, "Failed to get workload for remediation")
 		return remediations
 	}
+	original := workload.DeepCopyObject().(client.Object)
 
 	needsUpdate := false
 
+	// Workload fixes are only made if the guardrails allow updating the workload
+	canUpdate := true
+	if dr.Spec.Remediation.FixResources || dr.Spec.Remediation.FixEnvironment || dr.Spec.Remediation.FixImagePullPolicy || dr.Spec.Remediation.FixMemoryLimits || dr.Spec.Remediation.ApplyRecommendations {
+		decision := r.checkGuardrails(ctx, policy.Action{
+			Type:      "UpdateWorkload",
+			Kind:      dr.Spec.Target.Kind,
+			Name:      workload.GetName(),
+			Namespace: workload.GetNamespace(),
+			Labels:    workload.GetLabels(),
+			Reason:    "apply diagnostic fixes",
+		})
+		if !decision.Allowed {
+			canUpdate = false
+			remediations = append(remediations, deniedAction("UpdateWorkload", "Workload fixes blocked", decision))
+		}
+	}
+
 	// Fix resources
-	if dr.Spec.Remediation.FixResources {
+	if canUpdate && dr.Spec.Remediation.FixResources {
 		for _, issue := range issues {
 			if issue.Type == "MissingResources" || issue.Type == "MissingResourceLimits" {
 				if fixed := r.fixResources(ctx, workload, dr); fixed {
@@ -499,7 +750,7 @@ func (r *DiagnosticRemediationReconciler) performRemediation(ctx context.Context
 	}
 
 	// Fix environment variables
-	if dr.Spec.Remediation.FixEnvironment {
+	if canUpdate && dr.Spec.Remediation.FixEnvironment {
 		for _, issue := range issues {
 			if issue.Type == "MissingEnvVar" {
 				if fixed := r.fixEnvironment(ctx, workload, dr); fixed {
@@ -516,7 +767,7 @@ func (r *DiagnosticRemediationReconciler) performRemediation(ctx context.Context
 	}
 
 	// Fix image pull policy
-	if dr.Spec.Remediation.FixImagePullPolicy {
+	if canUpdate && dr.Spec.Remediation.FixImagePullPolicy {
 		for _, issue := range issues {
 			if issue.Type == "ImagePullPolicy" {
 				if fixed := r.fixImagePullPolicy(ctx, workload, dr); fixed {
@@ -532,11 +783,49 @@ func (r *DiagnosticRemediationReconciler) performRemediation(ctx context.Context
 		}
 	}
 
+	// Raise memory limits of OOM-killed containers and containers under memory pressure
+	if canUpdate && dr.Spec.Remediation.FixMemoryLimits {
+		for _, change := range fixMemoryLimits(workload, dr, issues) {
+			needsUpdate = true
+			remediations = append(remediations, aiopsv1alpha1.RemediationAction{
+				Type:        "IncreasedMemoryLimit",
+				Description: change,
+				Timestamp:   metav1.Now(),
+				Success:     true,
+			})
+		}
+	}
+
+	// Right-size requests
+	if canUpdate && dr.Spec.Remediation.ApplyRecommendations {
+		for _, change := range applyRecommendations(workload, dr, issues) {
+			needsUpdate = true
+			remediations = append(remediations, aiopsv1alpha1.RemediationAction{
+				Type:        "AppliedRecommendation",
+				Description: change,
+				Timestamp:   metav1.Now(),
+				Success:     true,
+			})
+		}
+	}
+
+	// The actions so far are the workload fixes, made in one update
+	workloadFixes := len(remediations)
+
 	// Create missing ConfigMaps/Secrets
 	if dr.Spec.Remediation.CreateMissingConfigs {
 		for _, issue := range issues {
 			if issue.Type == "MissingConfigMap" {
-				if created := r.createMissingConfigMap(ctx, dr, issue); created {
+				decision := r.checkGuardrails(ctx, policy.Action{
+					Type:      "CreateConfigMap",
+					Kind:      "ConfigMap",
+					Name:      issue.TargetName,
+					Namespace: issue.TargetNamespace,
+					Reason:    issue.Description,
+				})
+				if !decision.Allowed {
+					remediations = append(remediations, deniedAction("CreatedConfigMap", fmt.Sprintf("Creating missing ConfigMap blocked: %s", issue.Resource), decision))
+				} else if plan != nil || r.createMissingConfigMap(ctx, dr, issue) {
 					remediations = append(remediations, aiopsv1alpha1.RemediationAction{
 						Type:        "CreatedConfigMap",
 						Description: fmt.Sprintf("Created missing ConfigMap: %s", issue.Resource),
@@ -546,7 +835,16 @@ func (r *DiagnosticRemediationReconciler) performRemediation(ctx context.Context
 				}
 			}
 			if issue.Type == "MissingSecret" {
-				if created := r.createMissingSecret(ctx, dr, issue); created {
+				decision := r.checkGuardrails(ctx, policy.Action{
+					Type:      "CreateSecret",
+					Kind:      "Secret",
+					Name:      issue.TargetName,
+					Namespace: issue.TargetNamespace,
+					Reason:    issue.Description,
+				})
+				if !decision.Allowed {
+					remediations = append(remediations, deniedAction("CreatedSecret", fmt.Sprintf("Creating missing Secret blocked: %s", issue.Resource), decision))
+				} else if plan != nil || r.createMissingSecret(ctx, dr, issue) {
 					remediations = append(remediations, aiopsv1alpha1.RemediationAction{
 						Type:        "CreatedSecret",
 						Description: fmt.Sprintf("Created missing Secret: %s", issue.Resource),
@@ -558,8 +856,31 @@ func (r *DiagnosticRemediationReconciler) performRemediation(ctx context.Context
 		}
 	}
 
+	// Dry run: record what the update would change instead of making it
+	if plan != nil {
+		if needsUpdate {
+			patch, err := workloadPatch(original, workload)
+			if err != nil {
+				logger.Error(err, "Failed to compute workload patch")
+			}
+			plan.WorkloadPatch = patch
+			if dr.Spec.Remediation.RestartOnConfigChange {
+				remediations = append(remediations, aiopsv1alpha1.RemediationAction{
+					Type:        "RestartedPods",
+					Description: "Restarted pods after configuration changes",
+					Timestamp:   metav1.Now(),
+					Success:     true,
+				})
+			}
+		}
+		plan.Actions = remediations
+		return remediations
+	}
+
 	// Update workload if changes were made
 	if needsUpdate {
+		// Captured before the update fills in server-set fields
+		change, changeErr := newWorkloadChange(original, workload)
 		if err := r.Update(ctx, workload); err != nil {
 			logger.Error(err, "Failed to update workload")
 			remediations = append(remediations, aiopsv1alpha1.RemediationAction{
@@ -570,6 +891,12 @@ func (r *DiagnosticRemediationReconciler) performRemediation(ctx context.Context
 				ErrorMessage: err.Error(),
 			})
 		} else {
+			if changeErr != nil {
+				logger.Error(changeErr, "Failed to compute workload patches, the update can't be undone")
+			} else {
+				change.record(remediations[:workloadFixes])
+			}
+
 			// Restart pods if configured
 			if dr.Spec.Remediation.RestartOnConfigChange {
 				if err := r.restartPods(ctx, dr); err != nil {
@@ -700,9 +1027,6 @@ func (r *DiagnosticRemediationReconciler) fixEnvironment(ctx context.Context, wo
 func (r *DiagnosticRemediationReconciler) fixImagePullPolicy(ctx context.Context, workload client.Object, dr *aiopsv1alpha1.DiagnosticRemediation) bool {
 	changed := false
 	policy := dr.Spec.Remediation.DefaultImagePullPolicy
-	if policy == "" {
-		policy = string(corev1.PullIfNotPresent)
-	}
 
 	var containers *[]corev1.Container
 	switch w := workload.(type) {
@@ -725,21 +1049,16 @@ func (r *DiagnosticRemediationReconciler) fixImagePullPolicy(ctx context.Context
 	return changed
 }
 
-// createMissingConfigMap creates a ConfigMap if it doesn't exist
+// createMissingConfigMap creates the placeholder ConfigMap an issue reports missing
 func (r *DiagnosticRemediationReconciler) createMissingConfigMap(ctx context.Context, dr *aiopsv1alpha1.DiagnosticRemediation, issue aiopsv1alpha1.DiagnosticIssue) bool {
-	// Extract ConfigMap name from issue description
-	// This is a simplified implementation - in production, parse the issue more carefully
-	namespace := dr.Spec.Target.Namespace
-	cmName := extractResourceName(issue.Description, "ConfigMap")
-
-	if cmName == "" {
+	if issue.TargetName == "" {
 		return false
 	}
 
 	cm := &corev1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      cmName,
-			Namespace: namespace,
+			Name:      issue.TargetName,
+			Namespace: issue.TargetNamespace,
 		},
 		Data: map[string]string{
 			"placeholder": "created-by-diagnostic-remediator",
@@ -754,19 +1073,16 @@ func (r *DiagnosticRemediationReconciler) createMissingConfigMap(ctx context.Con
 	return true
 }
 
-// createMissingSecret creates a Secret if it doesn't exist
+// createMissingSecret creates the placeholder Secret an issue reports missing
 func (r *DiagnosticRemediationReconciler) createMissingSecret(ctx context.Context, dr *aiopsv1alpha1.DiagnosticRemediation, issue aiopsv1alpha1.DiagnosticIssue) bool {
-	namespace := dr.Spec.Target.Namespace
-	secretName := extractResourceName(issue.Description, "Secret")
-
-	if secretName == "" {
+	if issue.TargetName == "" {
 		return false
 	}
 
 	secret := &corev1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      secretName,
-			Namespace: namespace,
+			Name:      issue.TargetName,
+			Namespace: issue.TargetNamespace,
 		},
 		Type: corev1.SecretTypeOpaque,
 		Data: map[string][]byte{
@@ -783,24 +1099,44 @@ func (r *DiagnosticRemediationReconciler) createMissingSecret(ctx context.Contex
 
 // restartPods restarts pods by deleting them (ReplicaSet will recreate)
 func (r *DiagnosticRemediationReconciler) restartPods(ctx context.Context, dr *aiopsv1alpha1.DiagnosticRemediation) error {
-	pods := &corev1.PodList{}
-	selector := client.MatchingLabels(dr.Spec.Target.Labels)
-	if err := r.List(ctx, pods, client.InNamespace(dr.Spec.Target.Namespace), selector); err != nil {
+	pods, err := r.targetPods(ctx, dr)
+	if err != nil {
 		return err
 	}
 
-	for _, pod := range pods.Items {
+	denied := 0
+	var lastDenied policy.Decision
+	for _, pod := range pods {
+		decision := r.checkGuardrails(ctx, policy.Action{
+			Type:      "DeletePod",
+			Kind:      "Pod",
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			Labels:    pod.Labels,
+			Reason:    "restart after configuration changes",
+		})
+		if !decision.Allowed {
+			denied++
+			lastDenied = decision
+			continue
+		}
 		if err := r.Delete(ctx, &pod); err != nil {
 			return err
 		}
 	}
 
+	if denied > 0 {
+		return fmt.Errorf("%d pod restart(s) denied by guardrail policy %s: %s", denied, lastDenied.Rule, lastDenied.Message)
+	}
 	return nil
 }
 
 // Helper functions
 func (r *DiagnosticRemediationReconciler) checkHTTPEndpoint(url string) bool {
-	client := &http.Client{Timeout: 5 * time.Second}
+	client, err := httpclient.New(httpclient.Config{Timeout: 5 * time.Second})
+	if err != nil {
+		return false
+	}
 	resp, err := client.Get(url)
 	if err != nil {
 		return false
@@ -818,47 +1154,17 @@ func (r *DiagnosticRemediationReconciler) checkTCPEndpoint(address string) bool
 	return true
 }
 
-func extractResourceName(description, resourceType string) string {
-	// Simple extraction - in production, use regex or better parsing
-	parts := strings.Split(description, resourceType+":")
-	if len(parts) > 1 {
-		name := strings.TrimSpace(parts[1])
-		// Remove any trailing punctuation
-		name = strings.TrimRight(name, ".,;")
-		return name
-	}
-	return ""
-}
-
-// SetupWithManager sets up the controller with the Manager
 // checkPodHealth checks for pod health issues: CrashLoopBackOff, high restart counts, stuck states
 func (r *DiagnosticRemediationReconciler) checkPodHealth(ctx context.Context, dr *aiopsv1alpha1.DiagnosticRemediation, logger logr.Logger) []aiopsv1alpha1.DiagnosticIssue {
 	var issues []aiopsv1alpha1.DiagnosticIssue
 
-	// Get pods for the target workload
-	pods := &corev1.PodList{}
-	selector := client.MatchingLabels(dr.Spec.Target.Labels)
-	if len(dr.Spec.Target.Labels) == 0 {
-		// If no labels specified, try to find pods by owner reference
-		workload, err := r.getTargetWorkload(ctx, dr)
-		if err == nil {
-			switch w := workload.(type) {
-			case *appsv1.Deployment:
-				selector = client.MatchingLabels(w.Spec.Selector.MatchLabels)
-			case *appsv1.StatefulSet:
-				selector = client.MatchingLabels(w.Spec.Selector.MatchLabels)
-			case *appsv1.DaemonSet:
-				selector = client.MatchingLabels(w.Spec.Selector.MatchLabels)
-			}
-		}
-	}
-
-	if err := r.List(ctx, pods, client.InNamespace(dr.Spec.Target.Namespace), selector); err != nil {
+	pods, err := r.targetPods(ctx, dr)
+	if err != nil {
 		logger.Error(err, "Failed to list pods")
 		return issues
 	}
 
-	for _, pod := range pods.Items {
+	for _, pod := range pods {
 		// Check for CrashLoopBackOff
 		if pod.Status.Phase == corev1.PodFailed {
 			for _, containerStatus := range pod.Status.ContainerStatuses {
@@ -867,10 +1173,15 @@ func (r *DiagnosticRemediationReconciler) checkPodHealth(ctx context.Context, dr
 						containerStatus.State.Waiting.Reason == "ImagePullBackOff" ||
 						containerStatus.State.Waiting.Reason == "ErrImagePull" {
 						issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
-							Type:        "PodCrashLoopBackOff",
-							Severity:    "Critical",
-							Description: fmt.Sprintf("Pod %s is in %s state: %s", pod.Name, containerStatus.State.Waiting.Reason, containerStatus.State.Waiting.Message),
-							Resource:    fmt.Sprintf("pod/%s", pod.Name),
+							Type:            "PodCrashLoopBackOff",
+							Severity:        "Critical",
+							Description:     fmt.Sprintf("Pod %s is in %s state: %s", pod.Name, containerStatus.State.Waiting.Reason, containerStatus.State.Waiting.Message),
+							Resource:        fmt.Sprintf("pod/%s", pod.Name),
+							TargetKind:      "Pod",
+							TargetName:      pod.Name,
+							TargetNamespace: pod.Namespace,
+							ContainerName:   containerStatus.Name,
+							Revision:        podRevision(&pod),
 						})
 					}
 				}
@@ -881,10 +1192,15 @@ func (r *DiagnosticRemediationReconciler) checkPodHealth(ctx context.Context, dr
 		for _, containerStatus := range pod.Status.ContainerStatuses {
 			if containerStatus.RestartCount > 3 {
 				issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
-					Type:        "PodHighRestartCount",
-					Severity:    "Warning",
-					Description: fmt.Sprintf("Pod %s container %s has %d restarts", pod.Name, containerStatus.Name, containerStatus.RestartCount),
-					Resource:    fmt.Sprintf("pod/%s", pod.Name),
+					Type:            "PodHighRestartCount",
+					Severity:        "Warning",
+					Description:     fmt.Sprintf("Pod %s container %s has %d restarts", pod.Name, containerStatus.Name, containerStatus.RestartCount),
+					Resource:        fmt.Sprintf("pod/%s", pod.Name),
+					TargetKind:      "Pod",
+					TargetName:      pod.Name,
+					TargetNamespace: pod.Namespace,
+					ContainerName:   containerStatus.Name,
+					Revision:        podRevision(&pod),
 				})
 			}
 		}
@@ -894,10 +1210,14 @@ func (r *DiagnosticRemediationReconciler) checkPodHealth(ctx context.Context, dr
 			age := time.Since(pod.CreationTimestamp.Time)
 			if age > 5*time.Minute {
 				issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
-					Type:        "PodStuck",
-					Severity:    "Warning",
-					Description: fmt.Sprintf("Pod %s has been in Pending state for %v", pod.Name, age),
-					Resource:    fmt.Sprintf("pod/%s", pod.Name),
+					Type:            "PodStuck",
+					Severity:        "Warning",
+					Description:     fmt.Sprintf("Pod %s has been in Pending state for %v", pod.Name, age),
+					Resource:        fmt.Sprintf("pod/%s", pod.Name),
+					TargetKind:      "Pod",
+					TargetName:      pod.Name,
+					TargetNamespace: pod.Namespace,
+					Revision:        podRevision(&pod),
 				})
 			}
 		}
@@ -909,10 +1229,15 @@ func (r *DiagnosticRemediationReconciler) checkPodHealth(ctx context.Context, dr
 				age := time.Since(pod.CreationTimestamp.Time)
 				if age > 5*time.Minute {
 					issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
-						Type:        "PodStuck",
-						Severity:    "Warning",
-						Description: fmt.Sprintf("Pod %s container %s stuck in ContainerCreating for %v", pod.Name, containerStatus.Name, age),
-						Resource:    fmt.Sprintf("pod/%s", pod.Name),
+						Type:            "PodStuck",
+						Severity:        "Warning",
+						Description:     fmt.Sprintf("Pod %s container %s stuck in ContainerCreating for %v", pod.Name, containerStatus.Name, age),
+						Resource:        fmt.Sprintf("pod/%s", pod.Name),
+						TargetKind:      "Pod",
+						TargetName:      pod.Name,
+						TargetNamespace: pod.Namespace,
+						ContainerName:   containerStatus.Name,
+						Revision:        podRevision(&pod),
 					})
 				}
 			}
@@ -950,21 +1275,35 @@ func (r *DiagnosticRemediationReconciler) remediatePodHealth(ctx context.Context
 		return r.triggerRolloutRestart(ctx, workload, dr, logger)
 	}
 
-	// For non-Helm resources, extract pod name for potential deletion
-	parts := strings.Split(issue.Resource, "/")
-	if len(parts) != 2 || parts[0] != "pod" {
-		logger.Info("Invalid pod resource format, using rollout restart", "resource", issue.Resource)
+	// For non-Helm resources, the failing pod may be deleted
+	if issue.TargetKind != "Pod" || issue.TargetName == "" {
+		logger.Info("Issue doesn't name a pod, using rollout restart", "resource", issue.Resource)
 		return r.triggerRolloutRestart(ctx, workload, dr, logger)
 	}
-	podName := parts[1]
+	podName := issue.TargetName
 
 	// For CrashLoopBackOff or high restart counts on non-Helm resources, delete pod
 	if issue.Type == "PodCrashLoopBackOff" || issue.Type == "PodHighRestartCount" {
 		pod := &corev1.Pod{}
-		if err := r.Get(ctx, types.NamespacedName{Namespace: dr.Spec.Target.Namespace, Name: podName}, pod); err != nil {
+		if err := r.Get(ctx, types.NamespacedName{Namespace: issue.TargetNamespace, Name: podName}, pod); err != nil {
 			logger.Error(err, "Failed to get pod, falling back to rollout restart", "pod", podName)
 			return r.triggerRolloutRestart(ctx, workload, dr, logger)
 		}
+		if issue.Revision != "" && podRevision(pod) != issue.Revision {
+			// A StatefulSet recreated the pod from a newer revision since diagnosis
+			logger.Info("Pod no longer runs the diagnosed revision, skipping", "pod", podName, "revision", issue.Revision)
+			return false
+		}
+		if decision := r.checkGuardrails(ctx, policy.Action{
+			Type:      "DeletePod",
+			Kind:      "Pod",
+			Name:      pod.Name,
+			Namespace: pod.Namespace,
+			Labels:    pod.Labels,
+			Reason:    issue.Type,
+		}); !decision.Allowed {
+			return false
+		}
 		logger.Info("Deleting failing pod to trigger recreation", "pod", podName, "reason", issue.Type)
 		if err := r.Delete(ctx, pod); err != nil {
 			logger.Error(err, "Failed to delete pod, falling back to rollout restart", "pod", podName)
@@ -985,6 +1324,17 @@ func (r *DiagnosticRemediationReconciler) remediatePodHealth(ctx context.Context
 // This is equivalent to `kubectl rollout restart deployment/name -n namespace`
 // Includes idempotency check to avoid unnecessary restarts
 func (r *DiagnosticRemediationReconciler) triggerRolloutRestart(ctx context.Context, workload client.Object, dr *aiopsv1alpha1.DiagnosticRemediation, logger logr.Logger) bool {
+	if decision := r.checkGuardrails(ctx, policy.Action{
+		Type:      "RolloutRestart",
+		Kind:      dr.Spec.Target.Kind,
+		Name:      workload.GetName(),
+		Namespace: workload.GetNamespace(),
+		Labels:    workload.GetLabels(),
+		Reason:    "pod-health-remediation",
+	}); !decision.Allowed {
+		return false
+	}
+
 	switch w := workload.(type) {
 	case *appsv1.Deployment:
 		// Idempotency check: Don't restart if we just restarted recently (within last 2 minutes)
@@ -1044,8 +1394,25 @@ func (r *DiagnosticRemediationReconciler) triggerRolloutRestart(ctx context.Cont
 	}
 }
 
+// SetupWithManager sets up the controller with the Manager.
+// Besides the poll interval, spec and annotation changes, workload spec changes and pod health
+// changes re-diagnose the DiagnosticRemediations targeting the workload right away.
 func (r *DiagnosticRemediationReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &aiopsv1alpha1.DiagnosticRemediation{}, targetIndexKey, targetIndexValues); err != nil {
+		return err
+	}
+
+	workloadHandler := func(kind string) handler.EventHandler {
+		return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+			return r.workloadRequests(ctx, kind, obj)
+		})
+	}
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&aiopsv1alpha1.DiagnosticRemediation{}).
+		// Status patches don't re-trigger a reconcile
+		For(&aiopsv1alpha1.DiagnosticRemediation{}, builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.AnnotationChangedPredicate{}))).
+		Watches(&appsv1.Deployment{}, workloadHandler("Deployment"), builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&appsv1.StatefulSet{}, workloadHandler("StatefulSet"), builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&appsv1.DaemonSet{}, workloadHandler("DaemonSet"), builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(r.podRequests), builder.WithPredicates(podHealthChanged)).
 		Complete(r)
 }
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+	"github.com/prophet-aiops/diagnostic-remediator/internal/builders"
+	"github.com/prophet-aiops/pkg/conditions"
+	"github.com/prophet-aiops/pkg/testenv"
+)
+
+// TestReconcileFixResources runs a DiagnosticRemediation through finding a Deployment without
+// resources, auditing it, and adding the default resources once it enforces
+func TestReconcileFixResources(t *testing.T) {
+	c := testEnv.Require(t)
+	ctx := context.Background()
+	namespace := testEnv.Namespace(t)
+
+	deployment := testenv.Deployment(namespace, "web")
+	if err := c.Create(ctx, deployment); err != nil {
+		t.Fatal(err)
+	}
+	dr := builders.DiagnosticRemediation(namespace, func(dr *aiopsv1alpha1.DiagnosticRemediation) {
+		dr.Spec.Diagnostics.Resources = true
+		dr.Spec.Remediation.FixResources = true
+		dr.Spec.Remediation.DefaultResources = aiopsv1alpha1.ResourceSpec{
+			CPURequest: "100m", MemoryRequest: "128Mi", CPULimit: "500m", MemoryLimit: "256Mi",
+		}
+	})
+	if err := c.Create(ctx, dr); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &DiagnosticRemediationReconciler{Client: c, Scheme: c.Scheme()}
+	key := types.NamespacedName{Namespace: namespace, Name: dr.Name}
+	reconcile := func() (*aiopsv1alpha1.DiagnosticRemediation, *appsv1.Deployment) {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+		var got aiopsv1alpha1.DiagnosticRemediation
+		if err := c.Get(ctx, key, &got); err != nil {
+			t.Fatal(err)
+		}
+		var gotDeployment appsv1.Deployment
+		if err := c.Get(ctx, client.ObjectKeyFromObject(deployment), &gotDeployment); err != nil {
+			t.Fatal(err)
+		}
+		return &got, &gotDeployment
+	}
+
+	// Audit only reports the issues
+	got, gotDeployment := reconcile()
+	if got.Status.Phase != "IssuesFound" || len(got.Status.Issues) != 2 || len(got.Status.Remediations) != 0 {
+		t.Errorf("expected the missing requests and limits to be reported, got status %+v", got.Status)
+	}
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, conditions.Triggered) {
+		t.Errorf("expected the %s condition to be true, got %+v", conditions.Triggered, got.Status.Conditions)
+	}
+	if resources := gotDeployment.Spec.Template.Spec.Containers[0].Resources; len(resources.Requests) != 0 || len(resources.Limits) != 0 {
+		t.Errorf("expected an audit to leave the Deployment alone, got resources %+v", resources)
+	}
+
+	got.Spec.Mode = aiopsv1alpha1.ModeEnforce
+	if err := c.Update(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	got, gotDeployment = reconcile()
+	if got.Status.Phase != "Resolved" || got.Status.RemediationCount == 0 || got.Status.LastRemediated == nil {
+		t.Errorf("expected the issues to be remediated, got status %+v", got.Status)
+	}
+	resources := gotDeployment.Spec.Template.Spec.Containers[0].Resources
+	want := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m"), corev1.ResourceMemory: resource.MustParse("128Mi")},
+		Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m"), corev1.ResourceMemory: resource.MustParse("256Mi")},
+	}
+	for name, quantity := range want.Requests {
+		if got := resources.Requests[name]; got.Cmp(quantity) != 0 {
+			t.Errorf("got %s request %s, want %s", name, got.String(), quantity.String())
+		}
+	}
+	for name, quantity := range want.Limits {
+		if got := resources.Limits[name]; got.Cmp(quantity) != 0 {
+			t.Errorf("got %s limit %s, want %s", name, got.String(), quantity.String())
+		}
+	}
+
+	got, _ = reconcile()
+	if got.Status.Phase != "Resolved" || len(got.Status.Issues) != 0 {
+		t.Errorf("expected no issues once fixed, got status %+v", got.Status)
+	}
+	if meta.IsStatusConditionTrue(got.Status.Conditions, conditions.Triggered) {
+		t.Errorf("expected the %s condition to be false, got %+v", conditions.Triggered, got.Status.Conditions)
+	}
+}
+
+// TestReconcileWorkloadNotFound checks that a missing target is reported as an issue
+func TestReconcileWorkloadNotFound(t *testing.T) {
+	c := testEnv.Require(t)
+	ctx := context.Background()
+	namespace := testEnv.Namespace(t)
+
+	dr := builders.DiagnosticRemediation(namespace)
+	if err := c.Create(ctx, dr); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &DiagnosticRemediationReconciler{Client: c, Scheme: c.Scheme()}
+	key := types.NamespacedName{Namespace: namespace, Name: dr.Name}
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	var got aiopsv1alpha1.DiagnosticRemediation
+	if err := c.Get(ctx, key, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Status.Issues) != 1 || got.Status.Issues[0].Type != "WorkloadNotFound" {
+		t.Errorf("expected a WorkloadNotFound issue, got %+v", got.Status.Issues)
+	}
+}
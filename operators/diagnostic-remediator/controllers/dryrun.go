@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+)
+
+// workloadPatch renders the strategic merge patch from the original to the fixed workload as YAML.
+// Containers are merged by name, so the patch only shows the containers that change.
+func workloadPatch(original, fixed client.Object) (string, error) {
+	data, err := client.StrategicMergeFrom(original).Data(fixed)
+	if err != nil {
+		return "", err
+	}
+	patch, err := yaml.JSONToYAML(data)
+	if err != nil {
+		return "", err
+	}
+	return string(patch), nil
+}
+
+// planChanged reports whether the plan differs from the previous one, ignoring timestamps
+func planChanged(previous, plan *aiopsv1alpha1.RemediationPlan) bool {
+	if previous == nil || previous.WorkloadPatch != plan.WorkloadPatch || len(previous.Actions) != len(plan.Actions) {
+		return true
+	}
+	for i, action := range plan.Actions {
+		prev := previous.Actions[i]
+		if prev.Type != action.Type || prev.Description != action.Description || prev.Success != action.Success {
+			return true
+		}
+	}
+	return false
+}
+
+// planSummary describes the plan in one line for the Remediating condition and the DryRun event
+func planSummary(plan *aiopsv1alpha1.RemediationPlan) string {
+	if len(plan.Actions) == 0 {
+		return "No automatic fix applies to the issues found"
+	}
+	types := make([]string, 0, len(plan.Actions))
+	for _, action := range plan.Actions {
+		if !action.Success {
+			types = append(types, action.Type+" (denied)")
+			continue
+		}
+		types = append(types, action.Type)
+	}
+	return fmt.Sprintf("Would apply %d fixes: %s; see status.plan", len(plan.Actions), strings.Join(types, ", "))
+}
+
+// recordEvent records a Kubernetes event on the DiagnosticRemediation
+func (r *DiagnosticRemediationReconciler) recordEvent(ctx context.Context, dr *aiopsv1alpha1.DiagnosticRemediation, eventType, reason, message string) {
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-", dr.Name),
+			Namespace:    dr.Namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: aiopsv1alpha1.GroupVersion.String(),
+			Kind:       "DiagnosticRemediation",
+			Name:       dr.Name,
+			Namespace:  dr.Namespace,
+			UID:        dr.UID,
+		},
+		Type:    eventType,
+		Reason:  reason,
+		Message: message,
+		Source: corev1.EventSource{
+			Component: "diagnostic-remediator-controller",
+		},
+		FirstTimestamp: metav1.Now(),
+		LastTimestamp:  metav1.Now(),
+		Count:          1,
+	}
+
+	// The DiagnosticRemediation lives in the local cluster even when the target is remote
+	_ = r.hubClient().Create(ctx, event)
+}
@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/policy"
+)
+
+// guardrails is shared by all reconciles so compiled policies are reused until the ConfigMap changes
+var guardrails = policy.NewGuard()
+
+// checkGuardrails evaluates the action against the protected targets and the guardrail policies.
+// If the policies can't be read the action is denied.
+func (r *DiagnosticRemediationReconciler) checkGuardrails(ctx context.Context, action policy.Action) policy.Decision {
+	logger := log.FromContext(ctx)
+	action.Operator = "diagnostic-remediator"
+
+	if decision := policy.Protected(action, r.defaults.Protected(r.ProtectedNamespaces)); !decision.Allowed {
+		logger.Info("Action denied, target is protected", "action", action.Type, "namespace", action.Namespace, "name", action.Name, "rule", decision.Rule)
+		return decision
+	}
+
+	decision, err := guardrails.Check(ctx, r.hubClient(), r.Guardrails, action)
+	if err != nil {
+		logger.Error(err, "Failed to read guardrail policies, denying action", "action", action.Type, "name", action.Name)
+		return policy.Decision{Message: "guardrail policies could not be read: " + err.Error()}
+	}
+	if !decision.Allowed {
+		logger.Info("Action denied by guardrail policy", "action", action.Type, "namespace", action.Namespace, "name", action.Name, "rule", decision.Rule)
+	}
+	return decision
+}
+
+// deniedAction records an action that a guardrail policy blocked
+func deniedAction(actionType, description string, decision policy.Decision) aiopsv1alpha1.RemediationAction {
+	return aiopsv1alpha1.RemediationAction{
+		Type:         actionType,
+		Description:  description,
+		Timestamp:    metav1.Now(),
+		Success:      false,
+		ErrorMessage: fmt.Sprintf("denied by guardrail policy %s: %s", decision.Rule, decision.Message),
+	}
+}
@@ -0,0 +1,31 @@
+package controllers
+
+import (
+	"time"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/maintenance"
+)
+
+// openMaintenanceWindow returns the first maintenance window open at now and when it closes.
+// The error reports invalid windows, which are never open.
+func openMaintenanceWindow(windows []aiopsv1alpha1.MaintenanceWindow, now time.Time) (*aiopsv1alpha1.MaintenanceWindow, time.Time, error) {
+	converted := make([]maintenance.Window, len(windows))
+	for i, w := range windows {
+		converted[i] = maintenance.Window{
+			Name:       w.Name,
+			Schedule:   w.Schedule,
+			Recurrence: w.Recurrence,
+			Duration:   time.Duration(w.DurationMinutes) * time.Minute,
+			TimeZone:   w.TimeZone,
+		}
+		if w.Start != nil && w.End != nil {
+			converted[i].Start, converted[i].End = w.Start.Time, w.End.Time
+		}
+	}
+	i, until, err := maintenance.Open(converted, now)
+	if i < 0 {
+		return nil, time.Time{}, err
+	}
+	return &windows[i], until, err
+}
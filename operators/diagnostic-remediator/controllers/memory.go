@@ -0,0 +1,235 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+)
+
+// memoryPressureSamples is how many consecutive diagnoses a container must be under memory
+// pressure before it is reported, so short spikes aren't
+const memoryPressureSamples = 3
+
+// podMetricsListGVK is the metrics-server pod metrics list. It is read unstructured, so the
+// operator doesn't depend on the metrics API types.
+var podMetricsListGVK = schema.GroupVersionKind{Group: "metrics.k8s.io", Version: "v1beta1", Kind: "PodMetricsList"}
+
+// checkMemory reports containers of the target's pods that were OOM killed, and containers whose
+// memory usage stayed above memoryPressurePercent of their limit for memoryPressureSamples diagnoses
+func (r *DiagnosticRemediationReconciler) checkMemory(ctx context.Context, dr *aiopsv1alpha1.DiagnosticRemediation, logger logr.Logger) []aiopsv1alpha1.DiagnosticIssue {
+	var issues []aiopsv1alpha1.DiagnosticIssue
+
+	pods, err := r.targetPods(ctx, dr)
+	if err != nil {
+		logger.Error(err, "Failed to list pods")
+		return issues
+	}
+
+	// One issue per container, however many of its pods were OOM killed
+	oomKilled := map[string]bool{}
+	for _, pod := range pods {
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			if oomKilled[containerStatus.Name] || !wasOOMKilled(containerStatus) {
+				continue
+			}
+			oomKilled[containerStatus.Name] = true
+			limit := "no limit"
+			if qty, ok := containerLimit(&pod, containerStatus.Name, corev1.ResourceMemory); ok {
+				limit = "a limit of " + qty.String()
+			}
+			issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
+				Type:            "OOMKilled",
+				Severity:        "Critical",
+				Description:     fmt.Sprintf("Container %s of pod %s was OOM killed with %s", containerStatus.Name, pod.Name, limit),
+				Resource:        fmt.Sprintf("pod/%s", pod.Name),
+				SuggestedFix:    "Raise the container's memory limit or reduce its memory usage",
+				TargetKind:      "Pod",
+				TargetName:      pod.Name,
+				TargetNamespace: pod.Namespace,
+				ContainerName:   containerStatus.Name,
+				Revision:        podRevision(&pod),
+			})
+		}
+	}
+
+	if len(pods) == 0 {
+		dr.Status.MemoryPressure = nil
+		return issues
+	}
+	usage, err := r.podUsage(ctx, dr.Spec.Target.Namespace)
+	if err != nil {
+		// Usually metrics-server isn't installed; OOM kills are still reported
+		logger.V(1).Info("Pod metrics unavailable, skipping memory pressure check", "error", err.Error())
+		return issues
+	}
+
+	// The highest usage of each container across the pods, as a percentage of its limit
+	type pressure struct {
+		percent int64
+		pod     *corev1.Pod
+		limit   resource.Quantity
+	}
+	pressured := map[string]pressure{}
+	for i := range pods {
+		pod := &pods[i]
+		for name, used := range usage[pod.Name] {
+			memory, ok := used[corev1.ResourceMemory]
+			if !ok {
+				continue
+			}
+			limit, ok := containerLimit(pod, name, corev1.ResourceMemory)
+			if !ok || limit.IsZero() {
+				continue
+			}
+			percent := memory.Value() * 100 / limit.Value()
+			if percent >= int64(dr.Spec.Diagnostics.MemoryPressurePercent) && percent > pressured[name].percent {
+				pressured[name] = pressure{percent: percent, pod: pod, limit: limit}
+			}
+		}
+	}
+
+	samples := map[string]int32{}
+	for name, p := range pressured {
+		samples[name] = dr.Status.MemoryPressure[name] + 1
+		if samples[name] < memoryPressureSamples {
+			continue
+		}
+		issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
+			Type:     "MemoryPressure",
+			Severity: "Warning",
+			Description: fmt.Sprintf("Container %s uses %d%% of its %s memory limit in pod %s, above %d%% for %d consecutive diagnoses",
+				name, p.percent, p.limit.String(), p.pod.Name, dr.Spec.Diagnostics.MemoryPressurePercent, samples[name]),
+			Resource:        fmt.Sprintf("pod/%s", p.pod.Name),
+			SuggestedFix:    "Raise the container's memory limit before it is OOM killed",
+			TargetKind:      "Pod",
+			TargetName:      p.pod.Name,
+			TargetNamespace: p.pod.Namespace,
+			ContainerName:   name,
+			Revision:        podRevision(p.pod),
+		})
+	}
+	dr.Status.MemoryPressure = nil
+	if len(samples) > 0 {
+		dr.Status.MemoryPressure = samples
+	}
+
+	return issues
+}
+
+// podUsage returns the current resource usage reported by metrics-server, by pod and container name
+func (r *DiagnosticRemediationReconciler) podUsage(ctx context.Context, namespace string) (map[string]map[string]corev1.ResourceList, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(podMetricsListGVK)
+	if err := r.List(ctx, list, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	usage := map[string]map[string]corev1.ResourceList{}
+	for _, item := range list.Items {
+		containers, _, _ := unstructured.NestedSlice(item.Object, "containers")
+		for _, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(container, "name")
+			values, _, _ := unstructured.NestedStringMap(container, "usage")
+			used := corev1.ResourceList{}
+			for resourceName, value := range values {
+				if qty, err := resource.ParseQuantity(value); err == nil {
+					used[corev1.ResourceName(resourceName)] = qty
+				}
+			}
+			if usage[item.GetName()] == nil {
+				usage[item.GetName()] = map[string]corev1.ResourceList{}
+			}
+			usage[item.GetName()][name] = used
+		}
+	}
+	return usage, nil
+}
+
+// fixMemoryLimits raises the memory limit of each container with an OOMKilled or MemoryPressure
+// issue by memoryLimitIncrease.percent, up to memoryLimitIncrease.max, and describes each change.
+// Containers without a memory limit are left to fixResources.
+func fixMemoryLimits(workload client.Object, dr *aiopsv1alpha1.DiagnosticRemediation, issues []aiopsv1alpha1.DiagnosticIssue) []string {
+	var containers []corev1.Container
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		containers = w.Spec.Template.Spec.Containers
+	case *appsv1.StatefulSet:
+		containers = w.Spec.Template.Spec.Containers
+	case *appsv1.DaemonSet:
+		containers = w.Spec.Template.Spec.Containers
+	}
+
+	increase := dr.Spec.Remediation.MemoryLimitIncrease
+	var max *resource.Quantity
+	if increase.Max != "" {
+		if qty, err := resource.ParseQuantity(increase.Max); err == nil {
+			max = &qty
+		}
+	}
+
+	var changes []string
+	raised := map[string]bool{}
+	for _, issue := range issues {
+		if (issue.Type != "OOMKilled" && issue.Type != "MemoryPressure") || raised[issue.ContainerName] {
+			continue
+		}
+		raised[issue.ContainerName] = true
+
+		for i := range containers {
+			container := &containers[i]
+			current, ok := container.Resources.Limits[corev1.ResourceMemory]
+			if container.Name != issue.ContainerName || !ok {
+				continue
+			}
+
+			// Round up to whole MiB so the new limit reads well
+			const mi = 1 << 20
+			value := current.Value() * int64(100+increase.Percent) / 100
+			limit := *resource.NewQuantity((value+mi-1)/mi*mi, resource.BinarySI)
+			if max != nil && limit.Cmp(*max) > 0 {
+				limit = max.DeepCopy()
+			}
+			if limit.Cmp(current) <= 0 {
+				// Already at the cap
+				continue
+			}
+
+			container.Resources.Limits[corev1.ResourceMemory] = limit
+			changes = append(changes, fmt.Sprintf("Raised the memory limit of container %s from %s to %s", container.Name, current.String(), limit.String()))
+		}
+	}
+	return changes
+}
+
+// wasOOMKilled reports whether the container's current or last run was OOM killed
+func wasOOMKilled(containerStatus corev1.ContainerStatus) bool {
+	if terminated := containerStatus.State.Terminated; terminated != nil && terminated.Reason == "OOMKilled" {
+		return true
+	}
+	terminated := containerStatus.LastTerminationState.Terminated
+	return terminated != nil && terminated.Reason == "OOMKilled"
+}
+
+// containerLimit returns the container's limit for the resource, if it has one
+func containerLimit(pod *corev1.Pod, name string, resourceName corev1.ResourceName) (resource.Quantity, bool) {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == name {
+			qty, ok := container.Resources.Limits[resourceName]
+			return qty, ok
+		}
+	}
+	return resource.Quantity{}, false
+}
@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+)
+
+// checkNetworkPolicies reports NetworkPolicies that cut the target's pods off from all traffic
+// or block egress to its service dependencies, and targets no NetworkPolicy isolates
+func (r *DiagnosticRemediationReconciler) checkNetworkPolicies(ctx context.Context, workload client.Object, dr *aiopsv1alpha1.DiagnosticRemediation, logger logr.Logger) []aiopsv1alpha1.DiagnosticIssue {
+	var issues []aiopsv1alpha1.DiagnosticIssue
+	target := fmt.Sprintf("%s/%s", dr.Spec.Target.Kind, workload.GetName())
+
+	var policies networkingv1.NetworkPolicyList
+	if err := r.List(ctx, &policies, client.InNamespace(workload.GetNamespace())); err != nil {
+		logger.Error(err, "Failed to list network policies")
+		return issues
+	}
+
+	// Policies are additive: traffic is allowed if any policy selecting the pods allows it
+	podLabels := labels.Set(podTemplateLabels(workload))
+	var ingressPolicies, egressPolicies []networkingv1.NetworkPolicy
+	for _, policy := range policies.Items {
+		if !selectorMatches(&policy.Spec.PodSelector, podLabels) {
+			continue
+		}
+		ingress, egress := policyTypes(&policy)
+		if ingress {
+			ingressPolicies = append(ingressPolicies, policy)
+		}
+		if egress {
+			egressPolicies = append(egressPolicies, policy)
+		}
+	}
+
+	if len(ingressPolicies) == 0 && len(egressPolicies) == 0 {
+		description := fmt.Sprintf("No NetworkPolicy selects the pods of %s, so they accept traffic from any pod", target)
+		if len(policies.Items) == 0 {
+			description = fmt.Sprintf("Namespace %s has no NetworkPolicies, so the pods of %s accept traffic from any pod", workload.GetNamespace(), target)
+		}
+		return append(issues, aiopsv1alpha1.DiagnosticIssue{
+			Type:            "NetworkPolicyMissing",
+			Severity:        "Info",
+			Description:     description,
+			Resource:        target,
+			SuggestedFix:    "Add a default-deny NetworkPolicy and allow only the traffic the workload needs",
+			TargetKind:      dr.Spec.Target.Kind,
+			TargetName:      workload.GetName(),
+			TargetNamespace: workload.GetNamespace(),
+		})
+	}
+
+	if len(ingressPolicies) > 0 && !hasRules(ingressPolicies, true) {
+		issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
+			Type:            "NetworkPolicyDenyAllIngress",
+			Severity:        "Warning",
+			Description:     fmt.Sprintf("NetworkPolicies %s deny all ingress to the pods of %s", policyNames(ingressPolicies), target),
+			Resource:        "NetworkPolicy/" + ingressPolicies[0].Name,
+			SuggestedFix:    "Add an ingress rule allowing the workload's clients",
+			TargetKind:      "NetworkPolicy",
+			TargetName:      ingressPolicies[0].Name,
+			TargetNamespace: workload.GetNamespace(),
+		})
+	}
+
+	if len(egressPolicies) == 0 {
+		return issues
+	}
+	if !hasRules(egressPolicies, false) {
+		return append(issues, aiopsv1alpha1.DiagnosticIssue{
+			Type:            "NetworkPolicyDenyAllEgress",
+			Severity:        "Critical",
+			Description:     fmt.Sprintf("NetworkPolicies %s deny all egress from the pods of %s, including DNS and service dependencies", policyNames(egressPolicies), target),
+			Resource:        "NetworkPolicy/" + egressPolicies[0].Name,
+			SuggestedFix:    "Add egress rules allowing DNS and the workload's service dependencies",
+			TargetKind:      "NetworkPolicy",
+			TargetName:      egressPolicies[0].Name,
+			TargetNamespace: workload.GetNamespace(),
+		})
+	}
+
+	for _, dep := range dr.Spec.Diagnostics.ServiceDependencies {
+		allowed, err := r.egressAllowed(ctx, egressPolicies, dep)
+		if err != nil {
+			logger.Error(err, "Failed to check egress to service dependency", "service", dep.Name, "namespace", dep.Namespace)
+			continue
+		}
+		if !allowed {
+			issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
+				Type:     "NetworkPolicyBlocksDependency",
+				Severity: "Critical",
+				Description: fmt.Sprintf("NetworkPolicies %s block egress from the pods of %s to service dependency %s/%s port %d",
+					policyNames(egressPolicies), target, dep.Namespace, dep.Name, dep.Port),
+				Resource:        fmt.Sprintf("Service/%s", dep.Name),
+				SuggestedFix:    fmt.Sprintf("Add an egress rule allowing the pods behind Service %s/%s on port %d", dep.Namespace, dep.Name, dep.Port),
+				TargetKind:      "Service",
+				TargetName:      dep.Name,
+				TargetNamespace: dep.Namespace,
+			})
+		}
+	}
+
+	return issues
+}
+
+// egressAllowed reports whether any of the policies allows egress to the service dependency.
+// Services without a pod selector can't be attributed to pods and are assumed reachable.
+func (r *DiagnosticRemediationReconciler) egressAllowed(ctx context.Context, policies []networkingv1.NetworkPolicy, dep aiopsv1alpha1.ServiceDependency) (bool, error) {
+	svc := &corev1.Service{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: dep.Namespace, Name: dep.Name}, svc); err != nil {
+		// A missing Service is reported by the service dependency check
+		return true, client.IgnoreNotFound(err)
+	}
+	if len(svc.Spec.Selector) == 0 {
+		return true, nil
+	}
+	namespace := &corev1.Namespace{}
+	if err := r.Get(ctx, types.NamespacedName{Name: dep.Namespace}, namespace); err != nil {
+		return false, err
+	}
+
+	// Policies match the pod port the Service forwards to, not the Service port
+	port, portName := dep.Port, ""
+	for _, servicePort := range svc.Spec.Ports {
+		if servicePort.Port != dep.Port {
+			continue
+		}
+		switch {
+		case servicePort.TargetPort.Type == intstr.String:
+			port, portName = 0, servicePort.TargetPort.StrVal
+		case servicePort.TargetPort.IntVal != 0:
+			port = servicePort.TargetPort.IntVal
+		}
+	}
+
+	podLabels := labels.Set(svc.Spec.Selector)
+	for _, policy := range policies {
+		for _, rule := range policy.Spec.Egress {
+			if !portAllowed(rule.Ports, port, portName) {
+				continue
+			}
+			if len(rule.To) == 0 {
+				return true, nil
+			}
+			for _, peer := range rule.To {
+				if peerMatches(peer, policy.Namespace, namespace, podLabels) {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// podTemplateLabels returns the labels of the pods the workload creates
+func podTemplateLabels(workload client.Object) map[string]string {
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		return w.Spec.Template.Labels
+	case *appsv1.StatefulSet:
+		return w.Spec.Template.Labels
+	case *appsv1.DaemonSet:
+		return w.Spec.Template.Labels
+	}
+	return nil
+}
+
+// policyTypes returns whether the policy isolates pods for ingress and egress.
+// Without policyTypes, every policy isolates ingress and policies with egress rules isolate egress.
+func policyTypes(policy *networkingv1.NetworkPolicy) (ingress, egress bool) {
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return true, len(policy.Spec.Egress) > 0
+	}
+	for _, policyType := range policy.Spec.PolicyTypes {
+		switch policyType {
+		case networkingv1.PolicyTypeIngress:
+			ingress = true
+		case networkingv1.PolicyTypeEgress:
+			egress = true
+		}
+	}
+	return ingress, egress
+}
+
+// hasRules reports whether any of the policies has an ingress (or egress) rule
+func hasRules(policies []networkingv1.NetworkPolicy, ingress bool) bool {
+	for _, policy := range policies {
+		if (ingress && len(policy.Spec.Ingress) > 0) || (!ingress && len(policy.Spec.Egress) > 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// peerMatches reports whether the peer selects pods with podLabels in the namespace.
+// IP blocks can't be resolved to pods here and are assumed to match.
+func peerMatches(peer networkingv1.NetworkPolicyPeer, policyNamespace string, namespace *corev1.Namespace, podLabels labels.Set) bool {
+	if peer.IPBlock != nil {
+		return true
+	}
+	if peer.NamespaceSelector == nil {
+		if namespace.Name != policyNamespace {
+			return false
+		}
+	} else if !selectorMatches(peer.NamespaceSelector, labels.Set(namespace.Labels)) {
+		return false
+	}
+	return peer.PodSelector == nil || selectorMatches(peer.PodSelector, podLabels)
+}
+
+// portAllowed reports whether the rule ports allow TCP traffic to the port, given by number or,
+// when the Service targets a named container port, by name
+func portAllowed(ports []networkingv1.NetworkPolicyPort, port int32, name string) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	for _, p := range ports {
+		if p.Protocol != nil && *p.Protocol != corev1.ProtocolTCP {
+			continue
+		}
+		if p.Port == nil {
+			return true
+		}
+		if p.Port.Type == intstr.String {
+			if name != "" && p.Port.StrVal == name {
+				return true
+			}
+			continue
+		}
+		if port == 0 {
+			// The named port's number isn't known without the pods, so don't report a false block
+			return true
+		}
+		end := p.Port.IntVal
+		if p.EndPort != nil {
+			end = *p.EndPort
+		}
+		if port >= p.Port.IntVal && port <= end {
+			return true
+		}
+	}
+	return false
+}
+
+func selectorMatches(selector *metav1.LabelSelector, set labels.Set) bool {
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
+	}
+	return s.Matches(set)
+}
+
+func policyNames(policies []networkingv1.NetworkPolicy) string {
+	names := make([]string, 0, len(policies))
+	for _, policy := range policies {
+		names = append(names, policy.Name)
+	}
+	return strings.Join(names, ", ")
+}
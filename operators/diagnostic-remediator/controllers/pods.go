@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+)
+
+// targetPods returns the pods owned by the target workload. Pods are attributed through their
+// owner references (Pod -> ReplicaSet -> Deployment), so other workloads' pods sharing the labels are left out.
+func (r *DiagnosticRemediationReconciler) targetPods(ctx context.Context, dr *aiopsv1alpha1.DiagnosticRemediation) ([]corev1.Pod, error) {
+	workload, err := r.getTargetWorkload(ctx, dr)
+	if err != nil {
+		return nil, err
+	}
+
+	var labelSelector *metav1.LabelSelector
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		labelSelector = w.Spec.Selector
+	case *appsv1.StatefulSet:
+		labelSelector = w.Spec.Selector
+	case *appsv1.DaemonSet:
+		labelSelector = w.Spec.Selector
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector on %s %s: %w", dr.Spec.Target.Kind, workload.GetName(), err)
+	}
+	opts := []client.ListOption{client.InNamespace(workload.GetNamespace()), client.MatchingLabelsSelector{Selector: selector}}
+	if len(dr.Spec.Target.Labels) > 0 {
+		opts = append(opts, client.MatchingLabels(dr.Spec.Target.Labels))
+	}
+
+	// Deployments own their pods through ReplicaSets
+	var replicaSets map[types.UID]bool
+	if _, ok := workload.(*appsv1.Deployment); ok {
+		var list appsv1.ReplicaSetList
+		if err := r.List(ctx, &list, client.InNamespace(workload.GetNamespace()), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			return nil, err
+		}
+		replicaSets = map[types.UID]bool{}
+		for _, rs := range list.Items {
+			if owner := metav1.GetControllerOf(&rs); owner != nil && owner.UID == workload.GetUID() {
+				replicaSets[rs.UID] = true
+			}
+		}
+	}
+
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, opts...); err != nil {
+		return nil, err
+	}
+
+	var owned []corev1.Pod
+	for _, pod := range pods.Items {
+		owner := metav1.GetControllerOf(&pod)
+		if owner == nil {
+			continue
+		}
+		isOwned := owner.UID == workload.GetUID()
+		if replicaSets != nil {
+			isOwned = replicaSets[owner.UID]
+		}
+		if isOwned {
+			owned = append(owned, pod)
+		}
+	}
+	return owned, nil
+}
+
+// podRevision returns the ReplicaSet name for Deployment pods, or the controller-revision-hash for StatefulSet and DaemonSet pods
+func podRevision(pod *corev1.Pod) string {
+	if owner := metav1.GetControllerOf(pod); owner != nil && owner.Kind == "ReplicaSet" {
+		return owner.Name
+	}
+	return pod.Labels[appsv1.ControllerRevisionHashLabelKey]
+}
@@ -0,0 +1,383 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/httpclient"
+)
+
+const (
+	// recommendationTolerance is how far, as a fraction, a request may be off its recommendation
+	// before it is reported, so requests aren't changed for small usage shifts
+	recommendationTolerance = 0.15
+
+	// maxUsageSamples bounds the samples kept per container
+	maxUsageSamples = 10000
+)
+
+// usage holds the p95 CPU (cores) and memory (bytes) usage of a container
+type usage struct {
+	cpu     float64
+	memory  float64
+	samples int
+}
+
+// usageSample is a container's usage at one point in time
+type usageSample struct {
+	time   time.Time
+	cpu    float64
+	memory float64
+}
+
+// usageHistory keeps the metrics-server samples of each DiagnosticRemediation, by target workload
+// and container name. Samples live in memory, so they start over when the operator restarts.
+type usageHistory struct {
+	mu      sync.Mutex
+	samples map[types.NamespacedName]map[string]map[string][]usageSample
+}
+
+// usageSamples is shared by all reconciles
+var usageSamples = &usageHistory{samples: map[types.NamespacedName]map[string]map[string][]usageSample{}}
+
+// add records the samples of the workload and returns each container's samples within the window
+func (h *usageHistory) add(key types.NamespacedName, workload string, samples map[string][]usageSample, window time.Duration) map[string][]usageSample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.samples[key] == nil {
+		h.samples[key] = map[string]map[string][]usageSample{}
+	}
+	history := h.samples[key][workload]
+	if history == nil {
+		history = map[string][]usageSample{}
+		h.samples[key][workload] = history
+	}
+	for container, s := range samples {
+		history[container] = append(history[container], s...)
+	}
+
+	cutoff := time.Now().Add(-window)
+	result := map[string][]usageSample{}
+	for container, s := range history {
+		i := sort.Search(len(s), func(i int) bool { return s[i].time.After(cutoff) })
+		if len(s)-i > maxUsageSamples {
+			i = len(s) - maxUsageSamples
+		}
+		s = s[i:]
+		if len(s) == 0 {
+			delete(history, container)
+			continue
+		}
+		history[container] = s
+		result[container] = append([]usageSample(nil), s...)
+	}
+	return result
+}
+
+// forget drops the samples of a deleted DiagnosticRemediation
+func (h *usageHistory) forget(key types.NamespacedName) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.samples, key)
+}
+
+// checkRecommendations records request recommendations from the p95 of observed usage in status,
+// and reports containers whose requests are off their recommendation
+func (r *DiagnosticRemediationReconciler) checkRecommendations(ctx context.Context, workload client.Object, dr *aiopsv1alpha1.DiagnosticRemediation, logger logr.Logger) []aiopsv1alpha1.DiagnosticIssue {
+	var issues []aiopsv1alpha1.DiagnosticIssue
+
+	var p95 map[string]usage
+	var err error
+	switch dr.Spec.Recommendations.Source {
+	case "Prometheus":
+		p95, err = prometheusUsage(ctx, dr)
+	default:
+		p95, err = r.metricsServerUsage(ctx, dr)
+	}
+	if err != nil {
+		// Keep the last recommendations until usage can be read again
+		logger.Error(err, "Failed to read usage for recommendations", "source", dr.Spec.Recommendations.Source)
+		return issues
+	}
+
+	var containers []corev1.Container
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		containers = w.Spec.Template.Spec.Containers
+	case *appsv1.StatefulSet:
+		containers = w.Spec.Template.Spec.Containers
+	case *appsv1.DaemonSet:
+		containers = w.Spec.Template.Spec.Containers
+	}
+
+	var recommendations []aiopsv1alpha1.ContainerRecommendation
+	for _, container := range containers {
+		u, ok := p95[container.Name]
+		if !ok {
+			continue
+		}
+		cpu := resource.NewMilliQuantity(int64(math.Ceil(u.cpu*1000)), resource.DecimalSI)
+		const mi = 1 << 20
+		memory := resource.NewQuantity(int64(math.Ceil(u.memory/mi))*mi, resource.BinarySI)
+		recommendations = append(recommendations, aiopsv1alpha1.ContainerRecommendation{
+			Container:     container.Name,
+			CPURequest:    cpu.String(),
+			MemoryRequest: memory.String(),
+			Samples:       int32(u.samples),
+		})
+
+		cpuRequest := container.Resources.Requests[corev1.ResourceCPU]
+		memoryRequest := container.Resources.Requests[corev1.ResourceMemory]
+		if !offRecommendation(cpuRequest, *cpu) && !offRecommendation(memoryRequest, *memory) {
+			continue
+		}
+		issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
+			Type:     "RequestsNotRightSized",
+			Severity: "Info",
+			Description: fmt.Sprintf("Container %s requests cpu %s, memory %s; p95 usage is cpu %s, memory %s",
+				container.Name, quantityOrNone(cpuRequest), quantityOrNone(memoryRequest), cpu.String(), memory.String()),
+			Resource:        fmt.Sprintf("%s/%s", dr.Spec.Target.Kind, workload.GetName()),
+			SuggestedFix:    fmt.Sprintf("Set the requests of container %s to cpu %s, memory %s", container.Name, cpu.String(), memory.String()),
+			TargetKind:      dr.Spec.Target.Kind,
+			TargetName:      workload.GetName(),
+			TargetNamespace: workload.GetNamespace(),
+			ContainerName:   container.Name,
+		})
+	}
+	dr.Status.Recommendations = recommendations
+
+	return issues
+}
+
+// metricsServerUsage samples the target's pods and returns the p95 usage of each container with
+// at least minSamples samples in the window
+func (r *DiagnosticRemediationReconciler) metricsServerUsage(ctx context.Context, dr *aiopsv1alpha1.DiagnosticRemediation) (map[string]usage, error) {
+	pods, err := r.targetPods(ctx, dr)
+	if err != nil {
+		return nil, err
+	}
+	samples := map[string][]usageSample{}
+	if len(pods) > 0 {
+		current, err := r.podUsage(ctx, dr.Spec.Target.Namespace)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		for _, pod := range pods {
+			for container, used := range current[pod.Name] {
+				samples[container] = append(samples[container], usageSample{
+					time:   now,
+					cpu:    used.Cpu().AsApproximateFloat64(),
+					memory: used.Memory().AsApproximateFloat64(),
+				})
+			}
+		}
+	}
+
+	window := time.Duration(dr.Spec.Recommendations.WindowSeconds) * time.Second
+	workload := dr.Spec.Target.Namespace + "/" + dr.Spec.Target.Name
+	history := usageSamples.add(types.NamespacedName{Namespace: dr.Namespace, Name: dr.Name}, workload, samples, window)
+
+	p95 := map[string]usage{}
+	for container, s := range history {
+		if len(s) < int(dr.Spec.Recommendations.MinSamples) {
+			continue
+		}
+		cpu := make([]float64, len(s))
+		memory := make([]float64, len(s))
+		for i, sample := range s {
+			cpu[i], memory[i] = sample.cpu, sample.memory
+		}
+		p95[container] = usage{cpu: percentile(cpu, 0.95), memory: percentile(memory, 0.95), samples: len(s)}
+	}
+	return p95, nil
+}
+
+// prometheusUsage queries the p95 usage of each container of the target's pods over the window
+func prometheusUsage(ctx context.Context, dr *aiopsv1alpha1.DiagnosticRemediation) (map[string]usage, error) {
+	spec := dr.Spec.Recommendations
+	if spec.PrometheusURL == "" {
+		return nil, fmt.Errorf("recommendations.prometheusUrl is required with the Prometheus source")
+	}
+
+	// Pods are matched by name, so pods replaced during the window are included
+	var pods string
+	switch dr.Spec.Target.Kind {
+	case "Deployment":
+		pods = dr.Spec.Target.Name + "-[a-z0-9]+-[a-z0-9]+"
+	case "StatefulSet":
+		pods = dr.Spec.Target.Name + "-[0-9]+"
+	default:
+		pods = dr.Spec.Target.Name + "-[a-z0-9]+"
+	}
+	selector := fmt.Sprintf(`namespace=%q,pod=~%q,container!="",container!="POD"`, dr.Spec.Target.Namespace, pods)
+
+	cpu, err := queryByContainer(ctx, spec.PrometheusURL, fmt.Sprintf(
+		`max by (container) (quantile_over_time(0.95, rate(container_cpu_usage_seconds_total{%s}[5m])[%ds:1m]))`, selector, spec.WindowSeconds))
+	if err != nil {
+		return nil, err
+	}
+	memory, err := queryByContainer(ctx, spec.PrometheusURL, fmt.Sprintf(
+		`max by (container) (quantile_over_time(0.95, container_memory_working_set_bytes{%s}[%ds]))`, selector, spec.WindowSeconds))
+	if err != nil {
+		return nil, err
+	}
+
+	p95 := map[string]usage{}
+	for container, value := range cpu {
+		if mem, ok := memory[container]; ok {
+			p95[container] = usage{cpu: value, memory: mem}
+		}
+	}
+	return p95, nil
+}
+
+// queryByContainer runs an instant Prometheus query and returns the value of each series by its container label
+func queryByContainer(ctx context.Context, endpoint, query string) (map[string]float64, error) {
+	httpClient, err := httpclient.New(httpclient.Config{Timeout: 30 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	reqURL := fmt.Sprintf("%s/api/v1/query?%s", strings.TrimRight(endpoint, "/"), url.Values{"query": {query}}.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Prometheus: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Prometheus API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Data struct {
+			Result []struct {
+				Metric map[string]string `json:"metric"`
+				Value  []interface{}     `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	values := map[string]float64{}
+	for _, series := range result.Data.Result {
+		if len(series.Value) != 2 {
+			continue
+		}
+		raw, _ := series.Value[1].(string)
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(value) {
+			continue
+		}
+		values[series.Metric["container"]] = value
+	}
+	return values, nil
+}
+
+// applyRecommendations sets the requests of each container with a RequestsNotRightSized issue to
+// its recommendation, capped at the container's limits, and describes each change
+func applyRecommendations(workload client.Object, dr *aiopsv1alpha1.DiagnosticRemediation, issues []aiopsv1alpha1.DiagnosticIssue) []string {
+	var containers []corev1.Container
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		containers = w.Spec.Template.Spec.Containers
+	case *appsv1.StatefulSet:
+		containers = w.Spec.Template.Spec.Containers
+	case *appsv1.DaemonSet:
+		containers = w.Spec.Template.Spec.Containers
+	}
+
+	recommendations := map[string]aiopsv1alpha1.ContainerRecommendation{}
+	for _, rec := range dr.Status.Recommendations {
+		recommendations[rec.Container] = rec
+	}
+
+	var changes []string
+	for _, issue := range issues {
+		rec, ok := recommendations[issue.ContainerName]
+		if issue.Type != "RequestsNotRightSized" || !ok {
+			continue
+		}
+		for i := range containers {
+			container := &containers[i]
+			if container.Name != issue.ContainerName {
+				continue
+			}
+			if container.Resources.Requests == nil {
+				container.Resources.Requests = make(corev1.ResourceList)
+			}
+			changed := false
+			for name, value := range map[corev1.ResourceName]string{corev1.ResourceCPU: rec.CPURequest, corev1.ResourceMemory: rec.MemoryRequest} {
+				qty, err := resource.ParseQuantity(value)
+				if err != nil {
+					continue
+				}
+				if limit, ok := container.Resources.Limits[name]; ok && qty.Cmp(limit) > 0 {
+					qty = limit.DeepCopy()
+				}
+				if current := container.Resources.Requests[name]; current.Cmp(qty) != 0 {
+					container.Resources.Requests[name] = qty
+					changed = true
+				}
+			}
+			if changed {
+				cpu := container.Resources.Requests[corev1.ResourceCPU]
+				memory := container.Resources.Requests[corev1.ResourceMemory]
+				changes = append(changes, fmt.Sprintf("Set the requests of container %s to cpu %s, memory %s", container.Name, cpu.String(), memory.String()))
+			}
+		}
+	}
+	return changes
+}
+
+// offRecommendation reports whether the request is unset or further than the tolerance from the recommendation
+func offRecommendation(request, recommendation resource.Quantity) bool {
+	if request.IsZero() {
+		return true
+	}
+	want := recommendation.AsApproximateFloat64()
+	return math.Abs(request.AsApproximateFloat64()-want) > want*recommendationTolerance
+}
+
+func quantityOrNone(qty resource.Quantity) string {
+	if qty.IsZero() {
+		return "none"
+	}
+	return qty.String()
+}
+
+// percentile returns the p-th percentile of the values using the nearest-rank method
+func percentile(values []float64, p float64) float64 {
+	sort.Float64s(values)
+	rank := int(math.Ceil(p*float64(len(values)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return values[rank]
+}
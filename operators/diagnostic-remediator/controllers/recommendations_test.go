@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+	"github.com/prophet-aiops/diagnostic-remediator/internal/builders"
+	"github.com/prophet-aiops/pkg/testenv"
+)
+
+// newPrometheus returns a fake Prometheus reporting the given p95 CPU (cores) and memory (bytes)
+// usage by container
+func newPrometheus(t *testing.T, cpu, memory map[string]float64) *testenv.Prometheus {
+	return testenv.NewPrometheus(t, func(promQL string) []testenv.Series {
+		values := cpu
+		if strings.Contains(promQL, "container_memory_working_set_bytes") {
+			values = memory
+		}
+		var series []testenv.Series
+		for container, value := range values {
+			series = append(series, testenv.Series{Labels: map[string]string{"container": container}, Value: value})
+		}
+		return series
+	})
+}
+
+func TestPrometheusUsage(t *testing.T) {
+	prometheus := newPrometheus(t,
+		map[string]float64{"app": 0.25, "sidecar": 0.01},
+		map[string]float64{"app": 200 << 20})
+
+	p95, err := prometheusUsage(context.Background(), builders.DiagnosticRemediation("shop", builders.Recommendations(prometheus.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p95) != 1 || p95["app"].cpu != 0.25 || p95["app"].memory != 200<<20 {
+		t.Errorf("expected usage for the containers with both metrics, got %+v", p95)
+	}
+
+	queries := prometheus.Queries()
+	if len(queries) != 2 {
+		t.Fatalf("expected a CPU and a memory query, got %v", queries)
+	}
+	for _, query := range queries {
+		if !strings.Contains(query, `namespace="shop",pod=~"web-[a-z0-9]+-[a-z0-9]+"`) || !strings.Contains(query, "3600s") {
+			t.Errorf("query doesn't select the Deployment's pods over the window: %s", query)
+		}
+	}
+
+	if _, err := prometheusUsage(context.Background(), builders.DiagnosticRemediation("shop", builders.Recommendations(""))); err == nil {
+		t.Error("expected an error without prometheusUrl")
+	}
+}
+
+func TestRecommendations(t *testing.T) {
+	prometheus := newPrometheus(t,
+		map[string]float64{"app": 0.25, "sidecar": 0.0101},
+		map[string]float64{"app": 200 << 20, "sidecar": 30 << 20})
+	dr := builders.DiagnosticRemediation("shop", builders.Recommendations(prometheus.URL))
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{
+				Name: "app",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1"), corev1.ResourceMemory: resource.MustParse("1Gi")},
+					Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2"), corev1.ResourceMemory: resource.MustParse("128Mi")},
+				},
+			},
+			{
+				Name: "sidecar",
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("11m"), corev1.ResourceMemory: resource.MustParse("32Mi")},
+				},
+			},
+		}}}},
+	}
+
+	r := &DiagnosticRemediationReconciler{}
+	issues := r.checkRecommendations(context.Background(), deployment, dr, logr.Discard())
+	if len(issues) != 1 || issues[0].Type != "RequestsNotRightSized" || issues[0].ContainerName != "app" {
+		t.Fatalf("expected only the app container to be reported, got %+v", issues)
+	}
+	want := []aiopsv1alpha1.ContainerRecommendation{
+		{Container: "app", CPURequest: "250m", MemoryRequest: "200Mi"},
+		{Container: "sidecar", CPURequest: "11m", MemoryRequest: "30Mi"},
+	}
+	if len(dr.Status.Recommendations) != len(want) {
+		t.Fatalf("got recommendations %+v, want %+v", dr.Status.Recommendations, want)
+	}
+	for i, rec := range dr.Status.Recommendations {
+		if rec != want[i] {
+			t.Errorf("got recommendation %+v, want %+v", rec, want[i])
+		}
+	}
+
+	// Requests are capped at the limits
+	changes := applyRecommendations(deployment, dr, issues)
+	if len(changes) != 1 {
+		t.Fatalf("expected one change, got %v", changes)
+	}
+	requests := deployment.Spec.Template.Spec.Containers[0].Resources.Requests
+	if cpu, memory := requests[corev1.ResourceCPU], requests[corev1.ResourceMemory]; cpu.String() != "250m" || memory.String() != "128Mi" {
+		t.Errorf("got requests cpu %s, memory %s", cpu.String(), memory.String())
+	}
+	if sidecar := deployment.Spec.Template.Spec.Containers[1].Resources.Requests[corev1.ResourceCPU]; sidecar.String() != "11m" {
+		t.Errorf("expected the sidecar to be left alone, got cpu %s", sidecar.String())
+	}
+}
+
+func TestOffRecommendation(t *testing.T) {
+	tests := []struct {
+		request, recommendation string
+		want                    bool
+	}{
+		{request: "0", recommendation: "100m", want: true},
+		{request: "110m", recommendation: "100m"},
+		{request: "90m", recommendation: "100m"},
+		{request: "200m", recommendation: "100m", want: true},
+		{request: "80m", recommendation: "100m", want: true},
+	}
+	for _, tt := range tests {
+		if got := offRecommendation(resource.MustParse(tt.request), resource.MustParse(tt.recommendation)); got != tt.want {
+			t.Errorf("offRecommendation(%s, %s) = %v, want %v", tt.request, tt.recommendation, got, tt.want)
+		}
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := make([]float64, 0, 100)
+	for i := 100; i >= 1; i-- {
+		values = append(values, float64(i))
+	}
+	if got := percentile(values, 0.95); got != 95 {
+		t.Errorf("got p95 %v, want 95", got)
+	}
+	if got := percentile([]float64{3}, 0.95); got != 3 {
+		t.Errorf("got p95 %v of a single value, want 3", got)
+	}
+	if got := percentile([]float64{1, 2}, 0); got != 1 {
+		t.Errorf("got p0 %v, want 1", got)
+	}
+}
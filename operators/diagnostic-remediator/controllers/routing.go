@@ -0,0 +1,269 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+)
+
+// checkRouting reports why traffic may not reach the target: Services meant for it whose selector
+// doesn't match its pods, Services without ready endpoints or targeting ports its containers don't
+// declare, and Ingress backends referencing missing Services or ports.
+//
+// A Service is meant for the target if its selector matches the target's pods, if it has the
+// target's name, or if its selector matches no pod at all but shares a label key with the target's pods.
+func (r *DiagnosticRemediationReconciler) checkRouting(ctx context.Context, workload client.Object, dr *aiopsv1alpha1.DiagnosticRemediation, logger logr.Logger) []aiopsv1alpha1.DiagnosticIssue {
+	var issues []aiopsv1alpha1.DiagnosticIssue
+	namespace := workload.GetNamespace()
+	target := fmt.Sprintf("%s/%s", dr.Spec.Target.Kind, workload.GetName())
+	podLabels := labels.Set(podTemplateLabels(workload))
+
+	var services corev1.ServiceList
+	if err := r.List(ctx, &services, client.InNamespace(namespace)); err != nil {
+		logger.Error(err, "Failed to list services")
+		return issues
+	}
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(namespace)); err != nil {
+		logger.Error(err, "Failed to list pods")
+		return issues
+	}
+
+	// Services routing to the target, by name; mismatched ones are reported but not checked further
+	related := map[string]*corev1.Service{}
+	for i := range services.Items {
+		svc := &services.Items[i]
+		if len(svc.Spec.Selector) == 0 {
+			continue
+		}
+		selector := labels.SelectorFromSet(svc.Spec.Selector)
+		if selector.Matches(podLabels) {
+			related[svc.Name] = svc
+			continue
+		}
+		if svc.Name != workload.GetName() && !(sharesKey(svc.Spec.Selector, podLabels) && !matchesAnyPod(selector, pods.Items)) {
+			continue
+		}
+		related[svc.Name] = nil
+		issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
+			Type:     "ServiceSelectorMismatch",
+			Severity: "Critical",
+			Description: fmt.Sprintf("Service %s selects %s, which doesn't match the pods of %s labeled %s",
+				svc.Name, labels.Set(svc.Spec.Selector).String(), target, podLabels.String()),
+			Resource:        "Service/" + svc.Name,
+			SuggestedFix:    fmt.Sprintf("Change the selector of Service %s to labels the pods of %s carry", svc.Name, target),
+			TargetKind:      "Service",
+			TargetName:      svc.Name,
+			TargetNamespace: namespace,
+		})
+	}
+
+	containerPorts := podTemplatePorts(workload)
+	names := make([]string, 0, len(related))
+	for name := range related {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		svc := related[name]
+		if svc == nil {
+			continue
+		}
+
+		for _, port := range svc.Spec.Ports {
+			if port.TargetPort.Type == intstr.String && !containerPorts[port.TargetPort.StrVal] {
+				issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
+					Type:            "ServiceTargetPortMismatch",
+					Severity:        "Critical",
+					Description:     fmt.Sprintf("Service %s port %d targets container port %q, which no container of %s declares", svc.Name, port.Port, port.TargetPort.StrVal, target),
+					Resource:        "Service/" + svc.Name,
+					SuggestedFix:    fmt.Sprintf("Name a container port %q or change the Service's targetPort", port.TargetPort.StrVal),
+					TargetKind:      "Service",
+					TargetName:      svc.Name,
+					TargetNamespace: namespace,
+				})
+			}
+		}
+
+		ready, err := r.readyEndpoints(ctx, svc)
+		if err != nil {
+			logger.Error(err, "Failed to list endpoint slices", "service", svc.Name)
+			continue
+		}
+		if ready == 0 {
+			issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
+				Type:            "ServiceNoEndpoints",
+				Severity:        "Critical",
+				Description:     fmt.Sprintf("Service %s has no ready endpoints; none of the pods of %s it selects are ready", svc.Name, target),
+				Resource:        "Service/" + svc.Name,
+				SuggestedFix:    "Check why the pods aren't ready, e.g. failing readiness probes",
+				TargetKind:      "Service",
+				TargetName:      svc.Name,
+				TargetNamespace: namespace,
+			})
+		}
+	}
+
+	var ingresses networkingv1.IngressList
+	if err := r.List(ctx, &ingresses, client.InNamespace(namespace)); err != nil {
+		logger.Error(err, "Failed to list ingresses")
+		return issues
+	}
+	existing := map[string]*corev1.Service{}
+	for i := range services.Items {
+		existing[services.Items[i].Name] = &services.Items[i]
+	}
+	for _, ingress := range ingresses.Items {
+		for _, backend := range ingressBackends(&ingress) {
+			name := backend.service.Name
+			if _, ok := related[name]; !ok && name != workload.GetName() {
+				continue
+			}
+			svc, ok := existing[name]
+			switch {
+			case !ok:
+				issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
+					Type:            "IngressBackendMissingService",
+					Severity:        "Critical",
+					Description:     fmt.Sprintf("Ingress %s routes %s to Service %s, which doesn't exist", ingress.Name, backend.path, name),
+					Resource:        "Ingress/" + ingress.Name,
+					SuggestedFix:    fmt.Sprintf("Create Service %s for %s or point the Ingress at an existing Service", name, target),
+					TargetKind:      "Ingress",
+					TargetName:      ingress.Name,
+					TargetNamespace: namespace,
+				})
+			case !servicePortExists(svc, backend.service.Port):
+				issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
+					Type:     "IngressBackendPortMismatch",
+					Severity: "Critical",
+					Description: fmt.Sprintf("Ingress %s routes %s to port %s of Service %s, which doesn't expose it",
+						ingress.Name, backend.path, backendPort(backend.service.Port), name),
+					Resource:        "Ingress/" + ingress.Name,
+					SuggestedFix:    fmt.Sprintf("Use a port Service %s exposes", name),
+					TargetKind:      "Ingress",
+					TargetName:      ingress.Name,
+					TargetNamespace: namespace,
+				})
+			}
+		}
+	}
+
+	return issues
+}
+
+// readyEndpoints counts the ready endpoints in the Service's EndpointSlices
+func (r *DiagnosticRemediationReconciler) readyEndpoints(ctx context.Context, svc *corev1.Service) (int, error) {
+	var slices discoveryv1.EndpointSliceList
+	if err := r.List(ctx, &slices, client.InNamespace(svc.Namespace), client.MatchingLabels{discoveryv1.LabelServiceName: svc.Name}); err != nil {
+		return 0, err
+	}
+	ready := 0
+	for _, slice := range slices.Items {
+		for _, endpoint := range slice.Endpoints {
+			// A nil ready condition means ready
+			if endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready {
+				ready++
+			}
+		}
+	}
+	return ready, nil
+}
+
+// ingressBackend is a Service backend of an Ingress and the path routed to it
+type ingressBackend struct {
+	path    string
+	service *networkingv1.IngressServiceBackend
+}
+
+// ingressBackends returns the Service backends of the Ingress
+func ingressBackends(ingress *networkingv1.Ingress) []ingressBackend {
+	var backends []ingressBackend
+	if backend := ingress.Spec.DefaultBackend; backend != nil && backend.Service != nil {
+		backends = append(backends, ingressBackend{path: "unmatched requests", service: backend.Service})
+	}
+	for _, rule := range ingress.Spec.Rules {
+		if rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			if path.Backend.Service == nil {
+				continue
+			}
+			host := rule.Host
+			if host == "" {
+				host = "*"
+			}
+			backends = append(backends, ingressBackend{path: host + path.Path, service: path.Backend.Service})
+		}
+	}
+	return backends
+}
+
+// servicePortExists reports whether the Service exposes the Ingress backend port
+func servicePortExists(svc *corev1.Service, port networkingv1.ServiceBackendPort) bool {
+	for _, p := range svc.Spec.Ports {
+		if (port.Name != "" && p.Name == port.Name) || (port.Name == "" && p.Port == port.Number) {
+			return true
+		}
+	}
+	return false
+}
+
+func backendPort(port networkingv1.ServiceBackendPort) string {
+	if port.Name != "" {
+		return port.Name
+	}
+	return fmt.Sprintf("%d", port.Number)
+}
+
+// podTemplatePorts returns the names of the container ports the workload's pods declare
+func podTemplatePorts(workload client.Object) map[string]bool {
+	var containers []corev1.Container
+	switch w := workload.(type) {
+	case *appsv1.Deployment:
+		containers = w.Spec.Template.Spec.Containers
+	case *appsv1.StatefulSet:
+		containers = w.Spec.Template.Spec.Containers
+	case *appsv1.DaemonSet:
+		containers = w.Spec.Template.Spec.Containers
+	}
+	ports := map[string]bool{}
+	for _, container := range containers {
+		for _, port := range container.Ports {
+			if port.Name != "" {
+				ports[port.Name] = true
+			}
+		}
+	}
+	return ports
+}
+
+// sharesKey reports whether the selector uses any of the label keys
+func sharesKey(selector map[string]string, set labels.Set) bool {
+	for key := range selector {
+		if set.Has(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAnyPod(selector labels.Selector, pods []corev1.Pod) bool {
+	for _, pod := range pods {
+		if selector.Matches(labels.Set(pod.Labels)) {
+			return true
+		}
+	}
+	return false
+}
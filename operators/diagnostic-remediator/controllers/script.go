@@ -0,0 +1,256 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/policy"
+)
+
+const (
+	// scriptJobAnnotation records the DiagnosticRemediation that started a custom script Job
+	scriptJobAnnotation = "aiops.prophet.io/diagnostic-remediation"
+
+	// scriptJobTTL removes finished Jobs whose result was never collected, e.g. after the DiagnosticRemediation was deleted
+	scriptJobTTL = int32(600)
+
+	// scriptJobUser is the unprivileged user and group custom scripts run as
+	scriptJobUser = int64(65532)
+)
+
+// checkCustomScript runs the custom diagnostic script in a Job. A run spans reconciles: one creates
+// the Job, a later one collects the result and deletes the Job so the next reconcile starts a new run.
+// While the script runs, the issues it reported last time are kept.
+func (r *DiagnosticRemediationReconciler) checkCustomScript(ctx context.Context, workload client.Object, dr *aiopsv1alpha1.DiagnosticRemediation, logger logr.Logger) []aiopsv1alpha1.DiagnosticIssue {
+	// Jobs only ever run in the DiagnosticRemediation's own namespace
+	if workload.GetNamespace() != dr.Namespace {
+		return []aiopsv1alpha1.DiagnosticIssue{{
+			Type:     "CustomScriptDenied",
+			Severity: "Info",
+			Description: fmt.Sprintf("Not running the custom diagnostic script for %s %s/%s outside the DiagnosticRemediation's namespace",
+				dr.Spec.Target.Kind, workload.GetNamespace(), workload.GetName()),
+			Resource:        fmt.Sprintf("%s/%s", dr.Spec.Target.Kind, workload.GetName()),
+			TargetKind:      dr.Spec.Target.Kind,
+			TargetName:      workload.GetName(),
+			TargetNamespace: workload.GetNamespace(),
+		}}
+	}
+
+	job := &batchv1.Job{}
+	key := types.NamespacedName{Namespace: dr.Namespace, Name: scriptJobName(dr)}
+	if err := r.Get(ctx, key, job); err != nil {
+		if !apierrors.IsNotFound(err) {
+			logger.Error(err, "Failed to get custom script job", "job", key.Name)
+			return previousScriptIssues(dr)
+		}
+
+		job = newScriptJob(dr, workload, key.Name)
+		if decision := r.checkGuardrails(ctx, policy.Action{
+			Type:      "RunScript",
+			Kind:      "Job",
+			Name:      job.Name,
+			Namespace: job.Namespace,
+			Labels:    workload.GetLabels(),
+			Reason:    "custom diagnostic script",
+		}); !decision.Allowed {
+			return []aiopsv1alpha1.DiagnosticIssue{{
+				Type:            "CustomScriptDenied",
+				Severity:        "Info",
+				Description:     fmt.Sprintf("Custom diagnostic script denied by guardrail policy %s: %s", decision.Rule, decision.Message),
+				Resource:        "Job/" + job.Name,
+				TargetKind:      "Job",
+				TargetName:      job.Name,
+				TargetNamespace: job.Namespace,
+			}}
+		}
+		if err := r.Create(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+			logger.Error(err, "Failed to create custom script job", "job", key.Name)
+			return []aiopsv1alpha1.DiagnosticIssue{{
+				Type:            "CustomScriptFailed",
+				Severity:        "Warning",
+				Description:     fmt.Sprintf("Failed to start custom diagnostic script: %v", err),
+				Resource:        "Job/" + key.Name,
+				SuggestedFix:    "Check that the operator may create Jobs in the target namespace",
+				TargetKind:      "Job",
+				TargetName:      key.Name,
+				TargetNamespace: key.Namespace,
+			}}
+		}
+		if dr.Spec.Diagnostics.ScriptJob.ServiceAccountName != "" {
+			logger.Info("Ignoring scriptJob.serviceAccountName, custom scripts run without a service account token")
+		}
+		logger.Info("Started custom diagnostic script", "job", key.Name)
+		return previousScriptIssues(dr)
+	}
+
+	finished, condition := jobFinished(job)
+	if !finished || job.DeletionTimestamp != nil {
+		return previousScriptIssues(dr)
+	}
+
+	var issues []aiopsv1alpha1.DiagnosticIssue
+	switch {
+	case condition == nil:
+		// Completed: the script exited with 0
+	case condition.Reason == "DeadlineExceeded":
+		issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
+			Type:            "CustomScriptTimeout",
+			Severity:        "Warning",
+			Description:     fmt.Sprintf("Custom diagnostic script didn't finish within %ds", dr.Spec.Diagnostics.ScriptJob.TimeoutSeconds),
+			Resource:        "Job/" + job.Name,
+			SuggestedFix:    "Increase scriptJob.timeoutSeconds or make the script finish sooner",
+			TargetKind:      "Job",
+			TargetName:      job.Name,
+			TargetNamespace: job.Namespace,
+		})
+	default:
+		exitCode, output := r.scriptOutput(ctx, job)
+		issues = append(issues, aiopsv1alpha1.DiagnosticIssue{
+			Type:            "CustomScriptFailed",
+			Severity:        "Warning",
+			Description:     fmt.Sprintf("Custom diagnostic script exited with code %d: %s", exitCode, output),
+			Resource:        "Job/" + job.Name,
+			TargetKind:      "Job",
+			TargetName:      job.Name,
+			TargetNamespace: job.Namespace,
+		})
+	}
+
+	// Delete the Job and its pod; the next reconcile runs the script again
+	if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+		logger.Error(err, "Failed to delete custom script job", "job", job.Name)
+	}
+	return issues
+}
+
+// newScriptJob builds the Job running the custom diagnostic script against the workload. The Job
+// gets no credentials: it runs without a service account token, as a non-root user without
+// capabilities.
+func newScriptJob(dr *aiopsv1alpha1.DiagnosticRemediation, workload client.Object, name string) *batchv1.Job {
+	spec := dr.Spec.Diagnostics.ScriptJob
+	backoffLimit := int32(0)
+	deadline := int64(spec.TimeoutSeconds)
+	ttl := scriptJobTTL
+	automountToken := false
+	nonRoot := true
+	user := scriptJobUser
+	privilegeEscalation := false
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   dr.Namespace,
+			Labels:      map[string]string{"app.kubernetes.io/managed-by": "diagnostic-remediator"},
+			Annotations: map[string]string{scriptJobAnnotation: dr.Namespace + "/" + dr.Name},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			ActiveDeadlineSeconds:   &deadline,
+			TTLSecondsAfterFinished: &ttl,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy:                corev1.RestartPolicyNever,
+					AutomountServiceAccountToken: &automountToken,
+					SecurityContext: &corev1.PodSecurityContext{
+						RunAsNonRoot:   &nonRoot,
+						RunAsUser:      &user,
+						RunAsGroup:     &user,
+						SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+					},
+					Containers: []corev1.Container{{
+						Name:    "script",
+						Image:   spec.Image,
+						Command: []string{"sh", "-c", dr.Spec.Diagnostics.CustomScript},
+						Env: []corev1.EnvVar{
+							{Name: "TARGET_KIND", Value: dr.Spec.Target.Kind},
+							{Name: "TARGET_NAME", Value: workload.GetName()},
+							{Name: "TARGET_NAMESPACE", Value: workload.GetNamespace()},
+						},
+						SecurityContext: &corev1.SecurityContext{
+							AllowPrivilegeEscalation: &privilegeEscalation,
+							Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+						},
+						// A failing script's output becomes the termination message
+						TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+					}},
+				},
+			},
+		},
+	}
+}
+
+// scriptOutput returns the exit code and output of the Job's script container
+func (r *DiagnosticRemediationReconciler) scriptOutput(ctx context.Context, job *batchv1.Job) (int32, string) {
+	selector, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+	if err != nil {
+		return -1, "no output"
+	}
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return -1, "no output"
+	}
+	for _, pod := range pods.Items {
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			if terminated := containerStatus.State.Terminated; terminated != nil {
+				output := strings.TrimSpace(terminated.Message)
+				if output == "" {
+					output = "no output"
+				}
+				return terminated.ExitCode, output
+			}
+		}
+	}
+	return -1, "no output"
+}
+
+// jobFinished reports whether the Job has finished, returning its Failed condition if it failed
+func jobFinished(job *batchv1.Job) (bool, *batchv1.JobCondition) {
+	for i := range job.Status.Conditions {
+		condition := &job.Status.Conditions[i]
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return true, nil
+		case batchv1.JobFailed:
+			return true, condition
+		}
+	}
+	return false, nil
+}
+
+// previousScriptIssues returns the issues the last script run reported
+func previousScriptIssues(dr *aiopsv1alpha1.DiagnosticRemediation) []aiopsv1alpha1.DiagnosticIssue {
+	var issues []aiopsv1alpha1.DiagnosticIssue
+	for _, issue := range dr.Status.Issues {
+		if strings.HasPrefix(issue.Type, "CustomScript") {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// scriptJobName returns the name of the DiagnosticRemediation's script Job for its target. The hash
+// keeps DiagnosticRemediations with the same name in different namespaces, and the workloads a
+// selector matches, apart.
+func scriptJobName(dr *aiopsv1alpha1.DiagnosticRemediation) string {
+	h := fnv.New32a()
+	h.Write([]byte(dr.Namespace + "/" + dr.Name + "/" + dr.Spec.Target.Namespace + "/" + dr.Spec.Target.Name))
+	name := dr.Name
+	if len(name) > 46 {
+		name = name[:46]
+	}
+	return fmt.Sprintf("%s-script-%08x", strings.TrimRight(name, "-."), h.Sum32())
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+	"github.com/prophet-aiops/diagnostic-remediator/internal/builders"
+	"github.com/prophet-aiops/pkg/testenv"
+)
+
+func withScript(dr *aiopsv1alpha1.DiagnosticRemediation) {
+	dr.Spec.Diagnostics.CustomScript = "exit 0"
+	dr.Spec.Diagnostics.ScriptJob = &aiopsv1alpha1.ScriptJobSpec{Image: "busybox:1.36", ServiceAccountName: "admin", TimeoutSeconds: 60}
+}
+
+func TestNewScriptJob(t *testing.T) {
+	dr := builders.DiagnosticRemediation("shop", withScript)
+	job := newScriptJob(dr, testenv.Deployment("shop", "web"), scriptJobName(dr))
+
+	if job.Namespace != "shop" {
+		t.Errorf("got namespace %q, want shop", job.Namespace)
+	}
+	spec := job.Spec.Template.Spec
+	if spec.ServiceAccountName != "" {
+		t.Errorf("expected the service account to be ignored, got %q", spec.ServiceAccountName)
+	}
+	if spec.AutomountServiceAccountToken == nil || *spec.AutomountServiceAccountToken {
+		t.Error("expected the service account token not to be mounted")
+	}
+	if sc := spec.SecurityContext; sc == nil || sc.RunAsNonRoot == nil || !*sc.RunAsNonRoot || sc.RunAsUser == nil || *sc.RunAsUser != scriptJobUser {
+		t.Errorf("expected the pod to run as user %d, got %+v", scriptJobUser, sc)
+	}
+	sc := spec.Containers[0].SecurityContext
+	if sc == nil || sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+		t.Fatalf("expected privilege escalation to be disallowed, got %+v", sc)
+	}
+	if sc.Capabilities == nil || len(sc.Capabilities.Drop) != 1 || sc.Capabilities.Drop[0] != "ALL" {
+		t.Errorf("expected all capabilities to be dropped, got %+v", sc.Capabilities)
+	}
+}
+
+// TestCheckCustomScriptOtherNamespace checks that no Job is started for a target outside the
+// DiagnosticRemediation's namespace
+func TestCheckCustomScriptOtherNamespace(t *testing.T) {
+	r := &DiagnosticRemediationReconciler{}
+	dr := builders.DiagnosticRemediation("shop", withScript)
+
+	issues := r.checkCustomScript(context.Background(), testenv.Deployment("kube-system", "web"), dr, logr.Discard())
+	if len(issues) != 1 || issues[0].Type != "CustomScriptDenied" || issues[0].TargetNamespace != "kube-system" {
+		t.Errorf("expected a CustomScriptDenied issue, got %+v", issues)
+	}
+}
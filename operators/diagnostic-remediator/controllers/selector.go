@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/conditions"
+	"github.com/prophet-aiops/pkg/prophetconfig"
+)
+
+// reconcileSelector reconciles each workload the target selector matches as if it were the only
+// target, with the per-workload state kept in status.targets, and merges the results into dr's status.
+// The remediations-per-hour limit applies across all workloads.
+func (r *DiagnosticRemediationReconciler) reconcileSelector(ctx context.Context, dr *aiopsv1alpha1.DiagnosticRemediation, defaults prophetconfig.Spec, logger logr.Logger) time.Duration {
+	workloads, err := r.selectTargets(ctx, dr)
+	if err != nil {
+		logger.Error(err, "Failed to list target workloads")
+		dr.Status.Phase = "Failed"
+		dr.Status.ErrorMessage = err.Error()
+		return 1 * time.Minute
+	}
+
+	previous := map[string]aiopsv1alpha1.TargetStatus{}
+	for _, t := range dr.Status.Targets {
+		previous[t.Namespace+"/"+t.Name] = t
+	}
+	previousIssues := map[string][]aiopsv1alpha1.DiagnosticIssue{}
+	for _, issue := range dr.Status.Issues {
+		previousIssues[issue.Workload] = append(previousIssues[issue.Workload], issue)
+	}
+
+	requeue := 1 * time.Minute
+	var issues []aiopsv1alpha1.DiagnosticIssue
+	var targets []aiopsv1alpha1.TargetStatus
+	for _, workload := range workloads {
+		key := workload.GetNamespace() + "/" + workload.GetName()
+		prev := previous[key]
+
+		single := dr.DeepCopy()
+		single.Spec.Target.Namespace = workload.GetNamespace()
+		single.Spec.Target.Name = workload.GetName()
+		single.Spec.Target.Selector = nil
+		single.Spec.Target.AllNamespaces = false
+		single.Status = aiopsv1alpha1.DiagnosticRemediationStatus{
+			Issues:            previousIssues[key],
+			Remediations:      append([]aiopsv1alpha1.RemediationAction(nil), dr.Status.Remediations...),
+			LastRemediated:    prev.LastRemediated,
+			MaintenanceWindow: dr.Status.MaintenanceWindow,
+			MemoryPressure:    prev.MemoryPressure,
+			Recommendations:   prev.Recommendations,
+			Plan:              prev.Plan,
+		}
+		if after := r.reconcileTarget(ctx, single, defaults, logger.WithValues("workload", key)); after < requeue {
+			requeue = after
+		}
+
+		for _, issue := range single.Status.Issues {
+			issue.Workload = key
+			issues = append(issues, issue)
+		}
+		added := single.Status.Remediations[len(dr.Status.Remediations):]
+		for _, remediation := range added {
+			remediation.Workload = key
+			dr.Status.Remediations = append(dr.Status.Remediations, remediation)
+		}
+		dr.Status.RemediationCount += int32(len(added))
+
+		t := aiopsv1alpha1.TargetStatus{
+			Namespace:       workload.GetNamespace(),
+			Name:            workload.GetName(),
+			Phase:           single.Status.Phase,
+			IssueCount:      int32(len(single.Status.Issues)),
+			LastRemediated:  single.Status.LastRemediated,
+			MemoryPressure:  single.Status.MemoryPressure,
+			Recommendations: single.Status.Recommendations,
+			Plan:            single.Status.Plan,
+		}
+		if condition := meta.FindStatusCondition(single.Status.Conditions, conditions.Remediating); condition != nil {
+			t.Remediating = condition.Status == metav1.ConditionTrue
+			t.Reason, t.Message = condition.Reason, condition.Message
+		}
+		targets = append(targets, t)
+	}
+
+	dr.Status.Issues = issues
+	dr.Status.Targets = targets
+	dr.Status.MemoryPressure = nil
+	dr.Status.Recommendations = nil
+	dr.Status.Plan = nil
+	summarizeTargets(dr)
+	return requeue
+}
+
+// summarizeTargets sets dr's phase, last remediation and Remediating condition from its targets.
+// The Remediating condition follows the first target being remediated, or else the first with issues.
+func summarizeTargets(dr *aiopsv1alpha1.DiagnosticRemediation) {
+	dr.Status.Phase = "Resolved"
+	dr.Status.LastRemediated = nil
+	var remediating, withIssues *aiopsv1alpha1.TargetStatus
+	for i := range dr.Status.Targets {
+		t := &dr.Status.Targets[i]
+		if t.LastRemediated != nil && (dr.Status.LastRemediated == nil || t.LastRemediated.After(dr.Status.LastRemediated.Time)) {
+			dr.Status.LastRemediated = t.LastRemediated
+		}
+		if t.IssueCount > 0 || t.Phase == "IssuesFound" {
+			dr.Status.Phase = "IssuesFound"
+			if withIssues == nil {
+				withIssues = t
+			}
+		}
+		if t.Remediating && remediating == nil {
+			remediating = t
+		}
+	}
+
+	switch {
+	case len(dr.Status.Targets) == 0:
+		setRemediating(dr, false, "NoTargets", "No workload matches the target selector")
+	case remediating != nil:
+		setRemediating(dr, true, remediating.Reason, fmt.Sprintf("%s/%s: %s", remediating.Namespace, remediating.Name, remediating.Message))
+	case withIssues != nil:
+		setRemediating(dr, false, withIssues.Reason, fmt.Sprintf("%s/%s: %s", withIssues.Namespace, withIssues.Name, withIssues.Message))
+	default:
+		setRemediating(dr, false, "NoIssues", "No remediation needed")
+	}
+}
+
+// selectTargets returns the workloads of the target kind matching the target selector, sorted by namespace and name
+func (r *DiagnosticRemediationReconciler) selectTargets(ctx context.Context, dr *aiopsv1alpha1.DiagnosticRemediation) ([]client.Object, error) {
+	selector, err := metav1.LabelSelectorAsSelector(dr.Spec.Target.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target selector: %w", err)
+	}
+	opts := []client.ListOption{client.MatchingLabelsSelector{Selector: selector}}
+	if !dr.Spec.Target.AllNamespaces {
+		opts = append(opts, client.InNamespace(dr.Spec.Target.Namespace))
+	}
+
+	var workloads []client.Object
+	switch dr.Spec.Target.Kind {
+	case "Deployment":
+		var list appsv1.DeploymentList
+		if err := r.List(ctx, &list, opts...); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			workloads = append(workloads, &list.Items[i])
+		}
+	case "StatefulSet":
+		var list appsv1.StatefulSetList
+		if err := r.List(ctx, &list, opts...); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			workloads = append(workloads, &list.Items[i])
+		}
+	case "DaemonSet":
+		var list appsv1.DaemonSetList
+		if err := r.List(ctx, &list, opts...); err != nil {
+			return nil, err
+		}
+		for i := range list.Items {
+			workloads = append(workloads, &list.Items[i])
+		}
+	default:
+		return nil, fmt.Errorf("unsupported target kind: %s", dr.Spec.Target.Kind)
+	}
+
+	sort.Slice(workloads, func(i, j int) bool {
+		if workloads[i].GetNamespace() != workloads[j].GetNamespace() {
+			return workloads[i].GetNamespace() < workloads[j].GetNamespace()
+		}
+		return workloads[i].GetName() < workloads[j].GetName()
+	})
+	return workloads, nil
+}
+
+// describeTarget names the target for messages
+func describeTarget(dr *aiopsv1alpha1.DiagnosticRemediation) string {
+	if dr.Spec.Target.Selector != nil {
+		return fmt.Sprintf("%ss matching %s", dr.Spec.Target.Kind, metav1.FormatLabelSelector(dr.Spec.Target.Selector))
+	}
+	return fmt.Sprintf("%s/%s", dr.Spec.Target.Kind, dr.Spec.Target.Name)
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/policy"
+)
+
+// undoAnnotation holds the ID of the workload update to revert
+const undoAnnotation = "prophet.aiops.io/undo"
+
+// workloadChange is a workload update and the patch that reverts it
+type workloadChange struct {
+	id     string
+	patch  string
+	revert string
+}
+
+// newWorkloadChange computes the strategic merge patches from the original to the fixed workload and back
+func newWorkloadChange(original, fixed client.Object) (workloadChange, error) {
+	patch, err := client.StrategicMergeFrom(original).Data(fixed)
+	if err != nil {
+		return workloadChange{}, err
+	}
+	revert, err := client.StrategicMergeFrom(fixed).Data(original)
+	if err != nil {
+		return workloadChange{}, err
+	}
+	return workloadChange{id: utilrand.String(8), patch: string(patch), revert: string(revert)}, nil
+}
+
+// record attaches the change to the actions the update made
+func (c workloadChange) record(actions []aiopsv1alpha1.RemediationAction) {
+	for i := range actions {
+		actions[i].ID = c.id
+		actions[i].Patch = c.patch
+		actions[i].RevertPatch = c.revert
+	}
+}
+
+// undoRemediation reverts the workload update the undo annotation names, records the result as an
+// UndoRemediation action and removes the annotation. The workload's last remediation time is set
+// to now, so the cooldown passes before the operator fixes the same issues again.
+func (r *DiagnosticRemediationReconciler) undoRemediation(ctx context.Context, dr *aiopsv1alpha1.DiagnosticRemediation, logger logr.Logger) {
+	id := dr.Annotations[undoAnnotation]
+	logger = logger.WithValues("remediation", id)
+
+	action := r.revertUpdate(ctx, dr, id, logger)
+	dr.Status.Remediations = append(dr.Status.Remediations, action)
+	dr.Status.RemediationCount++
+	if action.Success {
+		now := metav1.Now()
+		for i := range dr.Status.Remediations {
+			if dr.Status.Remediations[i].ID == id {
+				dr.Status.Remediations[i].RevertedAt = &now
+			}
+		}
+		if action.Workload == "" {
+			dr.Status.LastRemediated = &now
+		}
+		for i := range dr.Status.Targets {
+			if dr.Status.Targets[i].Namespace+"/"+dr.Status.Targets[i].Name == action.Workload {
+				dr.Status.Targets[i].LastRemediated = &now
+			}
+		}
+		r.recordEvent(ctx, dr, corev1.EventTypeNormal, "Undone", action.Description)
+	} else {
+		r.recordEvent(ctx, dr, corev1.EventTypeWarning, "UndoFailed", fmt.Sprintf("%s: %s", action.Description, action.ErrorMessage))
+	}
+
+	// The annotation is removed either way, so a failed undo isn't retried on every reconcile
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, undoAnnotation))
+	obj := &aiopsv1alpha1.DiagnosticRemediation{ObjectMeta: metav1.ObjectMeta{Namespace: dr.Namespace, Name: dr.Name}}
+	if err := r.hubClient().Patch(ctx, obj, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		logger.Error(err, "Failed to remove undo annotation")
+	}
+}
+
+// revertUpdate applies the revert patch of the workload update with the ID
+func (r *DiagnosticRemediationReconciler) revertUpdate(ctx context.Context, dr *aiopsv1alpha1.DiagnosticRemediation, id string, logger logr.Logger) aiopsv1alpha1.RemediationAction {
+	result := aiopsv1alpha1.RemediationAction{
+		Type:        "UndoRemediation",
+		Description: fmt.Sprintf("Undo remediation %s", id),
+		Timestamp:   metav1.Now(),
+	}
+
+	var update *aiopsv1alpha1.RemediationAction
+	var fixes []string
+	for i := range dr.Status.Remediations {
+		if action := &dr.Status.Remediations[i]; action.ID == id && action.RevertPatch != "" {
+			update = action
+			fixes = append(fixes, action.Type)
+		}
+	}
+	switch {
+	case id == "" || update == nil:
+		result.ErrorMessage = "no workload update with this ID in status.remediations"
+		return result
+	case update.RevertedAt != nil:
+		result.ErrorMessage = fmt.Sprintf("already undone at %s", update.RevertedAt.Format(time.RFC3339))
+		return result
+	}
+	result.Workload = update.Workload
+
+	// The update may have been made on any of the workloads a selector matches
+	target := dr.DeepCopy()
+	if update.Workload != "" {
+		namespace, name, _ := strings.Cut(update.Workload, "/")
+		target.Spec.Target.Namespace = namespace
+		target.Spec.Target.Name = name
+	}
+	workload, err := r.getTargetWorkload(ctx, target)
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		return result
+	}
+
+	decision := r.checkGuardrails(ctx, policy.Action{
+		Type:      "UpdateWorkload",
+		Kind:      dr.Spec.Target.Kind,
+		Name:      workload.GetName(),
+		Namespace: workload.GetNamespace(),
+		Labels:    workload.GetLabels(),
+		Reason:    "undo remediation " + id,
+	})
+	if !decision.Allowed {
+		denied := deniedAction(result.Type, result.Description, decision)
+		denied.Workload = result.Workload
+		return denied
+	}
+
+	if err := r.Patch(ctx, workload, client.RawPatch(types.StrategicMergePatchType, []byte(update.RevertPatch))); err != nil {
+		logger.Error(err, "Failed to revert workload update")
+		result.ErrorMessage = err.Error()
+		return result
+	}
+	logger.Info("Reverted workload update")
+	result.Description = fmt.Sprintf("Undid remediation %s: %s", id, strings.Join(fixes, ", "))
+	result.Success = true
+	return result
+}
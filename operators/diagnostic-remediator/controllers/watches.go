@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+)
+
+// targetIndexKey indexes DiagnosticRemediations by the workloads they target: kind/namespace/name
+// for a named target, kind/namespace/* for a selector and kind/* for a selector in all namespaces
+const targetIndexKey = "spec.target"
+
+// targetIndexValues returns the index values of the DiagnosticRemediation. Targets in remote
+// clusters aren't indexed; only the poll interval re-diagnoses them.
+func targetIndexValues(obj client.Object) []string {
+	dr := obj.(*aiopsv1alpha1.DiagnosticRemediation)
+	if dr.Spec.ClusterRef != nil {
+		return nil
+	}
+	target := dr.Spec.Target
+	namespace := target.Namespace
+	if namespace == "" {
+		namespace = dr.Namespace
+	}
+	switch {
+	case target.Selector == nil:
+		return []string{target.Kind + "/" + namespace + "/" + target.Name}
+	case target.AllNamespaces:
+		return []string{target.Kind + "/*"}
+	default:
+		return []string{target.Kind + "/" + namespace + "/*"}
+	}
+}
+
+// workloadRequests returns the DiagnosticRemediations targeting the workload
+func (r *DiagnosticRemediationReconciler) workloadRequests(ctx context.Context, kind string, workload client.Object) []reconcile.Request {
+	logger := log.FromContext(ctx)
+	keys := []string{
+		kind + "/" + workload.GetNamespace() + "/" + workload.GetName(),
+		kind + "/" + workload.GetNamespace() + "/*",
+		kind + "/*",
+	}
+
+	var requests []reconcile.Request
+	for _, key := range keys {
+		var list aiopsv1alpha1.DiagnosticRemediationList
+		if err := r.List(ctx, &list, client.MatchingFields{targetIndexKey: key}); err != nil {
+			logger.Error(err, "Failed to list DiagnosticRemediations for workload", "workload", key)
+			continue
+		}
+		for _, dr := range list.Items {
+			if dr.Spec.Target.Selector != nil {
+				selector, err := metav1.LabelSelectorAsSelector(dr.Spec.Target.Selector)
+				if err != nil || !selector.Matches(labels.Set(workload.GetLabels())) {
+					continue
+				}
+			}
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: dr.Namespace, Name: dr.Name}})
+		}
+	}
+	return requests
+}
+
+// podRequests returns the DiagnosticRemediations targeting the workload that owns the pod
+func (r *DiagnosticRemediationReconciler) podRequests(ctx context.Context, obj client.Object) []reconcile.Request {
+	owner := metav1.GetControllerOf(obj)
+	if owner == nil {
+		return nil
+	}
+	key := types.NamespacedName{Namespace: obj.GetNamespace(), Name: owner.Name}
+
+	var workload client.Object
+	switch owner.Kind {
+	case "ReplicaSet":
+		// Deployments own their pods through ReplicaSets
+		var rs appsv1.ReplicaSet
+		if err := r.Get(ctx, key, &rs); err != nil {
+			return nil
+		}
+		rsOwner := metav1.GetControllerOf(&rs)
+		if rsOwner == nil || rsOwner.Kind != "Deployment" {
+			return nil
+		}
+		workload = &appsv1.Deployment{}
+		key.Name = rsOwner.Name
+	case "StatefulSet":
+		workload = &appsv1.StatefulSet{}
+	case "DaemonSet":
+		workload = &appsv1.DaemonSet{}
+	default:
+		return nil
+	}
+	if err := r.Get(ctx, key, workload); err != nil {
+		return nil
+	}
+	kind := owner.Kind
+	if kind == "ReplicaSet" {
+		kind = "Deployment"
+	}
+	return r.workloadRequests(ctx, kind, workload)
+}
+
+// podHealthChanged passes pod updates that change what the pod health and memory checks see:
+// the phase, and each container's restarts, readiness and waiting or termination reason
+var podHealthChanged = predicate.Funcs{
+	CreateFunc:  func(event.CreateEvent) bool { return false },
+	DeleteFunc:  func(event.DeleteEvent) bool { return false },
+	GenericFunc: func(event.GenericEvent) bool { return false },
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldPod, ok := e.ObjectOld.(*corev1.Pod)
+		if !ok {
+			return false
+		}
+		newPod, ok := e.ObjectNew.(*corev1.Pod)
+		if !ok {
+			return false
+		}
+		if oldPod.Status.Phase != newPod.Status.Phase || len(oldPod.Status.ContainerStatuses) != len(newPod.Status.ContainerStatuses) {
+			return true
+		}
+		for i, newStatus := range newPod.Status.ContainerStatuses {
+			oldStatus := oldPod.Status.ContainerStatuses[i]
+			if oldStatus.RestartCount != newStatus.RestartCount || oldStatus.Ready != newStatus.Ready ||
+				stateReason(oldStatus.State) != stateReason(newStatus.State) {
+				return true
+			}
+		}
+		return false
+	},
+}
+
+// stateReason returns why a container is waiting or terminated, or an empty string if it is running
+func stateReason(state corev1.ContainerState) string {
+	switch {
+	case state.Waiting != nil:
+		return state.Waiting.Reason
+	case state.Terminated != nil:
+		return state.Terminated.Reason
+	}
+	return ""
+}
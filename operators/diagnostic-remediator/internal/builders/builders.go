@@ -0,0 +1,36 @@
+// Package builders builds DiagnosticRemediations for tests. Builders return valid objects that
+// options change.
+package builders
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+)
+
+// DiagnosticRemediation returns a DiagnosticRemediation named web auditing the web Deployment in
+// namespace
+func DiagnosticRemediation(namespace string, options ...func(*aiopsv1alpha1.DiagnosticRemediation)) *aiopsv1alpha1.DiagnosticRemediation {
+	dr := &aiopsv1alpha1.DiagnosticRemediation{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: namespace},
+		Spec: aiopsv1alpha1.DiagnosticRemediationSpec{
+			Target: aiopsv1alpha1.TargetSpec{Namespace: namespace, Kind: "Deployment", Name: "web"},
+			Mode:   "Audit",
+		},
+	}
+	for _, option := range options {
+		option(dr)
+	}
+	return dr
+}
+
+// Recommendations computes request recommendations from the Prometheus at url over the last hour
+func Recommendations(url string) func(*aiopsv1alpha1.DiagnosticRemediation) {
+	return func(dr *aiopsv1alpha1.DiagnosticRemediation) {
+		dr.Spec.Recommendations = &aiopsv1alpha1.RecommendationSpec{
+			Source:        "Prometheus",
+			PrometheusURL: url,
+			WindowSeconds: 3600,
+		}
+	}
+}
@@ -0,0 +1,6 @@
+package v1alpha1
+
+import "sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+// Validator is the HealthCheck validating webhook, exported for the external tests
+var Validator admission.CustomValidator = &healthCheckValidator{}
@@ -0,0 +1,4 @@
+package v1alpha1
+
+// Hub marks v1alpha1 as the version HealthChecks are stored and converted through
+func (*HealthCheck) Hub() {}
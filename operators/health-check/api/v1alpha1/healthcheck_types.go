@@ -3,67 +3,138 @@ package v1alpha1
 import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // HealthCheckSpec defines the desired state of HealthCheck
 type HealthCheckSpec struct {
-	// TargetRef references the workload to check (Deployment, StatefulSet, Pod, etc.)
+	// TargetRef references the workload to check (Deployment, StatefulSet, DaemonSet, Pod, etc.) or selects its pods by label
 	TargetRef TargetRef `json:"targetRef"`
 
+	// ClusterRef checks a workload in a remote cluster (optional, defaults to the local cluster)
+	ClusterRef *ClusterRef `json:"clusterRef,omitempty"`
+
 	// Probes defines the health check probes to execute
+	// +kubebuilder:validation:MinItems=1
 	Probes []ProbeSpec `json:"probes"`
 
 	// FailureThreshold is the number of consecutive failures before marking unhealthy
 	// Default: 3
+	// +kubebuilder:validation:Minimum=1
 	// +kubebuilder:default=3
 	FailureThreshold int32 `json:"failureThreshold,omitempty"`
 
 	// PeriodSeconds is the interval between health checks in seconds
 	// Default: 10
+	// +kubebuilder:validation:Minimum=1
 	// +kubebuilder:default=10
 	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
 
 	// InitialDelaySeconds is the delay before starting health checks
 	// Default: 0
+	// +kubebuilder:validation:Minimum=0
 	// +kubebuilder:default=0
 	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty"`
 
 	// TimeoutSeconds is the timeout for each probe execution
 	// Default: 5
+	// +kubebuilder:validation:Minimum=1
 	// +kubebuilder:default=5
 	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
 
 	// Remediation defines what action to take when health check fails
 	Remediation RemediationSpec `json:"remediation,omitempty"`
+
+	// Notify sends notifications when the target becomes unhealthy and when it recovers
+	Notify NotifySpec `json:"notify,omitempty"`
+
+	// MaintenanceWindows hold off remediation, and optionally checks, while one is open
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+
+	// MinHealthScore fails the health check when the health score, the weighted percentage of
+	// probes that aren't failing, drops below it, even if no critical probe fails (optional)
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	MinHealthScore *int32 `json:"minHealthScore,omitempty"`
 }
 
-// TargetRef references a Kubernetes workload
+// TargetRef references a Kubernetes workload, or the pods a label selector matches
+// +kubebuilder:validation:XValidation:rule="has(self.name) != has(self.labelSelector)",message="exactly one of name and labelSelector must be set"
+// +kubebuilder:validation:XValidation:rule="has(self.labelSelector) || (has(self.apiVersion) && has(self.kind))",message="apiVersion and kind are required with name"
 type TargetRef struct {
-	// APIVersion of the target resource (e.g., "apps/v1")
-	APIVersion string `json:"apiVersion"`
+	// APIVersion of the target resource (e.g., "apps/v1"); optional with labelSelector
+	APIVersion string `json:"apiVersion,omitempty"`
 
-	// Kind of the target resource (e.g., "Deployment", "StatefulSet", "Pod")
-	Kind string `json:"kind"`
+	// Kind of the target resource (e.g., "Deployment", "DaemonSet", "Pod"); optional with labelSelector
+	// +kubebuilder:validation:Enum=Pod;Deployment;StatefulSet;DaemonSet;ReplicaSet
+	Kind string `json:"kind,omitempty"`
 
 	// Name of the target resource
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name,omitempty"`
+
+	// LabelSelector checks the pods whose labels match instead of a named resource, so pods
+	// without a workload controller can be checked too
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty"`
+
+	// Namespace of the target resource (optional); it must be the HealthCheck namespace
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// ClusterRef references a remote cluster registered with a kubeconfig Secret
+type ClusterRef struct {
+	// Name of the cluster, recorded in status and events.
+	// Without a secretRef the kubeconfig is read from the Cluster API Secret "<name>-kubeconfig"
+	// in the HealthCheck namespace.
+	// +kubebuilder:validation:MinLength=1
 	Name string `json:"name"`
 
-	// Namespace of the target resource (optional, defaults to HealthCheck namespace)
+	// SecretRef references a Secret holding the cluster's kubeconfig (optional). The kubeconfig must
+	// embed its credentials: exec and auth provider plugins and file paths are refused.
+	SecretRef *KubeconfigSecretReference `json:"secretRef,omitempty"`
+}
+
+// KubeconfigSecretReference references a Secret holding a kubeconfig
+type KubeconfigSecretReference struct {
+	// Name of the Secret
+	Name string `json:"name"`
+
+	// Namespace of the Secret (optional); it must be the HealthCheck namespace
 	Namespace string `json:"namespace,omitempty"`
+
+	// Key holding the kubeconfig
+	// Default: kubeconfig
+	// +kubebuilder:default=kubeconfig
+	Key string `json:"key,omitempty"`
 }
 
 // ProbeSpec defines a single health check probe
 type ProbeSpec struct {
 	// Name is a unique identifier for this probe
+	// +kubebuilder:validation:MinLength=1
 	Name string `json:"name"`
 
-	// Type of probe: "http", "tcp", "command", or "custom"
-	// +kubebuilder:validation:Enum=http;tcp;command;custom
+	// Type of probe: "http", "tcp", "command", "custom", or "external"
+	// +kubebuilder:validation:Enum=http;tcp;command;custom;external
 	Type string `json:"type"`
 
-	// HTTPGet defines an HTTP health check (used when type is "http")
+	// HTTPGet defines an HTTP health check (used when type is "http").
+	// The request is sent to each running target pod's IP unless host is set.
 	HTTPGet *corev1.HTTPGetAction `json:"httpGet,omitempty"`
 
+	// ExpectedStatus lists the HTTP status codes, or ranges such as "200-299", that count as success.
+	// Default: 200-399, as for kubelet probes
+	// +kubebuilder:validation:items:Pattern=`^[1-5][0-9][0-9](-[1-5][0-9][0-9])?$`
+	ExpectedStatus []string `json:"expectedStatus,omitempty"`
+
+	// BodyRegex must match the first 64KiB of the HTTP response body for the probe to succeed
+	BodyRegex string `json:"bodyRegex,omitempty"`
+
+	// SuccessQuorum is how many target pods, as a number or a percentage, must pass an HTTP probe.
+	// Default: 100%
+	// +kubebuilder:validation:XIntOrString
+	SuccessQuorum *intstr.IntOrString `json:"successQuorum,omitempty"`
+
 	// TCPSocket defines a TCP health check (used when type is "tcp")
 	TCPSocket *corev1.TCPSocketAction `json:"tcpSocket,omitempty"`
 
@@ -73,6 +144,33 @@ type ProbeSpec struct {
 	// Custom defines a custom health check (e.g., database connectivity)
 	// Used when type is "custom"
 	Custom *CustomProbe `json:"custom,omitempty"`
+
+	// External defines a check of an endpoint outside the cluster (used when type is "external")
+	External *ExternalProbe `json:"external,omitempty"`
+
+	// FailureThreshold is the number of consecutive failures before the probe counts as failing
+	// Default: 1
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+
+	// SuccessThreshold is the number of consecutive successes before a failing probe counts as passing again
+	// Default: 1
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	SuccessThreshold int32 `json:"successThreshold,omitempty"`
+
+	// Critical probes fail the health check when they fail. Non-critical probes are informational:
+	// they only lower the health score.
+	// Default: true
+	// +kubebuilder:default=true
+	Critical *bool `json:"critical,omitempty"`
+
+	// Weight of the probe in the health score
+	// Default: 1
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	Weight int32 `json:"weight,omitempty"`
 }
 
 // CustomProbe defines a custom health check (e.g., database connectivity, external API)
@@ -91,6 +189,33 @@ type CustomProbe struct {
 	Description string `json:"description,omitempty"`
 }
 
+// ExternalProbe checks a URL or TCP address from the operator instead of the target pods, like a
+// blackbox monitor. DNS resolution, connect and TLS handshake times and the server certificate's
+// expiry are reported in the probe result.
+// +kubebuilder:validation:XValidation:rule="has(self.url) != has(self.address)",message="exactly one of url and address must be set"
+type ExternalProbe struct {
+	// URL to send a GET request to, e.g. "https://shop.example.com/healthz".
+	// expectedStatus and bodyRegex apply to the response.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url,omitempty"`
+
+	// HTTPHeaders are sent with the request to url
+	HTTPHeaders []corev1.HTTPHeader `json:"httpHeaders,omitempty"`
+
+	// Address to open a TCP connection to, as host:port
+	Address string `json:"address,omitempty"`
+
+	// TLS performs a TLS handshake after connecting to address
+	TLS bool `json:"tls,omitempty"`
+
+	// InsecureSkipVerify accepts server certificates that don't verify. Their expiry is still checked.
+	InsecureSkipVerify bool `json:"insecureSkipVerify,omitempty"`
+
+	// MinCertificateValidityDays fails the probe when the server certificate expires within this many days
+	// +kubebuilder:validation:Minimum=0
+	MinCertificateValidityDays int32 `json:"minCertificateValidityDays,omitempty"`
+}
+
 // RemediationSpec defines remediation actions when health check fails
 type RemediationSpec struct {
 	// Action to take: "restart", "trigger-recovery-plan", "alert", or "none"
@@ -107,6 +232,7 @@ type RemediationSpec struct {
 
 	// CooldownSeconds is the minimum time between remediation actions
 	// Default: 300 (5 minutes)
+	// +kubebuilder:validation:Minimum=0
 	// +kubebuilder:default=300
 	CooldownSeconds int32 `json:"cooldownSeconds,omitempty"`
 }
@@ -120,11 +246,79 @@ type RecoveryPlanRef struct {
 	Namespace string `json:"namespace,omitempty"`
 }
 
+// NotifySpec defines notification settings
+type NotifySpec struct {
+	// Enabled enables notifications
+	Enabled bool `json:"enabled,omitempty"`
+
+	// WebhookURL is the webhook URL for notifications
+	WebhookURL string `json:"webhookUrl,omitempty"`
+
+	// EmailRecipients is a list of email addresses to notify
+	EmailRecipients []string `json:"emailRecipients,omitempty"`
+
+	// Channels are additional notification destinations
+	Channels []NotificationChannel `json:"channels,omitempty"`
+
+	// Template is a Go text/template for the notification text, rendered against the HealthCheck
+	// (e.g., "{{ .Name }} is unhealthy"). If empty, a default message is used.
+	Template string `json:"template,omitempty"`
+}
+
+// NotificationChannel is a notification destination
+type NotificationChannel struct {
+	// Type is the channel type: "webhook", "slack", "teams", "pagerduty", "opsgenie", or "email"
+	// +kubebuilder:validation:Enum=webhook;slack;teams;pagerduty;opsgenie;email
+	Type string `json:"type"`
+
+	// URL is the webhook URL (webhook, slack, teams) or an API endpoint override (pagerduty, opsgenie)
+	URL string `json:"url,omitempty"`
+
+	// SecretRef references a Secret holding channel credentials.
+	// Recognized keys: "url" (overrides url), "token" (webhook bearer token, PagerDuty routing key
+	// or Opsgenie API key), "username" and "password" (SMTP authentication)
+	SecretRef *SecretReference `json:"secretRef,omitempty"`
+
+	// SMTP configures the mail server (required if type is "email")
+	SMTP *SMTPSpec `json:"smtp,omitempty"`
+}
+
+// SMTPSpec configures an SMTP mail server
+type SMTPSpec struct {
+	// Host is the SMTP server host
+	Host string `json:"host"`
+
+	// Port is the SMTP server port
+	// Default: 587
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=587
+	Port int32 `json:"port,omitempty"`
+
+	// From is the sender address
+	From string `json:"from"`
+
+	// To is the list of recipients (defaults to emailRecipients)
+	To []string `json:"to,omitempty"`
+}
+
+// SecretReference references a Secret
+type SecretReference struct {
+	// Name of the Secret
+	Name string `json:"name"`
+
+	// Namespace of the Secret (optional); it must be the HealthCheck namespace
+	Namespace string `json:"namespace,omitempty"`
+}
+
 // HealthCheckStatus defines the observed state of HealthCheck
 type HealthCheckStatus struct {
 	// Healthy indicates whether the target workload is currently healthy
 	Healthy bool `json:"healthy"`
 
+	// Cluster is the remote cluster the target runs in (empty for the local cluster)
+	Cluster string `json:"cluster,omitempty"`
+
 	// LastCheckTime is the timestamp of the last health check
 	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
 
@@ -137,19 +331,72 @@ type HealthCheckStatus struct {
 	// ProbeResults contains the results of each probe
 	ProbeResults []ProbeResult `json:"probeResults,omitempty"`
 
+	// HealthScore is the weighted percentage of probes that aren't failing
+	HealthScore int32 `json:"healthScore"`
+
+	// MaintenanceWindow is the maintenance window in effect, if any
+	MaintenanceWindow *MaintenanceWindowStatus `json:"maintenanceWindow,omitempty"`
+
 	// LastRemediationTime is the timestamp of the last remediation action
 	LastRemediationTime *metav1.Time `json:"lastRemediationTime,omitempty"`
 
 	// RemediationCount is the number of remediation actions performed
 	RemediationCount int32 `json:"remediationCount"`
 
-	// Conditions represent the latest available observations
+	// ObservedGeneration is the most recent generation the operator has reconciled
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations, including the standard Ready, Progressing and Degraded conditions
+	// +listType=map
+	// +listMapKey=type
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 
 	// ErrorMessage contains any error message from the last check
 	ErrorMessage string `json:"errorMessage,omitempty"`
 }
 
+// MaintenanceWindow is a recurring or one-off period, such as a deploy window or planned downtime,
+// in which remediation is held off
+// +kubebuilder:validation:XValidation:rule="[has(self.schedule), has(self.recurrence), has(self.start)].exists_one(x, x)",message="exactly one of schedule, recurrence and start must be set"
+// +kubebuilder:validation:XValidation:rule="has(self.start) == has(self.end)",message="start and end must be set together"
+// +kubebuilder:validation:XValidation:rule="has(self.start) || has(self.durationMinutes)",message="durationMinutes is required with schedule and recurrence"
+type MaintenanceWindow struct {
+	// Name identifies the window in status and events
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Schedule is a cron expression for when the window opens, e.g. "0 2 * * 6" for Saturdays at 02:00
+	Schedule string `json:"schedule,omitempty"`
+
+	// Recurrence is an RFC 5545 recurrence rule for when the window opens, e.g. "FREQ=WEEKLY;BYDAY=SA;BYHOUR=2".
+	// FREQ DAILY, WEEKLY and MONTHLY with BYDAY, BYMONTHDAY, BYHOUR and BYMINUTE are supported.
+	Recurrence string `json:"recurrence,omitempty"`
+
+	// DurationMinutes the window stays open each time it opens
+	// +kubebuilder:validation:Minimum=1
+	DurationMinutes int32 `json:"durationMinutes,omitempty"`
+
+	// TimeZone of schedule and recurrence, as an IANA name such as "Europe/Berlin". Default: UTC
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Start and End bound a one-off window instead
+	Start *metav1.Time `json:"start,omitempty"`
+	End   *metav1.Time `json:"end,omitempty"`
+
+	// SuppressDetections also pauses checks during the window, so no detections are recorded.
+	// By default checks still run and only remediation is held off.
+	SuppressDetections bool `json:"suppressDetections,omitempty"`
+}
+
+// MaintenanceWindowStatus is the maintenance window in effect
+type MaintenanceWindowStatus struct {
+	// Name of the window
+	Name string `json:"name"`
+
+	// Until is when the window closes
+	Until metav1.Time `json:"until"`
+}
+
 // ProbeResult contains the result of a single probe execution
 type ProbeResult struct {
 	// Name of the probe
@@ -158,17 +405,80 @@ type ProbeResult struct {
 	// Success indicates whether the probe succeeded
 	Success bool `json:"success"`
 
+	// Failing indicates whether the probe counts as failing after its failure and success thresholds
+	Failing bool `json:"failing,omitempty"`
+
+	// ConsecutiveFailures is the number of consecutive runs the probe failed
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+
+	// ConsecutiveSuccesses is the number of consecutive runs the probe succeeded
+	ConsecutiveSuccesses int32 `json:"consecutiveSuccesses,omitempty"`
+
 	// LastCheckTime is when this probe was last executed
 	LastCheckTime *metav1.Time `json:"lastCheckTime,omitempty"`
 
 	// Message contains additional information about the probe result
 	Message string `json:"message,omitempty"`
+
+	// Pods holds the result on each pod, for probes run against every target pod
+	Pods []PodProbeResult `json:"pods,omitempty"`
+
+	// LatencyMilliseconds is how long the last run took; for custom probes, how long the Job ran
+	LatencyMilliseconds int64 `json:"latencyMilliseconds,omitempty"`
+
+	// External breaks down the last run of an external probe
+	External *ExternalProbeResult `json:"external,omitempty"`
+
+	// History holds the most recent runs, newest last, to show a flapping probe
+	// +kubebuilder:validation:MaxItems=10
+	History []ProbeRun `json:"history,omitempty"`
+}
+
+// ProbeRun is a past run of a probe
+type ProbeRun struct {
+	// Time the probe ran
+	Time metav1.Time `json:"time"`
+
+	// Success indicates whether the run succeeded
+	Success bool `json:"success"`
+
+	// LatencyMilliseconds is how long the run took
+	LatencyMilliseconds int64 `json:"latencyMilliseconds,omitempty"`
+}
+
+// ExternalProbeResult breaks down the last run of an external probe
+type ExternalProbeResult struct {
+	// DNSLookupMilliseconds is how long resolving the host took
+	DNSLookupMilliseconds int64 `json:"dnsLookupMilliseconds"`
+
+	// ConnectMilliseconds is how long opening the TCP connection took
+	ConnectMilliseconds int64 `json:"connectMilliseconds,omitempty"`
+
+	// TLSHandshakeMilliseconds is how long the TLS handshake took
+	TLSHandshakeMilliseconds int64 `json:"tlsHandshakeMilliseconds,omitempty"`
+
+	// CertificateExpiry is the earliest expiry of the certificates in the server's chain
+	CertificateExpiry *metav1.Time `json:"certificateExpiry,omitempty"`
+}
+
+// PodProbeResult is the result of a probe on a single pod
+type PodProbeResult struct {
+	// Pod name
+	Pod string `json:"pod"`
+
+	// Success indicates whether the probe succeeded on the pod
+	Success bool `json:"success"`
+
+	// Message describes the response or the failure
+	Message string `json:"message,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:storageversion
 //+kubebuilder:printcolumn:name="Healthy",type="boolean",JSONPath=".status.healthy"
 //+kubebuilder:printcolumn:name="Target",type="string",JSONPath=".spec.targetRef.kind + '/' + .spec.targetRef.name"
+//+kubebuilder:printcolumn:name="Cluster",type="string",JSONPath=".status.cluster",priority=1
 //+kubebuilder:printcolumn:name="Failure Count",type="integer",JSONPath=".status.failureCount"
 //+kubebuilder:printcolumn:name="Last Check",type="date",JSONPath=".status.lastCheckTime"
 //+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
@@ -0,0 +1,94 @@
+package v1alpha1_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/health-check/api/v1alpha1"
+	"github.com/prophet-aiops/health-check/internal/builders"
+)
+
+// TestValidationRules checks the CRD's CEL validation rules against the API server
+func TestValidationRules(t *testing.T) {
+	c := testEnv.Require(t)
+	namespace := testEnv.Namespace(t)
+	start := metav1.NewTime(time.Now().Truncate(time.Second))
+	end := metav1.NewTime(start.Add(time.Hour))
+
+	tests := map[string]struct {
+		modify func(*aiopsv1alpha1.HealthCheck)
+		error  string
+	}{
+		"valid": {
+			modify: func(*aiopsv1alpha1.HealthCheck) {},
+		},
+		"label selector": {
+			modify: func(hc *aiopsv1alpha1.HealthCheck) {
+				hc.Spec.TargetRef = aiopsv1alpha1.TargetRef{LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}}
+			},
+		},
+		"name and label selector": {
+			modify: func(hc *aiopsv1alpha1.HealthCheck) {
+				hc.Spec.TargetRef.LabelSelector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+			},
+			error: "exactly one of name and labelSelector must be set",
+		},
+		"name without apiVersion": {
+			modify: func(hc *aiopsv1alpha1.HealthCheck) { hc.Spec.TargetRef.APIVersion = "" },
+			error:  "apiVersion and kind are required with name",
+		},
+		"external url and address": {
+			modify: func(hc *aiopsv1alpha1.HealthCheck) {
+				hc.Spec.Probes = append(hc.Spec.Probes, aiopsv1alpha1.ProbeSpec{Name: "external", Type: "external", External: &aiopsv1alpha1.ExternalProbe{
+					URL: "https://shop.example.com", Address: "shop.example.com:443",
+				}})
+			},
+			error: "exactly one of url and address must be set",
+		},
+		"maintenance window with start and end": {
+			modify: func(hc *aiopsv1alpha1.HealthCheck) {
+				hc.Spec.MaintenanceWindows = []aiopsv1alpha1.MaintenanceWindow{{Name: "upgrade", Start: &start, End: &end}}
+			},
+		},
+		"maintenance window with schedule and start": {
+			modify: func(hc *aiopsv1alpha1.HealthCheck) {
+				hc.Spec.MaintenanceWindows = []aiopsv1alpha1.MaintenanceWindow{{Name: "upgrade", Schedule: "0 2 * * *", DurationMinutes: 60, Start: &start, End: &end}}
+			},
+			error: "exactly one of schedule, recurrence and start must be set",
+		},
+		"maintenance window without end": {
+			modify: func(hc *aiopsv1alpha1.HealthCheck) {
+				hc.Spec.MaintenanceWindows = []aiopsv1alpha1.MaintenanceWindow{{Name: "upgrade", Start: &start}}
+			},
+			error: "start and end must be set together",
+		},
+		"schedule without duration": {
+			modify: func(hc *aiopsv1alpha1.HealthCheck) {
+				hc.Spec.MaintenanceWindows = []aiopsv1alpha1.MaintenanceWindow{{Name: "nightly", Schedule: "0 2 * * *"}}
+			},
+			error: "durationMinutes is required with schedule and recurrence",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			healthCheck := builders.HealthCheck(namespace, func(healthCheck *aiopsv1alpha1.HealthCheck) {
+				healthCheck.Name = ""
+				healthCheck.GenerateName = "web-"
+			}, tt.modify)
+
+			err := c.Create(context.Background(), healthCheck)
+			switch {
+			case tt.error == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.error != "" && err == nil:
+				t.Errorf("expected %q", tt.error)
+			case tt.error != "" && !strings.Contains(err.Error(), tt.error):
+				t.Errorf("expected %q, got %v", tt.error, err)
+			}
+		})
+	}
+}
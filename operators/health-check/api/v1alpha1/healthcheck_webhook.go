@@ -0,0 +1,173 @@
+package v1alpha1
+
+import (
+	"context"
+	"fmt"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhookWithManager registers the HealthCheck defaulting and validating webhooks, and the
+// conversion webhook when v1beta1 is registered in the manager's scheme
+func (r *HealthCheck) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&healthCheckDefaulter{}).
+		WithValidator(&healthCheckValidator{}).
+		Complete()
+}
+
+// SetDefaults fills unset fields with their defaults. The defaulting webhook persists them;
+// the controller applies them again in memory so HealthChecks created without the webhook
+// behave the same.
+func (r *HealthCheck) SetDefaults() {
+	spec := &r.Spec
+	if spec.FailureThreshold == 0 {
+		spec.FailureThreshold = 3
+	}
+	if spec.PeriodSeconds == 0 {
+		spec.PeriodSeconds = 10
+	}
+	if spec.TimeoutSeconds == 0 {
+		spec.TimeoutSeconds = 5
+	}
+	if spec.TargetRef.Namespace == "" {
+		spec.TargetRef.Namespace = r.Namespace
+	}
+	for i := range spec.Probes {
+		probe := &spec.Probes[i]
+		if probe.FailureThreshold == 0 {
+			probe.FailureThreshold = 1
+		}
+		if probe.SuccessThreshold == 0 {
+			probe.SuccessThreshold = 1
+		}
+		if probe.Critical == nil {
+			critical := true
+			probe.Critical = &critical
+		}
+		if probe.Weight == 0 {
+			probe.Weight = 1
+		}
+	}
+	if ref := spec.ClusterRef; ref != nil && ref.SecretRef != nil {
+		if ref.SecretRef.Namespace == "" {
+			ref.SecretRef.Namespace = r.Namespace
+		}
+		if ref.SecretRef.Key == "" {
+			ref.SecretRef.Key = "kubeconfig"
+		}
+	}
+	if spec.Remediation.CooldownSeconds == 0 {
+		spec.Remediation.CooldownSeconds = 300
+	}
+	if ref := spec.Remediation.RecoveryPlanRef; ref != nil && ref.Namespace == "" {
+		ref.Namespace = r.Namespace
+	}
+	for i := range spec.Notify.Channels {
+		channel := &spec.Notify.Channels[i]
+		if channel.SecretRef != nil && channel.SecretRef.Namespace == "" {
+			channel.SecretRef.Namespace = r.Namespace
+		}
+		if channel.SMTP != nil && channel.SMTP.Port == 0 {
+			channel.SMTP.Port = 587
+		}
+	}
+}
+
+//+kubebuilder:webhook:path=/mutate-aiops-prophet-io-v1alpha1-healthcheck,mutating=true,failurePolicy=ignore,sideEffects=None,groups=aiops.prophet.io,resources=healthchecks,verbs=create;update,versions=v1alpha1,name=mhealthcheck.aiops.prophet.io,admissionReviewVersions=v1
+
+// healthCheckDefaulter fills in defaults so they are visible on the stored HealthCheck
+type healthCheckDefaulter struct{}
+
+var _ admission.CustomDefaulter = &healthCheckDefaulter{}
+
+// Default implements admission.CustomDefaulter
+func (d *healthCheckDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	healthCheck, ok := obj.(*HealthCheck)
+	if !ok {
+		return fmt.Errorf("expected a HealthCheck but got %T", obj)
+	}
+	healthCheck.SetDefaults()
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-aiops-prophet-io-v1alpha1-healthcheck,mutating=false,failurePolicy=ignore,sideEffects=None,groups=aiops.prophet.io,resources=healthchecks,verbs=create;update,versions=v1alpha1,name=vhealthcheck.aiops.prophet.io,admissionReviewVersions=v1
+
+// healthCheckValidator warns about deprecated fields and rejects targets and Secrets outside the
+// HealthCheck's namespace, and HTTP probes setting httpGet.host. The controller refuses those too,
+// as the webhook fails open.
+type healthCheckValidator struct{}
+
+var _ admission.CustomValidator = &healthCheckValidator{}
+
+// ValidateCreate implements admission.CustomValidator
+func (v *healthCheckValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return v.validate(obj)
+}
+
+// ValidateUpdate implements admission.CustomValidator
+func (v *healthCheckValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	return v.validate(newObj)
+}
+
+// ValidateDelete implements admission.CustomValidator
+func (v *healthCheckValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// validate returns a warning for each deprecated field set on the HealthCheck, and an error if it
+// references objects in other namespaces, sets httpGet.host or probes pod IPs in a remote cluster
+func (v *healthCheckValidator) validate(obj runtime.Object) (admission.Warnings, error) {
+	healthCheck, ok := obj.(*HealthCheck)
+	if !ok {
+		return nil, fmt.Errorf("expected a HealthCheck but got %T", obj)
+	}
+
+	var warnings admission.Warnings
+	if healthCheck.Spec.Notify.WebhookURL != "" {
+		warnings = append(warnings, "spec.notify.webhookUrl is deprecated and removed in v1beta1; add a channel with type \"webhook\" instead")
+	}
+	if len(healthCheck.Spec.Notify.EmailRecipients) > 0 {
+		warnings = append(warnings, "spec.notify.emailRecipients is deprecated and removed in v1beta1; set smtp.to on each email channel instead")
+	}
+	errs := healthCheck.ValidateNamespaces()
+	for i, probe := range healthCheck.Spec.Probes {
+		if probe.HTTPGet != nil && probe.HTTPGet.Host != "" {
+			errs = append(errs, field.Forbidden(field.NewPath("spec", "probes").Index(i).Child("httpGet", "host"),
+				"probes are sent to the pod IP; set a Host header instead"))
+		}
+		if healthCheck.Spec.ClusterRef != nil && (probe.Type == "http" || probe.Type == "tcp") {
+			errs = append(errs, field.Forbidden(field.NewPath("spec", "probes").Index(i).Child("type"),
+				"http and tcp probes connect to pod IPs, which the operator can't reach in a remote cluster; use a command, custom or external probe"))
+		}
+	}
+	if len(errs) > 0 {
+		return warnings, apierrors.NewInvalid(GroupVersion.WithKind("HealthCheck").GroupKind(), healthCheck.Name, errs)
+	}
+	return warnings, nil
+}
+
+// ValidateNamespaces returns an error for each reference to an object outside the HealthCheck's
+// namespace. Creating a HealthCheck must not let its author probe, exec into or restart pods,
+// or read Secrets, in namespaces they have no access to.
+func (r *HealthCheck) ValidateNamespaces() field.ErrorList {
+	var errs field.ErrorList
+	if namespace := r.Spec.TargetRef.Namespace; namespace != "" && namespace != r.Namespace {
+		errs = append(errs, field.Invalid(field.NewPath("spec", "targetRef", "namespace"), namespace, "must be the HealthCheck's namespace"))
+	}
+	if ref := r.Spec.ClusterRef; ref != nil && ref.SecretRef != nil && ref.SecretRef.Namespace != "" && ref.SecretRef.Namespace != r.Namespace {
+		errs = append(errs, field.Invalid(field.NewPath("spec", "clusterRef", "secretRef", "namespace"), ref.SecretRef.Namespace, "must be the HealthCheck's namespace"))
+	}
+	for i, channel := range r.Spec.Notify.Channels {
+		if ref := channel.SecretRef; ref != nil && ref.Namespace != "" && ref.Namespace != r.Namespace {
+			errs = append(errs, field.Invalid(field.NewPath("spec", "notify", "channels").Index(i).Child("secretRef", "namespace"),
+				ref.Namespace, "must be the HealthCheck's namespace"))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,113 @@
+package v1alpha1_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/health-check/api/v1alpha1"
+	"github.com/prophet-aiops/health-check/internal/builders"
+)
+
+func TestValidate(t *testing.T) {
+	tests := map[string]struct {
+		modify   func(*aiopsv1alpha1.HealthCheck)
+		errors   []string
+		warnings int
+	}{
+		"valid": {
+			modify: func(*aiopsv1alpha1.HealthCheck) {},
+		},
+		"target in own namespace": {
+			modify: func(hc *aiopsv1alpha1.HealthCheck) { hc.Spec.TargetRef.Namespace = "shop" },
+		},
+		"target in another namespace": {
+			modify: func(hc *aiopsv1alpha1.HealthCheck) { hc.Spec.TargetRef.Namespace = "kube-system" },
+			errors: []string{"spec.targetRef.namespace"},
+		},
+		"kubeconfig secret in another namespace": {
+			modify: func(hc *aiopsv1alpha1.HealthCheck) {
+				hc.Spec.ClusterRef = &aiopsv1alpha1.ClusterRef{Name: "edge", SecretRef: &aiopsv1alpha1.KubeconfigSecretReference{Name: "edge", Namespace: "prophet-system"}}
+			},
+			errors: []string{"spec.clusterRef.secretRef.namespace"},
+		},
+		"channel secret in another namespace": {
+			modify: func(hc *aiopsv1alpha1.HealthCheck) {
+				hc.Spec.Notify.Channels = []aiopsv1alpha1.NotificationChannel{
+					{Type: "slack", SecretRef: &aiopsv1alpha1.SecretReference{Name: "slack"}},
+					{Type: "webhook", SecretRef: &aiopsv1alpha1.SecretReference{Name: "hook", Namespace: "prophet-system"}},
+				}
+			},
+			errors: []string{"spec.notify.channels[1].secretRef.namespace"},
+		},
+		"httpGet.host": {
+			modify: func(hc *aiopsv1alpha1.HealthCheck) { hc.Spec.Probes[0].HTTPGet.Host = "169.254.169.254" },
+			errors: []string{"spec.probes[0].httpGet.host"},
+		},
+		"http probe in a remote cluster": {
+			modify: func(hc *aiopsv1alpha1.HealthCheck) { hc.Spec.ClusterRef = &aiopsv1alpha1.ClusterRef{Name: "edge"} },
+			errors: []string{"spec.probes[0].type"},
+		},
+		"command probe in a remote cluster": {
+			modify: func(hc *aiopsv1alpha1.HealthCheck) {
+				hc.Spec.ClusterRef = &aiopsv1alpha1.ClusterRef{Name: "edge"}
+				hc.Spec.Probes = []aiopsv1alpha1.ProbeSpec{{Name: "ready", Type: "command", Exec: &corev1.ExecAction{Command: []string{"true"}}}}
+			},
+		},
+		"deprecated notification fields": {
+			modify: func(hc *aiopsv1alpha1.HealthCheck) {
+				hc.Spec.Notify.WebhookURL = "https://alerts.example.com/hook"
+				hc.Spec.Notify.EmailRecipients = []string{"oncall@example.com"}
+			},
+			warnings: 2,
+		},
+	}
+	validator := aiopsv1alpha1.Validator
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			healthCheck := builders.HealthCheck("shop")
+			tt.modify(healthCheck)
+			warnings, err := validator.ValidateCreate(context.Background(), healthCheck)
+			if len(warnings) != tt.warnings {
+				t.Errorf("got warnings %v, want %d", warnings, tt.warnings)
+			}
+			if len(tt.errors) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected an error for %v", tt.errors)
+			}
+			for _, path := range tt.errors {
+				if !strings.Contains(err.Error(), path) {
+					t.Errorf("expected an error for %s, got %v", path, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSetDefaults(t *testing.T) {
+	healthCheck := builders.HealthCheck("shop")
+	healthCheck.Spec.ClusterRef = &aiopsv1alpha1.ClusterRef{Name: "edge", SecretRef: &aiopsv1alpha1.KubeconfigSecretReference{Name: "edge"}}
+	healthCheck.SetDefaults()
+
+	spec := healthCheck.Spec
+	if spec.FailureThreshold != 3 || spec.PeriodSeconds != 10 || spec.TimeoutSeconds != 5 || spec.Remediation.CooldownSeconds != 300 {
+		t.Errorf("unexpected defaults %+v", spec)
+	}
+	if spec.TargetRef.Namespace != "shop" || spec.ClusterRef.SecretRef.Namespace != "shop" || spec.ClusterRef.SecretRef.Key != "kubeconfig" {
+		t.Errorf("expected references to default to the HealthCheck's namespace, got %+v %+v", spec.TargetRef, spec.ClusterRef.SecretRef)
+	}
+	probe := spec.Probes[0]
+	if probe.FailureThreshold != 1 || probe.SuccessThreshold != 1 || probe.Critical == nil || !*probe.Critical || probe.Weight != 1 {
+		t.Errorf("unexpected probe defaults %+v", probe)
+	}
+	if errs := healthCheck.ValidateNamespaces(); len(errs) > 0 {
+		t.Errorf("defaults must pass validation: %v", errs)
+	}
+}
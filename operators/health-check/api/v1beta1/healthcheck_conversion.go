@@ -0,0 +1,157 @@
+package v1beta1
+
+import (
+	"encoding/json"
+	"maps"
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/prophet-aiops/health-check/api/v1alpha1"
+)
+
+// ConversionAnnotation keeps what one version can't represent of the other, so converting back
+// restores the object as it was
+const ConversionAnnotation = "aiops.prophet.io/conversion-data"
+
+// conversionData is the JSON stored in ConversionAnnotation
+type conversionData struct {
+	// Notify is the v1alpha1 notify block, restored as long as the v1beta1 one isn't changed
+	Notify *v1alpha1.NotifySpec `json:"notify,omitempty"`
+
+	// DefaultedAPIVersion records that the target apiVersion was only filled in for v1alpha1
+	DefaultedAPIVersion bool `json:"defaultedApiVersion,omitempty"`
+}
+
+// ConvertTo converts this HealthCheck to the v1alpha1 hub version
+func (src *HealthCheck) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.HealthCheck)
+	dst.ObjectMeta = src.ObjectMeta
+	data, err := popConversionData(&dst.ObjectMeta)
+	if err != nil {
+		return err
+	}
+	if err := convert(src.Spec, &dst.Spec); err != nil {
+		return err
+	}
+	if err := convert(src.Status, &dst.Status); err != nil {
+		return err
+	}
+
+	// Put back the deprecated notification fields, unless the channels were edited since
+	if data.Notify != nil {
+		notify, err := notifyFrom(*data.Notify)
+		if err != nil {
+			return err
+		}
+		if reflect.DeepEqual(notify, src.Spec.Notify) {
+			dst.Spec.Notify = *data.Notify
+		}
+	}
+
+	// v1alpha1 requires the target apiVersion with a kind
+	target := &dst.Spec.TargetRef
+	if target.APIVersion == "" && target.Kind != "" && target.LabelSelector == nil {
+		target.APIVersion = defaultAPIVersion(target.Kind)
+		return setConversionData(&dst.ObjectMeta, conversionData{DefaultedAPIVersion: true})
+	}
+	return nil
+}
+
+// ConvertFrom converts the v1alpha1 hub version to this HealthCheck
+func (dst *HealthCheck) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.HealthCheck)
+	dst.ObjectMeta = src.ObjectMeta
+	data, err := popConversionData(&dst.ObjectMeta)
+	if err != nil {
+		return err
+	}
+	if err := convert(src.Spec, &dst.Spec); err != nil {
+		return err
+	}
+	if err := convert(src.Status, &dst.Status); err != nil {
+		return err
+	}
+
+	target := &dst.Spec.TargetRef
+	if data.DefaultedAPIVersion && target.APIVersion == defaultAPIVersion(target.Kind) {
+		target.APIVersion = ""
+	}
+
+	notify := src.Spec.Notify
+	if dst.Spec.Notify, err = notifyFrom(notify); err != nil {
+		return err
+	}
+	if notify.WebhookURL != "" || len(notify.EmailRecipients) > 0 {
+		return setConversionData(&dst.ObjectMeta, conversionData{Notify: notify.DeepCopy()})
+	}
+	return nil
+}
+
+// notifyFrom converts a v1alpha1 notify block. The deprecated webhookUrl becomes the first
+// channel, and emailRecipients the recipients of email channels that don't list their own.
+func notifyFrom(notify v1alpha1.NotifySpec) (NotifySpec, error) {
+	var out NotifySpec
+	if err := convert(notify, &out); err != nil {
+		return out, err
+	}
+	if notify.WebhookURL != "" {
+		out.Channels = append([]NotificationChannel{{Type: "webhook", URL: notify.WebhookURL}}, out.Channels...)
+	}
+	if len(notify.EmailRecipients) > 0 {
+		for i := range out.Channels {
+			if smtp := out.Channels[i].SMTP; smtp != nil && len(smtp.To) == 0 {
+				smtp.To = append([]string(nil), notify.EmailRecipients...)
+			}
+		}
+	}
+	return out, nil
+}
+
+// popConversionData removes ConversionAnnotation from the object's metadata and returns its data.
+// The annotations are copied first, as the metadata is shared with the source object.
+func popConversionData(meta *metav1.ObjectMeta) (conversionData, error) {
+	var data conversionData
+	value, ok := meta.Annotations[ConversionAnnotation]
+	if !ok {
+		return data, nil
+	}
+	meta.Annotations = maps.Clone(meta.Annotations)
+	delete(meta.Annotations, ConversionAnnotation)
+	if len(meta.Annotations) == 0 {
+		meta.Annotations = nil
+	}
+	return data, json.Unmarshal([]byte(value), &data)
+}
+
+// setConversionData records data in ConversionAnnotation
+func setConversionData(meta *metav1.ObjectMeta, data conversionData) error {
+	value, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	meta.Annotations = maps.Clone(meta.Annotations)
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[ConversionAnnotation] = string(value)
+	return nil
+}
+
+// defaultAPIVersion returns the API version of a target kind
+func defaultAPIVersion(kind string) string {
+	if kind == "Pod" {
+		return "v1"
+	}
+	return "apps/v1"
+}
+
+// convert copies fields between versions by JSON name; fields that only exist on one side are dropped
+func convert(in, out interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,149 @@
+package v1beta1
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/prophet-aiops/health-check/api/v1alpha1"
+)
+
+func TestConvertFromRoundTrip(t *testing.T) {
+	smtp := &v1alpha1.SMTPSpec{Host: "smtp.example.com", Port: 587, From: "prophet@example.com"}
+	tests := map[string]v1alpha1.NotifySpec{
+		"no deprecated fields": {
+			Enabled:  true,
+			Channels: []v1alpha1.NotificationChannel{{Type: "slack", URL: "https://hooks.slack.com/x"}},
+		},
+		"webhookUrl": {
+			Enabled:    true,
+			WebhookURL: "https://alerts.example.com/hook",
+			Channels:   []v1alpha1.NotificationChannel{{Type: "slack", URL: "https://hooks.slack.com/x"}},
+		},
+		"emailRecipients without an email channel": {
+			Enabled:         true,
+			EmailRecipients: []string{"oncall@example.com"},
+		},
+		"emailRecipients with an email channel": {
+			Enabled:         true,
+			EmailRecipients: []string{"oncall@example.com"},
+			Channels:        []v1alpha1.NotificationChannel{{Type: "email", SMTP: smtp}},
+		},
+		"emailRecipients and a channel with its own recipients": {
+			EmailRecipients: []string{"oncall@example.com"},
+			Channels: []v1alpha1.NotificationChannel{{Type: "email", SMTP: &v1alpha1.SMTPSpec{
+				Host: "smtp.example.com", Port: 587, From: "prophet@example.com", To: []string{"team@example.com"},
+			}}},
+		},
+	}
+	for name, notify := range tests {
+		t.Run(name, func(t *testing.T) {
+			original := &v1alpha1.HealthCheck{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop", Annotations: map[string]string{"team": "payments"}},
+				Spec: v1alpha1.HealthCheckSpec{
+					TargetRef: v1alpha1.TargetRef{APIVersion: "apps/v1", Kind: "Deployment", Name: "web", Namespace: "shop"},
+					Notify:    notify,
+				},
+				Status: v1alpha1.HealthCheckStatus{Healthy: true, FailureCount: 2, HealthScore: 80},
+			}
+			hub := original.DeepCopy()
+
+			var beta HealthCheck
+			if err := beta.ConvertFrom(hub); err != nil {
+				t.Fatalf("ConvertFrom: %v", err)
+			}
+			if !reflect.DeepEqual(hub, original) {
+				t.Fatalf("ConvertFrom modified its source:\n got %+v\nwant %+v", hub, original)
+			}
+			var back v1alpha1.HealthCheck
+			if err := beta.ConvertTo(&back); err != nil {
+				t.Fatalf("ConvertTo: %v", err)
+			}
+			if !reflect.DeepEqual(&back, original) {
+				t.Errorf("round trip changed the HealthCheck:\n got %+v\nwant %+v", back, *original)
+			}
+		})
+	}
+}
+
+func TestConvertFromDeprecatedNotify(t *testing.T) {
+	hub := &v1alpha1.HealthCheck{Spec: v1alpha1.HealthCheckSpec{Notify: v1alpha1.NotifySpec{
+		WebhookURL:      "https://alerts.example.com/hook",
+		EmailRecipients: []string{"oncall@example.com"},
+		Channels:        []v1alpha1.NotificationChannel{{Type: "email", SMTP: &v1alpha1.SMTPSpec{Host: "smtp.example.com", From: "prophet@example.com"}}},
+	}}}
+
+	var beta HealthCheck
+	if err := beta.ConvertFrom(hub); err != nil {
+		t.Fatalf("ConvertFrom: %v", err)
+	}
+	channels := beta.Spec.Notify.Channels
+	if len(channels) != 2 || channels[0].Type != "webhook" || channels[0].URL != "https://alerts.example.com/hook" {
+		t.Fatalf("expected the webhookUrl as the first channel, got %+v", channels)
+	}
+	if to := channels[1].SMTP.To; !reflect.DeepEqual(to, []string{"oncall@example.com"}) {
+		t.Errorf("expected emailRecipients on the email channel, got %v", to)
+	}
+	if _, ok := beta.Annotations[ConversionAnnotation]; !ok {
+		t.Errorf("expected the %s annotation", ConversionAnnotation)
+	}
+}
+
+func TestConvertToEditedChannels(t *testing.T) {
+	hub := &v1alpha1.HealthCheck{Spec: v1alpha1.HealthCheckSpec{Notify: v1alpha1.NotifySpec{
+		WebhookURL: "https://alerts.example.com/hook",
+	}}}
+	var beta HealthCheck
+	if err := beta.ConvertFrom(hub); err != nil {
+		t.Fatalf("ConvertFrom: %v", err)
+	}
+
+	// Channels edited through v1beta1 win over the recorded v1alpha1 fields
+	beta.Spec.Notify.Channels = []NotificationChannel{{Type: "slack", URL: "https://hooks.slack.com/x"}}
+	var back v1alpha1.HealthCheck
+	if err := beta.ConvertTo(&back); err != nil {
+		t.Fatalf("ConvertTo: %v", err)
+	}
+	want := v1alpha1.NotifySpec{Channels: []v1alpha1.NotificationChannel{{Type: "slack", URL: "https://hooks.slack.com/x"}}}
+	if !reflect.DeepEqual(back.Spec.Notify, want) {
+		t.Errorf("got notify %+v, want %+v", back.Spec.Notify, want)
+	}
+	if back.Annotations != nil {
+		t.Errorf("expected no annotations, got %v", back.Annotations)
+	}
+}
+
+func TestConvertToRoundTrip(t *testing.T) {
+	tests := map[string]TargetRef{
+		"apiVersion set":   {APIVersion: "apps/v1", Kind: "StatefulSet", Name: "db"},
+		"apiVersion unset": {Kind: "Deployment", Name: "web"},
+		"pod":              {Kind: "Pod", Name: "web-0"},
+		"label selector":   {Kind: "Pod", LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}},
+	}
+	for name, target := range tests {
+		t.Run(name, func(t *testing.T) {
+			original := &HealthCheck{
+				ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "shop"},
+				Spec: HealthCheckSpec{
+					TargetRef: target,
+					Notify:    NotifySpec{Enabled: true, Channels: []NotificationChannel{{Type: "webhook", URL: "https://alerts.example.com/hook"}}},
+				},
+			}
+			var hub v1alpha1.HealthCheck
+			if err := original.DeepCopy().ConvertTo(&hub); err != nil {
+				t.Fatalf("ConvertTo: %v", err)
+			}
+			if target.LabelSelector == nil && hub.Spec.TargetRef.APIVersion == "" {
+				t.Errorf("expected the target apiVersion to be set for v1alpha1")
+			}
+			var back HealthCheck
+			if err := back.ConvertFrom(&hub); err != nil {
+				t.Fatalf("ConvertFrom: %v", err)
+			}
+			if !reflect.DeepEqual(&back, original) {
+				t.Errorf("round trip changed the HealthCheck:\n got %+v\nwant %+v", back, *original)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"os"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -12,13 +13,16 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	aiopsv1alpha1 "github.com/prophet-aiops/health-check/api/v1alpha1"
+	aiopsv1beta1 "github.com/prophet-aiops/health-check/api/v1beta1"
 	"github.com/prophet-aiops/health-check/controllers"
+	"github.com/prophet-aiops/pkg/httpclient"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -31,6 +35,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(aiopsv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(aiopsv1beta1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -38,11 +43,27 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var watchNamespaces string
+	var extraProtectedNamespaces string
+	var remoteClusterQPS float64
+	var remoteClusterBurst int
+	var externalProbeAllowedCIDRs string
+	var enableWebhooks bool
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", os.Getenv("WATCH_NAMESPACE"),
+		"Comma-separated namespaces to watch, defaulting to $WATCH_NAMESPACE. Leave empty to watch all namespaces.")
+	flag.StringVar(&extraProtectedNamespaces, "protected-namespaces", "",
+		"Comma-separated namespaces the operator must never act in, in addition to its own namespace and kube-system, kube-public and kube-node-lease.")
+	flag.Float64Var(&remoteClusterQPS, "remote-cluster-qps", 5, "API requests per second allowed to each remote cluster.")
+	flag.IntVar(&remoteClusterBurst, "remote-cluster-burst", 10, "API request burst allowed to each remote cluster.")
+	flag.StringVar(&externalProbeAllowedCIDRs, "external-probe-allowed-cidrs", "",
+		"Comma-separated CIDRs external probes may connect to although they are blocked by default: loopback, link-local and cloud metadata addresses.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", false,
+		"Serve the HealthCheck conversion and validating webhooks. Requires serving certificates in /tmp/k8s-webhook-server/serving-certs.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -51,6 +72,18 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	externalProbeAllowedNetworks, err := controllers.ParseCIDRs(externalProbeAllowedCIDRs)
+	if err != nil {
+		setupLog.Error(err, "invalid --external-probe-allowed-cidrs")
+		os.Exit(1)
+	}
+
+	// Fail fast on bad PROPHET_HTTP_* settings rather than on the first outbound request
+	if _, err := httpclient.New(httpclient.Config{}); err != nil {
+		setupLog.Error(err, "invalid outbound HTTP settings")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
 		Metrics: metricsserver.Options{
@@ -60,6 +93,7 @@ func main() {
 			Port: 9443,
 		}),
 		HealthProbeBindAddress: probeAddr,
+		Cache:                  cacheOptions(watchNamespaces),
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "health-check.prophet.io",
 	})
@@ -69,13 +103,24 @@ func main() {
 	}
 
 	if err = (&controllers.HealthCheckReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-		Log:    ctrl.Log.WithName("controllers").WithName("HealthCheck"),
+		Client:                       mgr.GetClient(),
+		Scheme:                       mgr.GetScheme(),
+		Log:                          ctrl.Log.WithName("controllers").WithName("HealthCheck"),
+		RESTConfig:                   mgr.GetConfig(),
+		RemoteClusterQPS:             float32(remoteClusterQPS),
+		RemoteClusterBurst:           remoteClusterBurst,
+		ProtectedNamespaces:          protectedNamespaces(extraProtectedNamespaces),
+		ExternalProbeAllowedNetworks: externalProbeAllowedNetworks,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "HealthCheck")
 		os.Exit(1)
 	}
+	if enableWebhooks {
+		if err = (&aiopsv1alpha1.HealthCheck{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "HealthCheck")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -93,3 +138,40 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// cacheOptions restricts the manager's cache to the comma-separated watch namespaces.
+// Extra namespaces the operator reads from are added in namespace-scoped mode.
+// With no watch namespaces every namespace is watched.
+func cacheOptions(watchNamespaces string, extra ...string) cache.Options {
+	namespaces := map[string]cache.Config{}
+	for _, namespace := range strings.Split(watchNamespaces, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces[namespace] = cache.Config{}
+		}
+	}
+	if len(namespaces) == 0 {
+		return cache.Options{}
+	}
+	for _, namespace := range extra {
+		if namespace != "" {
+			namespaces[namespace] = cache.Config{}
+		}
+	}
+	return cache.Options{DefaultNamespaces: namespaces}
+}
+
+// protectedNamespaces returns the operator's own namespace and the comma-separated extra namespaces
+func protectedNamespaces(extra string) []string {
+	var namespaces []string
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		namespaces = append(namespaces, namespace)
+	} else if data, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+		namespaces = append(namespaces, strings.TrimSpace(string(data)))
+	}
+	for _, namespace := range strings.Split(extra, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/health-check/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/clusters"
+)
+
+// remoteClusters is shared by all reconciles so each remote cluster gets one client
+var remoteClusters = clusters.NewRegistry()
+
+// targetClient returns a client for the cluster the HealthCheck target runs in
+func (r *HealthCheckReconciler) targetClient(ctx context.Context, healthCheck *aiopsv1alpha1.HealthCheck) (client.Client, error) {
+	if healthCheck.Spec.ClusterRef == nil {
+		return r.Client, nil
+	}
+	return remoteClusters.Client(ctx, r.Client, r.Scheme, clusterRef(healthCheck), r.remoteClusterOptions())
+}
+
+// targetConfig returns the REST config for the cluster the HealthCheck target runs in
+func (r *HealthCheckReconciler) targetConfig(ctx context.Context, healthCheck *aiopsv1alpha1.HealthCheck) (*rest.Config, error) {
+	if healthCheck.Spec.ClusterRef == nil {
+		if r.RESTConfig == nil {
+			return nil, fmt.Errorf("no REST config for the local cluster")
+		}
+		return r.RESTConfig, nil
+	}
+	return remoteClusters.Config(ctx, r.Client, r.Scheme, clusterRef(healthCheck), r.remoteClusterOptions())
+}
+
+// clusterRef locates the kubeconfig of the HealthCheck's remote cluster
+func clusterRef(healthCheck *aiopsv1alpha1.HealthCheck) clusters.Ref {
+	ref := healthCheck.Spec.ClusterRef
+
+	// Default to the Secret Cluster API writes for each workload cluster
+	clusterRef := clusters.Ref{
+		Cluster: ref.Name,
+		Secret:  types.NamespacedName{Namespace: healthCheck.Namespace, Name: ref.Name + "-kubeconfig"},
+		Key:     clusters.ClusterAPIKey,
+	}
+	if secretRef := ref.SecretRef; secretRef != nil {
+		// Another namespace is rejected by ValidateNamespaces before any client is built
+		clusterRef.Secret = types.NamespacedName{Namespace: healthCheck.Namespace, Name: secretRef.Name}
+		clusterRef.Key = secretRef.Key
+	}
+	return clusterRef
+}
+
+func (r *HealthCheckReconciler) remoteClusterOptions() clusters.Options {
+	return clusters.Options{
+		QPS:   r.RemoteClusterQPS,
+		Burst: r.RemoteClusterBurst,
+	}
+}
+
+// clusterName returns the remote cluster name, or an empty string for the local cluster
+func clusterName(healthCheck *aiopsv1alpha1.HealthCheck) string {
+	if healthCheck.Spec.ClusterRef == nil {
+		return ""
+	}
+	return healthCheck.Spec.ClusterRef.Name
+}
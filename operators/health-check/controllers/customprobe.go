@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/health-check/api/v1alpha1"
+)
+
+const (
+	// probeJobAnnotation records the HealthCheck and probe that started a custom probe Job
+	probeJobAnnotation = "aiops.prophet.io/health-check"
+
+	// probeJobTTL removes finished Jobs whose result was never collected, e.g. after the HealthCheck was deleted
+	probeJobTTL = int32(600)
+
+	// probeJobUser is the unprivileged user and group custom probe scripts run as
+	probeJobUser = int64(65532)
+)
+
+// executeCustomProbe runs the custom probe script in a Job next to the target. A run spans
+// reconciles: one creates the Job, a later one reads the exit code and deletes the Job so the next
+// reconcile starts a new run. done is false while the Job runs; latency is how long the Job ran.
+func (r *HealthCheckReconciler) executeCustomProbe(ctx context.Context, target client.Client, healthCheck *aiopsv1alpha1.HealthCheck, pod corev1.Pod, probe *aiopsv1alpha1.ProbeSpec) (success bool, message string, latency time.Duration, done bool) {
+	logger := log.FromContext(ctx)
+	if probe.Custom == nil || probe.Custom.Script == "" {
+		return false, "custom.script is required for custom probes", 0, true
+	}
+
+	// Jobs only ever run in the HealthCheck's own namespace
+	if pod.Namespace != healthCheck.Namespace {
+		return false, fmt.Sprintf("Not starting probe job for pod %s/%s outside the HealthCheck's namespace", pod.Namespace, pod.Name), 0, true
+	}
+
+	job := &batchv1.Job{}
+	key := types.NamespacedName{Namespace: healthCheck.Namespace, Name: probeJobName(healthCheck, probe.Name)}
+	if err := target.Get(ctx, key, job); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return false, fmt.Sprintf("Failed to get probe job %s: %v", key.Name, err), 0, true
+		}
+		job = newProbeJob(healthCheck, probe, pod, key.Name)
+		if err := target.Create(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+			return false, fmt.Sprintf("Failed to start probe job %s: %v", key.Name, err), 0, true
+		}
+		logger.Info("Started custom probe", "probe", probe.Name, "job", key.Name)
+		return false, "", 0, false
+	}
+
+	finished, condition := jobFinished(job)
+	if !finished || job.DeletionTimestamp != nil {
+		return false, "", 0, false
+	}
+	if start := job.Status.StartTime; start != nil {
+		end := job.Status.CompletionTime
+		if condition != nil {
+			end = &condition.LastTransitionTime
+		}
+		if end != nil {
+			latency = end.Sub(start.Time)
+		}
+	}
+
+	switch {
+	case condition == nil:
+		success, message = true, "Script exited with code 0"
+	case condition.Reason == "DeadlineExceeded":
+		message = fmt.Sprintf("Script didn't finish within %ds", healthCheck.Spec.TimeoutSeconds)
+	default:
+		exitCode, output := probeJobOutput(ctx, target, job)
+		message = fmt.Sprintf("Script exited with code %d: %s", exitCode, output)
+	}
+
+	// Delete the Job and its pod; the next reconcile runs the probe again
+	if err := target.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+		logger.Error(err, "Failed to delete custom probe job", "job", job.Name)
+	}
+	return success, message, latency, true
+}
+
+// newProbeJob builds the Job running the custom probe. Without an image it runs in the image of the
+// target pod's default container. The Job gets none of the target's credentials: no image pull
+// secrets and no service account token, and it runs as a non-root user without capabilities.
+func newProbeJob(healthCheck *aiopsv1alpha1.HealthCheck, probe *aiopsv1alpha1.ProbeSpec, pod corev1.Pod, name string) *batchv1.Job {
+	custom := probe.Custom
+	backoffLimit := int32(0)
+	deadline := int64(healthCheck.Spec.TimeoutSeconds)
+	ttl := probeJobTTL
+	automountToken := false
+	nonRoot := true
+	user := probeJobUser
+	privilegeEscalation := false
+
+	image := custom.Image
+	if image == "" {
+		container := execContainer(&pod)
+		for _, c := range pod.Spec.Containers {
+			if c.Name == container {
+				image = c.Image
+			}
+		}
+	}
+	env := []corev1.EnvVar{
+		{Name: "TARGET_KIND", Value: healthCheck.Spec.TargetRef.Kind},
+		{Name: "TARGET_NAME", Value: healthCheck.Spec.TargetRef.Name},
+		{Name: "TARGET_NAMESPACE", Value: pod.Namespace},
+	}
+	if selector := healthCheck.Spec.TargetRef.LabelSelector; selector != nil {
+		env = append(env, corev1.EnvVar{Name: "TARGET_SELECTOR", Value: metav1.FormatLabelSelector(selector)})
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   healthCheck.Namespace,
+			Labels:      map[string]string{"app.kubernetes.io/managed-by": "health-check"},
+			Annotations: map[string]string{probeJobAnnotation: healthCheck.Namespace + "/" + healthCheck.Name + "/" + probe.Name},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            &backoffLimit,
+			ActiveDeadlineSeconds:   &deadline,
+			TTLSecondsAfterFinished: &ttl,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy:                corev1.RestartPolicyNever,
+					AutomountServiceAccountToken: &automountToken,
+					SecurityContext: &corev1.PodSecurityContext{
+						RunAsNonRoot:   &nonRoot,
+						RunAsUser:      &user,
+						RunAsGroup:     &user,
+						SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+					},
+					Containers: []corev1.Container{{
+						Name:    "probe",
+						Image:   image,
+						Command: []string{"sh", "-c", custom.Script},
+						Env:     append(env, custom.Env...),
+						SecurityContext: &corev1.SecurityContext{
+							AllowPrivilegeEscalation: &privilegeEscalation,
+							Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+						},
+						// A failing script's output becomes the termination message
+						TerminationMessagePolicy: corev1.TerminationMessageFallbackToLogsOnError,
+					}},
+				},
+			},
+		},
+	}
+}
+
+// probeJobOutput returns the exit code and output of the Job's probe container
+func probeJobOutput(ctx context.Context, target client.Client, job *batchv1.Job) (int32, string) {
+	selector, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+	if err != nil {
+		return -1, "no output"
+	}
+	var pods corev1.PodList
+	if err := target.List(ctx, &pods, client.InNamespace(job.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return -1, "no output"
+	}
+	for _, pod := range pods.Items {
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			if terminated := containerStatus.State.Terminated; terminated != nil {
+				output := strings.TrimSpace(terminated.Message)
+				if output == "" {
+					output = "no output"
+				}
+				return terminated.ExitCode, output
+			}
+		}
+	}
+	return -1, "no output"
+}
+
+// jobFinished reports whether the Job has finished, returning its Failed condition if it failed
+func jobFinished(job *batchv1.Job) (bool, *batchv1.JobCondition) {
+	for i := range job.Status.Conditions {
+		condition := &job.Status.Conditions[i]
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return true, nil
+		case batchv1.JobFailed:
+			return true, condition
+		}
+	}
+	return false, nil
+}
+
+// previousProbeResult returns the probe's result from the last check, if any
+func previousProbeResult(healthCheck *aiopsv1alpha1.HealthCheck, name string) *aiopsv1alpha1.ProbeResult {
+	for i := range healthCheck.Status.ProbeResults {
+		if healthCheck.Status.ProbeResults[i].Name == name {
+			return &healthCheck.Status.ProbeResults[i]
+		}
+	}
+	return nil
+}
+
+// probeJobName returns the name of the custom probe's Job. The hash keeps HealthChecks with the
+// same name in different namespaces, and probe names that differ only in characters a name can't
+// hold, apart.
+func probeJobName(healthCheck *aiopsv1alpha1.HealthCheck, probe string) string {
+	h := fnv.New32a()
+	h.Write([]byte(healthCheck.Namespace + "/" + healthCheck.Name + "/" + probe))
+	name := strings.Map(func(c rune) rune {
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' {
+			return c
+		}
+		return '-'
+	}, strings.ToLower(healthCheck.Name+"-"+probe))
+	if len(name) > 47 {
+		name = name[:47]
+	}
+	return fmt.Sprintf("%s-probe-%08x", strings.TrimRight(name, "-."), h.Sum32())
+}
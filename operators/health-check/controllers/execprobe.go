@@ -0,0 +1,150 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/health-check/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/prophetconfig"
+)
+
+// maxExecOutput is how much of the command's output is kept in the probe message
+const maxExecOutput = 1024
+
+// defaultContainerAnnotation names the container kubectl exec uses when none is given
+const defaultContainerAnnotation = "kubectl.kubernetes.io/default-container"
+
+// executeCommandProbe runs the command in the first running target pod through the pod exec API.
+// Like a kubelet exec probe it passes when the command exits with 0. Commands are never run in
+// protected pods.
+func (r *HealthCheckReconciler) executeCommandProbe(ctx context.Context, healthCheck *aiopsv1alpha1.HealthCheck, defaults prophetconfig.Spec, pods []corev1.Pod, exec *corev1.ExecAction, timeout time.Duration) (bool, string) {
+	if exec == nil || len(exec.Command) == 0 {
+		return false, "exec.command is required for command probes"
+	}
+	var pod *corev1.Pod
+	for i := range pods {
+		if pods[i].Status.Phase == corev1.PodRunning && pods[i].DeletionTimestamp == nil {
+			pod = &pods[i]
+			break
+		}
+	}
+	if pod == nil {
+		return false, "No running target pods"
+	}
+	if pod.Namespace != healthCheck.Namespace {
+		return false, fmt.Sprintf("Not running command in pod %s/%s outside the HealthCheck's namespace", pod.Namespace, pod.Name)
+	}
+	if reason := r.protected(defaults, pod); reason != "" {
+		return false, fmt.Sprintf("Not running command in protected pod %s/%s: %s", pod.Namespace, pod.Name, reason)
+	}
+
+	config, err := r.targetConfig(ctx, healthCheck)
+	if err != nil {
+		return false, fmt.Sprintf("Failed to get config for target cluster: %v", err)
+	}
+	coreClient, err := corev1client.NewForConfig(config)
+	if err != nil {
+		return false, fmt.Sprintf("Failed to create exec client: %v", err)
+	}
+
+	container := execContainer(pod)
+	req := coreClient.RESTClient().Post().
+		Resource("pods").
+		Namespace(pod.Namespace).
+		Name(pod.Name).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   exec.Command,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(config, "POST", req.URL())
+	if err != nil {
+		return false, fmt.Sprintf("Failed to create exec stream: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	output := &truncatingBuffer{limit: maxExecOutput}
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: output, Stderr: output})
+
+	prefix := fmt.Sprintf("%s/%s", pod.Name, container)
+	var exitErr utilexec.ExitError
+	switch {
+	case err == nil:
+		return true, withOutput(fmt.Sprintf("%s: exit code 0", prefix), output)
+	case errors.As(err, &exitErr) && exitErr.Exited():
+		return false, withOutput(fmt.Sprintf("%s: exit code %d", prefix, exitErr.ExitStatus()), output)
+	case ctx.Err() == context.DeadlineExceeded:
+		return false, withOutput(fmt.Sprintf("%s: command timed out after %s", prefix, timeout), output)
+	default:
+		return false, fmt.Sprintf("%s: exec failed: %v", prefix, err)
+	}
+}
+
+// execContainer returns the container to run the command in: the pod's default container, else its first
+func execContainer(pod *corev1.Pod) string {
+	if name := pod.Annotations[defaultContainerAnnotation]; name != "" {
+		for _, c := range pod.Spec.Containers {
+			if c.Name == name {
+				return name
+			}
+		}
+	}
+	if len(pod.Spec.Containers) == 0 {
+		return ""
+	}
+	return pod.Spec.Containers[0].Name
+}
+
+// withOutput appends the command's output to the message
+func withOutput(message string, output *truncatingBuffer) string {
+	text := strings.TrimSpace(output.String())
+	if text == "" {
+		return message
+	}
+	if output.truncated {
+		text += "... (truncated)"
+	}
+	return fmt.Sprintf("%s: %s", message, text)
+}
+
+// truncatingBuffer keeps the first limit bytes written to it and discards the rest. Writes never
+// fail, so long output doesn't abort the command. Stdout and stderr are copied concurrently.
+type truncatingBuffer struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *truncatingBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if room := b.limit - b.buf.Len(); room < len(p) {
+		b.truncated = true
+		if room > 0 {
+			b.buf.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *truncatingBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
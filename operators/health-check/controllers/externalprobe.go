@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/health-check/api/v1alpha1"
+)
+
+// blockedNetworks are never connected to by external probes unless allowed explicitly: loopback,
+// link-local, which includes the 169.254.169.254 cloud metadata endpoint, the metadata endpoints
+// outside it, unspecified and multicast addresses. They reach the operator's own node and
+// credentials rather than anything a HealthCheck should watch.
+var blockedNetworks = mustParseCIDRs(
+	"127.0.0.0/8", "::1/128",
+	"169.254.0.0/16", "fe80::/10",
+	"100.100.100.200/32", "fd00:ec2::254/128",
+	"0.0.0.0/8", "::/128",
+	"224.0.0.0/4", "ff00::/8",
+)
+
+// ParseCIDRs parses comma-separated CIDRs such as "169.254.0.0/16,fd00::/8"
+func ParseCIDRs(list string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, cidr := range strings.Split(list, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks, err := ParseCIDRs(strings.Join(cidrs, ","))
+	if err != nil {
+		panic(err)
+	}
+	return networks
+}
+
+// checkExternalIP returns an error if external probes must not connect to the IP
+func checkExternalIP(ip net.IP, allowed []*net.IPNet) error {
+	for _, network := range allowed {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return fmt.Errorf("address %s is in blocked network %s", ip, network)
+		}
+	}
+	return nil
+}
+
+// executeExternalProbe checks the URL or TCP address from the operator. Connections aren't reused
+// between runs, so every run resolves the host and connects again.
+func (r *HealthCheckReconciler) executeExternalProbe(ctx context.Context, probe *aiopsv1alpha1.ProbeSpec, timeout time.Duration) (bool, string, *aiopsv1alpha1.ExternalProbeResult) {
+	external := probe.External
+	if external == nil || (external.URL == "") == (external.Address == "") {
+		return false, "one of external.url and external.address is required for external probes", nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result := &aiopsv1alpha1.ExternalProbeResult{}
+	var success bool
+	var message string
+	if external.URL != "" {
+		success, message = externalGet(ctx, probe, r.ExternalProbeAllowedNetworks, result)
+	} else {
+		success, message = externalConnect(ctx, external, r.ExternalProbeAllowedNetworks, result)
+	}
+
+	if expiry := result.CertificateExpiry; expiry != nil {
+		left := time.Until(expiry.Time)
+		message += fmt.Sprintf(", certificate expires in %d days", int(left.Hours()/24))
+		if success && left < time.Duration(external.MinCertificateValidityDays)*24*time.Hour {
+			success = false
+			message += fmt.Sprintf(", less than the required %d days", external.MinCertificateValidityDays)
+		}
+	}
+	return success, message, result
+}
+
+// externalGet sends the GET request to the probe's URL and checks the response like an HTTP probe
+func externalGet(ctx context.Context, probe *aiopsv1alpha1.ProbeSpec, allowed []*net.IPNet, result *aiopsv1alpha1.ExternalProbeResult) (bool, string) {
+	external := probe.External
+	var bodyRegex *regexp.Regexp
+	if probe.BodyRegex != "" {
+		var err error
+		if bodyRegex, err = regexp.Compile(probe.BodyRegex); err != nil {
+			return false, fmt.Sprintf("Invalid bodyRegex: %v", err)
+		}
+	}
+	expected, err := parseStatusRanges(probe.ExpectedStatus)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	var tlsStart time.Time
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			result.TLSHandshakeMilliseconds = time.Since(tlsStart).Milliseconds()
+		},
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, external.URL, nil)
+	if err != nil {
+		return false, err.Error()
+	}
+	for _, header := range external.HTTPHeaders {
+		if strings.EqualFold(header.Name, "Host") {
+			req.Host = header.Value
+			continue
+		}
+		req.Header.Add(header.Name, header.Value)
+	}
+
+	client := &http.Client{
+		// A transport per run, so each run resolves and connects again, without going through a proxy
+		Transport: &http.Transport{
+			Proxy: nil,
+			DialContext: func(ctx context.Context, _, address string) (net.Conn, error) {
+				return dialExternal(ctx, address, allowed, result)
+			},
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: external.InsecureSkipVerify},
+			DisableKeepAlives: true,
+		},
+		// Redirects are judged by their status code, as for HTTP probes
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return false, err.Error()
+	}
+	defer resp.Body.Close()
+	if resp.TLS != nil {
+		result.CertificateExpiry = certificateExpiry(resp.TLS)
+	}
+
+	if !statusExpected(resp.StatusCode, expected) {
+		return false, fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+	if bodyRegex != nil {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBody))
+		if err != nil {
+			return false, fmt.Sprintf("failed to read response body: %v", err)
+		}
+		if !bodyRegex.Match(body) {
+			return false, fmt.Sprintf("status %d, body doesn't match %q", resp.StatusCode, bodyRegex.String())
+		}
+	}
+	return true, fmt.Sprintf("status %d", resp.StatusCode)
+}
+
+// externalConnect opens a TCP connection to the probe's address and, with tls, performs a TLS handshake
+func externalConnect(ctx context.Context, external *aiopsv1alpha1.ExternalProbe, allowed []*net.IPNet, result *aiopsv1alpha1.ExternalProbeResult) (bool, string) {
+	conn, err := dialExternal(ctx, external.Address, allowed, result)
+	if err != nil {
+		return false, err.Error()
+	}
+	defer conn.Close()
+	if !external.TLS {
+		return true, fmt.Sprintf("connected to %s", conn.RemoteAddr())
+	}
+
+	host, _, _ := net.SplitHostPort(external.Address)
+	tlsConn := tls.Client(conn, &tls.Config{ServerName: host, InsecureSkipVerify: external.InsecureSkipVerify})
+	start := time.Now()
+	err = tlsConn.HandshakeContext(ctx)
+	result.TLSHandshakeMilliseconds = time.Since(start).Milliseconds()
+	if err != nil {
+		return false, fmt.Sprintf("TLS handshake with %s failed: %v", conn.RemoteAddr(), err)
+	}
+	state := tlsConn.ConnectionState()
+	result.CertificateExpiry = certificateExpiry(&state)
+	return true, fmt.Sprintf("TLS handshake with %s succeeded", conn.RemoteAddr())
+}
+
+// dialExternal resolves the host of the host:port address and connects to the first of its
+// addresses that accepts, recording how long resolving and connecting took. Addresses in blocked
+// networks are refused; they are checked after resolving, so DNS names can't point around the check.
+func dialExternal(ctx context.Context, address string, allowed []*net.IPNet, result *aiopsv1alpha1.ExternalProbeResult) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	addresses, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	result.DNSLookupMilliseconds = time.Since(start).Milliseconds()
+	if err != nil {
+		return nil, fmt.Errorf("DNS lookup failed: %w", err)
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("DNS lookup failed: no addresses for %s", host)
+	}
+
+	var dialer net.Dialer
+	var conn net.Conn
+	start = time.Now()
+	for _, ip := range addresses {
+		if err = checkExternalIP(ip.IP, allowed); err != nil {
+			continue
+		}
+		if conn, err = dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), port)); err == nil {
+			break
+		}
+	}
+	result.ConnectMilliseconds = time.Since(start).Milliseconds()
+	if err != nil {
+		return nil, fmt.Errorf("connect failed: %w", err)
+	}
+	return conn, nil
+}
+
+// certificateExpiry returns the earliest expiry of the certificates in the server's chain, or nil
+// without peer certificates
+func certificateExpiry(state *tls.ConnectionState) *metav1.Time {
+	var earliest time.Time
+	for _, cert := range state.PeerCertificates {
+		if earliest.IsZero() || cert.NotAfter.Before(earliest) {
+			earliest = cert.NotAfter
+		}
+	}
+	if earliest.IsZero() {
+		return nil
+	}
+	return &metav1.Time{Time: earliest}
+}
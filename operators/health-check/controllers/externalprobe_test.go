@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/health-check/api/v1alpha1"
+)
+
+func TestCheckExternalIP(t *testing.T) {
+	loopback := mustParseCIDRs("127.0.0.0/8")
+	tests := []struct {
+		ip      string
+		allowed []*net.IPNet
+		blocked bool
+	}{
+		{ip: "127.0.0.1", blocked: true},
+		{ip: "::1", blocked: true},
+		{ip: "169.254.169.254", blocked: true},
+		{ip: "fe80::1", blocked: true},
+		{ip: "100.100.100.200", blocked: true},
+		{ip: "fd00:ec2::254", blocked: true},
+		{ip: "0.0.0.0", blocked: true},
+		{ip: "224.0.0.1", blocked: true},
+		{ip: "10.0.0.1"},
+		{ip: "192.168.1.10"},
+		{ip: "93.184.216.34"},
+		{ip: "2606:2800:220:1::1"},
+		{ip: "127.0.0.1", allowed: loopback},
+		{ip: "169.254.169.254", allowed: loopback, blocked: true},
+	}
+	for _, tt := range tests {
+		err := checkExternalIP(net.ParseIP(tt.ip), tt.allowed)
+		if (err != nil) != tt.blocked {
+			t.Errorf("checkExternalIP(%s, %v) = %v, want blocked %v", tt.ip, tt.allowed, err, tt.blocked)
+		}
+	}
+}
+
+func TestParseCIDRs(t *testing.T) {
+	networks, err := ParseCIDRs(" 127.0.0.0/8, ,fd00::/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(networks) != 2 || networks[0].String() != "127.0.0.0/8" || networks[1].String() != "fd00::/8" {
+		t.Errorf("unexpected networks %v", networks)
+	}
+	if _, err := ParseCIDRs("127.0.0.1"); err == nil {
+		t.Error("expected an error for an address without a prefix length")
+	}
+}
+
+func TestExecuteExternalProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/redirect" {
+			http.Redirect(w, req, "/", http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("status: green"))
+	}))
+	defer server.Close()
+
+	get := func(url string) *aiopsv1alpha1.ProbeSpec {
+		return &aiopsv1alpha1.ProbeSpec{Name: "external", Type: "external", External: &aiopsv1alpha1.ExternalProbe{URL: url}}
+	}
+	connect := &aiopsv1alpha1.ProbeSpec{Name: "external", Type: "external", External: &aiopsv1alpha1.ExternalProbe{
+		Address: server.Listener.Addr().String(),
+	}}
+
+	t.Run("loopback is blocked by default", func(t *testing.T) {
+		r := &HealthCheckReconciler{}
+		for _, probe := range []*aiopsv1alpha1.ProbeSpec{get(server.URL), connect} {
+			success, message, _ := r.executeExternalProbe(context.Background(), probe, time.Second)
+			if success || !strings.Contains(message, "blocked network 127.0.0.0/8") {
+				t.Errorf("expected the loopback address to be refused, got %v %q", success, message)
+			}
+		}
+	})
+
+	t.Run("metadata endpoint is blocked", func(t *testing.T) {
+		r := &HealthCheckReconciler{ExternalProbeAllowedNetworks: mustParseCIDRs("127.0.0.0/8")}
+		success, message, _ := r.executeExternalProbe(context.Background(), get("http://169.254.169.254/latest/meta-data/"), time.Second)
+		if success || !strings.Contains(message, "blocked network 169.254.0.0/16") {
+			t.Errorf("expected the metadata endpoint to be refused, got %v %q", success, message)
+		}
+	})
+
+	r := &HealthCheckReconciler{ExternalProbeAllowedNetworks: mustParseCIDRs("127.0.0.0/8")}
+
+	t.Run("allowed network", func(t *testing.T) {
+		probe := get(server.URL)
+		probe.BodyRegex = "green"
+		success, message, result := r.executeExternalProbe(context.Background(), probe, time.Second)
+		if !success || message != "status 200" {
+			t.Errorf("expected success, got %v %q", success, message)
+		}
+		if result == nil {
+			t.Fatal("expected an external probe result")
+		}
+	})
+
+	t.Run("redirects are judged by status", func(t *testing.T) {
+		probe := get(server.URL + "/redirect")
+		probe.ExpectedStatus = []string{"200"}
+		if success, message, _ := r.executeExternalProbe(context.Background(), probe, time.Second); success || message != "unexpected status 302" {
+			t.Errorf("expected the redirect to fail the probe, got %v %q", success, message)
+		}
+	})
+
+	t.Run("tcp", func(t *testing.T) {
+		if success, message, _ := r.executeExternalProbe(context.Background(), connect, time.Second); !success {
+			t.Errorf("expected success, got %q", message)
+		}
+	})
+
+	t.Run("url and address are exclusive", func(t *testing.T) {
+		probe := get(server.URL)
+		probe.External.Address = server.Listener.Addr().String()
+		if success, _, _ := r.executeExternalProbe(context.Background(), probe, time.Second); success {
+			t.Error("expected a probe with both url and address to fail")
+		}
+	})
+}
+
+func TestCertificateExpiry(t *testing.T) {
+	leaf := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	intermediate := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	state := &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{NotAfter: leaf}, {NotAfter: intermediate}}}
+	if got := certificateExpiry(state); got == nil || !got.Time.Equal(intermediate) {
+		t.Errorf("got %v, want the intermediate's expiry %v", got, intermediate)
+	}
+	if got := certificateExpiry(&tls.ConnectionState{}); got != nil {
+		t.Errorf("got %v without certificates, want nil", got)
+	}
+}
@@ -9,14 +9,20 @@ import (
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	aiopsv1alpha1 "github.com/prophet-aiops/health-check/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/conditions"
+	"github.com/prophet-aiops/pkg/notifier"
+	"github.com/prophet-aiops/pkg/prophetconfig"
+	"github.com/prophet-aiops/pkg/status"
 )
 
 // HealthCheckReconciler reconciles a HealthCheck object
@@ -24,15 +30,38 @@ type HealthCheckReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	Log    logr.Logger
+
+	// RESTConfig connects to the local cluster for pod exec, which the client doesn't support.
+	// Remote clusters use the config from their kubeconfig.
+	RESTConfig *rest.Config
+
+	// RemoteClusterQPS and RemoteClusterBurst rate limit the API requests made to each remote cluster.
+	// Zero uses the defaults (5 and 10).
+	RemoteClusterQPS   float32
+	RemoteClusterBurst int
+
+	// ProtectedNamespaces are never restarted in, in addition to the system namespaces.
+	// They should include the operator's own namespace.
+	ProtectedNamespaces []string
+
+	// ExternalProbeAllowedNetworks are networks external probes may connect to although they are
+	// blocked by default, such as loopback and link-local addresses
+	ExternalProbeAllowedNetworks []*net.IPNet
 }
 
 //+kubebuilder:rbac:groups=aiops.prophet.io,resources=healthchecks,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=aiops.prophet.io,resources=healthchecks/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=aiops.prophet.io,resources=healthchecks/finalizers,verbs=update
 //+kubebuilder:rbac:groups=aiops.prophet.io,resources=anomalyactions,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=aiops.prophet.io,resources=prophetconfigs,verbs=get
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch
 //+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;delete
+//+kubebuilder:rbac:groups="",resources=pods/exec,verbs=create
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop
@@ -41,8 +70,15 @@ func (r *HealthCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	var healthCheck aiopsv1alpha1.HealthCheck
 	if err := r.Get(ctx, req.NamespacedName, &healthCheck); err != nil {
+		if apierrors.IsNotFound(err) {
+			deleteMetrics(req.Namespace, req.Name)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	// Apply defaults in case the defaulting webhook isn't installed
+	healthCheck.SetDefaults()
+	// Patch status against the defaulted object so only status changes are sent
+	base := healthCheck.DeepCopy()
 
 	logger.Info("Reconciling HealthCheck", "name", req.Name, "healthy", healthCheck.Status.Healthy)
 
@@ -55,21 +91,85 @@ func (r *HealthCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		}
 	}
 
-	// Execute all probes
-	allHealthy := true
-	probeResults := make([]aiopsv1alpha1.ProbeResult, 0, len(healthCheck.Spec.Probes))
+	period := time.Duration(healthCheck.Spec.PeriodSeconds) * time.Second
 
-	for _, probe := range healthCheck.Spec.Probes {
-		result := r.executeProbe(ctx, &healthCheck, &probe)
-		probeResults = append(probeResults, result)
-		if !result.Success {
-			allHealthy = false
+	// The validating webhook fails open, so references to other namespaces are refused here too
+	if errs := healthCheck.ValidateNamespaces(); len(errs) > 0 {
+		err := errs.ToAggregate()
+		logger.Error(err, "Refusing references outside the HealthCheck's namespace")
+		healthCheck.Status.ErrorMessage = err.Error()
+		healthCheck.Status.ObservedGeneration = healthCheck.Generation
+		conditions.Apply(&healthCheck.Status.Conditions, healthCheck.Generation, conditions.Summary{
+			Degraded: true,
+			Reason:   "InvalidSpec",
+			Message:  err.Error(),
+		})
+		// Nothing to retry until the spec changes
+		return ctrl.Result{}, status.Patch(ctx, r.Client, &healthCheck, base)
+	}
+
+	// Probes and remediation run against the target's cluster, status stays in this one
+	healthCheck.Status.Cluster = clusterName(&healthCheck)
+	target, err := r.targetClient(ctx, &healthCheck)
+	if err != nil {
+		logger.Error(err, "Failed to get client for target cluster", "cluster", healthCheck.Status.Cluster)
+		healthCheck.Status.ErrorMessage = err.Error()
+		healthCheck.Status.ObservedGeneration = healthCheck.Generation
+		conditions.Apply(&healthCheck.Status.Conditions, healthCheck.Generation, conditions.Summary{
+			Degraded: true,
+			Reason:   "ClusterUnavailable",
+			Message:  err.Error(),
+		})
+		if err := status.Patch(ctx, r.Client, &healthCheck, base); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: period}, nil
+	}
+	healthCheck.Status.ErrorMessage = ""
+
+	// Cluster-wide defaults; settings on the HealthCheck take precedence
+	defaults, err := prophetconfig.Get(ctx, r.Client)
+	if err != nil {
+		logger.Error(err, "Failed to read ProphetConfig, using built-in defaults")
+	}
+
+	// An open maintenance window holds off remediation, and checks if it suppresses detections
+	window, windowEnd, err := openMaintenanceWindow(healthCheck.Spec.MaintenanceWindows, time.Now())
+	if err != nil {
+		logger.Error(err, "Ignoring invalid maintenance windows")
+	}
+	healthCheck.Status.MaintenanceWindow = nil
+	if window != nil {
+		healthCheck.Status.MaintenanceWindow = &aiopsv1alpha1.MaintenanceWindowStatus{Name: window.Name, Until: metav1.NewTime(windowEnd)}
+		if window.SuppressDetections {
+			logger.Info("Checks paused by maintenance window", "window", window.Name, "until", windowEnd)
+			conditions.Set(&healthCheck.Status.Conditions, healthCheck.Generation, conditions.Remediating, false, "MaintenanceWindow",
+				fmt.Sprintf("Checks and remediation paused by maintenance window %s until %s", window.Name, windowEnd.Format(time.RFC3339)))
+			if err := status.Patch(ctx, r.Client, &healthCheck, base); err != nil {
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{RequeueAfter: time.Until(windowEnd)}, nil
 		}
 	}
 
+	// Execute all probes. The check fails when a critical probe is failing or the health score is too low.
+	probeResults := make([]aiopsv1alpha1.ProbeResult, 0, len(healthCheck.Spec.Probes))
+	for _, probe := range healthCheck.Spec.Probes {
+		probeResults = append(probeResults, r.executeProbe(ctx, target, &healthCheck, defaults, &probe))
+	}
+	score, criticalFailing := healthScore(healthCheck.Spec.Probes, probeResults)
+	allHealthy := !criticalFailing
+	if minScore := healthCheck.Spec.MinHealthScore; minScore != nil && score < *minScore {
+		allHealthy = false
+	}
+
+	// A HealthCheck that has never run counts as healthy so its first result can notify
+	wasHealthy := healthCheck.Status.Healthy || healthCheck.Status.LastCheckTime == nil
+
 	now := metav1.Now()
 	healthCheck.Status.LastCheckTime = &now
 	healthCheck.Status.ProbeResults = probeResults
+	healthCheck.Status.HealthScore = score
 
 	// Update failure count
 	if !allHealthy {
@@ -90,78 +190,164 @@ func (r *HealthCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		healthCheck.Status.Healthy = false
 		logger.Info("Health check failed", "failureCount", healthCheck.Status.FailureCount, "threshold", healthCheck.Spec.FailureThreshold)
 
-		// Trigger remediation if configured
-		if healthCheck.Spec.Remediation.Action != "" && healthCheck.Spec.Remediation.Action != "none" {
-			if err := r.triggerRemediation(ctx, &healthCheck); err != nil {
+		// Trigger remediation if configured, unless a maintenance window is open
+		if window != nil {
+			conditions.Set(&healthCheck.Status.Conditions, healthCheck.Generation, conditions.Remediating, true, "MaintenanceWindow",
+				fmt.Sprintf("Remediation held off by maintenance window %s until %s", window.Name, windowEnd.Format(time.RFC3339)))
+		} else if healthCheck.Spec.Remediation.Action != "" && healthCheck.Spec.Remediation.Action != "none" {
+			if err := r.triggerRemediation(ctx, target, &healthCheck, defaults); err != nil {
 				logger.Error(err, "Failed to trigger remediation")
 				healthCheck.Status.ErrorMessage = err.Error()
 			}
+		} else {
+			conditions.Set(&healthCheck.Status.Conditions, healthCheck.Generation, conditions.Remediating, false, "RemediationDisabled", "No remediation action is configured")
 		}
 	} else {
 		healthCheck.Status.Healthy = true
+		conditions.Set(&healthCheck.Status.Conditions, healthCheck.Generation, conditions.Remediating, false, "TargetHealthy", "No remediation needed")
 	}
 
-	// Update conditions
-	condition := metav1.Condition{
-		Type:               "Healthy",
-		Status:             metav1.ConditionTrue,
-		Reason:             "AllProbesPassed",
-		Message:            "All health check probes are passing",
-		LastTransitionTime: now,
+	// Notify on health transitions
+	if unhealthy && wasHealthy {
+		if err := r.notify(ctx, &healthCheck, defaults, false); err != nil {
+			logger.Error(err, "Failed to send notification")
+		}
+	} else if !unhealthy && !wasHealthy {
+		if err := r.notify(ctx, &healthCheck, defaults, true); err != nil {
+			logger.Error(err, "Failed to send resolved notification")
+		}
 	}
+
+	// Update conditions
+	summary := conditions.Summary{Reason: "AllProbesPassed", Message: "All health check probes are passing"}
 	if !healthCheck.Status.Healthy {
-		condition.Status = metav1.ConditionFalse
-		condition.Reason = "ProbesFailing"
-		condition.Message = fmt.Sprintf("%d consecutive failures (threshold: %d)", healthCheck.Status.FailureCount, healthCheck.Spec.FailureThreshold)
+		summary = conditions.Summary{
+			Degraded: true,
+			Reason:   "ProbesFailing",
+			Message:  fmt.Sprintf("%d consecutive failures (threshold: %d)", healthCheck.Status.FailureCount, healthCheck.Spec.FailureThreshold),
+		}
 	}
-	healthCheck.Status.Conditions = []metav1.Condition{condition}
+	conditions.Set(&healthCheck.Status.Conditions, healthCheck.Generation, "Healthy", healthCheck.Status.Healthy, summary.Reason, summary.Message)
+	conditions.Set(&healthCheck.Status.Conditions, healthCheck.Generation, conditions.Triggered, !healthCheck.Status.Healthy, summary.Reason, summary.Message)
+	if healthCheck.Status.ErrorMessage != "" {
+		summary = conditions.Summary{Degraded: true, Reason: "RemediationFailed", Message: healthCheck.Status.ErrorMessage}
+	}
+	healthCheck.Status.ObservedGeneration = healthCheck.Generation
+	conditions.Apply(&healthCheck.Status.Conditions, healthCheck.Generation, summary)
 
 	// Update status
-	if err := r.Status().Update(ctx, &healthCheck); err != nil {
+	if err := status.Patch(ctx, r.Client, &healthCheck, base); err != nil {
 		return ctrl.Result{}, err
 	}
+	recordMetrics(&healthCheck)
 
 	// Requeue after period
-	period := time.Duration(healthCheck.Spec.PeriodSeconds) * time.Second
 	return ctrl.Result{RequeueAfter: period}, nil
 }
 
+// notify sends a notification to the configured channels
+func (r *HealthCheckReconciler) notify(ctx context.Context, healthCheck *aiopsv1alpha1.HealthCheck, defaults prophetconfig.Spec, resolved bool) error {
+	notify := healthCheck.Spec.Notify
+	if !notify.Enabled {
+		return nil
+	}
+
+	target := targetName(healthCheck.Spec.TargetRef)
+	message := fmt.Sprintf("%s is unhealthy: %d consecutive failures (threshold: %d)",
+		target, healthCheck.Status.FailureCount, healthCheck.Spec.FailureThreshold)
+	if resolved {
+		message = fmt.Sprintf("%s is healthy again", target)
+	}
+
+	channels, err := r.notificationChannels(ctx, notify, defaults, healthCheck.Namespace)
+	if err != nil {
+		return err
+	}
+	text, err := notificationText(notify, healthCheck, message)
+	if err != nil {
+		return err
+	}
+
+	fields := map[string]string{"target": target}
+	if cluster := clusterName(healthCheck); cluster != "" {
+		fields["cluster"] = cluster
+	}
+	for _, result := range healthCheck.Status.ProbeResults {
+		if result.Failing {
+			fields["probe "+result.Name] = result.Message
+		}
+	}
+	fields["health score"] = fmt.Sprintf("%d%%", healthCheck.Status.HealthScore)
+
+	return notifications.Send(ctx, channels, notifier.Message{
+		Title:    fmt.Sprintf("HealthCheck %s/%s", healthCheck.Namespace, healthCheck.Name),
+		Text:     text,
+		Severity: "critical",
+		Source:   "health-check",
+		Key:      fmt.Sprintf("healthcheck/%s/%s", healthCheck.Namespace, healthCheck.Name),
+		Resolved: resolved,
+		Fields:   fields,
+	})
+}
+
 // executeProbe executes a single health check probe
-func (r *HealthCheckReconciler) executeProbe(ctx context.Context, healthCheck *aiopsv1alpha1.HealthCheck, probe *aiopsv1alpha1.ProbeSpec) aiopsv1alpha1.ProbeResult {
+func (r *HealthCheckReconciler) executeProbe(ctx context.Context, target client.Client, healthCheck *aiopsv1alpha1.HealthCheck, defaults prophetconfig.Spec, probe *aiopsv1alpha1.ProbeSpec) aiopsv1alpha1.ProbeResult {
+	previous := previousProbeResult(healthCheck, probe.Name)
 	result := aiopsv1alpha1.ProbeResult{
 		Name:          probe.Name,
 		LastCheckTime: &metav1.Time{Time: time.Now()},
 	}
 
-	// Get target pods to check
-	pods, err := r.getTargetPods(ctx, healthCheck)
-	if err != nil {
+	// Pod IPs of remote clusters aren't reachable from the operator
+	if cluster := clusterName(healthCheck); cluster != "" && (probe.Type == "http" || probe.Type == "tcp") {
 		result.Success = false
-		result.Message = fmt.Sprintf("Failed to get target pods: %v", err)
-		return result
+		result.Message = fmt.Sprintf("%s probes can't reach pods in remote cluster %s; use a command, custom or external probe", probe.Type, cluster)
+		return countRun(result, previous, probe)
 	}
 
-	if len(pods) == 0 {
-		result.Success = false
-		result.Message = "No target pods found"
-		return result
+	// Get target pods to check; external probes don't need them
+	var pods []corev1.Pod
+	if probe.Type != "external" {
+		var err error
+		pods, err = getTargetPods(ctx, target, healthCheck)
+		if err != nil {
+			result.Success = false
+			result.Message = fmt.Sprintf("Failed to get target pods: %v", err)
+			return countRun(result, previous, probe)
+		}
+
+		if len(pods) == 0 {
+			result.Success = false
+			result.Message = "No target pods found"
+			return countRun(result, previous, probe)
+		}
 	}
 
 	// Execute probe against first pod (or all pods for composite checks)
 	timeout := time.Duration(healthCheck.Spec.TimeoutSeconds) * time.Second
-	if timeout == 0 {
-		timeout = 5 * time.Second
-	}
+	start := time.Now()
+	var latency time.Duration
 
 	switch probe.Type {
 	case "http":
-		result.Success = r.executeHTTPProbe(ctx, pods[0], probe.HTTPGet, timeout)
+		result.Success, result.Message, result.Pods = r.executeHTTPProbe(ctx, pods, probe, timeout)
 	case "tcp":
 		result.Success = r.executeTCPProbe(ctx, pods[0], probe.TCPSocket, timeout)
 	case "command":
-		result.Success = r.executeCommandProbe(ctx, pods[0], probe.Exec, timeout)
+		result.Success, result.Message = r.executeCommandProbe(ctx, healthCheck, defaults, pods, probe.Exec, timeout)
 	case "custom":
-		result.Success = r.executeCustomProbe(ctx, pods[0], probe.Custom, timeout)
+		var done bool
+		result.Success, result.Message, latency, done = r.executeCustomProbe(ctx, target, healthCheck, pods[0], probe)
+		if !done {
+			// Keep the last result while the probe Job runs; a probe that hasn't finished a run yet passes
+			if previous != nil {
+				return *previous
+			}
+			result.Success, result.Message = true, "Waiting for the first run to finish"
+			return result
+		}
+	case "external":
+		result.Success, result.Message, result.External = r.executeExternalProbe(ctx, probe, timeout)
 	default:
 		result.Success = false
 		result.Message = fmt.Sprintf("Unknown probe type: %s", probe.Type)
@@ -170,70 +356,142 @@ func (r *HealthCheckReconciler) executeProbe(ctx context.Context, healthCheck *a
 	if !result.Success && result.Message == "" {
 		result.Message = fmt.Sprintf("Probe %s failed", probe.Name)
 	}
+	if probe.Type != "custom" {
+		latency = time.Since(start)
+	}
+	result.LatencyMilliseconds = latency.Milliseconds()
+	observeLatency(healthCheck, probe, latency)
+
+	return countRun(result, previous, probe)
+}
 
+// countRun updates the probe's consecutive failures and successes and its history with the run's
+// result, and whether the probe counts as failing: it starts failing after failureThreshold
+// consecutive failures and stops after successThreshold consecutive successes.
+func countRun(result aiopsv1alpha1.ProbeResult, previous *aiopsv1alpha1.ProbeResult, probe *aiopsv1alpha1.ProbeSpec) aiopsv1alpha1.ProbeResult {
+	if previous != nil {
+		result.Failing = previous.Failing
+		result.ConsecutiveFailures = previous.ConsecutiveFailures
+		result.ConsecutiveSuccesses = previous.ConsecutiveSuccesses
+		result.History = append([]aiopsv1alpha1.ProbeRun(nil), previous.History...)
+	}
+	result.History = append(result.History, aiopsv1alpha1.ProbeRun{
+		Time:                *result.LastCheckTime,
+		Success:             result.Success,
+		LatencyMilliseconds: result.LatencyMilliseconds,
+	})
+	if len(result.History) > maxProbeHistory {
+		result.History = result.History[len(result.History)-maxProbeHistory:]
+	}
+	if result.Success {
+		result.ConsecutiveSuccesses++
+		result.ConsecutiveFailures = 0
+		if result.ConsecutiveSuccesses >= probe.SuccessThreshold {
+			result.Failing = false
+		}
+	} else {
+		result.ConsecutiveFailures++
+		result.ConsecutiveSuccesses = 0
+		if result.ConsecutiveFailures >= probe.FailureThreshold {
+			result.Failing = true
+		}
+	}
 	return result
 }
 
-// getTargetPods retrieves pods for the target workload
-func (r *HealthCheckReconciler) getTargetPods(ctx context.Context, healthCheck *aiopsv1alpha1.HealthCheck) ([]corev1.Pod, error) {
-	namespace := healthCheck.Spec.TargetRef.Namespace
-	if namespace == "" {
-		namespace = healthCheck.Namespace
+// healthScore returns the weighted percentage of probes that aren't failing and whether a critical probe is failing
+func healthScore(probes []aiopsv1alpha1.ProbeSpec, results []aiopsv1alpha1.ProbeResult) (int32, bool) {
+	var total, passing int32
+	criticalFailing := false
+	for i, probe := range probes {
+		total += probe.Weight
+		if !results[i].Failing {
+			passing += probe.Weight
+		} else if probe.Critical == nil || *probe.Critical {
+			criticalFailing = true
+		}
+	}
+	if total == 0 {
+		return 100, criticalFailing
 	}
+	return passing * 100 / total, criticalFailing
+}
 
-	switch healthCheck.Spec.TargetRef.Kind {
+// getTargetPods retrieves pods for the target workload, or the pods the target's label selector
+// matches, from the target's cluster
+func getTargetPods(ctx context.Context, target client.Client, healthCheck *aiopsv1alpha1.HealthCheck) ([]corev1.Pod, error) {
+	ref := healthCheck.Spec.TargetRef
+	if ref.LabelSelector != nil {
+		return listPods(ctx, target, ref.Namespace, ref.LabelSelector)
+	}
+	key := types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+
+	// Workload pods are the ones matching the workload's selector
+	var selector *metav1.LabelSelector
+	switch ref.Kind {
 	case "Pod":
 		var pod corev1.Pod
-		if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: healthCheck.Spec.TargetRef.Name}, &pod); err != nil {
+		if err := target.Get(ctx, key, &pod); err != nil {
 			return nil, err
 		}
 		return []corev1.Pod{pod}, nil
 
 	case "Deployment":
 		var deployment appsv1.Deployment
-		if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: healthCheck.Spec.TargetRef.Name}, &deployment); err != nil {
-			return nil, err
-		}
-		// Get pods matching deployment labels
-		pods := &corev1.PodList{}
-		selector := client.MatchingLabels(deployment.Spec.Selector.MatchLabels)
-		if err := r.List(ctx, pods, client.InNamespace(namespace), selector); err != nil {
+		if err := target.Get(ctx, key, &deployment); err != nil {
 			return nil, err
 		}
-		return pods.Items, nil
+		selector = deployment.Spec.Selector
 
 	case "StatefulSet":
 		var statefulSet appsv1.StatefulSet
-		if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: healthCheck.Spec.TargetRef.Name}, &statefulSet); err != nil {
+		if err := target.Get(ctx, key, &statefulSet); err != nil {
 			return nil, err
 		}
-		// Get pods matching statefulset labels
-		pods := &corev1.PodList{}
-		selector := client.MatchingLabels(statefulSet.Spec.Selector.MatchLabels)
-		if err := r.List(ctx, pods, client.InNamespace(namespace), selector); err != nil {
+		selector = statefulSet.Spec.Selector
+
+	case "DaemonSet":
+		var daemonSet appsv1.DaemonSet
+		if err := target.Get(ctx, key, &daemonSet); err != nil {
+			return nil, err
+		}
+		selector = daemonSet.Spec.Selector
+
+	case "ReplicaSet":
+		var replicaSet appsv1.ReplicaSet
+		if err := target.Get(ctx, key, &replicaSet); err != nil {
 			return nil, err
 		}
-		return pods.Items, nil
+		selector = replicaSet.Spec.Selector
 
 	default:
-		return nil, fmt.Errorf("unsupported target kind: %s", healthCheck.Spec.TargetRef.Kind)
+		return nil, fmt.Errorf("unsupported target kind: %s", ref.Kind)
+	}
+	if selector == nil {
+		return nil, fmt.Errorf("%s %s has no selector", ref.Kind, ref.Name)
 	}
+	return listPods(ctx, target, ref.Namespace, selector)
 }
 
-// executeHTTPProbe executes an HTTP health check
-func (r *HealthCheckReconciler) executeHTTPProbe(ctx context.Context, pod corev1.Pod, httpGet *corev1.HTTPGetAction, timeout time.Duration) bool {
-	if httpGet == nil {
-		return false
+// listPods lists the pods in the namespace matching the label selector, including matchExpressions
+func listPods(ctx context.Context, target client.Client, namespace string, labelSelector *metav1.LabelSelector) ([]corev1.Pod, error) {
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label selector: %w", err)
+	}
+	pods := &corev1.PodList{}
+	if err := target.List(ctx, pods, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
 	}
+	return pods.Items, nil
+}
 
-	// Simplified implementation: check if pod is ready
-	// In production, this should make an actual HTTP request to pod IP:port
-	for _, condition := range pod.Status.Conditions {
-		if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
-			return true
-		}
+// targetName describes the target in messages: Kind/name, or the label selector
+func targetName(ref aiopsv1alpha1.TargetRef) string {
+	if ref.LabelSelector != nil {
+		return fmt.Sprintf("pods matching %s", metav1.FormatLabelSelector(ref.LabelSelector))
 	}
-	return false
+	return fmt.Sprintf("%s/%s", ref.Kind, ref.Name)
 }
 
 // executeTCPProbe executes a TCP health check
@@ -257,40 +515,20 @@ func (r *HealthCheckReconciler) executeTCPProbe(ctx context.Context, pod corev1.
 	return false
 }
 
-// executeCommandProbe executes a command-based health check
-func (r *HealthCheckReconciler) executeCommandProbe(ctx context.Context, pod corev1.Pod, exec *corev1.ExecAction, timeout time.Duration) bool {
-	if exec == nil {
-		return false
-	}
-
-	// Simplified: check if pod is running
-	// In production, this should exec into the pod and run the command
-	// This requires pod exec permissions and a more complex implementation
-	return pod.Status.Phase == corev1.PodRunning
-}
-
-// executeCustomProbe executes a custom health check
-func (r *HealthCheckReconciler) executeCustomProbe(ctx context.Context, pod corev1.Pod, custom *aiopsv1alpha1.CustomProbe, timeout time.Duration) bool {
-	if custom == nil {
-		return false
-	}
-
-	// Simplified: for now, just check pod is running
-	// In production, this would create a Job or Pod to execute the custom script
-	// and check the result
-	return pod.Status.Phase == corev1.PodRunning
-}
-
 // triggerRemediation triggers remediation actions when health check fails
-func (r *HealthCheckReconciler) triggerRemediation(ctx context.Context, healthCheck *aiopsv1alpha1.HealthCheck) error {
+func (r *HealthCheckReconciler) triggerRemediation(ctx context.Context, target client.Client, healthCheck *aiopsv1alpha1.HealthCheck, defaults prophetconfig.Spec) error {
 	logger := log.FromContext(ctx)
 	remediation := healthCheck.Spec.Remediation
+	setRemediating := func(status bool, reason, message string) {
+		conditions.Set(&healthCheck.Status.Conditions, healthCheck.Generation, conditions.Remediating, status, reason, message)
+	}
 
 	// Check cooldown
 	if healthCheck.Status.LastRemediationTime != nil {
-		cooldown := time.Duration(remediation.CooldownSeconds) * time.Second
-		if time.Since(healthCheck.Status.LastRemediationTime.Time) < cooldown {
+		cooldown := defaults.Cooldown(remediation.CooldownSeconds)
+		if elapsed := time.Since(healthCheck.Status.LastRemediationTime.Time); elapsed < cooldown {
 			logger.Info("In cooldown period, skipping remediation")
+			setRemediating(true, "CooldownActive", fmt.Sprintf("Next %s allowed in %s", remediation.Action, (cooldown-elapsed).Round(time.Second)))
 			return nil
 		}
 	}
@@ -298,39 +536,64 @@ func (r *HealthCheckReconciler) triggerRemediation(ctx context.Context, healthCh
 	// Check if approval required
 	if remediation.RequireApproval {
 		logger.Info("Remediation requires approval, skipping")
+		setRemediating(false, "AwaitingApproval", fmt.Sprintf("Remediation %s requires approval", remediation.Action))
 		return nil
 	}
 
+	var err error
 	switch remediation.Action {
 	case "restart":
-		return r.restartTarget(ctx, healthCheck)
+		err = r.restartTarget(ctx, target, healthCheck, defaults)
 
 	case "trigger-recovery-plan":
-		return r.triggerRecoveryPlan(ctx, healthCheck)
+		err = r.triggerRecoveryPlan(ctx, healthCheck)
 
 	case "alert":
 		// Create event for alerting
-		r.recordEvent(ctx, healthCheck, "Warning", "HealthCheckFailed", "Health check failed, alerting")
+		message := "Health check failed, alerting"
+		if healthCheck.Status.Cluster != "" {
+			message = fmt.Sprintf("Health check failed in cluster %s, alerting", healthCheck.Status.Cluster)
+		}
+		r.recordEvent(ctx, healthCheck, "Warning", "HealthCheckFailed", message)
+		setRemediating(false, "AlertOnly", "Remediation action is alert")
 		return nil
 
 	default:
-		return fmt.Errorf("unknown remediation action: %s", remediation.Action)
+		err = fmt.Errorf("unknown remediation action: %s", remediation.Action)
 	}
+
+	if err != nil {
+		setRemediating(false, "RemediationFailed", err.Error())
+		return err
+	}
+	setRemediating(true, "RemediationTriggered", fmt.Sprintf("Remediation %s triggered", remediation.Action))
+	return nil
 }
 
 // restartTarget restarts the target workload
-func (r *HealthCheckReconciler) restartTarget(ctx context.Context, healthCheck *aiopsv1alpha1.HealthCheck) error {
+func (r *HealthCheckReconciler) restartTarget(ctx context.Context, target client.Client, healthCheck *aiopsv1alpha1.HealthCheck, defaults prophetconfig.Spec) error {
 	logger := log.FromContext(ctx)
-	pods, err := r.getTargetPods(ctx, healthCheck)
+	pods, err := getTargetPods(ctx, target, healthCheck)
 	if err != nil {
 		return err
 	}
 
+	restarted := 0
 	for _, pod := range pods {
-		logger.Info("Restarting pod due to health check failure", "pod", pod.Name)
-		if err := r.Delete(ctx, &pod); err != nil {
+		if reason := r.protected(defaults, &pod); reason != "" {
+			logger.Info("Not restarting protected pod", "pod", pod.Name, "reason", reason)
+			r.recordEvent(ctx, healthCheck, "Warning", "TargetProtected",
+				fmt.Sprintf("Not restarting pod %s/%s: %s", pod.Namespace, pod.Name, reason))
+			continue
+		}
+		logger.Info("Restarting pod due to health check failure", "pod", pod.Name, "cluster", healthCheck.Status.Cluster)
+		if err := target.Delete(ctx, &pod); err != nil {
 			return err
 		}
+		restarted++
+	}
+	if restarted == 0 {
+		return nil
 	}
 
 	now := metav1.Now()
@@ -348,9 +611,6 @@ func (r *HealthCheckReconciler) triggerRecoveryPlan(ctx context.Context, healthC
 
 	ref := healthCheck.Spec.Remediation.RecoveryPlanRef
 	namespace := ref.Namespace
-	if namespace == "" {
-		namespace = healthCheck.Namespace
-	}
 
 	// Get or create AnomalyAction
 	// For now, we'll just log - in production, create/update AnomalyAction
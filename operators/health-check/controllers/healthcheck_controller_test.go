@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/health-check/api/v1alpha1"
+	"github.com/prophet-aiops/health-check/internal/builders"
+	"github.com/prophet-aiops/pkg/conditions"
+	"github.com/prophet-aiops/pkg/testenv"
+)
+
+// TestReconcileHTTPProbe runs a HealthCheck through failing, alerting and recovering against a
+// Deployment whose pod is served by a local test server
+func TestReconcileHTTPProbe(t *testing.T) {
+	c := testEnv.Require(t)
+	ctx := context.Background()
+	namespace := testEnv.Namespace(t)
+	webhook := testenv.NewWebhook(t)
+
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+	_, portString, _ := net.SplitHostPort(server.Listener.Addr().String())
+	port, _ := strconv.Atoi(portString)
+
+	// The API server doesn't run pods, so the pod's status points it at the test server
+	if err := c.Create(ctx, testenv.Deployment(namespace, "web")); err != nil {
+		t.Fatal(err)
+	}
+	pod := testenv.Pod(namespace, "web-1", map[string]string{"app": "web"})
+	if err := c.Create(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+	pod.Status = corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "127.0.0.1", PodIPs: []corev1.PodIP{{IP: "127.0.0.1"}}}
+	if err := c.Status().Update(ctx, pod); err != nil {
+		t.Fatal(err)
+	}
+
+	healthCheck := builders.HealthCheck(namespace, builders.Probes(builders.HTTPProbe("http", "/healthz", intstr.FromInt(port))), func(healthCheck *aiopsv1alpha1.HealthCheck) {
+		healthCheck.Spec.FailureThreshold = 1
+		healthCheck.Spec.Remediation.Action = "alert"
+		healthCheck.Spec.Notify = aiopsv1alpha1.NotifySpec{
+			Enabled:  true,
+			Channels: []aiopsv1alpha1.NotificationChannel{{Type: "webhook", URL: webhook.URL}},
+		}
+	})
+	if err := c.Create(ctx, healthCheck); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &HealthCheckReconciler{Client: c, Scheme: c.Scheme()}
+	key := types.NamespacedName{Namespace: namespace, Name: healthCheck.Name}
+	reconcile := func() *aiopsv1alpha1.HealthCheck {
+		t.Helper()
+		if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+		var got aiopsv1alpha1.HealthCheck
+		if err := c.Get(ctx, key, &got); err != nil {
+			t.Fatal(err)
+		}
+		return &got
+	}
+
+	got := reconcile()
+	if got.Status.Healthy || got.Status.FailureCount != 1 || got.Status.HealthScore != 0 {
+		t.Errorf("expected the HealthCheck to fail, got status %+v", got.Status)
+	}
+	if len(got.Status.ProbeResults) != 1 || len(got.Status.ProbeResults[0].Pods) != 1 || got.Status.ProbeResults[0].Pods[0].Success {
+		t.Errorf("expected the pod's probe to fail, got %+v", got.Status.ProbeResults)
+	}
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, conditions.Triggered) {
+		t.Errorf("expected the %s condition to be true, got %+v", conditions.Triggered, got.Status.Conditions)
+	}
+	if requests := webhook.Requests(); len(requests) != 1 || !strings.Contains(string(requests[0].Body), "is unhealthy") {
+		t.Fatalf("expected an unhealthy notification, got %d requests", len(requests))
+	}
+
+	healthy.Store(true)
+	got = reconcile()
+	if !got.Status.Healthy || got.Status.FailureCount != 0 || got.Status.HealthScore != 100 {
+		t.Errorf("expected the HealthCheck to recover, got status %+v", got.Status)
+	}
+	if !meta.IsStatusConditionTrue(got.Status.Conditions, "Healthy") {
+		t.Errorf("expected the Healthy condition to be true, got %+v", got.Status.Conditions)
+	}
+	if requests := webhook.Requests(); len(requests) != 2 || !strings.Contains(string(requests[1].Body), "is healthy again") {
+		t.Errorf("expected a resolved notification, got %d requests", len(requests))
+	}
+}
+
+// TestReconcileTargetMissing checks that a HealthCheck of a missing workload fails
+func TestReconcileTargetMissing(t *testing.T) {
+	c := testEnv.Require(t)
+	ctx := context.Background()
+	namespace := testEnv.Namespace(t)
+
+	healthCheck := builders.HealthCheck(namespace, func(healthCheck *aiopsv1alpha1.HealthCheck) {
+		healthCheck.Spec.FailureThreshold = 1
+	})
+	if err := c.Create(ctx, healthCheck); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &HealthCheckReconciler{Client: c, Scheme: c.Scheme()}
+	key := types.NamespacedName{Namespace: namespace, Name: healthCheck.Name}
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile: %v", err)
+	}
+	var got aiopsv1alpha1.HealthCheck
+	if err := c.Get(ctx, key, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Healthy || len(got.Status.ProbeResults) != 1 || !strings.Contains(got.Status.ProbeResults[0].Message, "Failed to get target pods") {
+		t.Errorf("expected the probe to fail to find the Deployment, got status %+v", got.Status)
+	}
+}
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/health-check/api/v1alpha1"
+)
+
+const (
+	// maxProbeBody is how much of a response body is matched against bodyRegex
+	maxProbeBody = 64 << 10
+
+	// maxConcurrentHTTPProbes bounds how many pods are probed at once, so a few slow pods cost
+	// one timeout rather than one each
+	maxConcurrentHTTPProbes = 10
+)
+
+// probeTransport sends HTTP probes straight to the pods. Like the kubelet it skips proxies and
+// certificate verification, since pods rarely serve certificates for their IPs.
+var probeTransport = &http.Transport{
+	DialContext:       (&net.Dialer{Timeout: 5 * time.Second}).DialContext,
+	TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+	DisableKeepAlives: true,
+}
+
+// executeHTTPProbe sends the HTTP probe to every running target pod, several at a time, and reports
+// whether enough pods passed to meet the success quorum, with the result on each pod
+func (r *HealthCheckReconciler) executeHTTPProbe(ctx context.Context, pods []corev1.Pod, probe *aiopsv1alpha1.ProbeSpec, timeout time.Duration) (bool, string, []aiopsv1alpha1.PodProbeResult) {
+	if probe.HTTPGet == nil {
+		return false, "httpGet is required for http probes", nil
+	}
+	var bodyRegex *regexp.Regexp
+	if probe.BodyRegex != "" {
+		var err error
+		if bodyRegex, err = regexp.Compile(probe.BodyRegex); err != nil {
+			return false, fmt.Sprintf("Invalid bodyRegex: %v", err), nil
+		}
+	}
+	expected, err := parseStatusRanges(probe.ExpectedStatus)
+	if err != nil {
+		return false, err.Error(), nil
+	}
+
+	if probe.HTTPGet.Host != "" {
+		return false, "httpGet.host is not supported: probes are sent to the pod IP, set a Host header instead", nil
+	}
+
+	var running []*corev1.Pod
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Status.Phase == corev1.PodRunning && pod.Status.PodIP != "" && pod.DeletionTimestamp == nil {
+			running = append(running, pod)
+		}
+	}
+	if len(running) == 0 {
+		return false, "No running target pods", nil
+	}
+
+	results := make([]aiopsv1alpha1.PodProbeResult, len(running))
+	limit := make(chan struct{}, maxConcurrentHTTPProbes)
+	var wg sync.WaitGroup
+	for i, pod := range running {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func() {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			success, message := httpGet(ctx, pod, probe.HTTPGet, expected, bodyRegex, timeout)
+			results[i] = aiopsv1alpha1.PodProbeResult{Pod: pod.Name, Success: success, Message: message}
+		}()
+	}
+	wg.Wait()
+	passed := 0
+	for _, result := range results {
+		if result.Success {
+			passed++
+		}
+	}
+
+	quorum := intstr.FromString("100%")
+	if probe.SuccessQuorum != nil {
+		quorum = *probe.SuccessQuorum
+	}
+	required, err := intstr.GetScaledValueFromIntOrPercent(&quorum, len(results), true)
+	if err != nil {
+		return false, fmt.Sprintf("Invalid successQuorum: %v", err), results
+	}
+	message := fmt.Sprintf("%d of %d pods passed, %d required", passed, len(results), required)
+	for _, result := range results {
+		if !result.Success {
+			message += fmt.Sprintf("; %s: %s", result.Pod, result.Message)
+			break
+		}
+	}
+	return passed >= required, message, results
+}
+
+// httpGet sends the probe request to the pod's IP and checks the response. A Host header only
+// changes the header sent, never where the request goes.
+func httpGet(ctx context.Context, pod *corev1.Pod, action *corev1.HTTPGetAction, expected [][2]int, bodyRegex *regexp.Regexp, timeout time.Duration) (bool, string) {
+	port, err := resolvePort(pod, action.Port)
+	if err != nil {
+		return false, err.Error()
+	}
+	host := pod.Status.PodIP
+	scheme := strings.ToLower(string(action.Scheme))
+	if scheme == "" {
+		scheme = "http"
+	}
+	path := action.Path
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	url := fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, strconv.Itoa(port)), path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, err.Error()
+	}
+	for _, header := range action.HTTPHeaders {
+		if strings.EqualFold(header.Name, "Host") {
+			req.Host = header.Value
+			continue
+		}
+		req.Header.Add(header.Name, header.Value)
+	}
+
+	client := &http.Client{
+		Transport: probeTransport,
+		// Redirects are judged by their status code, as 3xx counts as success by default
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err.Error()
+	}
+	defer resp.Body.Close()
+
+	if !statusExpected(resp.StatusCode, expected) {
+		return false, fmt.Sprintf("unexpected status %d", resp.StatusCode)
+	}
+	if bodyRegex != nil {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxProbeBody))
+		if err != nil {
+			return false, fmt.Sprintf("failed to read response body: %v", err)
+		}
+		if !bodyRegex.Match(body) {
+			return false, fmt.Sprintf("status %d, body doesn't match %q", resp.StatusCode, bodyRegex.String())
+		}
+	}
+	return true, fmt.Sprintf("status %d", resp.StatusCode)
+}
+
+// resolvePort returns the port number, looking up named ports in the pod's containers
+func resolvePort(pod *corev1.Pod, port intstr.IntOrString) (int, error) {
+	if port.Type == intstr.Int {
+		return port.IntValue(), nil
+	}
+	for _, container := range pod.Spec.Containers {
+		for _, p := range container.Ports {
+			if p.Name == port.StrVal {
+				return int(p.ContainerPort), nil
+			}
+		}
+	}
+	if n, err := strconv.Atoi(port.StrVal); err == nil {
+		return n, nil
+	}
+	return 0, fmt.Errorf("no container port named %q", port.StrVal)
+}
+
+// parseStatusRanges parses status codes and ranges such as "200-299", defaulting to 200-399
+func parseStatusRanges(values []string) ([][2]int, error) {
+	if len(values) == 0 {
+		return [][2]int{{200, 399}}, nil
+	}
+	ranges := make([][2]int, 0, len(values))
+	for _, value := range values {
+		low, high, isRange := strings.Cut(value, "-")
+		from, err := strconv.Atoi(low)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expectedStatus %q", value)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.Atoi(high); err != nil || to < from {
+				return nil, fmt.Errorf("invalid expectedStatus %q", value)
+			}
+		}
+		ranges = append(ranges, [2]int{from, to})
+	}
+	return ranges, nil
+}
+
+func statusExpected(code int, ranges [][2]int) bool {
+	for _, r := range ranges {
+		if code >= r[0] && code <= r[1] {
+			return true
+		}
+	}
+	return false
+}
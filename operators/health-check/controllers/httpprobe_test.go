@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/health-check/api/v1alpha1"
+	"github.com/prophet-aiops/health-check/internal/builders"
+	"github.com/prophet-aiops/pkg/prophetconfig"
+)
+
+// probePod returns a running pod at 127.0.0.1 whose "http" port is the test server's
+func probePod(t *testing.T, name string, server *httptest.Server) corev1.Pod {
+	t.Helper()
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	number, _ := strconv.Atoi(port)
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "shop"},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Name:  "app",
+			Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: int32(number)}},
+		}}},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning, PodIP: "127.0.0.1"},
+	}
+}
+
+func TestExecuteHTTPProbe(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/healthz" {
+			http.NotFound(w, req)
+			return
+		}
+		_, _ = w.Write([]byte("ok from " + req.Host))
+	}))
+	defer healthy.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	r := &HealthCheckReconciler{}
+	pods := []corev1.Pod{probePod(t, "web-0", healthy), probePod(t, "web-1", failing)}
+	stopped := probePod(t, "web-2", failing)
+	stopped.Status.Phase = corev1.PodSucceeded
+	pods = append(pods, stopped)
+
+	t.Run("all pods must pass by default", func(t *testing.T) {
+		success, message, results := r.executeHTTPProbe(context.Background(), pods, builders.HTTPProbe("http", "healthz", intstr.FromString("http")), time.Second)
+		if success {
+			t.Errorf("expected failure, got %q", message)
+		}
+		if len(results) != 2 {
+			t.Fatalf("expected results for the 2 running pods, got %+v", results)
+		}
+		if !results[0].Success || results[1].Success || results[1].Message != "unexpected status 503" {
+			t.Errorf("unexpected results %+v", results)
+		}
+		if !strings.HasPrefix(message, "1 of 2 pods passed, 2 required") {
+			t.Errorf("unexpected message %q", message)
+		}
+	})
+
+	t.Run("quorum", func(t *testing.T) {
+		probe := builders.HTTPProbe("http", "/healthz", intstr.FromString("http"))
+		quorum := intstr.FromString("50%")
+		probe.SuccessQuorum = &quorum
+		if success, message, _ := r.executeHTTPProbe(context.Background(), pods, probe, time.Second); !success {
+			t.Errorf("expected success with a 50%% quorum, got %q", message)
+		}
+	})
+
+	t.Run("body regex and Host header", func(t *testing.T) {
+		probe := builders.HTTPProbe("http", "/healthz", intstr.FromString("http"))
+		probe.HTTPGet.HTTPHeaders = []corev1.HTTPHeader{{Name: "Host", Value: "web.shop"}}
+		probe.BodyRegex = "^ok from web\\.shop$"
+		success, message, _ := r.executeHTTPProbe(context.Background(), pods[:1], probe, time.Second)
+		if !success {
+			t.Errorf("expected success, got %q", message)
+		}
+	})
+
+	t.Run("httpGet.host refused", func(t *testing.T) {
+		probe := builders.HTTPProbe("http", "/healthz", intstr.FromString("http"))
+		probe.HTTPGet.Host = "169.254.169.254"
+		success, message, _ := r.executeHTTPProbe(context.Background(), pods[:1], probe, time.Second)
+		if success || !strings.Contains(message, "httpGet.host is not supported") {
+			t.Errorf("expected httpGet.host to be refused, got %v %q", success, message)
+		}
+	})
+
+	t.Run("no running pods", func(t *testing.T) {
+		if success, message, _ := r.executeHTTPProbe(context.Background(), pods[2:], builders.HTTPProbe("http", "/healthz", intstr.FromString("http")), time.Second); success || message != "No running target pods" {
+			t.Errorf("unexpected result %v %q", success, message)
+		}
+	})
+}
+
+func TestExecuteHTTPProbeConcurrently(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	var pods []corev1.Pod
+	for i := 0; i < maxConcurrentHTTPProbes; i++ {
+		pods = append(pods, probePod(t, "web-"+strconv.Itoa(i), slow))
+	}
+	start := time.Now()
+	r := &HealthCheckReconciler{}
+	if success, message, _ := r.executeHTTPProbe(context.Background(), pods, builders.HTTPProbe("http", "/", intstr.FromString("http")), 2*time.Second); !success {
+		t.Fatalf("expected success, got %q", message)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("probing %d slow pods took %v; they should be probed concurrently", len(pods), elapsed)
+	}
+}
+
+// TestExecuteProbeRemoteCluster checks that pod IP probes are refused for remote clusters
+func TestExecuteProbeRemoteCluster(t *testing.T) {
+	r := &HealthCheckReconciler{}
+	for _, probe := range []*aiopsv1alpha1.ProbeSpec{
+		builders.HTTPProbe("http", "/healthz", intstr.FromString("http")),
+		{Name: "tcp", Type: "tcp", TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromString("http")}},
+	} {
+		healthCheck := builders.HealthCheck("shop", builders.Probes(probe), func(healthCheck *aiopsv1alpha1.HealthCheck) {
+			healthCheck.Spec.ClusterRef = &aiopsv1alpha1.ClusterRef{Name: "edge"}
+		})
+		result := r.executeProbe(context.Background(), nil, healthCheck, prophetconfig.Spec{}, probe)
+		if result.Success || !strings.Contains(result.Message, "remote cluster edge") {
+			t.Errorf("expected the %s probe to be refused, got %+v", probe.Type, result)
+		}
+	}
+}
+
+func TestParseStatusRanges(t *testing.T) {
+	tests := []struct {
+		values  []string
+		code    int
+		want    bool
+		wantErr bool
+	}{
+		{values: nil, code: 200, want: true},
+		{values: nil, code: 302, want: true},
+		{values: nil, code: 404, want: false},
+		{values: []string{"200-204", "418"}, code: 418, want: true},
+		{values: []string{"200-204", "418"}, code: 205, want: false},
+		{values: []string{"abc"}, wantErr: true},
+		{values: []string{"300-200"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		ranges, err := parseStatusRanges(tt.values)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseStatusRanges(%v): error %v, wantErr %v", tt.values, err, tt.wantErr)
+			continue
+		}
+		if err == nil && statusExpected(tt.code, ranges) != tt.want {
+			t.Errorf("statusExpected(%d, %v) = %v, want %v", tt.code, tt.values, !tt.want, tt.want)
+		}
+	}
+}
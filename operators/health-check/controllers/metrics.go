@@ -0,0 +1,118 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/health-check/api/v1alpha1"
+)
+
+// maxProbeHistory is how many past runs of each probe are kept in status
+const maxProbeHistory = 10
+
+var (
+	healthyGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prophet_healthcheck_healthy",
+		Help: "Whether the HealthCheck target is healthy (1) or not (0)",
+	}, []string{"namespace", "name", "cluster"})
+
+	healthScoreGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prophet_healthcheck_health_score",
+		Help: "Weighted percentage of the HealthCheck's probes that aren't failing",
+	}, []string{"namespace", "name", "cluster"})
+
+	probeSuccessGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prophet_healthcheck_probe_success",
+		Help: "Whether the probe's last run succeeded (1) or not (0)",
+	}, []string{"namespace", "name", "probe", "type"})
+
+	probeConsecutiveFailuresGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prophet_healthcheck_probe_consecutive_failures",
+		Help: "Number of consecutive runs the probe failed",
+	}, []string{"namespace", "name", "probe", "type"})
+
+	probeDNSLookupGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prophet_healthcheck_probe_dns_lookup_seconds",
+		Help: "How long resolving the host took in the external probe's last run",
+	}, []string{"namespace", "name", "probe"})
+
+	certificateExpiryGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "prophet_healthcheck_certificate_expiry_timestamp_seconds",
+		Help: "Unix time the first certificate in the chain served to the external probe expires",
+	}, []string{"namespace", "name", "probe"})
+
+	probeLatencyHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "prophet_healthcheck_probe_duration_seconds",
+		Help:    "How long probe runs took; for custom probes, how long the Job ran",
+		Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"namespace", "name", "probe", "type"})
+)
+
+func init() {
+	// Registered with the controller-runtime registry so they are served on the manager's metrics endpoint
+	metrics.Registry.MustRegister(healthyGauge, healthScoreGauge, probeSuccessGauge, probeConsecutiveFailuresGauge,
+		probeDNSLookupGauge, certificateExpiryGauge, probeLatencyHistogram)
+}
+
+// recordMetrics updates the exported gauges from the HealthCheck status
+func recordMetrics(healthCheck *aiopsv1alpha1.HealthCheck) {
+	// Drop old gauge series first so removed probes and a changed cluster don't leave stale ones behind
+	labels := prometheus.Labels{"namespace": healthCheck.Namespace, "name": healthCheck.Name}
+	healthyGauge.DeletePartialMatch(labels)
+	healthScoreGauge.DeletePartialMatch(labels)
+	probeSuccessGauge.DeletePartialMatch(labels)
+	probeConsecutiveFailuresGauge.DeletePartialMatch(labels)
+	probeDNSLookupGauge.DeletePartialMatch(labels)
+	certificateExpiryGauge.DeletePartialMatch(labels)
+
+	healthy := 0.0
+	if healthCheck.Status.Healthy {
+		healthy = 1
+	}
+	healthyGauge.WithLabelValues(healthCheck.Namespace, healthCheck.Name, healthCheck.Status.Cluster).Set(healthy)
+	healthScoreGauge.WithLabelValues(healthCheck.Namespace, healthCheck.Name, healthCheck.Status.Cluster).
+		Set(float64(healthCheck.Status.HealthScore))
+
+	probeTypes := map[string]string{}
+	for _, probe := range healthCheck.Spec.Probes {
+		probeTypes[probe.Name] = probe.Type
+	}
+	for _, result := range healthCheck.Status.ProbeResults {
+		success := 0.0
+		if result.Success {
+			success = 1
+		}
+		probeType := probeTypes[result.Name]
+		probeSuccessGauge.WithLabelValues(healthCheck.Namespace, healthCheck.Name, result.Name, probeType).Set(success)
+		probeConsecutiveFailuresGauge.WithLabelValues(healthCheck.Namespace, healthCheck.Name, result.Name, probeType).
+			Set(float64(result.ConsecutiveFailures))
+		if external := result.External; external != nil {
+			probeDNSLookupGauge.WithLabelValues(healthCheck.Namespace, healthCheck.Name, result.Name).
+				Set(float64(external.DNSLookupMilliseconds) / 1000)
+			if external.CertificateExpiry != nil {
+				certificateExpiryGauge.WithLabelValues(healthCheck.Namespace, healthCheck.Name, result.Name).
+					Set(float64(external.CertificateExpiry.Unix()))
+			}
+		}
+	}
+}
+
+// observeLatency records how long a probe run took
+func observeLatency(healthCheck *aiopsv1alpha1.HealthCheck, probe *aiopsv1alpha1.ProbeSpec, latency time.Duration) {
+	probeLatencyHistogram.WithLabelValues(healthCheck.Namespace, healthCheck.Name, probe.Name, probe.Type).
+		Observe(latency.Seconds())
+}
+
+// deleteMetrics removes all series for a HealthCheck
+func deleteMetrics(namespace, name string) {
+	labels := prometheus.Labels{"namespace": namespace, "name": name}
+	healthyGauge.DeletePartialMatch(labels)
+	healthScoreGauge.DeletePartialMatch(labels)
+	probeSuccessGauge.DeletePartialMatch(labels)
+	probeConsecutiveFailuresGauge.DeletePartialMatch(labels)
+	probeDNSLookupGauge.DeletePartialMatch(labels)
+	certificateExpiryGauge.DeletePartialMatch(labels)
+	probeLatencyHistogram.DeletePartialMatch(labels)
+}
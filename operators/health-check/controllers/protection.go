@@ -0,0 +1,25 @@
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/prophet-aiops/pkg/policy"
+	"github.com/prophet-aiops/pkg/prophetconfig"
+)
+
+// protected returns why a pod must not be restarted or have commands run in it, or an empty
+// string if it may be. Prophet's own namespace is passed in through ProtectedNamespaces, and the
+// ProphetConfig can add more.
+func (r *HealthCheckReconciler) protected(defaults prophetconfig.Spec, pod *corev1.Pod) string {
+	decision := policy.Protected(policy.Action{
+		Operator:  "health-check",
+		Kind:      "Pod",
+		Name:      pod.Name,
+		Namespace: pod.Namespace,
+		Labels:    pod.Labels,
+	}, defaults.Protected(r.ProtectedNamespaces))
+	if decision.Allowed {
+		return ""
+	}
+	return decision.Message
+}
@@ -0,0 +1,46 @@
+// Package builders builds HealthChecks for tests. Builders return valid objects that options
+// change.
+package builders
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/health-check/api/v1alpha1"
+)
+
+// HealthCheck returns a HealthCheck named web probing /healthz on port 8080 of the web
+// Deployment's pods in namespace
+func HealthCheck(namespace string, options ...func(*aiopsv1alpha1.HealthCheck)) *aiopsv1alpha1.HealthCheck {
+	healthCheck := &aiopsv1alpha1.HealthCheck{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: namespace},
+		Spec: aiopsv1alpha1.HealthCheckSpec{
+			TargetRef: aiopsv1alpha1.TargetRef{APIVersion: "apps/v1", Kind: "Deployment", Name: "web"},
+			Probes:    []aiopsv1alpha1.ProbeSpec{*HTTPProbe("http", "/healthz", intstr.FromInt(8080))},
+		},
+	}
+	for _, option := range options {
+		option(healthCheck)
+	}
+	return healthCheck
+}
+
+// HTTPProbe returns an HTTP probe of path on port, a number or a container port name
+func HTTPProbe(name, path string, port intstr.IntOrString) *aiopsv1alpha1.ProbeSpec {
+	return &aiopsv1alpha1.ProbeSpec{
+		Name:    name,
+		Type:    "http",
+		HTTPGet: &corev1.HTTPGetAction{Path: path, Port: port},
+	}
+}
+
+// Probes replaces the HealthCheck's probes
+func Probes(probes ...*aiopsv1alpha1.ProbeSpec) func(*aiopsv1alpha1.HealthCheck) {
+	return func(healthCheck *aiopsv1alpha1.HealthCheck) {
+		healthCheck.Spec.Probes = nil
+		for _, probe := range probes {
+			healthCheck.Spec.Probes = append(healthCheck.Spec.Probes, *probe)
+		}
+	}
+}
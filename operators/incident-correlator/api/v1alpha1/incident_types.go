@@ -0,0 +1,139 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// StateLabel is "open" or "resolved", used to find the open Incident for a target
+const StateLabel = "aiops.prophet.io/incident-state"
+
+// IncidentSpec defines the desired state of Incident
+type IncidentSpec struct {
+	// Target is the workload the correlated signals are about
+	Target IncidentTarget `json:"target"`
+
+	// CorrelationWindowSeconds is how long the Incident stays open after its signals clear.
+	// New signals for the same target within the window join this Incident.
+	// Default: 900 (15 minutes)
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=900
+	CorrelationWindowSeconds int32 `json:"correlationWindowSeconds,omitempty"`
+}
+
+// IncidentTarget identifies a workload
+type IncidentTarget struct {
+	// Kind of the workload (e.g., "Deployment", "StatefulSet")
+	// +kubebuilder:validation:MinLength=1
+	Kind string `json:"kind"`
+
+	// Name of the workload
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Namespace of the workload
+	Namespace string `json:"namespace"`
+}
+
+// IncidentStatus defines the observed state of Incident
+type IncidentStatus struct {
+	// Phase: Open, Resolved
+	Phase string `json:"phase,omitempty"`
+
+	// Severity is the highest severity among the signals: Critical, Warning, Info
+	Severity string `json:"severity,omitempty"`
+
+	// FirstSeen is when the first signal was observed
+	FirstSeen *metav1.Time `json:"firstSeen,omitempty"`
+
+	// LastSeen is when a signal last changed
+	LastSeen *metav1.Time `json:"lastSeen,omitempty"`
+
+	// ResolvedTime is when the Incident was resolved
+	ResolvedTime *metav1.Time `json:"resolvedTime,omitempty"`
+
+	// Signals are the resources reporting a problem with the target
+	Signals []IncidentSignal `json:"signals,omitempty"`
+
+	// Timeline is the ordered list of events for the Incident (most recent last)
+	Timeline []TimelineEntry `json:"timeline,omitempty"`
+
+	// ActionsTaken are the remediation actions reported by the signals
+	ActionsTaken []string `json:"actionsTaken,omitempty"`
+
+	// ObservedGeneration is the most recent generation the operator has reconciled
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions represent the latest available observations, including the standard Ready, Progressing and Degraded conditions
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// IncidentSignal is a resource reporting a problem with the target
+type IncidentSignal struct {
+	// Kind of the reporting resource (e.g., "HealthCheck", "DiagnosticRemediation")
+	Kind string `json:"kind"`
+
+	// Name of the reporting resource
+	Name string `json:"name"`
+
+	// Namespace of the reporting resource
+	Namespace string `json:"namespace"`
+
+	// Active indicates the resource is still reporting a problem
+	Active bool `json:"active"`
+
+	// Severity: Critical, Warning, Info
+	Severity string `json:"severity,omitempty"`
+
+	// Message is the latest message from the resource
+	Message string `json:"message,omitempty"`
+
+	// FirstSeen is when the signal was first observed
+	FirstSeen metav1.Time `json:"firstSeen"`
+
+	// LastSeen is when the signal was last observed active
+	LastSeen metav1.Time `json:"lastSeen"`
+}
+
+// TimelineEntry is a single event in an Incident's timeline
+type TimelineEntry struct {
+	// Time of the event
+	Time metav1.Time `json:"time"`
+
+	// Source is the reporting resource, as Kind/Name
+	Source string `json:"source"`
+
+	// Message describes the event
+	Message string `json:"message"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+//+kubebuilder:printcolumn:name="Severity",type="string",JSONPath=".status.severity"
+//+kubebuilder:printcolumn:name="Target",type="string",JSONPath=".spec.target.kind + '/' + .spec.target.name"
+//+kubebuilder:printcolumn:name="Last Seen",type="date",JSONPath=".status.lastSeen"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// Incident is the Schema for the incidents API
+type Incident struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   IncidentSpec   `json:"spec,omitempty"`
+	Status IncidentStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// IncidentList contains a list of Incident
+type IncidentList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Incident `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Incident{}, &IncidentList{})
+}
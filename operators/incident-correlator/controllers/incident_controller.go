@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/incident-correlator/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/status"
+)
+
+// IncidentReconciler resolves Incidents once their signals have been clear for the correlation window
+type IncidentReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+}
+
+//+kubebuilder:rbac:groups=aiops.prophet.io,resources=incidents,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=aiops.prophet.io,resources=incidents/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=aiops.prophet.io,resources=incidents/finalizers,verbs=update
+
+// Reconcile is part of the main kubernetes reconciliation loop
+func (r *IncidentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	var incident aiopsv1alpha1.Incident
+	if err := r.Get(ctx, req.NamespacedName, &incident); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if incident.Status.Phase == "Resolved" {
+		return ctrl.Result{}, nil
+	}
+
+	for _, signal := range incident.Status.Signals {
+		if signal.Active {
+			// Signal controllers update the Incident when signals clear
+			return ctrl.Result{}, nil
+		}
+	}
+
+	window := time.Duration(incident.Spec.CorrelationWindowSeconds) * time.Second
+	if window == 0 {
+		window = 15 * time.Minute
+	}
+	if incident.Status.LastSeen != nil {
+		if remaining := window - time.Since(incident.Status.LastSeen.Time); remaining > 0 {
+			return ctrl.Result{RequeueAfter: remaining}, nil
+		}
+	}
+
+	// Take the same lock as the signal controllers so a new signal can't join while resolving
+	incidentMu.Lock()
+	defer incidentMu.Unlock()
+
+	// Re-check on conflicts in case a signal became active again since the Incident was read
+	if err := status.Update(ctx, r.Client, &incident, func() bool {
+		return resolve(&incident, metav1.Now())
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+	if incident.Status.Phase != "Resolved" {
+		return ctrl.Result{}, nil
+	}
+
+	// Relabel so the signal controllers open a new Incident for later signals
+	patch := client.MergeFrom(incident.DeepCopy())
+	if incident.Labels == nil {
+		incident.Labels = map[string]string{}
+	}
+	incident.Labels[aiopsv1alpha1.StateLabel] = "resolved"
+	if err := r.Patch(ctx, &incident, patch); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	logger.Info("Resolved incident", "target", incident.Spec.Target.Kind+"/"+incident.Spec.Target.Name)
+	return ctrl.Result{}, nil
+}
+
+// resolve marks the Incident resolved, returning false if it is already resolved or has active signals
+func resolve(incident *aiopsv1alpha1.Incident, now metav1.Time) bool {
+	if incident.Status.Phase == "Resolved" {
+		return false
+	}
+	for _, signal := range incident.Status.Signals {
+		if signal.Active {
+			return false
+		}
+	}
+
+	incident.Status.Phase = "Resolved"
+	incident.Status.ResolvedTime = &now
+	addTimelineEntry(incident, now, "Incident/"+incident.Name, "Resolved: no active signals within the correlation window")
+	summarize(incident)
+	return true
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *IncidentReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&aiopsv1alpha1.Incident{}).
+		Complete(r)
+}
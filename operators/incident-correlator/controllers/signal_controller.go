@@ -0,0 +1,321 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/incident-correlator/api/v1alpha1"
+	"github.com/prophet-aiops/pkg/conditions"
+	"github.com/prophet-aiops/pkg/status"
+)
+
+// maxTimelineEntries bounds the Incident timeline so status stays small
+const maxTimelineEntries = 50
+
+// targetIndexKey indexes Incidents by target kind and name
+const targetIndexKey = "spec.target"
+
+var (
+	// incidentMu serializes Incident lookups and creation across the signal controllers
+	// so two kinds reporting the same target at once don't open two Incidents
+	incidentMu sync.Mutex
+
+	// indexOnce registers the target index once, however many signal controllers are set up
+	indexOnce sync.Once
+)
+
+// SignalReconciler correlates one kind of Prophet resource into Incidents
+type SignalReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+	Log    logr.Logger
+
+	// Kind is the resource kind to correlate, one of SignalKinds
+	Kind string
+}
+
+//+kubebuilder:rbac:groups=aiops.prophet.io,resources=healthchecks,verbs=get;list;watch
+//+kubebuilder:rbac:groups=aiops.prophet.io,resources=diagnosticremediations,verbs=get;list;watch
+
+// Reconcile records the resource's signal in the open Incident for its target
+func (r *SignalReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	source := signalSources[r.Kind]
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(source.gvk)
+	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
+		if apierrors.IsNotFound(err) {
+			// The resource is gone, so it no longer reports a problem
+			return ctrl.Result{}, r.deactivateSignal(ctx, req.Namespace, req.Name)
+		}
+		return ctrl.Result{}, err
+	}
+
+	s, ok := source.extract(obj)
+	if !ok {
+		return ctrl.Result{}, nil
+	}
+
+	incidentMu.Lock()
+	defer incidentMu.Unlock()
+
+	incident, err := r.openIncident(ctx, s.target)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if incident == nil {
+		if !s.active {
+			return ctrl.Result{}, nil
+		}
+		if incident, err = r.createIncident(ctx, s.target); err != nil {
+			return ctrl.Result{}, err
+		}
+		logger.Info("Opened incident", "incident", incident.Name, "target", s.target.Kind+"/"+s.target.Name)
+	}
+
+	// Other signal controllers write the same Incident, so re-apply the signal on conflicts
+	now := metav1.Now()
+	return ctrl.Result{}, status.Update(ctx, r.Client, incident, func() bool {
+		return applySignal(incident, r.Kind, obj.GetNamespace(), obj.GetName(), s, now)
+	})
+}
+
+// openIncident returns the open Incident for the target, or nil if there is none
+func (r *SignalReconciler) openIncident(ctx context.Context, target aiopsv1alpha1.IncidentTarget) (*aiopsv1alpha1.Incident, error) {
+	var incidents aiopsv1alpha1.IncidentList
+	if err := r.List(ctx, &incidents,
+		client.InNamespace(target.Namespace),
+		client.MatchingLabels{aiopsv1alpha1.StateLabel: "open"},
+		client.MatchingFields{targetIndexKey: targetKey(target)},
+	); err != nil {
+		return nil, err
+	}
+	if len(incidents.Items) == 0 {
+		return nil, nil
+	}
+	return &incidents.Items[0], nil
+}
+
+// createIncident creates a new open Incident for the target
+func (r *SignalReconciler) createIncident(ctx context.Context, target aiopsv1alpha1.IncidentTarget) (*aiopsv1alpha1.Incident, error) {
+	prefix := strings.ToLower(fmt.Sprintf("%s-%s", target.Kind, target.Name))
+	if len(prefix) > 50 {
+		prefix = prefix[:50]
+	}
+
+	incident := &aiopsv1alpha1.Incident{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: prefix + "-",
+			Namespace:    target.Namespace,
+			Labels:       map[string]string{aiopsv1alpha1.StateLabel: "open"},
+		},
+		Spec: aiopsv1alpha1.IncidentSpec{Target: target},
+	}
+	if err := r.Create(ctx, incident); err != nil {
+		return nil, err
+	}
+	return incident, nil
+}
+
+// deactivateSignal marks a deleted resource's signal inactive in any open Incident
+func (r *SignalReconciler) deactivateSignal(ctx context.Context, namespace, name string) error {
+	incidentMu.Lock()
+	defer incidentMu.Unlock()
+
+	var incidents aiopsv1alpha1.IncidentList
+	if err := r.List(ctx, &incidents, client.MatchingLabels{aiopsv1alpha1.StateLabel: "open"}); err != nil {
+		return err
+	}
+
+	now := metav1.Now()
+	for i := range incidents.Items {
+		incident := &incidents.Items[i]
+		if err := status.Update(ctx, r.Client, incident, func() bool {
+			return clearSignal(incident, r.Kind, namespace, name, now)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clearSignal marks the resource's signal inactive, returning true if it was active
+func clearSignal(incident *aiopsv1alpha1.Incident, kind, namespace, name string, now metav1.Time) bool {
+	changed := false
+	for i := range incident.Status.Signals {
+		signal := &incident.Status.Signals[i]
+		if signal.Kind != kind || signal.Namespace != namespace || signal.Name != name || !signal.Active {
+			continue
+		}
+		signal.Active = false
+		incident.Status.LastSeen = &now
+		addTimelineEntry(incident, now, signal.Kind+"/"+signal.Name, "Resource deleted")
+		changed = true
+	}
+	if changed {
+		summarize(incident)
+	}
+	return changed
+}
+
+// applySignal records the signal in the Incident status, returning true if anything changed
+func applySignal(incident *aiopsv1alpha1.Incident, kind, namespace, name string, s signal, now metav1.Time) bool {
+	source := kind + "/" + name
+	var existing *aiopsv1alpha1.IncidentSignal
+	for i := range incident.Status.Signals {
+		if incident.Status.Signals[i].Kind == kind && incident.Status.Signals[i].Namespace == namespace && incident.Status.Signals[i].Name == name {
+			existing = &incident.Status.Signals[i]
+		}
+	}
+
+	changed := false
+	switch {
+	case existing == nil:
+		if !s.active {
+			return false
+		}
+		incident.Status.Signals = append(incident.Status.Signals, aiopsv1alpha1.IncidentSignal{
+			Kind:      kind,
+			Name:      name,
+			Namespace: namespace,
+			Active:    true,
+			Severity:  s.severity,
+			Message:   s.message,
+			FirstSeen: now,
+			LastSeen:  now,
+		})
+		addTimelineEntry(incident, now, source, s.message)
+		changed = true
+	case existing.Active != s.active || existing.Message != s.message || existing.Severity != s.severity:
+		existing.Active = s.active
+		existing.Message = s.message
+		existing.Severity = s.severity
+		if s.active {
+			existing.LastSeen = now
+		}
+		addTimelineEntry(incident, now, source, s.message)
+		changed = true
+	}
+
+	for _, action := range s.actions {
+		if !containsString(incident.Status.ActionsTaken, action) {
+			incident.Status.ActionsTaken = append(incident.Status.ActionsTaken, action)
+			addTimelineEntry(incident, now, source, "Action: "+action)
+			changed = true
+		}
+	}
+
+	if changed {
+		if incident.Status.FirstSeen == nil {
+			// First signal of a new Incident
+			incident.Status.Phase = "Open"
+			incident.Status.FirstSeen = &now
+		}
+		incident.Status.LastSeen = &now
+		summarize(incident)
+	}
+	return changed
+}
+
+// addTimelineEntry appends to the timeline, dropping the oldest entries past maxTimelineEntries
+func addTimelineEntry(incident *aiopsv1alpha1.Incident, now metav1.Time, source, message string) {
+	incident.Status.Timeline = append(incident.Status.Timeline, aiopsv1alpha1.TimelineEntry{
+		Time:    now,
+		Source:  source,
+		Message: message,
+	})
+	if extra := len(incident.Status.Timeline) - maxTimelineEntries; extra > 0 {
+		incident.Status.Timeline = incident.Status.Timeline[extra:]
+	}
+}
+
+// summarize derives severity and conditions from the signals
+func summarize(incident *aiopsv1alpha1.Incident) {
+	severity := ""
+	active := []string{}
+	for _, signal := range incident.Status.Signals {
+		if signal.Active {
+			active = append(active, signal.Kind+"/"+signal.Name)
+		}
+		if severityRank(signal.Severity) > severityRank(severity) {
+			severity = signal.Severity
+		}
+	}
+	sort.Strings(active)
+	incident.Status.Severity = severity
+
+	summary := conditions.Summary{Degraded: true, Reason: "SignalsActive", Message: fmt.Sprintf("Active signals: %s", strings.Join(active, ", "))}
+	if len(active) == 0 {
+		summary = conditions.Summary{Progressing: true, Reason: "SignalsCleared", Message: "No active signals, waiting for the correlation window to pass"}
+	}
+	if incident.Status.Phase == "Resolved" {
+		summary = conditions.Summary{Reason: "Resolved", Message: "Incident resolved"}
+	}
+	conditions.Set(&incident.Status.Conditions, incident.Generation, "Active", len(active) > 0, summary.Reason, summary.Message)
+	conditions.Set(&incident.Status.Conditions, incident.Generation, conditions.Triggered, len(active) > 0, summary.Reason, summary.Message)
+	incident.Status.ObservedGeneration = incident.Generation
+	conditions.Apply(&incident.Status.Conditions, incident.Generation, summary)
+}
+
+// targetKey is the index value for an Incident target
+func targetKey(target aiopsv1alpha1.IncidentTarget) string {
+	return target.Kind + "/" + target.Name
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// SetupWithManager sets up the controller with the Manager.
+// Kinds whose CRD isn't installed are skipped so the correlator runs with any subset of operators.
+func (r *SignalReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	source, ok := signalSources[r.Kind]
+	if !ok {
+		return fmt.Errorf("unsupported signal kind: %s", r.Kind)
+	}
+
+	if _, err := mgr.GetRESTMapper().RESTMapping(source.gvk.GroupKind(), source.gvk.Version); err != nil {
+		if meta.IsNoMatchError(err) {
+			r.Log.Info("CRD not installed, not correlating", "kind", r.Kind)
+			return nil
+		}
+		return err
+	}
+
+	var indexErr error
+	indexOnce.Do(func() {
+		indexErr = mgr.GetFieldIndexer().IndexField(context.Background(), &aiopsv1alpha1.Incident{}, targetIndexKey,
+			func(obj client.Object) []string {
+				return []string{targetKey(obj.(*aiopsv1alpha1.Incident).Spec.Target)}
+			})
+	})
+	if indexErr != nil {
+		return indexErr
+	}
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(source.gvk)
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("signal-" + strings.ToLower(r.Kind)).
+		For(obj).
+		Complete(r)
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/incident-correlator/api/v1alpha1"
+)
+
+// signal is what a Prophet resource reports about a workload
+type signal struct {
+	target   aiopsv1alpha1.IncidentTarget
+	active   bool
+	severity string
+	message  string
+	actions  []string
+}
+
+// signalSource extracts signals from a Prophet resource kind.
+// Resources are read as unstructured so the correlator doesn't depend on the other operators' modules.
+type signalSource struct {
+	gvk schema.GroupVersionKind
+
+	// extract returns false if the resource doesn't reference a single workload
+	extract func(obj *unstructured.Unstructured) (signal, bool)
+}
+
+// signalSources are the resource kinds the correlator watches
+var signalSources = map[string]signalSource{
+	"HealthCheck": {
+		gvk:     aiopsv1alpha1.GroupVersion.WithKind("HealthCheck"),
+		extract: healthCheckSignal,
+	},
+	"DiagnosticRemediation": {
+		gvk:     aiopsv1alpha1.GroupVersion.WithKind("DiagnosticRemediation"),
+		extract: diagnosticRemediationSignal,
+	},
+}
+
+// SignalKinds lists the kinds that can be correlated into Incidents
+var SignalKinds = []string{"HealthCheck", "DiagnosticRemediation"}
+
+// healthCheckSignal reports a HealthCheck as active while its target is unhealthy
+func healthCheckSignal(obj *unstructured.Unstructured) (signal, bool) {
+	kind, _, _ := unstructured.NestedString(obj.Object, "spec", "targetRef", "kind")
+	name, _, _ := unstructured.NestedString(obj.Object, "spec", "targetRef", "name")
+	namespace, _, _ := unstructured.NestedString(obj.Object, "spec", "targetRef", "namespace")
+	if kind == "" || name == "" {
+		return signal{}, false
+	}
+	if namespace == "" {
+		namespace = obj.GetNamespace()
+	}
+
+	healthy, _, _ := unstructured.NestedBool(obj.Object, "status", "healthy")
+	_, checked, _ := unstructured.NestedString(obj.Object, "status", "lastCheckTime")
+	failures, _, _ := unstructured.NestedInt64(obj.Object, "status", "failureCount")
+
+	s := signal{
+		target:   aiopsv1alpha1.IncidentTarget{Kind: kind, Name: name, Namespace: namespace},
+		active:   checked && !healthy,
+		severity: "Critical",
+		message:  fmt.Sprintf("Health check failing (%d consecutive failures)", failures),
+	}
+	if s.active {
+		if message := conditionMessage(obj); message != "" {
+			s.message = message
+		}
+	} else {
+		s.message = "Health check passing"
+	}
+
+	action, _, _ := unstructured.NestedString(obj.Object, "spec", "remediation", "action")
+	remediations, _, _ := unstructured.NestedInt64(obj.Object, "status", "remediationCount")
+	if remediations > 0 && action != "" && action != "none" {
+		s.actions = append(s.actions, fmt.Sprintf("HealthCheck/%s: %s (%d times)", obj.GetName(), action, remediations))
+	}
+	return s, true
+}
+
+// diagnosticRemediationSignal reports a DiagnosticRemediation as active while it has unresolved issues
+func diagnosticRemediationSignal(obj *unstructured.Unstructured) (signal, bool) {
+	kind, _, _ := unstructured.NestedString(obj.Object, "spec", "target", "kind")
+	name, _, _ := unstructured.NestedString(obj.Object, "spec", "target", "name")
+	namespace, _, _ := unstructured.NestedString(obj.Object, "spec", "target", "namespace")
+	if kind == "" || name == "" {
+		// Label-selected targets can't be attributed to a single workload
+		return signal{}, false
+	}
+	if namespace == "" {
+		namespace = obj.GetNamespace()
+	}
+
+	phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+	issues, _, _ := unstructured.NestedSlice(obj.Object, "status", "issues")
+
+	s := signal{
+		target:   aiopsv1alpha1.IncidentTarget{Kind: kind, Name: name, Namespace: namespace},
+		severity: "Info",
+		message:  fmt.Sprintf("Diagnostics %s", phase),
+	}
+	switch phase {
+	case "IssuesFound", "Remediating", "Failed":
+		s.active = len(issues) > 0 || phase == "Failed"
+	}
+
+	for _, item := range issues {
+		issue, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		severity, _ := issue["severity"].(string)
+		if severityRank(severity) > severityRank(s.severity) {
+			s.severity = severity
+		}
+	}
+	if s.active && len(issues) > 0 {
+		if issue, ok := issues[0].(map[string]interface{}); ok {
+			description, _ := issue["description"].(string)
+			s.message = fmt.Sprintf("%d diagnostic issue(s): %s", len(issues), description)
+		}
+	}
+
+	remediations, _, _ := unstructured.NestedSlice(obj.Object, "status", "remediations")
+	for _, item := range remediations {
+		remediation, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if success, _ := remediation["success"].(bool); success {
+			description, _ := remediation["description"].(string)
+			s.actions = append(s.actions, fmt.Sprintf("DiagnosticRemediation/%s: %s", obj.GetName(), description))
+		}
+	}
+	return s, true
+}
+
+// conditionMessage returns the message of the first status condition
+func conditionMessage(obj *unstructured.Unstructured) string {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if len(conditions) == 0 {
+		return ""
+	}
+	condition, ok := conditions[0].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	message, _ := condition["message"].(string)
+	return message
+}
+
+// severityRank orders severities so the highest can be reported
+func severityRank(severity string) int {
+	switch severity {
+	case "Critical":
+		return 3
+	case "Warning":
+		return 2
+	case "Info":
+		return 1
+	default:
+		return 0
+	}
+}
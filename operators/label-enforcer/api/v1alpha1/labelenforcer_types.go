@@ -5,10 +5,18 @@ import (
 )
 
 // LabelEnforcerSpec defines the desired state of LabelEnforcer
+// +kubebuilder:validation:XValidation:rule="has(self.requiredLabels) || has(self.requiredAnnotations)",message="at least one of requiredLabels or requiredAnnotations is required"
+// +kubebuilder:validation:XValidation:rule="has(self.targetResource) || has(self.targetResources)",message="at least one of targetResource or targetResources is required"
+// +kubebuilder:validation:XValidation:rule="!has(self.propagateToPodTemplate) || !self.propagateToPodTemplate || (has(self.allowRollout) && self.allowRollout)",message="propagateToPodTemplate rolls out the workloads' pods and requires allowRollout"
 type LabelEnforcerSpec struct {
-	// Target resources to enforce labels/annotations on
-	// +kubebuilder:validation:Enum=pods;deployments;statefulsets;daemonsets;services;configmaps;secrets
-	TargetResource string `json:"targetResource"`
+	// TargetResources are the resources to enforce labels/annotations on
+	// +kubebuilder:validation:MinItems=1
+	// +listType=set
+	TargetResources []TargetResource `json:"targetResources,omitempty"`
+
+	// TargetResource is a single resource to enforce labels/annotations on.
+	// Deprecated: use targetResources; both can be set and are combined.
+	TargetResource TargetResource `json:"targetResource,omitempty"`
 
 	// Namespace to watch (empty means all namespaces)
 	Namespace string `json:"namespace,omitempty"`
@@ -16,33 +24,120 @@ type LabelEnforcerSpec struct {
 	// Label selector to match target resources
 	LabelSelector map[string]string `json:"labelSelector,omitempty"`
 
+	// ExcludeNamespaces are namespaces whose resources are left alone
+	// +listType=set
+	ExcludeNamespaces []string `json:"excludeNamespaces,omitempty"`
+
+	// ExcludeNames are regular expressions; resources whose whole name matches one are left alone
+	ExcludeNames []string `json:"excludeNames,omitempty"`
+
 	// Required labels that must be present on resources
 	RequiredLabels map[string]string `json:"requiredLabels,omitempty"`
 
 	// Required annotations that must be present on resources
 	RequiredAnnotations map[string]string `json:"requiredAnnotations,omitempty"`
 
+	// AllowOverwrite are the protected label and annotation keys the enforcer may change. Keys
+	// owned by Helm or by the controllers selecting resources by them are protected: those with a
+	// helm.sh/ or meta.helm.sh/ prefix, app.kubernetes.io/managed-by, app.kubernetes.io/instance,
+	// app.kubernetes.io/part-of, pod-template-hash, controller-revision-hash and
+	// statefulset.kubernetes.io/pod-name. They're added when missing, but a different value is kept.
+	// +listType=set
+	AllowOverwrite []string `json:"allowOverwrite,omitempty"`
+
+	// PropagateToPodTemplate also sets the required labels and annotations in the pod template of
+	// Deployments, StatefulSets and DaemonSets, so they reach the pods. Changing the template rolls
+	// out new pods, so it requires allowRollout.
+	PropagateToPodTemplate bool `json:"propagateToPodTemplate,omitempty"`
+
+	// AllowRollout acknowledges that propagating to pod templates restarts the workloads' pods
+	AllowRollout bool `json:"allowRollout,omitempty"`
+
+	// ResyncIntervalSeconds is how often all target resources are checked again, on top of
+	// checking resources as they're created or their labels and annotations change
+	// Default: 600 (10 minutes)
+	// +kubebuilder:validation:Minimum=30
+	// +kubebuilder:default=600
+	ResyncIntervalSeconds int32 `json:"resyncIntervalSeconds,omitempty"`
+
 	// Whether to enforce on existing resources (default: true)
 	EnforceExisting bool `json:"enforceExisting,omitempty"`
+
+	// EnforcementMode is how required labels and annotations are applied: "Reconcile" corrects
+	// existing resources, "Webhook" adds them in a mutating admission webhook as resources are
+	// created or updated, and "Both" does both. The webhook must be enabled on the operator.
+	// Default: Reconcile
+	// +kubebuilder:validation:Enum=Webhook;Reconcile;Both
+	// +kubebuilder:default=Reconcile
+	EnforcementMode string `json:"enforcementMode,omitempty"`
+
+	// Action is what happens to non-compliant resources: "Enforce" adds the missing labels and
+	// annotations, "Report" only lists the resources in status and the label_enforcer_violations
+	// metric, so a policy can be rolled out gradually. Report mode never mutates resources, also
+	// not in the webhook. Default: Enforce
+	// +kubebuilder:validation:Enum=Enforce;Report
+	// +kubebuilder:default=Enforce
+	Action string `json:"action,omitempty"`
 }
 
+// TargetResource is a resource type labels and annotations are enforced on
+// +kubebuilder:validation:Enum=pods;deployments;statefulsets;daemonsets;services;configmaps;secrets
+type TargetResource string
+
 // LabelEnforcerStatus defines the observed state of LabelEnforcer
 type LabelEnforcerStatus struct {
 	// Number of resources that were corrected
 	CorrectedResources int32 `json:"correctedResources,omitempty"`
 
+	// CorrectedByResource breaks correctedResources down by target resource
+	CorrectedByResource map[string]int32 `json:"correctedByResource,omitempty"`
+
+	// ViolationCount is how many resources were non-compliant when last reported, in Report mode
+	ViolationCount int32 `json:"violationCount,omitempty"`
+
+	// Violations are the non-compliant resources found in Report mode, up to the first 100
+	Violations []Violation `json:"violations,omitempty"`
+
 	// Last time a correction was made
 	LastCorrected *metav1.Time `json:"lastCorrected,omitempty"`
 
-	// Conditions for the enforcer
+	// Most recent generation reconciled
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions for the enforcer, including the standard Ready, Progressing and Degraded conditions
+	// +listType=map
+	// +listMapKey=type
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// Violation is a resource that is missing required labels or annotations
+type Violation struct {
+	// Resource is the target resource, e.g. deployments
+	Resource string `json:"resource"`
+
+	// Namespace of the resource
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the resource
+	Name string `json:"name"`
+
+	// MissingLabels are the required labels that are missing or have a different value
+	MissingLabels []string `json:"missingLabels,omitempty"`
+
+	// MissingAnnotations are the required annotations that are missing or have a different value
+	MissingAnnotations []string `json:"missingAnnotations,omitempty"`
+}
+
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
-//+kubebuilder:printcolumn:name="Target",type="string",JSONPath=".spec.targetResource"
+//+kubebuilder:resource:shortName=lenf
+//+kubebuilder:printcolumn:name="Targets",type="string",JSONPath=".spec.targetResources"
 //+kubebuilder:printcolumn:name="Namespace",type="string",JSONPath=".spec.namespace"
+//+kubebuilder:printcolumn:name="Mode",type="string",JSONPath=".spec.enforcementMode"
+//+kubebuilder:printcolumn:name="Action",type="string",JSONPath=".spec.action"
 //+kubebuilder:printcolumn:name="Corrected",type="integer",JSONPath=".status.correctedResources"
+//+kubebuilder:printcolumn:name="Violations",type="integer",JSONPath=".status.violationCount"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // LabelEnforcer is the Schema for the labelenforcers API
 type LabelEnforcer struct {
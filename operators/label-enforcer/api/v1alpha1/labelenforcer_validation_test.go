@@ -0,0 +1,74 @@
+package v1alpha1_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/prophet/operators/label-enforcer/api/v1alpha1"
+	"github.com/prophet-aiops/prophet/operators/label-enforcer/internal/builders"
+)
+
+// TestValidationRules checks the CRD's CEL validation rules against the API server
+func TestValidationRules(t *testing.T) {
+	c := testEnv.Require(t)
+	namespace := testEnv.Namespace(t)
+
+	tests := map[string]struct {
+		modify func(*aiopsv1alpha1.LabelEnforcerSpec)
+		error  string
+	}{
+		"valid": {
+			modify: func(*aiopsv1alpha1.LabelEnforcerSpec) {},
+		},
+		"deprecated targetResource": {
+			modify: func(spec *aiopsv1alpha1.LabelEnforcerSpec) {
+				spec.TargetResources = nil
+				spec.TargetResource = "deployments"
+			},
+		},
+		"no target resources": {
+			modify: func(spec *aiopsv1alpha1.LabelEnforcerSpec) { spec.TargetResources = nil },
+			error:  "at least one of targetResource or targetResources is required",
+		},
+		"annotations only": {
+			modify: func(spec *aiopsv1alpha1.LabelEnforcerSpec) {
+				spec.RequiredLabels = nil
+				spec.RequiredAnnotations = map[string]string{"owner": "shop-team"}
+			},
+		},
+		"no labels or annotations": {
+			modify: func(spec *aiopsv1alpha1.LabelEnforcerSpec) { spec.RequiredLabels = nil },
+			error:  "at least one of requiredLabels or requiredAnnotations is required",
+		},
+		"propagation with rollout": {
+			modify: func(spec *aiopsv1alpha1.LabelEnforcerSpec) {
+				spec.PropagateToPodTemplate = true
+				spec.AllowRollout = true
+			},
+		},
+		"propagation without rollout": {
+			modify: func(spec *aiopsv1alpha1.LabelEnforcerSpec) { spec.PropagateToPodTemplate = true },
+			error:  "propagateToPodTemplate rolls out the workloads' pods and requires allowRollout",
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			labelEnforcer := builders.LabelEnforcer(namespace, func(labelEnforcer *aiopsv1alpha1.LabelEnforcer) {
+				labelEnforcer.Name = ""
+				labelEnforcer.GenerateName = "team-"
+				tt.modify(&labelEnforcer.Spec)
+			})
+
+			err := c.Create(context.Background(), labelEnforcer)
+			switch {
+			case tt.error == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.error != "" && err == nil:
+				t.Errorf("expected %q", tt.error)
+			case tt.error != "" && !strings.Contains(err.Error(), tt.error):
+				t.Errorf("expected %q, got %v", tt.error, err)
+			}
+		})
+	}
+}
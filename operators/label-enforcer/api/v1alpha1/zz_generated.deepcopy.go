@@ -71,6 +71,11 @@ func (in *LabelEnforcerList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LabelEnforcerSpec) DeepCopyInto(out *LabelEnforcerSpec) {
 	*out = *in
+	if in.TargetResources != nil {
+		in, out := &in.TargetResources, &out.TargetResources
+		*out = make([]TargetResource, len(*in))
+		copy(*out, *in)
+	}
 	if in.LabelSelector != nil {
 		in, out := &in.LabelSelector, &out.LabelSelector
 		*out = make(map[string]string, len(*in))
@@ -78,6 +83,16 @@ func (in *LabelEnforcerSpec) DeepCopyInto(out *LabelEnforcerSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.ExcludeNamespaces != nil {
+		in, out := &in.ExcludeNamespaces, &out.ExcludeNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExcludeNames != nil {
+		in, out := &in.ExcludeNames, &out.ExcludeNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.RequiredLabels != nil {
 		in, out := &in.RequiredLabels, &out.RequiredLabels
 		*out = make(map[string]string, len(*in))
@@ -92,6 +107,11 @@ func (in *LabelEnforcerSpec) DeepCopyInto(out *LabelEnforcerSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.AllowOverwrite != nil {
+		in, out := &in.AllowOverwrite, &out.AllowOverwrite
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LabelEnforcerSpec.
@@ -107,6 +127,20 @@ func (in *LabelEnforcerSpec) DeepCopy() *LabelEnforcerSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LabelEnforcerStatus) DeepCopyInto(out *LabelEnforcerStatus) {
 	*out = *in
+	if in.CorrectedByResource != nil {
+		in, out := &in.CorrectedByResource, &out.CorrectedByResource
+		*out = make(map[string]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Violations != nil {
+		in, out := &in.Violations, &out.Violations
+		*out = make([]Violation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.LastCorrected != nil {
 		in, out := &in.LastCorrected, &out.LastCorrected
 		*out = new(metav1.Time)
@@ -130,3 +164,28 @@ func (in *LabelEnforcerStatus) DeepCopy() *LabelEnforcerStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Violation) DeepCopyInto(out *Violation) {
+	*out = *in
+	if in.MissingLabels != nil {
+		in, out := &in.MissingLabels, &out.MissingLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MissingAnnotations != nil {
+		in, out := &in.MissingAnnotations, &out.MissingAnnotations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Violation.
+func (in *Violation) DeepCopy() *Violation {
+	if in == nil {
+		return nil
+	}
+	out := new(Violation)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/prophet/operators/label-enforcer/api/v1alpha1"
+)
+
+// protectedPrefixes and protectedKeys are the label and annotation keys owned by Helm or by the
+// controllers that select resources by them. The enforcer adds them when missing but doesn't
+// change their value unless the key is in allowOverwrite, so it never fights their owner.
+var (
+	protectedPrefixes = []string{"helm.sh/", "meta.helm.sh/"}
+	protectedKeys     = []string{
+		"app.kubernetes.io/managed-by",
+		"app.kubernetes.io/instance",
+		"app.kubernetes.io/part-of",
+		"pod-template-hash",
+		"controller-revision-hash",
+		"statefulset.kubernetes.io/pod-name",
+	}
+)
+
+// protected reports whether the enforcer may not overwrite the key
+func protected(enforcer *aiopsv1alpha1.LabelEnforcer, key string) bool {
+	if slices.Contains(enforcer.Spec.AllowOverwrite, key) {
+		return false
+	}
+	if slices.Contains(protectedKeys, key) {
+		return true
+	}
+	return slices.ContainsFunc(protectedPrefixes, func(prefix string) bool { return strings.HasPrefix(key, prefix) })
+}
+
+// exclusions are the namespaces and resource names an enforcer leaves alone
+type exclusions struct {
+	namespaces []string
+	names      []*regexp.Regexp
+}
+
+// exclusionsOf compiles the enforcer's exclusions
+func exclusionsOf(enforcer *aiopsv1alpha1.LabelEnforcer) (*exclusions, error) {
+	e := &exclusions{namespaces: enforcer.Spec.ExcludeNamespaces}
+	for _, pattern := range enforcer.Spec.ExcludeNames {
+		// Anchored, so a pattern must match the whole name
+		name, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid excludeNames pattern %q: %w", pattern, err)
+		}
+		e.names = append(e.names, name)
+	}
+	return e, nil
+}
+
+// excludes reports whether the namespace or the resource name is excluded
+func (e *exclusions) excludes(namespace, name string) bool {
+	if slices.Contains(e.namespaces, namespace) {
+		return true
+	}
+	return slices.ContainsFunc(e.names, func(pattern *regexp.Regexp) bool { return pattern.MatchString(name) })
+}
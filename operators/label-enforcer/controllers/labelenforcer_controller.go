@@ -2,16 +2,28 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
-	appsv1 "k8s.io/api/apps/v1"
-	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
+	"github.com/prophet-aiops/pkg/conditions"
+	"github.com/prophet-aiops/pkg/status"
 	aiopsv1alpha1 "github.com/prophet-aiops/prophet/operators/label-enforcer/api/v1alpha1"
 )
 
@@ -40,309 +52,218 @@ func (r *LabelEnforcerReconciler) Reconcile(ctx context.Context, req ctrl.Reques
 
 	var labelEnforcer aiopsv1alpha1.LabelEnforcer
 	if err := r.Get(ctx, req.NamespacedName, &labelEnforcer); err != nil {
+		if apierrors.IsNotFound(err) {
+			deleteMetrics(req.Namespace, req.Name)
+		}
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
+	base := labelEnforcer.DeepCopy()
+
+	resources := targetResources(&labelEnforcer)
+	logger.Info("Reconciling LabelEnforcer", "name", req.Name, "targets", resources, "action", labelEnforcer.Spec.Action)
+
+	// Retrying doesn't help an invalid exclusion; it's reconciled again when the spec is fixed
+	if _, err := exclusionsOf(&labelEnforcer); err != nil {
+		logger.Error(err, "Invalid exclusions")
+		labelEnforcer.Status.ObservedGeneration = labelEnforcer.Generation
+		conditions.Apply(&labelEnforcer.Status.Conditions, labelEnforcer.Generation, conditions.Summary{
+			Degraded: true,
+			Reason:   "InvalidExclusions",
+			Message:  err.Error(),
+		})
+		return ctrl.Result{}, status.Patch(ctx, r.Client, &labelEnforcer, base)
+	}
 
-	logger.Info("Reconciling LabelEnforcer", "name", req.Name, "target", labelEnforcer.Spec.TargetResource)
-
-	// Find and correct resources that need enforcement
-	correctedCount, err := r.enforceLabelsAndAnnotations(ctx, &labelEnforcer)
-	if err != nil {
-		logger.Error(err, "Failed to enforce labels/annotations")
-		return ctrl.Result{}, err
+	// In Report mode nothing is changed, whatever the enforcement mode
+	if labelEnforcer.Spec.Action == "Report" {
+		return r.report(ctx, &labelEnforcer, base, resources)
 	}
 
-	// Update status if corrections were made
-	if correctedCount > 0 {
-		labelEnforcer.Status.CorrectedResources = int32(correctedCount)
-		labelEnforcer.Status.LastCorrected = &metav1.Time{Time: metav1.Now().Time}
-		if err := r.Status().Update(ctx, &labelEnforcer); err != nil {
+	// Drop what an earlier Report mode found, the resources are corrected now
+	labelEnforcer.Status.Violations = nil
+	labelEnforcer.Status.ViolationCount = 0
+	meta.RemoveStatusCondition(&labelEnforcer.Status.Conditions, conditions.Triggered)
+	deleteMetrics(labelEnforcer.Namespace, labelEnforcer.Name)
+
+	// In Webhook mode resources are labeled as they're admitted and existing ones are left alone
+	if labelEnforcer.Spec.EnforcementMode == "Webhook" {
+		labelEnforcer.Status.ObservedGeneration = labelEnforcer.Generation
+		conditions.Apply(&labelEnforcer.Status.Conditions, labelEnforcer.Generation, conditions.Summary{
+			Reason:  "WebhookEnforced",
+			Message: fmt.Sprintf("Required labels and annotations are added to %s by the admission webhook", joinResources(resources)),
+		})
+		if err := status.Patch(ctx, r.Client, &labelEnforcer, base); err != nil {
 			logger.Error(err, "Failed to update status")
 			return ctrl.Result{}, err
 		}
-		logger.Info("Corrected resources", "count", correctedCount)
+		return ctrl.Result{}, nil
 	}
 
-	return ctrl.Result{}, nil
-}
-
-// enforceLabelsAndAnnotations finds resources and ensures they have required labels/annotations
-func (r *LabelEnforcerReconciler) enforceLabelsAndAnnotations(ctx context.Context, enforcer *aiopsv1alpha1.LabelEnforcer) (int, error) {
-	logger := log.FromContext(ctx)
-	correctedCount := 0
+	// Find and correct resources that need enforcement
+	corrected, err := r.enforceLabelsAndAnnotations(ctx, &labelEnforcer)
+	labelEnforcer.Status.ObservedGeneration = labelEnforcer.Generation
 
-	switch enforcer.Spec.TargetResource {
-	case "pods":
-		count, err := r.enforceOnPods(ctx, enforcer)
-		if err != nil {
-			return correctedCount, err
-		}
-		correctedCount += count
-	case "deployments":
-		count, err := r.enforceOnDeployments(ctx, enforcer)
-		if err != nil {
-			return correctedCount, err
-		}
-		correctedCount += count
-	case "services":
-		count, err := r.enforceOnServices(ctx, enforcer)
-		if err != nil {
-			return correctedCount, err
-		}
-		correctedCount += count
-	case "configmaps":
-		count, err := r.enforceOnConfigMaps(ctx, enforcer)
-		if err != nil {
-			return correctedCount, err
-		}
-		correctedCount += count
-	case "secrets":
-		count, err := r.enforceOnSecrets(ctx, enforcer)
-		if err != nil {
-			return correctedCount, err
-		}
+	// Update status, recording corrections if any were made, also when some target resources failed
+	correctedCount := int32(0)
+	for _, count := range corrected {
 		correctedCount += count
-	default:
-		logger.Info("Unsupported target resource", "resource", enforcer.Spec.TargetResource)
 	}
-
-	return correctedCount, nil
-}
-
-// enforceOnPods ensures pods have required labels/annotations
-func (r *LabelEnforcerReconciler) enforceOnPods(ctx context.Context, enforcer *aiopsv1alpha1.LabelEnforcer) (int, error) {
-	logger := log.FromContext(ctx)
-	correctedCount := 0
-
-	var podList corev1.PodList
-	listOpts := []client.ListOption{
-		client.InNamespace(enforceNamespace(enforcer)),
-	}
-
-	if enforcer.Spec.LabelSelector != nil {
-		selector := client.MatchingLabels(enforcer.Spec.LabelSelector)
-		listOpts = append(listOpts, selector)
-	}
-
-	if err := r.List(ctx, &podList, listOpts...); err != nil {
-		return correctedCount, err
+	if correctedCount > 0 {
+		labelEnforcer.Status.CorrectedResources = correctedCount
+		labelEnforcer.Status.CorrectedByResource = corrected
+		labelEnforcer.Status.LastCorrected = &metav1.Time{Time: metav1.Now().Time}
+		logger.Info("Corrected resources", "count", correctedCount, "byResource", corrected)
 	}
-
-	for _, pod := range podList.Items {
-		needsUpdate := false
-
-		// Check and add required labels
-		if pod.Labels == nil {
-			pod.Labels = make(map[string]string)
-		}
-		for key, value := range enforcer.Spec.RequiredLabels {
-			if currentValue, exists := pod.Labels[key]; !exists || currentValue != value {
-				pod.Labels[key] = value
-				needsUpdate = true
-			}
-		}
-
-		// Check and add required annotations
-		if pod.Annotations == nil {
-			pod.Annotations = make(map[string]string)
-		}
-		for key, value := range enforcer.Spec.RequiredAnnotations {
-			if currentValue, exists := pod.Annotations[key]; !exists || currentValue != value {
-				pod.Annotations[key] = value
-				needsUpdate = true
-			}
+	if err != nil {
+		logger.Error(err, "Failed to enforce labels/annotations")
+		conditions.Apply(&labelEnforcer.Status.Conditions, labelEnforcer.Generation, conditions.Summary{
+			Degraded: true,
+			Reason:   "EnforcementFailed",
+			Message:  err.Error(),
+		})
+		if statusErr := status.Patch(ctx, r.Client, &labelEnforcer, base); statusErr != nil {
+			logger.Error(statusErr, "Failed to update status")
 		}
+		return ctrl.Result{}, err
+	}
 
-		if needsUpdate {
-			if err := r.Update(ctx, &pod); err != nil {
-				logger.Error(err, "Failed to update pod", "name", pod.Name)
-				continue
-			}
-			correctedCount++
-			logger.Info("Corrected pod labels/annotations", "name", pod.Name)
-		}
+	conditions.Apply(&labelEnforcer.Status.Conditions, labelEnforcer.Generation, conditions.Summary{
+		Reason:  "Enforced",
+		Message: fmt.Sprintf("Required labels and annotations are enforced on %s", joinResources(resources)),
+	})
+	if err := status.Patch(ctx, r.Client, &labelEnforcer, base); err != nil {
+		logger.Error(err, "Failed to update status")
+		return ctrl.Result{}, err
 	}
 
-	return correctedCount, nil
+	return ctrl.Result{RequeueAfter: resyncInterval(&labelEnforcer)}, nil
 }
 
-// enforceOnDeployments ensures deployments have required labels/annotations
-func (r *LabelEnforcerReconciler) enforceOnDeployments(ctx context.Context, enforcer *aiopsv1alpha1.LabelEnforcer) (int, error) {
+// report records the non-compliant target resources in status and metrics
+func (r *LabelEnforcerReconciler) report(ctx context.Context, labelEnforcer, base *aiopsv1alpha1.LabelEnforcer, resources []aiopsv1alpha1.TargetResource) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
-	correctedCount := 0
 
-	var deploymentList appsv1.DeploymentList
-	listOpts := []client.ListOption{
-		client.InNamespace(enforceNamespace(enforcer)),
+	violations, counts, err := r.reportViolations(ctx, labelEnforcer)
+	labelEnforcer.Status.ObservedGeneration = labelEnforcer.Generation
+	if err != nil {
+		logger.Error(err, "Failed to report violations")
+		conditions.Apply(&labelEnforcer.Status.Conditions, labelEnforcer.Generation, conditions.Summary{
+			Degraded: true,
+			Reason:   "ReportFailed",
+			Message:  err.Error(),
+		})
+		if statusErr := status.Patch(ctx, r.Client, labelEnforcer, base); statusErr != nil {
+			logger.Error(statusErr, "Failed to update status")
+		}
+		return ctrl.Result{}, err
 	}
 
-	if enforcer.Spec.LabelSelector != nil {
-		selector := client.MatchingLabels(enforcer.Spec.LabelSelector)
-		listOpts = append(listOpts, selector)
+	total := 0
+	for _, count := range counts {
+		total += count
 	}
-
-	if err := r.List(ctx, &deploymentList, listOpts...); err != nil {
-		return correctedCount, err
+	labelEnforcer.Status.Violations = violations
+	labelEnforcer.Status.ViolationCount = int32(total)
+	recordViolations(labelEnforcer.Namespace, labelEnforcer.Name, counts)
+	logger.Info("Reported violations", "count", total, "byResource", counts)
+
+	message := fmt.Sprintf("%d resources are missing required labels or annotations, checked %s", total, joinResources(resources))
+	conditions.Set(&labelEnforcer.Status.Conditions, labelEnforcer.Generation, conditions.Triggered, total > 0, "ViolationsFound", message)
+	conditions.Apply(&labelEnforcer.Status.Conditions, labelEnforcer.Generation, conditions.Summary{
+		Reason:  "Reported",
+		Message: message,
+	})
+	if err := status.Patch(ctx, r.Client, labelEnforcer, base); err != nil {
+		logger.Error(err, "Failed to update status")
+		return ctrl.Result{}, err
 	}
+	return ctrl.Result{RequeueAfter: resyncInterval(labelEnforcer)}, nil
+}
 
-	for _, deployment := range deploymentList.Items {
-		needsUpdate := false
-
-		// Check and add required labels
-		if deployment.Labels == nil {
-			deployment.Labels = make(map[string]string)
-		}
-		for key, value := range enforcer.Spec.RequiredLabels {
-			if currentValue, exists := deployment.Labels[key]; !exists || currentValue != value {
-				deployment.Labels[key] = value
-				needsUpdate = true
-			}
-		}
-
-		// Check and add required annotations
-		if deployment.Annotations == nil {
-			deployment.Annotations = make(map[string]string)
-		}
-		for key, value := range enforcer.Spec.RequiredAnnotations {
-			if currentValue, exists := deployment.Annotations[key]; !exists || currentValue != value {
-				deployment.Annotations[key] = value
-				needsUpdate = true
-			}
-		}
+// targetKinds maps each target resource to its kind
+var targetKinds = map[aiopsv1alpha1.TargetResource]schema.GroupVersionKind{
+	"pods":         {Version: "v1", Kind: "Pod"},
+	"services":     {Version: "v1", Kind: "Service"},
+	"configmaps":   {Version: "v1", Kind: "ConfigMap"},
+	"secrets":      {Version: "v1", Kind: "Secret"},
+	"deployments":  {Group: "apps", Version: "v1", Kind: "Deployment"},
+	"statefulsets": {Group: "apps", Version: "v1", Kind: "StatefulSet"},
+	"daemonsets":   {Group: "apps", Version: "v1", Kind: "DaemonSet"},
+}
 
-		if needsUpdate {
-			if err := r.Update(ctx, &deployment); err != nil {
-				logger.Error(err, "Failed to update deployment", "name", deployment.Name)
-				continue
-			}
-			correctedCount++
-			logger.Info("Corrected deployment labels/annotations", "name", deployment.Name)
-		}
+// targetResources returns the enforcer's target resources, including the deprecated targetResource
+func targetResources(enforcer *aiopsv1alpha1.LabelEnforcer) []aiopsv1alpha1.TargetResource {
+	resources := append([]aiopsv1alpha1.TargetResource(nil), enforcer.Spec.TargetResources...)
+	if resource := enforcer.Spec.TargetResource; resource != "" && !slices.Contains(resources, resource) {
+		resources = append(resources, resource)
 	}
-
-	return correctedCount, nil
+	return resources
 }
 
-// enforceOnServices ensures services have required labels/annotations
-func (r *LabelEnforcerReconciler) enforceOnServices(ctx context.Context, enforcer *aiopsv1alpha1.LabelEnforcer) (int, error) {
+// enforceLabelsAndAnnotations finds resources and ensures they have required labels/annotations,
+// returning how many were corrected per target resource. A failing target resource doesn't stop
+// the others.
+func (r *LabelEnforcerReconciler) enforceLabelsAndAnnotations(ctx context.Context, enforcer *aiopsv1alpha1.LabelEnforcer) (map[string]int32, error) {
 	logger := log.FromContext(ctx)
-	correctedCount := 0
-
-	var serviceList corev1.ServiceList
-	listOpts := []client.ListOption{
-		client.InNamespace(enforceNamespace(enforcer)),
-	}
-
-	if enforcer.Spec.LabelSelector != nil {
-		selector := client.MatchingLabels(enforcer.Spec.LabelSelector)
-		listOpts = append(listOpts, selector)
-	}
-
-	if err := r.List(ctx, &serviceList, listOpts...); err != nil {
-		return correctedCount, err
-	}
-
-	for _, service := range serviceList.Items {
-		needsUpdate := false
-
-		// Check and add required labels
-		if service.Labels == nil {
-			service.Labels = make(map[string]string)
+	corrected := map[string]int32{}
+	var errs []error
+
+	for _, resource := range targetResources(enforcer) {
+		gvk, ok := targetKinds[resource]
+		if !ok {
+			logger.Info("Unsupported target resource", "resource", resource)
+			continue
 		}
-		for key, value := range enforcer.Spec.RequiredLabels {
-			if currentValue, exists := service.Labels[key]; !exists || currentValue != value {
-				service.Labels[key] = value
-				needsUpdate = true
-			}
-		}
-
-		// Check and add required annotations
-		if service.Annotations == nil {
-			service.Annotations = make(map[string]string)
-		}
-		for key, value := range enforcer.Spec.RequiredAnnotations {
-			if currentValue, exists := service.Annotations[key]; !exists || currentValue != value {
-				service.Annotations[key] = value
-				needsUpdate = true
-			}
-		}
-
-		if needsUpdate {
-			if err := r.Update(ctx, &service); err != nil {
-				logger.Error(err, "Failed to update service", "name", service.Name)
-				continue
-			}
-			correctedCount++
-			logger.Info("Corrected service labels/annotations", "name", service.Name)
+		count, err := r.enforceOn(ctx, enforcer, gvk)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", resource, err))
 		}
+		corrected[string(resource)] = int32(count)
 	}
 
-	return correctedCount, nil
+	return corrected, errors.Join(errs...)
 }
 
-// enforceOnConfigMaps ensures configmaps have required labels/annotations
-func (r *LabelEnforcerReconciler) enforceOnConfigMaps(ctx context.Context, enforcer *aiopsv1alpha1.LabelEnforcer) (int, error) {
+// enforceOn ensures resources of the kind have required labels/annotations
+func (r *LabelEnforcerReconciler) enforceOn(ctx context.Context, enforcer *aiopsv1alpha1.LabelEnforcer, gvk schema.GroupVersionKind) (int, error) {
 	logger := log.FromContext(ctx)
 	correctedCount := 0
 
-	var configMapList corev1.ConfigMapList
-	listOpts := []client.ListOption{
-		client.InNamespace(enforceNamespace(enforcer)),
-	}
-
-	if enforcer.Spec.LabelSelector != nil {
-		selector := client.MatchingLabels(enforcer.Spec.LabelSelector)
-		listOpts = append(listOpts, selector)
-	}
-
-	if err := r.List(ctx, &configMapList, listOpts...); err != nil {
+	list, err := r.listTargets(ctx, enforcer, gvk)
+	if err != nil {
 		return correctedCount, err
 	}
 
-	for _, configMap := range configMapList.Items {
-		needsUpdate := false
-
-		// Check and add required labels
-		if configMap.Labels == nil {
-			configMap.Labels = make(map[string]string)
-		}
-		for key, value := range enforcer.Spec.RequiredLabels {
-			if currentValue, exists := configMap.Labels[key]; !exists || currentValue != value {
-				configMap.Labels[key] = value
-				needsUpdate = true
-			}
+	for i := range list.Items {
+		obj := &list.Items[i]
+		original := obj.DeepCopy()
+		changed, refused := applyRequired(obj, enforcer)
+		if len(refused) > 0 {
+			logger.Info("Not overwriting protected keys", "kind", gvk.Kind, "namespace", obj.GetNamespace(), "name", obj.GetName(), "keys", refused)
 		}
-
-		// Check and add required annotations
-		if configMap.Annotations == nil {
-			configMap.Annotations = make(map[string]string)
+		if !changed {
+			continue
 		}
-		for key, value := range enforcer.Spec.RequiredAnnotations {
-			if currentValue, exists := configMap.Annotations[key]; !exists || currentValue != value {
-				configMap.Annotations[key] = value
-				needsUpdate = true
-			}
-		}
-
-		if needsUpdate {
-			if err := r.Update(ctx, &configMap); err != nil {
-				logger.Error(err, "Failed to update configmap", "name", configMap.Name)
-				continue
-			}
-			correctedCount++
-			logger.Info("Corrected configmap labels/annotations", "name", configMap.Name)
+		// Patch only what changed, so concurrent changes to the rest of the resource aren't overwritten
+		if err := r.Patch(ctx, obj, client.MergeFrom(original)); err != nil {
+			logger.Error(err, "Failed to update resource", "kind", gvk.Kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
+			continue
 		}
+		correctedCount++
+		logger.Info("Corrected labels/annotations", "kind", gvk.Kind, "namespace", obj.GetNamespace(), "name", obj.GetName())
 	}
 
 	return correctedCount, nil
 }
 
-// enforceOnSecrets ensures secrets have required labels/annotations
-func (r *LabelEnforcerReconciler) enforceOnSecrets(ctx context.Context, enforcer *aiopsv1alpha1.LabelEnforcer) (int, error) {
-	logger := log.FromContext(ctx)
-	correctedCount := 0
-
-	var secretList corev1.SecretList
+// listTargets lists the enforcer's target resources of the kind, leaving out excluded ones
+func (r *LabelEnforcerReconciler) listTargets(ctx context.Context, enforcer *aiopsv1alpha1.LabelEnforcer, gvk schema.GroupVersionKind) (*unstructured.UnstructuredList, error) {
+	exclude, err := exclusionsOf(enforcer)
+	if err != nil {
+		return nil, err
+	}
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
 	listOpts := []client.ListOption{
 		client.InNamespace(enforceNamespace(enforcer)),
 	}
@@ -352,46 +273,20 @@ func (r *LabelEnforcerReconciler) enforceOnSecrets(ctx context.Context, enforcer
 		listOpts = append(listOpts, selector)
 	}
 
-	if err := r.List(ctx, &secretList, listOpts...); err != nil {
-		return correctedCount, err
+	if err := r.List(ctx, list, listOpts...); err != nil {
+		return nil, err
 	}
+	list.Items = slices.DeleteFunc(list.Items, func(obj unstructured.Unstructured) bool { return exclude.excludes(obj.GetNamespace(), obj.GetName()) })
+	return list, nil
+}
 
-	for _, secret := range secretList.Items {
-		needsUpdate := false
-
-		// Check and add required labels
-		if secret.Labels == nil {
-			secret.Labels = make(map[string]string)
-		}
-		for key, value := range enforcer.Spec.RequiredLabels {
-			if currentValue, exists := secret.Labels[key]; !exists || currentValue != value {
-				secret.Labels[key] = value
-				needsUpdate = true
-			}
-		}
-
-		// Check and add required annotations
-		if secret.Annotations == nil {
-			secret.Annotations = make(map[string]string)
-		}
-		for key, value := range enforcer.Spec.RequiredAnnotations {
-			if currentValue, exists := secret.Annotations[key]; !exists || currentValue != value {
-				secret.Annotations[key] = value
-				needsUpdate = true
-			}
-		}
-
-		if needsUpdate {
-			if err := r.Update(ctx, &secret); err != nil {
-				logger.Error(err, "Failed to update secret", "name", secret.Name)
-				continue
-			}
-			correctedCount++
-			logger.Info("Corrected secret labels/annotations", "name", secret.Name)
-		}
+// joinResources lists the target resources in messages
+func joinResources(resources []aiopsv1alpha1.TargetResource) string {
+	names := make([]string, len(resources))
+	for i, resource := range resources {
+		names[i] = string(resource)
 	}
-
-	return correctedCount, nil
+	return strings.Join(names, ", ")
 }
 
 // enforceNamespace returns the namespace to enforce in, defaulting to all namespaces if empty
@@ -402,9 +297,25 @@ func enforceNamespace(enforcer *aiopsv1alpha1.LabelEnforcer) string {
 	return ""
 }
 
-// SetupWithManager sets up the controller with the Manager.
+// resyncInterval returns how long until all target resources are checked again
+func resyncInterval(enforcer *aiopsv1alpha1.LabelEnforcer) time.Duration {
+	if enforcer.Spec.ResyncIntervalSeconds > 0 {
+		return time.Duration(enforcer.Spec.ResyncIntervalSeconds) * time.Second
+	}
+	return 10 * time.Minute
+}
+
+// SetupWithManager sets up the controller with the Manager. Target resources are watched by their
+// metadata only, so created resources and changed labels and annotations are corrected right away
+// without caching whole pods and secrets.
 func (r *LabelEnforcerReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&aiopsv1alpha1.LabelEnforcer{}).
-		Complete(r)
+	b := ctrl.NewControllerManagedBy(mgr).
+		For(&aiopsv1alpha1.LabelEnforcer{})
+	for resource, gvk := range targetKinds {
+		obj := &metav1.PartialObjectMetadata{}
+		obj.SetGroupVersionKind(gvk)
+		b = b.WatchesMetadata(obj, handler.EnqueueRequestsFromMapFunc(r.enforcersFor(resource)),
+			builder.WithPredicates(predicate.Or(predicate.LabelChangedPredicate{}, predicate.AnnotationChangedPredicate{})))
+	}
+	return b.Complete(r)
 }
@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var violationsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "label_enforcer_violations",
+	Help: "Number of target resources missing required labels or annotations, reported by LabelEnforcers in Report mode",
+}, []string{"namespace", "name", "resource"})
+
+func init() {
+	// Registered with the controller-runtime registry so it is served on the manager's metrics endpoint
+	metrics.Registry.MustRegister(violationsGauge)
+}
+
+// recordViolations sets the violation gauges of a LabelEnforcer, one per target resource
+func recordViolations(namespace, name string, violations map[string]int) {
+	deleteMetrics(namespace, name)
+	for resource, count := range violations {
+		violationsGauge.WithLabelValues(namespace, name, resource).Set(float64(count))
+	}
+}
+
+// deleteMetrics removes all series for a LabelEnforcer
+func deleteMetrics(namespace, name string) {
+	violationsGauge.DeletePartialMatch(prometheus.Labels{"namespace": namespace, "name": name})
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"slices"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/prophet/operators/label-enforcer/api/v1alpha1"
+)
+
+// podTemplateKinds are the target kinds whose pod template labels and annotations can be enforced
+var podTemplateKinds = []string{"Deployment", "StatefulSet", "DaemonSet"}
+
+// propagates reports whether the enforcer's labels and annotations also go into pod templates.
+// Changing a pod template rolls out new pods, so it's only done with allowRollout.
+func propagates(enforcer *aiopsv1alpha1.LabelEnforcer) bool {
+	return enforcer.Spec.PropagateToPodTemplate && enforcer.Spec.AllowRollout
+}
+
+// applyToPodTemplate sets the enforcer's required labels and annotations in the workload's pod
+// template, reporting whether anything changed and the keys it refused to overwrite. Besides the
+// protected keys, labels the workload's selector uses are kept, as the template must keep matching it.
+func applyToPodTemplate(obj *unstructured.Unstructured, enforcer *aiopsv1alpha1.LabelEnforcer) (bool, []string) {
+	selectorKeys := selectorKeysOf(obj)
+	keepLabel := func(key string) bool { return protected(enforcer, key) || slices.Contains(selectorKeys, key) }
+	keepAnnotation := func(key string) bool { return protected(enforcer, key) }
+
+	changed := false
+	var refused []string
+	for _, field := range []struct {
+		name     string
+		required map[string]string
+		keep     func(string) bool
+	}{
+		{"labels", enforcer.Spec.RequiredLabels, keepLabel},
+		{"annotations", enforcer.Spec.RequiredAnnotations, keepAnnotation},
+	} {
+		path := []string{"spec", "template", "metadata", field.name}
+		current, _, _ := unstructured.NestedStringMap(obj.Object, path...)
+		updated, fieldChanged, fieldRefused := withRequired(current, field.required, field.keep)
+		refused = append(refused, fieldRefused...)
+		if !fieldChanged {
+			continue
+		}
+		if err := unstructured.SetNestedStringMap(obj.Object, updated, path...); err != nil {
+			continue
+		}
+		changed = true
+	}
+	return changed, refused
+}
+
+// selectorKeysOf returns the label keys in the workload's selector
+func selectorKeysOf(obj *unstructured.Unstructured) []string {
+	var keys []string
+	matchLabels, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "selector", "matchLabels")
+	for key := range matchLabels {
+		keys = append(keys, key)
+	}
+	expressions, _, _ := unstructured.NestedSlice(obj.Object, "spec", "selector", "matchExpressions")
+	for _, expression := range expressions {
+		if expression, ok := expression.(map[string]interface{}); ok {
+			if key, ok := expression["key"].(string); ok {
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/prophet/operators/label-enforcer/api/v1alpha1"
+)
+
+// maxViolations is how many non-compliant resources are listed in status
+const maxViolations = 100
+
+// reportViolations finds the target resources missing required labels or annotations without
+// changing them, returning the first maxViolations of them and how many there are per target resource
+func (r *LabelEnforcerReconciler) reportViolations(ctx context.Context, enforcer *aiopsv1alpha1.LabelEnforcer) ([]aiopsv1alpha1.Violation, map[string]int, error) {
+	var violations []aiopsv1alpha1.Violation
+	counts := map[string]int{}
+	var errs []error
+
+	for _, resource := range targetResources(enforcer) {
+		gvk, ok := targetKinds[resource]
+		if !ok {
+			continue
+		}
+		found, err := r.violationsOf(ctx, enforcer, resource, gvk)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", resource, err))
+			continue
+		}
+		counts[string(resource)] = len(found)
+		violations = append(violations, found...)
+	}
+
+	if len(violations) > maxViolations {
+		violations = violations[:maxViolations]
+	}
+	return violations, counts, errors.Join(errs...)
+}
+
+// violationsOf lists the resources of the kind that are missing required labels or annotations
+func (r *LabelEnforcerReconciler) violationsOf(ctx context.Context, enforcer *aiopsv1alpha1.LabelEnforcer, resource aiopsv1alpha1.TargetResource, gvk schema.GroupVersionKind) ([]aiopsv1alpha1.Violation, error) {
+	list, err := r.listTargets(ctx, enforcer, gvk)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []aiopsv1alpha1.Violation
+	for _, obj := range list.Items {
+		missingLabels := missingKeys(obj.GetLabels(), enforcer.Spec.RequiredLabels)
+		missingAnnotations := missingKeys(obj.GetAnnotations(), enforcer.Spec.RequiredAnnotations)
+		if len(missingLabels) == 0 && len(missingAnnotations) == 0 {
+			continue
+		}
+		violations = append(violations, aiopsv1alpha1.Violation{
+			Resource:           string(resource),
+			Namespace:          obj.GetNamespace(),
+			Name:               obj.GetName(),
+			MissingLabels:      missingLabels,
+			MissingAnnotations: missingAnnotations,
+		})
+	}
+	return violations, nil
+}
+
+// missingKeys returns the sorted required keys that current doesn't have with the required value
+func missingKeys(current, required map[string]string) []string {
+	var missing []string
+	for key, value := range required {
+		if currentValue, exists := current[key]; !exists || currentValue != value {
+			missing = append(missing, key)
+		}
+	}
+	slices.Sort(missing)
+	return missing
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"context"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/prophet/operators/label-enforcer/api/v1alpha1"
+)
+
+// enforcersFor returns a map function that enqueues the LabelEnforcers a created or changed
+// resource of the target resource is checked by
+func (r *LabelEnforcerReconciler) enforcersFor(resource aiopsv1alpha1.TargetResource) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		var enforcers aiopsv1alpha1.LabelEnforcerList
+		if err := r.List(ctx, &enforcers); err != nil {
+			log.FromContext(ctx).Error(err, "Failed to list LabelEnforcers")
+			return nil
+		}
+
+		var requests []reconcile.Request
+		for i := range enforcers.Items {
+			enforcer := &enforcers.Items[i]
+			// Enforcers in Webhook mode leave existing resources to the webhook
+			if enforcer.Spec.EnforcementMode == "Webhook" && enforcer.Spec.Action != "Report" {
+				continue
+			}
+			if ok, _ := selects(enforcer, resource, obj.GetNamespace(), obj.GetName(), obj.GetLabels()); ok {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(enforcer)})
+			}
+		}
+		return requests
+	}
+}
+
+// selects reports whether a resource with the namespace, name and labels is one of the enforcer's
+// targets; it fails when the enforcer's exclusions are invalid
+func selects(enforcer *aiopsv1alpha1.LabelEnforcer, resource aiopsv1alpha1.TargetResource, namespace, name string, objLabels map[string]string) (bool, error) {
+	if !slices.Contains(targetResources(enforcer), resource) {
+		return false, nil
+	}
+	if enforcer.Spec.Namespace != "" && enforcer.Spec.Namespace != namespace {
+		return false, nil
+	}
+	if !labels.SelectorFromSet(enforcer.Spec.LabelSelector).Matches(labels.Set(objLabels)) {
+		return false, nil
+	}
+	exclude, err := exclusionsOf(enforcer)
+	if err != nil {
+		return false, err
+	}
+	return !exclude.excludes(namespace, name), nil
+}
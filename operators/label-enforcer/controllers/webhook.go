@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/prophet/operators/label-enforcer/api/v1alpha1"
+)
+
+// WebhookPath is where the label injection webhook is served
+const WebhookPath = "/mutate-label-enforcer"
+
+//+kubebuilder:webhook:path=/mutate-label-enforcer,mutating=true,failurePolicy=ignore,sideEffects=None,groups="";apps,resources=pods;services;configmaps;secrets;deployments;statefulsets;daemonsets,verbs=create;update,versions=v1,name=mlabelenforcer.aiops.prophet.io,admissionReviewVersions=v1
+
+// LabelInjector is a mutating admission webhook that adds the required labels and annotations of
+// LabelEnforcers in Webhook or Both mode to resources as they're created or updated
+type LabelInjector struct {
+	Client client.Client
+}
+
+// SetupWebhookWithManager registers the webhook with the manager's webhook server
+func (w *LabelInjector) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	mgr.GetWebhookServer().Register(WebhookPath, &webhook.Admission{Handler: w})
+	return nil
+}
+
+// Handle patches the object with the labels and annotations of every enforcer that matches it
+func (w *LabelInjector) Handle(ctx context.Context, req admission.Request) admission.Response {
+	logger := log.FromContext(ctx)
+
+	var enforcers aiopsv1alpha1.LabelEnforcerList
+	if err := w.Client.List(ctx, &enforcers); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	obj := &unstructured.Unstructured{}
+	if err := obj.UnmarshalJSON(req.Object.Raw); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	var applied []string
+	for i := range enforcers.Items {
+		enforcer := &enforcers.Items[i]
+		if !webhookEnforced(enforcer) || !admits(ctx, enforcer, req, obj) {
+			continue
+		}
+		changed, refused := applyRequired(obj, enforcer)
+		if len(refused) > 0 {
+			logger.Info("Not overwriting protected keys", "enforcer", enforcer.Namespace+"/"+enforcer.Name,
+				"namespace", req.Namespace, "name", obj.GetName(), "keys", refused)
+		}
+		if changed {
+			applied = append(applied, enforcer.Namespace+"/"+enforcer.Name)
+		}
+	}
+	if len(applied) == 0 {
+		return admission.Allowed("")
+	}
+
+	mutated, err := json.Marshal(obj)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	logger.Info("Added required labels/annotations", "resource", req.Resource.Resource,
+		"namespace", req.Namespace, "name", obj.GetName(), "enforcers", applied)
+	return admission.PatchResponseFromRaw(req.Object.Raw, mutated)
+}
+
+// admits reports whether the admitted object is one of the enforcer's target resources
+func admits(ctx context.Context, enforcer *aiopsv1alpha1.LabelEnforcer, req admission.Request, obj *unstructured.Unstructured) bool {
+	resource := aiopsv1alpha1.TargetResource(req.Resource.Resource)
+	gvk, ok := targetKinds[resource]
+	if !ok || req.Resource.Group != gvk.Group {
+		return false
+	}
+	// Objects being created may have only a generateName yet
+	name := obj.GetName()
+	if name == "" {
+		name = obj.GetGenerateName()
+	}
+	ok, err := selects(enforcer, resource, req.Namespace, name, obj.GetLabels())
+	if err != nil {
+		// Without valid exclusions it's unknown what the enforcer should leave alone
+		log.FromContext(ctx).Error(err, "Skipping enforcer", "enforcer", enforcer.Namespace+"/"+enforcer.Name)
+	}
+	return ok
+}
+
+// webhookEnforced reports whether the webhook applies the enforcer's labels and annotations;
+// enforcers in Report mode never mutate
+func webhookEnforced(enforcer *aiopsv1alpha1.LabelEnforcer) bool {
+	if enforcer.Spec.Action == "Report" {
+		return false
+	}
+	return enforcer.Spec.EnforcementMode == "Webhook" || enforcer.Spec.EnforcementMode == "Both"
+}
+
+// applyRequired sets the enforcer's required labels and annotations on the object, and on its pod
+// template when the enforcer propagates them, reporting whether anything changed and the
+// protected keys it refused to overwrite
+func applyRequired(obj *unstructured.Unstructured, enforcer *aiopsv1alpha1.LabelEnforcer) (bool, []string) {
+	keep := func(key string) bool { return protected(enforcer, key) }
+	changed := false
+	objLabels, labelsChanged, refused := withRequired(obj.GetLabels(), enforcer.Spec.RequiredLabels, keep)
+	if labelsChanged {
+		obj.SetLabels(objLabels)
+		changed = true
+	}
+	annotations, annotationsChanged, refusedAnnotations := withRequired(obj.GetAnnotations(), enforcer.Spec.RequiredAnnotations, keep)
+	if annotationsChanged {
+		obj.SetAnnotations(annotations)
+		changed = true
+	}
+	refused = append(refused, refusedAnnotations...)
+
+	if propagates(enforcer) && slices.Contains(podTemplateKinds, obj.GetKind()) {
+		templateChanged, refusedTemplate := applyToPodTemplate(obj, enforcer)
+		changed = changed || templateChanged
+		refused = append(refused, refusedTemplate...)
+	}
+	return changed, refused
+}
+
+// withRequired returns current with the required keys set, whether any had to be set, and the
+// keys that were left with their current value because keep returned true
+func withRequired(current, required map[string]string, keep func(key string) bool) (map[string]string, bool, []string) {
+	changed := false
+	var refused []string
+	for key, value := range required {
+		currentValue, exists := current[key]
+		if exists && currentValue == value {
+			continue
+		}
+		if exists && keep(key) {
+			refused = append(refused, key)
+			continue
+		}
+		if current == nil {
+			current = make(map[string]string)
+		}
+		current[key] = value
+		changed = true
+	}
+	return current, changed, refused
+}
@@ -0,0 +1,26 @@
+// Package builders builds LabelEnforcers for tests. Builders return valid objects that options
+// change.
+package builders
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	aiopsv1alpha1 "github.com/prophet-aiops/prophet/operators/label-enforcer/api/v1alpha1"
+)
+
+// LabelEnforcer returns a LabelEnforcer named team requiring team=shop on the Deployments and
+// StatefulSets in namespace
+func LabelEnforcer(namespace string, options ...func(*aiopsv1alpha1.LabelEnforcer)) *aiopsv1alpha1.LabelEnforcer {
+	labelEnforcer := &aiopsv1alpha1.LabelEnforcer{
+		ObjectMeta: metav1.ObjectMeta{Name: "team", Namespace: namespace},
+		Spec: aiopsv1alpha1.LabelEnforcerSpec{
+			TargetResources: []aiopsv1alpha1.TargetResource{"deployments", "statefulsets"},
+			Namespace:       namespace,
+			RequiredLabels:  map[string]string{"team": "shop"},
+		},
+	}
+	for _, option := range options {
+		option(labelEnforcer)
+	}
+	return labelEnforcer
+}
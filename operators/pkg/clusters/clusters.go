@@ -0,0 +1,152 @@
+// Package clusters builds clients for remote clusters registered through kubeconfig Secrets,
+// including the "<cluster>-kubeconfig" Secrets written by Cluster API. Callers must only pass
+// Secrets from the namespace of the resource naming the cluster.
+package clusters
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultKey is the Secret key holding the kubeconfig when one is referenced explicitly
+	DefaultKey = "kubeconfig"
+
+	// ClusterAPIKey is the key Cluster API uses in "<cluster>-kubeconfig" Secrets
+	ClusterAPIKey = "value"
+)
+
+// Ref locates the kubeconfig of a remote cluster
+type Ref struct {
+	// Cluster is the cluster name, used in errors
+	Cluster string
+
+	// Secret is the Secret holding the kubeconfig
+	Secret types.NamespacedName
+
+	// Key is the Secret key holding the kubeconfig
+	Key string
+}
+
+// Options configures the clients built for remote clusters
+type Options struct {
+	// QPS is the per-cluster API request rate limit. Default: 5
+	QPS float32
+
+	// Burst is the per-cluster API request burst. Default: 10
+	Burst int
+}
+
+// Registry caches one client and REST config per remote cluster. It is safe for concurrent use.
+type Registry struct {
+	mu      sync.Mutex
+	clients map[types.NamespacedName]cachedClient
+}
+
+type cachedClient struct {
+	version string
+	config  *rest.Config
+	client  client.Client
+}
+
+// NewRegistry returns an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{clients: map[types.NamespacedName]cachedClient{}}
+}
+
+// Client returns a client for the cluster, reading its kubeconfig with reader.
+// Clients are rebuilt when the kubeconfig Secret changes.
+func (r *Registry) Client(ctx context.Context, reader client.Reader, scheme *runtime.Scheme, ref Ref, options Options) (client.Client, error) {
+	cached, err := r.get(ctx, reader, scheme, ref, options)
+	if err != nil {
+		return nil, err
+	}
+	return cached.client, nil
+}
+
+// Config returns the REST config for the cluster, for APIs the client doesn't cover such as pod exec.
+// The config is a copy the caller may modify.
+func (r *Registry) Config(ctx context.Context, reader client.Reader, scheme *runtime.Scheme, ref Ref, options Options) (*rest.Config, error) {
+	cached, err := r.get(ctx, reader, scheme, ref, options)
+	if err != nil {
+		return nil, err
+	}
+	return rest.CopyConfig(cached.config), nil
+}
+
+func (r *Registry) get(ctx context.Context, reader client.Reader, scheme *runtime.Scheme, ref Ref, options Options) (cachedClient, error) {
+	var secret corev1.Secret
+	if err := reader.Get(ctx, ref.Secret, &secret); err != nil {
+		return cachedClient{}, fmt.Errorf("failed to get kubeconfig secret for cluster %s: %w", ref.Cluster, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cached, ok := r.clients[ref.Secret]; ok && cached.version == secret.ResourceVersion {
+		return cached, nil
+	}
+
+	kubeconfig, ok := secret.Data[ref.Key]
+	if !ok {
+		return cachedClient{}, fmt.Errorf("kubeconfig secret %s for cluster %s has no key %q", ref.Secret, ref.Cluster, ref.Key)
+	}
+	config, err := restConfig(kubeconfig)
+	if err != nil {
+		return cachedClient{}, fmt.Errorf("invalid kubeconfig for cluster %s: %w", ref.Cluster, err)
+	}
+
+	config.QPS = options.QPS
+	if config.QPS == 0 {
+		config.QPS = 5
+	}
+	config.Burst = options.Burst
+	if config.Burst == 0 {
+		config.Burst = 10
+	}
+
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return cachedClient{}, fmt.Errorf("failed to create client for cluster %s: %w", ref.Cluster, err)
+	}
+	cached := cachedClient{version: secret.ResourceVersion, config: config, client: c}
+	r.clients[ref.Secret] = cached
+	return cached, nil
+}
+
+// restConfig builds a REST config from the kubeconfig. Kubeconfigs come from Secrets users write,
+// so anything that makes the operator run a command or read one of its own files is refused:
+// exec and auth provider plugins, and token, certificate and key files.
+func restConfig(kubeconfig []byte) (*rest.Config, error) {
+	config, err := clientcmd.Load(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	for name, authInfo := range config.AuthInfos {
+		switch {
+		case authInfo.Exec != nil:
+			return nil, fmt.Errorf("user %q: exec credential plugins are not allowed", name)
+		case authInfo.AuthProvider != nil:
+			return nil, fmt.Errorf("user %q: auth provider plugins are not allowed", name)
+		case authInfo.TokenFile != "":
+			return nil, fmt.Errorf("user %q: tokenFile is not allowed, use token", name)
+		case authInfo.ClientCertificate != "":
+			return nil, fmt.Errorf("user %q: client-certificate is not allowed, use client-certificate-data", name)
+		case authInfo.ClientKey != "":
+			return nil, fmt.Errorf("user %q: client-key is not allowed, use client-key-data", name)
+		}
+	}
+	for name, cluster := range config.Clusters {
+		if cluster.CertificateAuthority != "" {
+			return nil, fmt.Errorf("cluster %q: certificate-authority is not allowed, use certificate-authority-data", name)
+		}
+	}
+	return clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}).ClientConfig()
+}
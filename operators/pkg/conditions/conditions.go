@@ -0,0 +1,71 @@
+// Package conditions maintains the standard Ready, Progressing and Degraded status conditions
+// that kstatus, Argo CD, Flux and "kubectl wait --for=condition=Ready" understand,
+// and the Triggered and Remediating conditions shared by the alerting and remediating operators.
+// Conditions are updated in place, so a condition's lastTransitionTime only changes when its status does.
+package conditions
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// Ready is True when the resource is reconciled and nothing needs attention
+	Ready = "Ready"
+
+	// Progressing is True while the operator is still acting on the resource
+	Progressing = "Progressing"
+
+	// Degraded is True when the reconcile failed or the watched target is unhealthy
+	Degraded = "Degraded"
+
+	// Triggered is True while the resource's alert fires: a threshold is exceeded,
+	// the target is unhealthy or issues were found
+	Triggered = "Triggered"
+
+	// Remediating is True while the operator is acting on the target to fix it,
+	// including waiting out a cooldown between remediations
+	Remediating = "Remediating"
+)
+
+// Summary is the outcome of a reconcile
+type Summary struct {
+	// Progressing is true while the operator is still acting, e.g. remediating
+	Progressing bool
+
+	// Degraded is true when the reconcile failed or the watched target is unhealthy
+	Degraded bool
+
+	// Reason is a CamelCase reason for the summary. Default: Reconciled
+	Reason string
+
+	// Message explains the summary
+	Message string
+}
+
+// Set adds or updates a condition for the observed generation
+func Set(conditions *[]metav1.Condition, generation int64, conditionType string, status bool, reason, message string) {
+	condition := metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             reason,
+		Message:            message,
+	}
+	if status {
+		condition.Status = metav1.ConditionTrue
+	}
+	meta.SetStatusCondition(conditions, condition)
+}
+
+// Apply sets Ready, Progressing and Degraded from the summary.
+// Ready is True only when the resource is neither progressing nor degraded.
+func Apply(conditions *[]metav1.Condition, generation int64, summary Summary) {
+	reason := summary.Reason
+	if reason == "" {
+		reason = "Reconciled"
+	}
+	Set(conditions, generation, Ready, !summary.Progressing && !summary.Degraded, reason, summary.Message)
+	Set(conditions, generation, Progressing, summary.Progressing, reason, summary.Message)
+	Set(conditions, generation, Degraded, summary.Degraded, reason, summary.Message)
+}
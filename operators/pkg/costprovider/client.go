@@ -0,0 +1,159 @@
+// Package costprovider is a small client for the OpenCost/Kubecost allocation API.
+package costprovider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prophet-aiops/pkg/httpclient"
+)
+
+// DefaultEndpoint is the in-cluster OpenCost API endpoint
+const DefaultEndpoint = "http://opencost.opencost.svc.cluster.local:9003"
+
+// Config configures how the cost provider API is reached
+type Config struct {
+	// Endpoint is the base URL of the OpenCost/Kubecost API
+	Endpoint string
+
+	// CABundle is a PEM encoded CA bundle used to verify the provider's certificate
+	CABundle []byte
+
+	// ClientCert and ClientKey are a PEM encoded client certificate and key for mutual TLS
+	ClientCert []byte
+	ClientKey  []byte
+
+	// InsecureSkipVerify disables TLS certificate verification
+	InsecureSkipVerify bool
+
+	// BearerToken is sent as "Authorization: Bearer <token>"
+	BearerToken string
+
+	// Username and Password are sent as HTTP basic auth
+	Username string
+	Password string
+
+	// APIKey is a Kubecost API key, sent in the X-API-Key header
+	APIKey string
+
+	// Timeout is the request timeout (default: 10s)
+	Timeout time.Duration
+}
+
+// Client queries the allocation API of a cost provider
+type Client struct {
+	config     Config
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the given configuration
+func NewClient(config Config) (*Client, error) {
+	if config.Endpoint == "" {
+		config.Endpoint = DefaultEndpoint
+	}
+	config.Endpoint = strings.TrimRight(config.Endpoint, "/")
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	httpClient, err := httpclient.New(httpclient.Config{
+		CABundle:           config.CABundle,
+		ClientCert:         config.ClientCert,
+		ClientKey:          config.ClientKey,
+		InsecureSkipVerify: config.InsecureSkipVerify,
+		Timeout:            config.Timeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid cost provider TLS configuration: %w", err)
+	}
+
+	return &Client{
+		config:     config,
+		httpClient: httpClient,
+	}, nil
+}
+
+// Allocation queries /allocation with the given parameters and returns the
+// total cost per allocation name (namespace, controller, label value, ...)
+func (c *Client) Allocation(ctx context.Context, params url.Values) (map[string]float64, error) {
+	reqURL := fmt.Sprintf("%s/allocation?%s", c.config.Endpoint, params.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.authenticate(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch cost data (OpenCost may not be deployed): %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("OpenCost API returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var data map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+	return AllocationCosts(data["data"]), nil
+}
+
+// authenticate adds the configured credentials to the request
+func (c *Client) authenticate(req *http.Request) {
+	switch {
+	case c.config.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+c.config.BearerToken)
+	case c.config.Username != "":
+		req.SetBasicAuth(c.config.Username, c.config.Password)
+	}
+	if c.config.APIKey != "" {
+		req.Header.Set("X-API-Key", c.config.APIKey)
+	}
+}
+
+// AllocationCosts flattens an allocation "data" field into total cost per
+// allocation name. OpenCost returns either a single set of allocations or a
+// list of sets (one per step in the window); costs for the same name are summed.
+func AllocationCosts(data interface{}) map[string]float64 {
+	costs := map[string]float64{}
+	var sets []interface{}
+	switch d := data.(type) {
+	case map[string]interface{}:
+		sets = []interface{}{d}
+	case []interface{}:
+		sets = d
+	}
+
+	for _, set := range sets {
+		allocations, ok := set.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for name, allocation := range allocations {
+			if alloc, ok := allocation.(map[string]interface{}); ok {
+				if cost, ok := alloc["totalCost"].(float64); ok {
+					costs[name] += cost
+				}
+			}
+		}
+	}
+	return costs
+}
+
+// Total sums all allocation costs
+func Total(costs map[string]float64) float64 {
+	total := 0.0
+	for _, cost := range costs {
+		total += cost
+	}
+	return total
+}
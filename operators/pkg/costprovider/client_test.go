@@ -0,0 +1,101 @@
+package costprovider
+
+import (
+	"context"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/prophet-aiops/pkg/testenv"
+)
+
+func TestAllocation(t *testing.T) {
+	opencost := testenv.NewOpenCost(t, map[string]float64{"shop": 12.5, "__idle__": 3})
+	client, err := NewClient(Config{Endpoint: opencost.URL + "/", BearerToken: "secret", APIKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	costs, err := client.Allocation(context.Background(), url.Values{"window": {"1d"}, "aggregate": {"namespace"}})
+	if err != nil {
+		t.Fatalf("Allocation: %v", err)
+	}
+	if want := map[string]float64{"shop": 12.5, "__idle__": 3}; !reflect.DeepEqual(costs, want) {
+		t.Errorf("got costs %v, want %v", costs, want)
+	}
+
+	requests := opencost.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	req := requests[0]
+	if got := req.URL.Query().Get("aggregate"); got != "namespace" {
+		t.Errorf("got aggregate %q, want namespace", got)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("got Authorization %q", got)
+	}
+	if got := req.Header.Get("X-API-Key"); got != "key" {
+		t.Errorf("got X-API-Key %q", got)
+	}
+}
+
+func TestAllocationBasicAuth(t *testing.T) {
+	opencost := testenv.NewOpenCost(t, nil)
+	client, err := NewClient(Config{Endpoint: opencost.URL, Username: "prophet", Password: "pass"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Allocation(context.Background(), url.Values{}); err != nil {
+		t.Fatalf("Allocation: %v", err)
+	}
+	username, password, ok := opencost.Requests()[0].BasicAuth()
+	if !ok || username != "prophet" || password != "pass" {
+		t.Errorf("got basic auth %q/%q (%v)", username, password, ok)
+	}
+}
+
+func TestAllocationError(t *testing.T) {
+	opencost := testenv.NewOpenCost(t, nil)
+	client, err := NewClient(Config{Endpoint: opencost.URL + "/missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Allocation(context.Background(), url.Values{}); err == nil {
+		t.Error("expected an error for a non-200 response")
+	}
+}
+
+func TestAllocationCosts(t *testing.T) {
+	tests := map[string]struct {
+		data interface{}
+		want map[string]float64
+	}{
+		"single set": {
+			data: map[string]interface{}{"web": map[string]interface{}{"totalCost": 1.5}},
+			want: map[string]float64{"web": 1.5},
+		},
+		"sets summed by name": {
+			data: []interface{}{
+				map[string]interface{}{"web": map[string]interface{}{"totalCost": 1.5}},
+				map[string]interface{}{"web": map[string]interface{}{"totalCost": 2.0}, "db": map[string]interface{}{"totalCost": 4.0}},
+			},
+			want: map[string]float64{"web": 3.5, "db": 4},
+		},
+		"malformed entries skipped": {
+			data: []interface{}{"oops", map[string]interface{}{"web": "oops", "db": map[string]interface{}{"totalCost": "1"}}},
+			want: map[string]float64{},
+		},
+		"no data": {
+			data: nil,
+			want: map[string]float64{},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := AllocationCosts(tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
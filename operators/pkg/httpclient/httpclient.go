@@ -0,0 +1,237 @@
+// Package httpclient builds the HTTP clients Prophet operators use for outbound integrations
+// (cost providers, notification channels, service checks). Clients honor HTTP_PROXY, HTTPS_PROXY
+// and NO_PROXY, and add the process-wide CA bundle, client certificate and headers configured
+// through the PROPHET_HTTP_* environment variables, typically pointing at files mounted from Secrets.
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables holding the process-wide settings
+const (
+	// CAFileEnv names a PEM CA bundle trusted in addition to the system roots
+	CAFileEnv = "PROPHET_HTTP_CA_FILE"
+
+	// ClientCertFileEnv and ClientKeyFileEnv name a PEM client certificate and key for mutual TLS
+	ClientCertFileEnv = "PROPHET_HTTP_CLIENT_CERT_FILE"
+	ClientKeyFileEnv  = "PROPHET_HTTP_CLIENT_KEY_FILE"
+
+	// HeadersFileEnv names a file of "Name: Value" lines added to every request
+	HeadersFileEnv = "PROPHET_HTTP_HEADERS_FILE"
+)
+
+// Config configures an HTTP client
+type Config struct {
+	// CABundle is a PEM encoded CA bundle trusted in addition to the system roots
+	CABundle []byte
+
+	// ClientCert and ClientKey are a PEM encoded client certificate and key for mutual TLS
+	ClientCert []byte
+	ClientKey  []byte
+
+	// InsecureSkipVerify disables TLS certificate verification
+	InsecureSkipVerify bool
+
+	// Headers are added to every request that doesn't already set them
+	Headers map[string]string
+
+	// Timeout is the request timeout. Default: 10s
+	Timeout time.Duration
+}
+
+// maxClients bounds the client cache; when it's full the cache starts over
+const maxClients = 64
+
+var (
+	loadDefaults sync.Once
+	defaults     Config
+	defaultsErr  error
+
+	clientsMu sync.Mutex
+	clients   = map[[sha256.Size]byte]*http.Client{}
+)
+
+// New returns a client for config, merged with the process-wide settings.
+// CA bundles are combined; the process-wide client certificate and headers
+// apply unless config sets its own. Clients are cached by their settings, so
+// callers asking for one on every reconcile share its transport and connections.
+func New(config Config) (*http.Client, error) {
+	loadDefaults.Do(func() {
+		defaults, defaultsErr = fromEnvironment()
+	})
+	if defaultsErr != nil {
+		return nil, defaultsErr
+	}
+
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if len(config.ClientCert) == 0 {
+		config.ClientCert, config.ClientKey = defaults.ClientCert, defaults.ClientKey
+	}
+	caBundle := append(append([]byte{}, defaults.CABundle...), config.CABundle...)
+	headers := map[string]string{}
+	for _, h := range []map[string]string{defaults.Headers, config.Headers} {
+		for name, value := range h {
+			headers[name] = value
+		}
+	}
+
+	key := fingerprint(caBundle, config, headers)
+	clientsMu.Lock()
+	defer clientsMu.Unlock()
+	if client, ok := clients[key]; ok {
+		return client, nil
+	}
+
+	// The default transport already reads HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if len(caBundle) > 0 || len(config.ClientCert) > 0 || config.InsecureSkipVerify {
+		tlsConfig := &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			InsecureSkipVerify: config.InsecureSkipVerify,
+		}
+		if len(caBundle) > 0 {
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(caBundle) {
+				return nil, fmt.Errorf("CA bundle does not contain any valid PEM certificates")
+			}
+			tlsConfig.RootCAs = pool
+		}
+		if len(config.ClientCert) > 0 {
+			cert, err := tls.X509KeyPair(config.ClientCert, config.ClientKey)
+			if err != nil {
+				return nil, fmt.Errorf("invalid client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	var roundTripper http.RoundTripper = transport
+	if len(headers) > 0 {
+		roundTripper = &headerTransport{headers: headers, next: transport}
+	}
+	client := &http.Client{Timeout: config.Timeout, Transport: roundTripper}
+	if len(clients) >= maxClients {
+		// Clients handed out stay usable; only their idle connections go
+		for _, old := range clients {
+			old.CloseIdleConnections()
+		}
+		clear(clients)
+	}
+	clients[key] = client
+	return client, nil
+}
+
+// fingerprint identifies the effective settings of a client
+func fingerprint(caBundle []byte, config Config, headers map[string]string) [sha256.Size]byte {
+	h := sha256.New()
+	write := func(data []byte) {
+		_ = binary.Write(h, binary.BigEndian, uint64(len(data)))
+		h.Write(data)
+	}
+	write(caBundle)
+	write(config.ClientCert)
+	write(config.ClientKey)
+	write([]byte(fmt.Sprintf("%t %d", config.InsecureSkipVerify, config.Timeout)))
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		write([]byte(name))
+		write([]byte(headers[name]))
+	}
+	var key [sha256.Size]byte
+	copy(key[:], h.Sum(nil))
+	return key
+}
+
+// fromEnvironment reads the process-wide settings
+func fromEnvironment() (Config, error) {
+	var config Config
+	read := func(env string) ([]byte, error) {
+		path := os.Getenv(env)
+		if path == "" {
+			return nil, nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", env, err)
+		}
+		return data, nil
+	}
+
+	var err error
+	if config.CABundle, err = read(CAFileEnv); err != nil {
+		return config, err
+	}
+	if config.ClientCert, err = read(ClientCertFileEnv); err != nil {
+		return config, err
+	}
+	if config.ClientKey, err = read(ClientKeyFileEnv); err != nil {
+		return config, err
+	}
+	if (len(config.ClientCert) == 0) != (len(config.ClientKey) == 0) {
+		return config, fmt.Errorf("%s and %s must be set together", ClientCertFileEnv, ClientKeyFileEnv)
+	}
+
+	headers, err := read(HeadersFileEnv)
+	if err != nil {
+		return config, err
+	}
+	config.Headers, err = parseHeaders(headers)
+	return config, err
+}
+
+// parseHeaders parses "Name: Value" lines, skipping blank lines and lines starting with #
+func parseHeaders(data []byte) (map[string]string, error) {
+	headers := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("%s: invalid header line %q, expected \"Name: Value\"", HeadersFileEnv, line)
+		}
+		headers[http.CanonicalHeaderKey(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+	return headers, scanner.Err()
+}
+
+// headerTransport adds headers to requests that don't already set them
+type headerTransport struct {
+	headers map[string]string
+	next    http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for name, value := range t.headers {
+		if req.Header.Get(name) == "" {
+			req.Header.Set(name, value)
+		}
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,179 @@
+// Package maintenance decides whether a maintenance window, during which operators hold off
+// remediation, is open. Recurring windows open on a cron schedule or an RFC 5545 recurrence rule.
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Embed the timezone database so windows can use IANA time zones in distroless images
+	_ "time/tzdata"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Window is a recurring or one-off maintenance window
+type Window struct {
+	// Name identifies the window in status and events
+	Name string
+
+	// Schedule is a cron expression for when a recurring window opens
+	Schedule string
+
+	// Recurrence is an RFC 5545 recurrence rule for when a recurring window opens, used when Schedule is empty
+	Recurrence string
+
+	// Duration a recurring window stays open
+	Duration time.Duration
+
+	// Start and End bound a one-off window
+	Start, End time.Time
+
+	// TimeZone of Schedule and Recurrence, as an IANA name. Default: UTC
+	TimeZone string
+}
+
+// Open returns the index of the first of the windows that is open at now, or -1, and when it
+// closes. Invalid windows are skipped and reported in the error, so one bad window doesn't
+// disable the others.
+func Open(windows []Window, now time.Time) (int, time.Time, error) {
+	var errs []error
+	for i := range windows {
+		until, open, err := windows[i].openAt(now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("maintenance window %s: %w", windows[i].Name, err))
+			continue
+		}
+		if open {
+			return i, until, errors.Join(errs...)
+		}
+	}
+	return -1, time.Time{}, errors.Join(errs...)
+}
+
+// openAt reports whether the window is open at now and when it closes
+func (w *Window) openAt(now time.Time) (time.Time, bool, error) {
+	if w.Schedule == "" && w.Recurrence == "" {
+		if w.Start.IsZero() || w.End.IsZero() {
+			return time.Time{}, false, fmt.Errorf("one of schedule, recurrence or start and end is required")
+		}
+		return w.End, !now.Before(w.Start) && now.Before(w.End), nil
+	}
+
+	if w.Duration <= 0 {
+		return time.Time{}, false, fmt.Errorf("duration is required with a schedule or recurrence")
+	}
+	spec := w.Schedule
+	if spec == "" {
+		var err error
+		if spec, err = recurrenceToCron(w.Recurrence); err != nil {
+			return time.Time{}, false, err
+		}
+	}
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+	location := time.UTC
+	if w.TimeZone != "" {
+		if location, err = time.LoadLocation(w.TimeZone); err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid time zone %q: %w", w.TimeZone, err)
+		}
+	}
+
+	// The window is open if it last opened less than Duration ago
+	opened := schedule.Next(now.In(location).Add(-w.Duration))
+	if opened.After(now) {
+		return time.Time{}, false, nil
+	}
+	return opened.Add(w.Duration), true, nil
+}
+
+var weekdays = map[string]string{"SU": "0", "MO": "1", "TU": "2", "WE": "3", "TH": "4", "FR": "5", "SA": "6"}
+
+// recurrenceToCron converts an RFC 5545 recurrence rule to a cron expression. Rules with FREQ
+// DAILY, WEEKLY or MONTHLY and the BYDAY (without ordinals), BYMONTHDAY, BYHOUR and BYMINUTE
+// parts are supported; the window opens at BYHOUR and BYMINUTE, defaulting to midnight.
+func recurrenceToCron(rule string) (string, error) {
+	parts := map[string]string{}
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:"), ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return "", fmt.Errorf("invalid recurrence part %q", part)
+		}
+		parts[strings.ToUpper(key)] = strings.ToUpper(value)
+	}
+
+	minute, hour, dayOfMonth, dayOfWeek := "0", "0", "*", "*"
+	for key, value := range parts {
+		switch key {
+		case "FREQ", "WKST":
+		case "INTERVAL":
+			if value != "1" {
+				return "", fmt.Errorf("INTERVAL other than 1 is not supported")
+			}
+		case "BYMINUTE":
+			if err := checkNumbers(value, 0, 59); err != nil {
+				return "", fmt.Errorf("invalid BYMINUTE: %w", err)
+			}
+			minute = value
+		case "BYHOUR":
+			if err := checkNumbers(value, 0, 23); err != nil {
+				return "", fmt.Errorf("invalid BYHOUR: %w", err)
+			}
+			hour = value
+		case "BYMONTHDAY":
+			if err := checkNumbers(value, 1, 31); err != nil {
+				return "", fmt.Errorf("invalid BYMONTHDAY: %w", err)
+			}
+			dayOfMonth = value
+		case "BYDAY":
+			var days []string
+			for _, day := range strings.Split(value, ",") {
+				n, ok := weekdays[day]
+				if !ok {
+					return "", fmt.Errorf("invalid BYDAY %q; ordinals such as 1MO are not supported", day)
+				}
+				days = append(days, n)
+			}
+			dayOfWeek = strings.Join(days, ",")
+		default:
+			return "", fmt.Errorf("%s is not supported", key)
+		}
+	}
+
+	// Cron matches days that match either field, a recurrence rule only days that match both
+	if dayOfMonth != "*" && dayOfWeek != "*" {
+		return "", fmt.Errorf("BYDAY and BYMONTHDAY together are not supported")
+	}
+	switch parts["FREQ"] {
+	case "DAILY":
+	case "WEEKLY":
+		if dayOfWeek == "*" {
+			return "", fmt.Errorf("FREQ=WEEKLY requires BYDAY")
+		}
+	case "MONTHLY":
+		if dayOfMonth == "*" && dayOfWeek == "*" {
+			return "", fmt.Errorf("FREQ=MONTHLY requires BYMONTHDAY or BYDAY")
+		}
+	case "":
+		return "", fmt.Errorf("FREQ is required")
+	default:
+		return "", fmt.Errorf("FREQ=%s is not supported", parts["FREQ"])
+	}
+	return strings.Join([]string{minute, hour, dayOfMonth, "*", dayOfWeek}, " "), nil
+}
+
+// checkNumbers checks a comma-separated list of numbers from low to high
+func checkNumbers(list string, low, high int) error {
+	for _, value := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < low || n > high {
+			return fmt.Errorf("%q is not a number from %d to %d", value, low, high)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,458 @@
+// Package notifier delivers Prophet notifications to webhooks, Slack, Microsoft Teams,
+// PagerDuty, Opsgenie and email, with templating, retries and rate limiting.
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/prophet-aiops/pkg/httpclient"
+)
+
+const (
+	// DefaultPagerDutyURL is the PagerDuty Events API v2 endpoint
+	DefaultPagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+	// DefaultOpsgenieURL is the Opsgenie Alert API endpoint
+	DefaultOpsgenieURL = "https://api.opsgenie.com/v2/alerts"
+)
+
+// Channel is a resolved notification destination, with credentials already read from Secrets
+type Channel struct {
+	// Type is one of "webhook", "slack", "teams", "pagerduty", "opsgenie" or "email"
+	Type string
+
+	// URL is the webhook URL, or an API endpoint override for pagerduty and opsgenie
+	URL string
+
+	// Token is the bearer token (webhook), routing key (pagerduty) or API key (opsgenie)
+	Token string
+
+	// SMTP settings, used when Type is "email"
+	SMTPHost string
+	SMTPPort int32
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// Message is a single notification
+type Message struct {
+	// Title is a short summary of the notification
+	Title string `json:"title"`
+
+	// Text is the notification body
+	Text string `json:"text"`
+
+	// Severity is "critical", "warning" or "info"
+	Severity string `json:"severity"`
+
+	// Source is the component sending the notification (e.g., "cost-alert")
+	Source string `json:"source"`
+
+	// Key identifies the alerting object and is used for deduplication and rate limiting
+	Key string `json:"key"`
+
+	// Resolved marks the notification as a resolution of a previous alert
+	Resolved bool `json:"resolved,omitempty"`
+
+	// Fields are additional details attached to the notification
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// Options configures a Notifier
+type Options struct {
+	// Retries is the number of retries after a failed delivery. Default: 3
+	Retries int
+
+	// Backoff is the delay before the first retry, doubled on each attempt. Default: 1s
+	Backoff time.Duration
+
+	// MinInterval is the minimum time between messages with the same channel and key. Default: 1m
+	MinInterval time.Duration
+
+	// Timeout is the HTTP timeout per attempt. Default: 10s
+	Timeout time.Duration
+}
+
+// Notifier sends messages to channels. It is safe for concurrent use.
+type Notifier struct {
+	options Options
+
+	// httpClient is built on first use, see client
+	httpClientOnce sync.Once
+	httpClient     *http.Client
+	httpClientErr  error
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+}
+
+// New returns a Notifier, applying defaults for unset options
+func New(options Options) *Notifier {
+	if options.Retries == 0 {
+		options.Retries = 3
+	}
+	if options.Backoff == 0 {
+		options.Backoff = 1 * time.Second
+	}
+	if options.MinInterval == 0 {
+		options.MinInterval = 1 * time.Minute
+	}
+	if options.Timeout == 0 {
+		options.Timeout = 10 * time.Second
+	}
+	return &Notifier{
+		options:  options,
+		lastSent: map[string]time.Time{},
+	}
+}
+
+// Render executes a Go text/template against data
+func Render(text string, data interface{}) (string, error) {
+	tmpl, err := template.New("notification").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid notification template: %w", err)
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render notification template: %w", err)
+	}
+	return out.String(), nil
+}
+
+// Send delivers msg to every channel, returning the combined error of failed deliveries.
+// Messages repeated for the same channel and key within MinInterval are dropped.
+func (n *Notifier) Send(ctx context.Context, channels []Channel, msg Message) error {
+	var errs []error
+	for _, channel := range channels {
+		if err := n.send(ctx, channel, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", channel.Type, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// send delivers msg to a single channel, with rate limiting and retries
+func (n *Notifier) send(ctx context.Context, channel Channel, msg Message) error {
+	// Resolutions are never rate limited so that a recovery is always reported
+	if !msg.Resolved && !n.allow(channel, msg.Key) {
+		return nil
+	}
+
+	backoff := n.options.Backoff
+	var err error
+	for attempt := 0; attempt <= n.options.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		if err = n.deliver(ctx, channel, msg); err == nil || !retryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// allow records a send for the channel and key, returning false if one happened within MinInterval
+func (n *Notifier) allow(channel Channel, key string) bool {
+	id := channel.Type + "|" + channel.URL + "|" + strings.Join(channel.To, ",") + "|" + key
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	now := time.Now()
+	if last, ok := n.lastSent[id]; ok && now.Sub(last) < n.options.MinInterval {
+		return false
+	}
+	n.lastSent[id] = now
+	return true
+}
+
+func (n *Notifier) deliver(ctx context.Context, channel Channel, msg Message) error {
+	switch channel.Type {
+	case "webhook":
+		return n.postJSON(ctx, channel.URL, bearer(channel.Token), msg)
+	case "slack":
+		return n.postJSON(ctx, channel.URL, nil, slackPayload(msg))
+	case "teams":
+		return n.postJSON(ctx, channel.URL, nil, teamsPayload(msg))
+	case "pagerduty":
+		endpoint := channel.URL
+		if endpoint == "" {
+			endpoint = DefaultPagerDutyURL
+		}
+		return n.postJSON(ctx, endpoint, nil, pagerDutyPayload(channel.Token, msg))
+	case "opsgenie":
+		endpoint := channel.URL
+		if endpoint == "" {
+			endpoint = DefaultOpsgenieURL
+		}
+		headers := map[string]string{"Authorization": "GenieKey " + channel.Token}
+		if msg.Resolved {
+			return n.postJSON(ctx, fmt.Sprintf("%s/%s/close?identifierType=alias", endpoint, url.PathEscape(msg.Key)), headers,
+				map[string]string{"source": msg.Source, "note": msg.Text})
+		}
+		return n.postJSON(ctx, endpoint, headers, opsgeniePayload(msg))
+	case "email":
+		return sendEmail(channel, msg)
+	default:
+		return permanent(fmt.Errorf("unsupported notification channel type: %s", channel.Type))
+	}
+}
+
+// client returns the HTTP client, built on first use so it picks up the
+// process-wide outbound HTTP settings
+func (n *Notifier) client() (*http.Client, error) {
+	n.httpClientOnce.Do(func() {
+		n.httpClient, n.httpClientErr = httpclient.New(httpclient.Config{Timeout: n.options.Timeout})
+	})
+	return n.httpClient, n.httpClientErr
+}
+
+// postJSON posts payload as JSON, treating any non-2xx response as an error
+func (n *Notifier) postJSON(ctx context.Context, endpoint string, headers map[string]string, payload interface{}) error {
+	if endpoint == "" {
+		return permanent(fmt.Errorf("no URL configured"))
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return permanent(err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	httpClient, err := n.client()
+	if err != nil {
+		return permanent(err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := fmt.Errorf("returned status %d: %s", resp.StatusCode, string(respBody))
+		// Only server errors and throttling are worth retrying
+		if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return permanent(err)
+		}
+		return err
+	}
+	return nil
+}
+
+func bearer(token string) map[string]string {
+	if token == "" {
+		return nil
+	}
+	return map[string]string{"Authorization": "Bearer " + token}
+}
+
+// slackPayload renders msg as Block Kit blocks. The text is shown where blocks can't be, e.g. in
+// push notifications.
+func slackPayload(msg Message) map[string]interface{} {
+	text := fmt.Sprintf("*%s*\n%s", title(msg), msg.Text)
+	for _, k := range sortedKeys(msg.Fields) {
+		text += fmt.Sprintf("\n• %s: %s", k, msg.Fields[k])
+	}
+
+	blocks := []map[string]interface{}{{
+		"type": "header",
+		"text": map[string]string{"type": "plain_text", "text": truncate(title(msg), 150)},
+	}}
+	if msg.Text != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]string{"type": "mrkdwn", "text": truncate(msg.Text, 3000)},
+		})
+	}
+	// A section holds at most 10 fields
+	var fields []map[string]string
+	for _, k := range sortedKeys(msg.Fields) {
+		fields = append(fields, map[string]string{"type": "mrkdwn", "text": truncate(fmt.Sprintf("*%s*\n%s", k, msg.Fields[k]), 2000)})
+	}
+	for len(fields) > 0 {
+		n := len(fields)
+		if n > 10 {
+			n = 10
+		}
+		blocks = append(blocks, map[string]interface{}{"type": "section", "fields": fields[:n]})
+		fields = fields[n:]
+	}
+	severity := msg.Severity
+	if msg.Resolved {
+		severity = "resolved"
+	}
+	blocks = append(blocks, map[string]interface{}{
+		"type":     "context",
+		"elements": []map[string]string{{"type": "mrkdwn", "text": fmt.Sprintf("%s | %s", msg.Source, severity)}},
+	})
+
+	return map[string]interface{}{"text": text, "blocks": blocks}
+}
+
+func teamsPayload(msg Message) map[string]interface{} {
+	facts := []map[string]string{}
+	for _, k := range sortedKeys(msg.Fields) {
+		facts = append(facts, map[string]string{"name": k, "value": msg.Fields[k]})
+	}
+	return map[string]interface{}{
+		"@type":      "MessageCard",
+		"@context":   "http://schema.org/extensions",
+		"summary":    title(msg),
+		"title":      title(msg),
+		"text":       msg.Text,
+		"themeColor": themeColor(msg),
+		"sections":   []map[string]interface{}{{"facts": facts}},
+	}
+}
+
+func pagerDutyPayload(routingKey string, msg Message) map[string]interface{} {
+	action := "trigger"
+	if msg.Resolved {
+		action = "resolve"
+	}
+	severity := msg.Severity
+	switch severity {
+	case "critical", "warning", "info":
+	default:
+		severity = "error"
+	}
+	summary := msg.Title
+	if msg.Text != "" {
+		summary += ": " + msg.Text
+	}
+	return map[string]interface{}{
+		"routing_key":  routingKey,
+		"event_action": action,
+		"dedup_key":    msg.Key,
+		"payload": map[string]interface{}{
+			"summary":        truncate(summary, 1024),
+			"source":         msg.Source,
+			"severity":       severity,
+			"custom_details": msg.Fields,
+		},
+	}
+}
+
+func opsgeniePayload(msg Message) map[string]interface{} {
+	priority := "P3"
+	switch msg.Severity {
+	case "critical":
+		priority = "P1"
+	case "warning":
+		priority = "P3"
+	case "info":
+		priority = "P5"
+	}
+	return map[string]interface{}{
+		"message":     truncate(msg.Title, 130),
+		"alias":       msg.Key,
+		"description": msg.Text,
+		"priority":    priority,
+		"source":      msg.Source,
+		"details":     msg.Fields,
+	}
+}
+
+func sendEmail(channel Channel, msg Message) error {
+	if channel.SMTPHost == "" || channel.From == "" || len(channel.To) == 0 {
+		return permanent(fmt.Errorf("smtp host, from and recipients are required"))
+	}
+	port := channel.SMTPPort
+	if port == 0 {
+		port = 587
+	}
+
+	var auth smtp.Auth
+	if channel.Username != "" {
+		auth = smtp.PlainAuth("", channel.Username, channel.Password, channel.SMTPHost)
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", channel.From)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(channel.To, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", title(msg))
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	body.WriteString(msg.Text)
+	for _, k := range sortedKeys(msg.Fields) {
+		fmt.Fprintf(&body, "\r\n%s: %s", k, msg.Fields[k])
+	}
+
+	return smtp.SendMail(fmt.Sprintf("%s:%d", channel.SMTPHost, port), auth, channel.From, channel.To, []byte(body.String()))
+}
+
+func title(msg Message) string {
+	if msg.Resolved {
+		return "[RESOLVED] " + msg.Title
+	}
+	return msg.Title
+}
+
+func themeColor(msg Message) string {
+	switch {
+	case msg.Resolved:
+		return "2EB886"
+	case msg.Severity == "critical":
+		return "D00000"
+	case msg.Severity == "warning":
+		return "FFA500"
+	default:
+		return "439FE0"
+	}
+}
+
+func sortedKeys(fields map[string]string) []string {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
+
+// permanentError marks an error that retrying won't fix
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+func permanent(err error) error { return permanentError{err} }
+
+func retryable(err error) bool {
+	var p permanentError
+	return !errors.As(err, &p)
+}
@@ -0,0 +1,100 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/prophet-aiops/pkg/testenv"
+)
+
+func testNotifier() *Notifier {
+	return New(Options{Retries: 2, Backoff: time.Millisecond, MinInterval: time.Hour, Timeout: time.Second})
+}
+
+func TestSendWebhook(t *testing.T) {
+	webhook := testenv.NewWebhook(t)
+	msg := Message{Title: "CostAlert shop/daily", Text: "over budget", Severity: "warning", Source: "cost-alert", Key: "costalert/shop/daily"}
+	err := testNotifier().Send(context.Background(), []Channel{{Type: "webhook", URL: webhook.URL, Token: "secret"}}, msg)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	requests := webhook.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("expected 1 request, got %d", len(requests))
+	}
+	if got := requests[0].Header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("got Authorization %q", got)
+	}
+	var received Message
+	if err := json.Unmarshal(requests[0].Body, &received); err != nil {
+		t.Fatal(err)
+	}
+	if received.Title != msg.Title || received.Text != msg.Text || received.Key != msg.Key {
+		t.Errorf("got message %+v, want %+v", received, msg)
+	}
+}
+
+func TestSendRetries(t *testing.T) {
+	tests := map[string]struct {
+		status   []int
+		requests int
+		wantErr  bool
+	}{
+		"server error retried":     {status: []int{500, 503}, requests: 3},
+		"throttling retried":       {status: []int{429}, requests: 2},
+		"client error not retried": {status: []int{400}, requests: 1, wantErr: true},
+		"retries exhausted":        {status: []int{500, 500, 500}, requests: 3, wantErr: true},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			webhook := testenv.NewWebhook(t, tt.status...)
+			err := testNotifier().Send(context.Background(), []Channel{{Type: "webhook", URL: webhook.URL}}, Message{Key: "k"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := len(webhook.Requests()); got != tt.requests {
+				t.Errorf("got %d requests, want %d", got, tt.requests)
+			}
+		})
+	}
+}
+
+func TestSendRateLimit(t *testing.T) {
+	webhook := testenv.NewWebhook(t)
+	n := testNotifier()
+	channels := []Channel{{Type: "slack", URL: webhook.URL}}
+	for i := 0; i < 3; i++ {
+		if err := n.Send(context.Background(), channels, Message{Text: "firing", Key: "healthcheck/shop/web"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := n.Send(context.Background(), channels, Message{Text: "resolved", Key: "healthcheck/shop/web", Resolved: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Send(context.Background(), channels, Message{Text: "firing", Key: "healthcheck/shop/db"}); err != nil {
+		t.Fatal(err)
+	}
+	// One firing message per key, and the resolution
+	if got := len(webhook.Requests()); got != 3 {
+		t.Errorf("got %d requests, want 3", got)
+	}
+}
+
+func TestSendUnsupportedChannel(t *testing.T) {
+	if err := testNotifier().Send(context.Background(), []Channel{{Type: "carrier-pigeon"}}, Message{Key: "k"}); err == nil {
+		t.Error("expected an error for an unsupported channel type")
+	}
+}
+
+func TestRender(t *testing.T) {
+	text, err := Render("{{ .Name }} is unhealthy", struct{ Name string }{"web"})
+	if err != nil || text != "web is unhealthy" {
+		t.Errorf("got %q, %v", text, err)
+	}
+	if _, err := Render("{{ .Name", nil); err == nil {
+		t.Error("expected an error for an invalid template")
+	}
+}
@@ -0,0 +1,220 @@
+// Package policy evaluates org-wide guardrail policies, written as CEL expressions,
+// before a Prophet operator mutates the cluster.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	// Embed the timezone database so expressions can use now.getHours("Europe/Berlin")
+	// in distroless images
+	_ "time/tzdata"
+
+	"github.com/google/cel-go/cel"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+)
+
+// PoliciesKey is the ConfigMap key holding the guardrail rules
+const PoliciesKey = "policies.yaml"
+
+// Rule denies an action when its expression evaluates to true.
+//
+// Expressions see two variables:
+//   - action: a map with operator, type, kind, name, namespace, labels and reason
+//   - now: the current time, as a CEL timestamp
+//
+// For example, this expression denies evictions in production namespaces during business hours:
+//
+//	action.type == "EvictPod" && action.namespace.startsWith("prod") &&
+//	  now.getHours("Europe/Berlin") >= 9 && now.getHours("Europe/Berlin") < 18
+type Rule struct {
+	// Name identifies the rule in events and logs
+	Name string `json:"name"`
+
+	// Description explains the rule and is reported when it denies an action
+	Description string `json:"description,omitempty"`
+
+	// Expression is a CEL expression returning a bool; true denies the action
+	Expression string `json:"expression"`
+}
+
+// Action is a mutation an operator is about to make
+type Action struct {
+	// Operator is the component making the change (e.g., "budget-guard")
+	Operator string
+
+	// Type is the kind of change (e.g., "EvictPod", "UpdateWorkload", "RolloutRestart")
+	Type string
+
+	// Kind, Name and Namespace identify the object being changed
+	Kind      string
+	Name      string
+	Namespace string
+
+	// Labels are the labels of the object being changed
+	Labels map[string]string
+
+	// Reason is why the operator wants to make the change
+	Reason string
+}
+
+// Decision is the outcome of evaluating an Action
+type Decision struct {
+	// Allowed is false if a rule denied the action
+	Allowed bool
+
+	// Rule is the name of the rule that denied the action
+	Rule string
+
+	// Message explains the denial
+	Message string
+}
+
+// Engine holds compiled rules
+type Engine struct {
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	Rule
+	program cel.Program
+}
+
+// Parse reads rules from YAML
+func Parse(data []byte) ([]Rule, error) {
+	var rules []Rule
+	if err := yaml.UnmarshalStrict(data, &rules); err != nil {
+		return nil, fmt.Errorf("invalid guardrail policies: %w", err)
+	}
+	return rules, nil
+}
+
+// Compile type-checks the rules, returning an error for the first invalid one
+func Compile(rules []Rule) (*Engine, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("action", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("now", cel.TimestampType),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := &Engine{}
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("guardrail policy without a name")
+		}
+		ast, issues := env.Compile(rule.Expression)
+		if issues.Err() != nil {
+			return nil, fmt.Errorf("guardrail policy %s: %w", rule.Name, issues.Err())
+		}
+		if !ast.OutputType().IsExactType(cel.BoolType) {
+			return nil, fmt.Errorf("guardrail policy %s: expression must return a bool, got %s", rule.Name, ast.OutputType())
+		}
+		program, err := env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("guardrail policy %s: %w", rule.Name, err)
+		}
+		engine.rules = append(engine.rules, compiledRule{Rule: rule, program: program})
+	}
+	return engine, nil
+}
+
+// Evaluate checks the action against every rule.
+// A rule that fails to evaluate denies the action, so a broken guardrail never lets a change through.
+func (e *Engine) Evaluate(action Action, now time.Time) Decision {
+	labels := action.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	vars := map[string]interface{}{
+		"action": map[string]interface{}{
+			"operator":  action.Operator,
+			"type":      action.Type,
+			"kind":      action.Kind,
+			"name":      action.Name,
+			"namespace": action.Namespace,
+			"labels":    labels,
+			"reason":    action.Reason,
+		},
+		"now": now,
+	}
+
+	for _, rule := range e.rules {
+		out, _, err := rule.program.Eval(vars)
+		if err != nil {
+			return Decision{Rule: rule.Name, Message: fmt.Sprintf("guardrail policy %s failed to evaluate: %v", rule.Name, err)}
+		}
+		if denied, ok := out.Value().(bool); !ok || denied {
+			message := rule.Description
+			if message == "" {
+				message = fmt.Sprintf("denied by guardrail policy %s", rule.Name)
+			}
+			return Decision{Rule: rule.Name, Message: message}
+		}
+	}
+	return Decision{Allowed: true}
+}
+
+// Guard loads rules from a ConfigMap and evaluates actions against them.
+// Rules are recompiled only when the ConfigMap changes. It is safe for concurrent use.
+type Guard struct {
+	mu      sync.Mutex
+	source  string
+	engine  *Engine
+	loadErr error
+}
+
+// NewGuard returns an empty Guard
+func NewGuard() *Guard {
+	return &Guard{}
+}
+
+// Check evaluates the action against the rules in the ConfigMap.
+// An empty ConfigMap reference or a missing ConfigMap allows everything;
+// invalid rules deny everything until they are fixed.
+func (g *Guard) Check(ctx context.Context, reader client.Reader, ref types.NamespacedName, action Action) (Decision, error) {
+	if ref.Name == "" {
+		return Decision{Allowed: true}, nil
+	}
+
+	var cm corev1.ConfigMap
+	if err := reader.Get(ctx, ref, &cm); err != nil {
+		if apierrors.IsNotFound(err) {
+			return Decision{Allowed: true}, nil
+		}
+		return Decision{}, err
+	}
+
+	engine, err := g.load(ref, &cm)
+	if err != nil {
+		return Decision{Rule: ref.String(), Message: err.Error()}, nil
+	}
+	return engine.Evaluate(action, time.Now()), nil
+}
+
+// load returns the compiled rules for the ConfigMap, compiling them if it changed
+func (g *Guard) load(ref types.NamespacedName, cm *corev1.ConfigMap) (*Engine, error) {
+	source := ref.String() + "@" + cm.ResourceVersion
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.source == source {
+		return g.engine, g.loadErr
+	}
+
+	g.source = source
+	g.engine, g.loadErr = nil, nil
+	rules, err := Parse([]byte(cm.Data[PoliciesKey]))
+	if err == nil {
+		g.engine, err = Compile(rules)
+	}
+	g.loadErr = err
+	return g.engine, g.loadErr
+}
@@ -0,0 +1,32 @@
+package policy
+
+import "fmt"
+
+// ProtectedLabel marks a resource no Prophet operator may act on, e.g. aiops.prophet.io/protected=true
+const ProtectedLabel = "aiops.prophet.io/protected"
+
+// SystemNamespaces are always protected
+var SystemNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// Protected denies actions on protected targets: resources in the system namespaces or in
+// namespaces (typically the operator's own), and resources labeled with ProtectedLabel.
+// It is built in and checked before guardrail policies, so policies can't allow these actions.
+func Protected(action Action, namespaces []string) Decision {
+	if action.Labels[ProtectedLabel] == "true" {
+		return Decision{
+			Rule:    "protected-resource",
+			Message: fmt.Sprintf("%s %s/%s is labeled %s=true", action.Kind, action.Namespace, action.Name, ProtectedLabel),
+		}
+	}
+	for _, list := range [][]string{SystemNamespaces, namespaces} {
+		for _, namespace := range list {
+			if namespace != "" && action.Namespace == namespace {
+				return Decision{
+					Rule:    "protected-namespace",
+					Message: fmt.Sprintf("namespace %s is protected", namespace),
+				}
+			}
+		}
+	}
+	return Decision{Allowed: true}
+}
@@ -0,0 +1,98 @@
+// Package prophetconfig reads the cluster-wide ProphetConfig, which holds defaults shared by
+// all Prophet operators. It is read on every reconcile, so changes apply without a restart.
+package prophetconfig
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Name is the name of the singleton ProphetConfig; other ProphetConfigs are ignored
+const Name = "default"
+
+// GroupVersionKind identifies the cluster-scoped ProphetConfig resource
+var GroupVersionKind = schema.GroupVersionKind{Group: "aiops.prophet.io", Version: "v1alpha1", Kind: "ProphetConfig"}
+
+// Spec is the ProphetConfig spec. Settings on individual resources take precedence
+// unless stated otherwise.
+type Spec struct {
+	// ProtectedNamespaces are protected in addition to each operator's own protected namespaces
+	ProtectedNamespaces []string `json:"protectedNamespaces,omitempty"`
+
+	// Remediation holds defaults for operators that change workloads
+	Remediation RemediationDefaults `json:"remediation,omitempty"`
+
+	// CostProvider holds defaults for operators that query costs
+	CostProvider CostProviderDefaults `json:"costProvider,omitempty"`
+
+	// Notify holds notification defaults
+	Notify NotifyDefaults `json:"notify,omitempty"`
+}
+
+// RemediationDefaults holds remediation defaults
+type RemediationDefaults struct {
+	// MinCooldownSeconds is the shortest cooldown between remediations of the same target.
+	// Longer per-resource cooldowns are kept; shorter ones are raised to it.
+	MinCooldownSeconds int32 `json:"minCooldownSeconds,omitempty"`
+}
+
+// CostProviderDefaults holds cost provider defaults
+type CostProviderDefaults struct {
+	// Endpoint is the OpenCost/Kubecost API endpoint used by resources that don't set one
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// NotifyDefaults holds notification defaults
+type NotifyDefaults struct {
+	// WebhookURL receives notifications from resources that enable notifications
+	// without configuring a destination
+	WebhookURL string `json:"webhookUrl,omitempty"`
+}
+
+// Get reads the ProphetConfig. A missing ProphetConfig or ProphetConfig CRD yields an empty Spec,
+// as does a namespace-scoped install that isn't allowed to read it.
+func Get(ctx context.Context, reader client.Reader) (Spec, error) {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(GroupVersionKind)
+	if err := reader.Get(ctx, types.NamespacedName{Name: Name}, obj); err != nil {
+		if apierrors.IsNotFound(err) || apierrors.IsForbidden(err) || meta.IsNoMatchError(err) {
+			return Spec{}, nil
+		}
+		return Spec{}, fmt.Errorf("failed to get ProphetConfig %s: %w", Name, err)
+	}
+
+	var spec Spec
+	raw, _, err := unstructured.NestedMap(obj.Object, "spec")
+	if err != nil {
+		return Spec{}, fmt.Errorf("invalid ProphetConfig %s: %w", Name, err)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(raw, &spec); err != nil {
+		return Spec{}, fmt.Errorf("invalid ProphetConfig %s: %w", Name, err)
+	}
+	return spec, nil
+}
+
+// Cooldown returns the per-resource cooldown, raised to the minimum cooldown if it is shorter
+func (s Spec) Cooldown(seconds int32) time.Duration {
+	if seconds < s.Remediation.MinCooldownSeconds {
+		seconds = s.Remediation.MinCooldownSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Protected returns the namespaces plus the ProphetConfig's protected namespaces
+func (s Spec) Protected(namespaces []string) []string {
+	if len(s.ProtectedNamespaces) == 0 {
+		return namespaces
+	}
+	return append(append([]string{}, namespaces...), s.ProtectedNamespaces...)
+}
@@ -0,0 +1,39 @@
+// Package status writes resource status through the status subresource with patches,
+// so status writes don't fail when the spec or metadata changed since the resource was read.
+package status
+
+import (
+	"context"
+
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Patch writes the status changes made to obj since base was copied from it.
+// The merge patch carries no resourceVersion, so it never conflicts; use it when
+// the controller is the only writer of the status.
+func Patch(ctx context.Context, c client.Client, obj, base client.Object) error {
+	return c.Status().Patch(ctx, obj, client.MergeFrom(base))
+}
+
+// Update applies mutate to obj and patches the status, guarded by obj's resourceVersion.
+// On a conflict obj is read again and mutate re-applied, so writers sharing a status
+// list don't drop each other's entries. mutate returns false when there is nothing to write.
+func Update(ctx context.Context, c client.Client, obj client.Object, mutate func() bool) error {
+	key := client.ObjectKeyFromObject(obj)
+	first := true
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if !first {
+			if err := c.Get(ctx, key, obj); err != nil {
+				return err
+			}
+		}
+		first = false
+
+		base := obj.DeepCopyObject().(client.Object)
+		if !mutate() {
+			return nil
+		}
+		return c.Status().Patch(ctx, obj, client.MergeFromWithOptions(base, client.MergeFromWithOptimisticLock{}))
+	})
+}
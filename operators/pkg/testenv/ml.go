@@ -0,0 +1,259 @@
+package testenv
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Forecast is a Grafana ML metric forecast: the predicted value and its bounds for a series
+type Forecast struct {
+	Labels    map[string]string
+	Predicted float64
+	Lower     float64
+	Upper     float64
+}
+
+// GrafanaML is a fake Grafana serving ML forecasts through its Prometheus data source proxy
+type GrafanaML struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	queries  []string
+	requests []Request
+}
+
+// NewGrafanaML starts a fake Grafana answering instant queries on
+// /api/datasources/proxy/uid/<uid>/api/v1/query with the forecasts forecast returns, one series
+// per ml_forecast label value: yhat, yhat_lower and yhat_upper. It's closed when the test ends.
+func NewGrafanaML(t testing.TB, forecast func(promQL string) []Forecast) *GrafanaML {
+	g := &GrafanaML{}
+	g.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.HasPrefix(req.URL.Path, "/api/datasources/proxy/uid/") || !strings.HasSuffix(req.URL.Path, "/api/v1/query") {
+			http.NotFound(w, req)
+			return
+		}
+		promQL := queryOf(req)
+		g.mu.Lock()
+		g.queries = append(g.queries, promQL)
+		g.requests = append(g.requests, Request{Header: req.Header.Clone()})
+		g.mu.Unlock()
+
+		var series []Series
+		for _, f := range forecast(promQL) {
+			for band, value := range map[string]float64{"yhat": f.Predicted, "yhat_lower": f.Lower, "yhat_upper": f.Upper} {
+				labels := map[string]string{"ml_forecast": band}
+				for name, v := range f.Labels {
+					labels[name] = v
+				}
+				series = append(series, Series{Labels: labels, Value: value})
+			}
+		}
+		writeVector(w, series)
+	}))
+	t.Cleanup(g.Close)
+	return g
+}
+
+// Queries returns the PromQL queries received so far
+func (g *GrafanaML) Queries() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.queries...)
+}
+
+// Requests returns the requests received so far, e.g. to check their Authorization header
+func (g *GrafanaML) Requests() []Request {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]Request(nil), g.requests...)
+}
+
+// OllamaRequest is a generate or chat request received by the fake Ollama
+type OllamaRequest struct {
+	Path   string
+	Model  string
+	Prompt string
+}
+
+// Ollama is a fake Ollama API
+type Ollama struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	models   []string
+	requests []OllamaRequest
+}
+
+// NewOllama starts a fake Ollama serving models, answering /api/generate and /api/chat with what
+// reply returns for the prompt, or the last chat message. Responses are streamed as NDJSON unless
+// the request sets "stream": false, like Ollama does. It's closed when the test ends.
+func NewOllama(t testing.TB, reply func(model, prompt string) string, models ...string) *Ollama {
+	o := &Ollama{models: models}
+	o.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/api/tags":
+			var tags []interface{}
+			for _, model := range o.models {
+				tags = append(tags, map[string]interface{}{"name": model, "model": model})
+			}
+			writeJSON(w, map[string]interface{}{"models": tags})
+			return
+		case "/api/generate", "/api/chat":
+		default:
+			http.NotFound(w, req)
+			return
+		}
+
+		var body struct {
+			Model    string `json:"model"`
+			Prompt   string `json:"prompt"`
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+			Stream *bool `json:"stream"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !o.serves(body.Model) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "model '" + body.Model + "' not found"})
+			return
+		}
+		prompt := body.Prompt
+		if n := len(body.Messages); n > 0 {
+			prompt = body.Messages[n-1].Content
+		}
+		o.mu.Lock()
+		o.requests = append(o.requests, OllamaRequest{Path: req.URL.Path, Model: body.Model, Prompt: prompt})
+		o.mu.Unlock()
+
+		answer := reply(body.Model, prompt)
+		chunk := func(text string, done bool) map[string]interface{} {
+			response := map[string]interface{}{
+				"model":      body.Model,
+				"created_at": time.Now().UTC().Format(time.RFC3339Nano),
+				"done":       done,
+			}
+			if req.URL.Path == "/api/chat" {
+				response["message"] = map[string]string{"role": "assistant", "content": text}
+			} else {
+				response["response"] = text
+			}
+			return response
+		}
+		if body.Stream != nil && !*body.Stream {
+			writeJSON(w, chunk(answer, true))
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(w)
+		for _, word := range strings.SplitAfter(answer, " ") {
+			_ = encoder.Encode(chunk(word, false))
+		}
+		_ = encoder.Encode(chunk("", true))
+	}))
+	t.Cleanup(o.Close)
+	return o
+}
+
+// serves reports whether the fake serves model; with no models configured it serves any
+func (o *Ollama) serves(model string) bool {
+	if len(o.models) == 0 {
+		return true
+	}
+	for _, m := range o.models {
+		if m == model {
+			return true
+		}
+	}
+	return false
+}
+
+// Requests returns the generate and chat requests received so far
+func (o *Ollama) Requests() []OllamaRequest {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]OllamaRequest(nil), o.requests...)
+}
+
+// K8sGPTResult is a problem found by the fake K8sGPT analysis
+type K8sGPTResult struct {
+	Kind         string
+	Name         string
+	Errors       []string
+	Details      string
+	ParentObject string
+}
+
+// K8sGPT is a fake K8sGPT server answering analyze requests
+type K8sGPT struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	results  []K8sGPTResult
+	requests []Request
+}
+
+// NewK8sGPT starts a fake K8sGPT answering POST /v1/analyze with results. It's closed when the
+// test ends.
+func NewK8sGPT(t testing.TB, results ...K8sGPTResult) *K8sGPT {
+	k := &K8sGPT{results: results}
+	k.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/v1/analyze" || req.Method != http.MethodPost {
+			http.NotFound(w, req)
+			return
+		}
+		var body json.RawMessage
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		k.mu.Lock()
+		k.requests = append(k.requests, Request{Header: req.Header.Clone(), Body: body})
+		results := []interface{}{}
+		problems := 0
+		for _, result := range k.results {
+			failures := []interface{}{}
+			for _, text := range result.Errors {
+				failures = append(failures, map[string]string{"text": text})
+			}
+			problems += len(result.Errors)
+			results = append(results, map[string]interface{}{
+				"kind":         result.Kind,
+				"name":         result.Name,
+				"error":        failures,
+				"details":      result.Details,
+				"parentObject": result.ParentObject,
+			})
+		}
+		k.mu.Unlock()
+
+		status := "OK"
+		if problems > 0 {
+			status = "ProblemDetected"
+		}
+		writeJSON(w, map[string]interface{}{"status": status, "problems": problems, "results": results})
+	}))
+	t.Cleanup(k.Close)
+	return k
+}
+
+// SetResults replaces the results returned by later analyses
+func (k *K8sGPT) SetResults(results ...K8sGPTResult) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.results = results
+}
+
+// Requests returns the analyze requests received so far
+func (k *K8sGPT) Requests() []Request {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return append([]Request(nil), k.requests...)
+}
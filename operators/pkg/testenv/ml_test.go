@@ -0,0 +1,160 @@
+package testenv
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGrafanaML(t *testing.T) {
+	grafana := NewGrafanaML(t, func(promQL string) []Forecast {
+		return []Forecast{{Labels: map[string]string{"namespace": "shop"}, Predicted: 10, Lower: 8, Upper: 12}}
+	})
+	req, _ := http.NewRequest(http.MethodGet, grafana.URL+"/api/datasources/proxy/uid/ml/api/v1/query?query="+url.QueryEscape("cost:predicted"), nil)
+	req.Header.Set("Authorization", "Bearer token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Data struct {
+			Result []struct {
+				Metric map[string]string `json:"metric"`
+				Value  []interface{}     `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, series := range body.Data.Result {
+		if series.Metric["namespace"] != "shop" {
+			t.Errorf("series %v lost the forecast's labels", series.Metric)
+		}
+		got[series.Metric["ml_forecast"]] = series.Value[1].(string)
+	}
+	want := map[string]string{"yhat": "10", "yhat_lower": "8", "yhat_upper": "12"}
+	for band, value := range want {
+		if got[band] != value {
+			t.Errorf("got %s %q, want %q", band, got[band], value)
+		}
+	}
+	if queries := grafana.Queries(); len(queries) != 1 || queries[0] != "cost:predicted" {
+		t.Errorf("got queries %v", queries)
+	}
+	if auth := grafana.Requests()[0].Header.Get("Authorization"); auth != "Bearer token" {
+		t.Errorf("got Authorization %q", auth)
+	}
+}
+
+func TestOllama(t *testing.T) {
+	ollama := NewOllama(t, func(model, prompt string) string {
+		return "scale the deployment"
+	}, "llama3")
+
+	tests := map[string]struct {
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		"generate": {
+			path:   "/api/generate",
+			body:   `{"model":"llama3","prompt":"why is web failing?","stream":false}`,
+			status: http.StatusOK,
+			want:   "scale the deployment",
+		},
+		"chat streamed": {
+			path:   "/api/chat",
+			body:   `{"model":"llama3","messages":[{"role":"user","content":"why is web failing?"}]}`,
+			status: http.StatusOK,
+			want:   "scale the deployment",
+		},
+		"unknown model": {
+			path:   "/api/generate",
+			body:   `{"model":"mistral","prompt":"hi"}`,
+			status: http.StatusNotFound,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			resp, err := http.Post(ollama.URL+tt.path, "application/json", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			// Streamed answers come as NDJSON chunks, others as a single one
+			var answer string
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				var chunk struct {
+					Response string `json:"response"`
+					Message  struct {
+						Content string `json:"content"`
+					} `json:"message"`
+				}
+				if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+					t.Fatal(err)
+				}
+				answer += chunk.Response + chunk.Message.Content
+			}
+			if answer != tt.want {
+				t.Errorf("got %q, want %q", answer, tt.want)
+			}
+		})
+	}
+
+	requests := ollama.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests, want 2", len(requests))
+	}
+	for _, req := range requests {
+		if req.Prompt != "why is web failing?" {
+			t.Errorf("got prompt %q for %s", req.Prompt, req.Path)
+		}
+	}
+}
+
+func TestK8sGPT(t *testing.T) {
+	k8sgpt := NewK8sGPT(t, K8sGPTResult{Kind: "Pod", Name: "shop/web-1", Errors: []string{"back-off restarting failed container"}})
+
+	analyze := func() (status string, problems int) {
+		resp, err := http.Post(k8sgpt.URL+"/v1/analyze", "application/json", strings.NewReader(`{"explain":false}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var body struct {
+			Status   string `json:"status"`
+			Problems int    `json:"problems"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return body.Status, body.Problems
+	}
+
+	if status, problems := analyze(); status != "ProblemDetected" || problems != 1 {
+		t.Errorf("got %s with %d problems, want ProblemDetected with 1", status, problems)
+	}
+	k8sgpt.SetResults()
+	if status, problems := analyze(); status != "OK" || problems != 0 {
+		t.Errorf("got %s with %d problems, want OK with 0", status, problems)
+	}
+	if got := len(k8sgpt.Requests()); got != 2 {
+		t.Errorf("got %d requests, want 2", got)
+	}
+}
@@ -0,0 +1,174 @@
+package testenv
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// OpenCost is a fake OpenCost allocation API
+type OpenCost struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	costs    map[string]float64
+	requests []*http.Request
+}
+
+// NewOpenCost starts a fake OpenCost answering every /allocation query with costs, the total cost
+// per allocation name. It's closed when the test ends.
+func NewOpenCost(t testing.TB, costs map[string]float64) *OpenCost {
+	o := &OpenCost{costs: costs}
+	o.Server = httptest.NewServer(http.HandlerFunc(o.serve))
+	t.Cleanup(o.Close)
+	return o
+}
+
+// SetCosts replaces the costs returned by later queries
+func (o *OpenCost) SetCosts(costs map[string]float64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.costs = costs
+}
+
+// Requests returns the allocation requests received so far
+func (o *OpenCost) Requests() []*http.Request {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]*http.Request(nil), o.requests...)
+}
+
+func (o *OpenCost) serve(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != "/allocation" {
+		http.NotFound(w, req)
+		return
+	}
+	o.mu.Lock()
+	o.requests = append(o.requests, req.Clone(req.Context()))
+	allocations := map[string]interface{}{}
+	for name, cost := range o.costs {
+		allocations[name] = map[string]interface{}{"name": name, "totalCost": cost}
+	}
+	o.mu.Unlock()
+
+	writeJSON(w, map[string]interface{}{
+		"code": http.StatusOK,
+		"data": []interface{}{allocations},
+	})
+}
+
+// Series is a sample returned by the fake Prometheus
+type Series struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// Prometheus is a fake Prometheus query API
+type Prometheus struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	queries []string
+}
+
+// NewPrometheus starts a fake Prometheus answering instant queries on /api/v1/query with the
+// vector query returns. It's closed when the test ends.
+func NewPrometheus(t testing.TB, query func(promQL string) []Series) *Prometheus {
+	p := &Prometheus{}
+	p.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/api/v1/query" {
+			http.NotFound(w, req)
+			return
+		}
+		promQL := queryOf(req)
+		p.mu.Lock()
+		p.queries = append(p.queries, promQL)
+		p.mu.Unlock()
+		writeVector(w, query(promQL))
+	}))
+	t.Cleanup(p.Close)
+	return p
+}
+
+// Queries returns the PromQL queries received so far
+func (p *Prometheus) Queries() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.queries...)
+}
+
+// Request is a request received by the fake webhook
+type Request struct {
+	Header http.Header
+	Body   []byte
+}
+
+// Webhook is a fake notification receiver
+type Webhook struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	status   []int
+	requests []Request
+}
+
+// NewWebhook starts a fake webhook recording the requests it receives. It answers with status, in
+// turn, and 200 once they are used up. It's closed when the test ends.
+func NewWebhook(t testing.TB, status ...int) *Webhook {
+	w := &Webhook{status: status}
+	w.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		w.mu.Lock()
+		w.requests = append(w.requests, Request{Header: req.Header.Clone(), Body: body})
+		code := http.StatusOK
+		if len(w.status) > 0 {
+			code, w.status = w.status[0], w.status[1:]
+		}
+		w.mu.Unlock()
+		rw.WriteHeader(code)
+	}))
+	t.Cleanup(w.Close)
+	return w
+}
+
+// Requests returns the requests received so far
+func (w *Webhook) Requests() []Request {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Request(nil), w.requests...)
+}
+
+// queryOf returns the PromQL query of an instant query, sent as a GET or a POST form
+func queryOf(req *http.Request) string {
+	promQL := req.URL.Query().Get("query")
+	if promQL == "" && req.Method == http.MethodPost {
+		if err := req.ParseForm(); err == nil {
+			promQL = req.PostForm.Get("query")
+		}
+	}
+	return promQL
+}
+
+// writeVector writes an instant query response holding series
+func writeVector(w http.ResponseWriter, series []Series) {
+	result := []interface{}{}
+	for _, s := range series {
+		result = append(result, map[string]interface{}{
+			"metric": s.Labels,
+			"value":  []interface{}{0, strconv.FormatFloat(s.Value, 'f', -1, 64)},
+		})
+	}
+	writeJSON(w, map[string]interface{}{
+		"status": "success",
+		"data":   map[string]interface{}{"resultType": "vector", "result": result},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"flag"
+	"net"
+	"os"
+	"strings"
+
+	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
+	// to ensure that exec-entrypoint and run can make use of them.
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	budgetguardv1alpha1 "github.com/prophet-aiops/budget-guard/api/v1alpha1"
+	budgetguard "github.com/prophet-aiops/budget-guard/controllers"
+	costalertv1alpha1 "github.com/prophet-aiops/cost-alert/api/v1alpha1"
+	costalert "github.com/prophet-aiops/cost-alert/controllers"
+	diagnosticv1alpha1 "github.com/prophet-aiops/diagnostic-remediator/api/v1alpha1"
+	diagnostic "github.com/prophet-aiops/diagnostic-remediator/controllers"
+	healthcheckv1alpha1 "github.com/prophet-aiops/health-check/api/v1alpha1"
+	healthcheck "github.com/prophet-aiops/health-check/controllers"
+	labelenforcerv1alpha1 "github.com/prophet-aiops/prophet/operators/label-enforcer/api/v1alpha1"
+	labelenforcer "github.com/prophet-aiops/prophet/operators/label-enforcer/controllers"
+)
+
+var (
+	scheme   = runtime.NewScheme()
+	setupLog = ctrl.Log.WithName("setup")
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(budgetguardv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(costalertv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(diagnosticv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(healthcheckv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(labelenforcerv1alpha1.AddToScheme(scheme))
+}
+
+// controller is a reconciler that can be enabled in the combined manager
+type controller struct {
+	name    string
+	enabled bool
+	setup   func(mgr ctrl.Manager) error
+}
+
+func main() {
+	var metricsAddr string
+	var enableLeaderElection bool
+	var probeAddr string
+	var watchNamespaces string
+	var extraProtectedNamespaces string
+	var remoteClusterQPS float64
+	var remoteClusterBurst int
+	var externalProbeAllowedCIDRs string
+	var guardrailsConfigMap string
+	var guardrails types.NamespacedName
+	var externalProbeAllowedNetworks []*net.IPNet
+	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
+	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
+	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
+		"Enable leader election for controller manager. "+
+			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", os.Getenv("WATCH_NAMESPACE"),
+		"Comma-separated namespaces to watch, defaulting to $WATCH_NAMESPACE. Leave empty to watch all namespaces.")
+	flag.StringVar(&extraProtectedNamespaces, "protected-namespaces", "",
+		"Comma-separated namespaces the operator must never act in, in addition to its own namespace and kube-system, kube-public and kube-node-lease.")
+	flag.Float64Var(&remoteClusterQPS, "remote-cluster-qps", 5, "API requests per second allowed to each remote cluster.")
+	flag.IntVar(&remoteClusterBurst, "remote-cluster-burst", 10, "API request burst allowed to each remote cluster.")
+	flag.StringVar(&externalProbeAllowedCIDRs, "external-probe-allowed-cidrs", "",
+		"Comma-separated CIDRs external probes may connect to although they are blocked by default: loopback, link-local and cloud metadata addresses.")
+	flag.StringVar(&guardrailsConfigMap, "guardrails-configmap", "prophet-operators/prophet-guardrails",
+		"The namespace/name of the ConfigMap holding guardrail policies. Set to an empty string to disable guardrails.")
+
+	// Every controller is opt-in so the combined manager only needs RBAC for what it runs
+	controllers := []*controller{
+		{name: "BudgetGuard", setup: func(mgr ctrl.Manager) error {
+			return (&budgetguard.BudgetGuardReconciler{
+				Client:              mgr.GetClient(),
+				Scheme:              mgr.GetScheme(),
+				Log:                 ctrl.Log.WithName("controllers").WithName("BudgetGuard"),
+				Guardrails:          guardrails,
+				ProtectedNamespaces: protectedNamespaces(extraProtectedNamespaces),
+				SecretNamespace:     operatorNamespace(),
+			}).SetupWithManager(mgr)
+		}},
+		{name: "CostAlert", setup: func(mgr ctrl.Manager) error {
+			return (&costalert.CostAlertReconciler{
+				Client: mgr.GetClient(),
+				Scheme: mgr.GetScheme(),
+				Log:    ctrl.Log.WithName("controllers").WithName("CostAlert"),
+			}).SetupWithManager(mgr)
+		}},
+		{name: "DiagnosticRemediation", setup: func(mgr ctrl.Manager) error {
+			return (&diagnostic.DiagnosticRemediationReconciler{
+				Client:              mgr.GetClient(),
+				Scheme:              mgr.GetScheme(),
+				Guardrails:          guardrails,
+				RemoteClusterQPS:    float32(remoteClusterQPS),
+				RemoteClusterBurst:  remoteClusterBurst,
+				ProtectedNamespaces: protectedNamespaces(extraProtectedNamespaces),
+			}).SetupWithManager(mgr)
+		}},
+		{name: "HealthCheck", setup: func(mgr ctrl.Manager) error {
+			return (&healthcheck.HealthCheckReconciler{
+				Client:                       mgr.GetClient(),
+				Scheme:                       mgr.GetScheme(),
+				Log:                          ctrl.Log.WithName("controllers").WithName("HealthCheck"),
+				RESTConfig:                   mgr.GetConfig(),
+				RemoteClusterQPS:             float32(remoteClusterQPS),
+				RemoteClusterBurst:           remoteClusterBurst,
+				ProtectedNamespaces:          protectedNamespaces(extraProtectedNamespaces),
+				ExternalProbeAllowedNetworks: externalProbeAllowedNetworks,
+			}).SetupWithManager(mgr)
+		}},
+		{name: "LabelEnforcer", setup: func(mgr ctrl.Manager) error {
+			return (&labelenforcer.LabelEnforcerReconciler{
+				Client: mgr.GetClient(),
+				Scheme: mgr.GetScheme(),
+				Log:    ctrl.Log.WithName("controllers").WithName("LabelEnforcer"),
+			}).SetupWithManager(mgr)
+		}},
+	}
+	flag.BoolVar(&controllers[0].enabled, "enable-budget-guard", false, "Enable the BudgetGuard controller.")
+	flag.BoolVar(&controllers[1].enabled, "enable-cost-alert", false, "Enable the CostAlert controller.")
+	flag.BoolVar(&controllers[2].enabled, "enable-diagnostic-remediator", false, "Enable the DiagnosticRemediation controller.")
+	flag.BoolVar(&controllers[3].enabled, "enable-health-check", false, "Enable the HealthCheck controller.")
+	flag.BoolVar(&controllers[4].enabled, "enable-label-enforcer", false, "Enable the LabelEnforcer controller.")
+
+	opts := zap.Options{
+		Development: true,
+	}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	if guardrailsConfigMap != "" {
+		namespace, name, ok := strings.Cut(guardrailsConfigMap, "/")
+		if !ok || namespace == "" || name == "" {
+			setupLog.Info("invalid --guardrails-configmap, expected namespace/name", "value", guardrailsConfigMap)
+			os.Exit(1)
+		}
+		guardrails = types.NamespacedName{Namespace: namespace, Name: name}
+	}
+
+	externalProbeAllowedNetworks, err := healthcheck.ParseCIDRs(externalProbeAllowedCIDRs)
+	if err != nil {
+		setupLog.Error(err, "invalid --external-probe-allowed-cidrs")
+		os.Exit(1)
+	}
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+		Scheme: scheme,
+		Metrics: metricsserver.Options{
+			BindAddress: metricsAddr,
+		},
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Port: 9443,
+		}),
+		HealthProbeBindAddress: probeAddr,
+		Cache:                  cacheOptions(watchNamespaces, guardrails.Namespace, operatorNamespace()),
+		LeaderElection:         enableLeaderElection,
+		LeaderElectionID:       "prophet-manager.prophet.io",
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to start manager")
+		os.Exit(1)
+	}
+
+	enabled := 0
+	for _, c := range controllers {
+		if !c.enabled {
+			continue
+		}
+		if err := c.setup(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", c.name)
+			os.Exit(1)
+		}
+		setupLog.Info("enabled controller", "controller", c.name)
+		enabled++
+	}
+	if enabled == 0 {
+		setupLog.Info("no controllers enabled, pass at least one --enable-<operator> flag")
+		os.Exit(1)
+	}
+
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up health check")
+		os.Exit(1)
+	}
+	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		setupLog.Error(err, "unable to set up ready check")
+		os.Exit(1)
+	}
+
+	setupLog.Info("starting manager")
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		setupLog.Error(err, "problem running manager")
+		os.Exit(1)
+	}
+}
+
+// cacheOptions restricts the manager's cache to the comma-separated watch namespaces.
+// Extra namespaces the operator reads from are added in namespace-scoped mode.
+// With no watch namespaces every namespace is watched.
+func cacheOptions(watchNamespaces string, extra ...string) cache.Options {
+	namespaces := map[string]cache.Config{}
+	for _, namespace := range strings.Split(watchNamespaces, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces[namespace] = cache.Config{}
+		}
+	}
+	if len(namespaces) == 0 {
+		return cache.Options{}
+	}
+	for _, namespace := range extra {
+		if namespace != "" {
+			namespaces[namespace] = cache.Config{}
+		}
+	}
+	return cache.Options{DefaultNamespaces: namespaces}
+}
+
+// protectedNamespaces returns the operator's own namespace and the comma-separated extra namespaces
+func protectedNamespaces(extra string) []string {
+	var namespaces []string
+	if namespace := operatorNamespace(); namespace != "" {
+		namespaces = append(namespaces, namespace)
+	}
+	for _, namespace := range strings.Split(extra, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// operatorNamespace returns the namespace the operator runs in, or "" if it can't be determined
+func operatorNamespace() string {
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace
+	}
+	if data, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	return ""
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"sigs.k8s.io/yaml"
+)
+
+// RunRequestedAnnotation records when prophetctl asked for an on-demand run.
+// Changing it triggers a reconcile, which runs the health check or diagnostics immediately.
+const RunRequestedAnnotation = "aiops.prophet.io/run-requested-at"
+
+// runGet lists resources, or gets a single one
+func runGet(args []string) error {
+	var opts globalOptions
+	var allNamespaces bool
+	fs := flag.NewFlagSet("get", flag.ExitOnError)
+	opts.bind(fs)
+	fs.BoolVar(&allNamespaces, "A", false, "List across all namespaces.")
+	fs.BoolVar(&allNamespaces, "all-namespaces", false, "List across all namespaces.")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 1 || len(positional) > 2 {
+		return fmt.Errorf("usage: prophetctl get <resource> [name] [-n namespace | -A]")
+	}
+
+	res, err := lookupResource(positional[0])
+	if err != nil {
+		return err
+	}
+	client, namespace, err := opts.client()
+	if err != nil {
+		return err
+	}
+	if allNamespaces {
+		namespace = metav1.NamespaceAll
+	}
+
+	ctx := context.Background()
+	var items []unstructured.Unstructured
+	if len(positional) == 2 {
+		obj, err := resourceClient(client, res, namespace).Get(ctx, positional[1], metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		items = append(items, *obj)
+	} else {
+		list, err := resourceClient(client, res, namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+		items = list.Items
+	}
+	if len(items) == 0 {
+		fmt.Println("No resources found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	headers := []string{"NAME"}
+	if res.namespaced && allNamespaces {
+		headers = append([]string{"NAMESPACE"}, headers...)
+	}
+	for _, c := range res.columns {
+		headers = append(headers, c.header)
+	}
+	fmt.Fprintln(w, strings.Join(headers, "\t"))
+	for i := range items {
+		obj := &items[i]
+		row := []string{obj.GetName()}
+		if res.namespaced && allNamespaces {
+			row = append([]string{obj.GetNamespace()}, row...)
+		}
+		for _, c := range res.columns {
+			row = append(row, c.value(obj))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// runDescribe prints a resource's spec and status
+func runDescribe(args []string) error {
+	var opts globalOptions
+	fs := flag.NewFlagSet("describe", flag.ExitOnError)
+	opts.bind(fs)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return fmt.Errorf("usage: prophetctl describe <resource> <name> [-n namespace]")
+	}
+
+	res, err := lookupResource(positional[0])
+	if err != nil {
+		return err
+	}
+	client, namespace, err := opts.client()
+	if err != nil {
+		return err
+	}
+
+	obj, err := resourceClient(client, res, namespace).Get(context.Background(), positional[1], metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Name:       %s\n", obj.GetName())
+	if res.namespaced {
+		fmt.Printf("Namespace:  %s\n", obj.GetNamespace())
+	}
+	fmt.Printf("Kind:       %s\n", res.kind)
+	for _, section := range []string{"spec", "status"} {
+		value, found, _ := unstructured.NestedFieldNoCopy(obj.Object, section)
+		if !found {
+			continue
+		}
+		out, err := yaml.Marshal(value)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s%s:\n", strings.ToUpper(section[:1]), section[1:])
+		for _, line := range strings.Split(strings.TrimRight(string(out), "\n"), "\n") {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+	return nil
+}
+
+// runTrigger requests an immediate health check or diagnostics run, optionally waiting for the result
+func runTrigger(args []string) error {
+	var opts globalOptions
+	var wait time.Duration
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	opts.bind(fs)
+	fs.DurationVar(&wait, "wait", 0, "Wait up to this long for the run to finish and print the result.")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return fmt.Errorf("usage: prophetctl run <healthcheck|diagnostic> <name> [-n namespace] [--wait duration]")
+	}
+
+	res, err := lookupResource(positional[0])
+	if err != nil {
+		return err
+	}
+	// lastRun is the status field each operator sets when it finishes a run
+	var lastRun string
+	switch res.kind {
+	case "HealthCheck":
+		lastRun = "status.lastCheckTime"
+	case "DiagnosticRemediation":
+		lastRun = "status.lastDiagnosed"
+	default:
+		return fmt.Errorf("run supports healthchecks and diagnostics, not %s", positional[0])
+	}
+
+	client, namespace, err := opts.client()
+	if err != nil {
+		return err
+	}
+	rc := resourceClient(client, res, namespace)
+	ctx := context.Background()
+
+	// RFC3339 has second precision, so compare against the start of the current second
+	requested := time.Now().Truncate(time.Second)
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{RunRequestedAnnotation: requested.UTC().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := rc.Patch(ctx, positional[1], types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		return err
+	}
+	fmt.Printf("Requested %s %s/%s run\n", res.kind, namespace, positional[1])
+	if wait == 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(wait)
+	for time.Now().Before(deadline) {
+		obj, err := rc.Get(ctx, positional[1], metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		value, _, _ := unstructured.NestedString(obj.Object, strings.Split(lastRun, ".")...)
+		if t, err := time.Parse(time.RFC3339, value); err == nil && !t.Before(requested) {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			for _, c := range res.columns {
+				fmt.Fprintf(w, "%s:\t%s\n", c.header, c.value(obj))
+			}
+			return w.Flush()
+		}
+		time.Sleep(2 * time.Second)
+	}
+	return fmt.Errorf("timed out after %s waiting for the run to finish", wait)
+}
+
+func resourceClient(client dynamic.Interface, res resource, namespace string) dynamic.ResourceInterface {
+	if !res.namespaced {
+		return client.Resource(res.gvr)
+	}
+	return client.Resource(res.gvr).Namespace(namespace)
+}
@@ -0,0 +1,108 @@
+// prophetctl inspects Prophet resources and triggers on-demand health checks and diagnostics
+// without hand-editing custom resources.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/tools/clientcmd"
+
+	// Import all Kubernetes client auth plugins
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+)
+
+const usage = `prophetctl inspects Prophet resources and triggers on-demand checks.
+
+Usage:
+  prophetctl get <resource> [name] [-n namespace | -A]
+  prophetctl describe <resource> <name> [-n namespace]
+  prophetctl run <healthcheck|diagnostic> <name> [-n namespace] [--wait duration]
+
+Resources:
+  healthchecks (hc), diagnostics (dr), incidents (inc), budgetguards (bg), costalerts (ca)
+
+Global flags:
+  --kubeconfig  path to the kubeconfig file (default: $KUBECONFIG or ~/.kube/config)
+  --context     kubeconfig context to use
+`
+
+// globalOptions are the flags shared by every command
+type globalOptions struct {
+	kubeconfig  string
+	kubeContext string
+	namespace   string
+}
+
+func (o *globalOptions) bind(fs *flag.FlagSet) {
+	fs.StringVar(&o.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file.")
+	fs.StringVar(&o.kubeContext, "context", "", "Kubeconfig context to use.")
+	fs.StringVar(&o.namespace, "n", "", "Namespace (default: the context's namespace).")
+	fs.StringVar(&o.namespace, "namespace", "", "Namespace (default: the context's namespace).")
+}
+
+// client returns a dynamic client and the effective namespace
+func (o *globalOptions) client() (dynamic.Interface, string, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if o.kubeconfig != "" {
+		rules.ExplicitPath = o.kubeconfig
+	}
+	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: o.kubeContext})
+
+	restConfig, err := config.ClientConfig()
+	if err != nil {
+		return nil, "", err
+	}
+	namespace := o.namespace
+	if namespace == "" {
+		if namespace, _, err = config.Namespace(); err != nil {
+			return nil, "", err
+		}
+	}
+
+	client, err := dynamic.NewForConfig(restConfig)
+	return client, namespace, err
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "get":
+		err = runGet(os.Args[2:])
+	case "describe":
+		err = runDescribe(os.Args[2:])
+	case "run":
+		err = runTrigger(os.Args[2:])
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+	default:
+		err = fmt.Errorf("unknown command %q", os.Args[1])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// parseArgs parses flags that may appear before or after positional arguments
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// column is a table column printed by "get"
+type column struct {
+	header string
+	value  func(obj *unstructured.Unstructured) string
+}
+
+// resource is a Prophet resource kind prophetctl knows about
+type resource struct {
+	gvr        schema.GroupVersionResource
+	kind       string
+	namespaced bool
+	columns    []column
+}
+
+func prophetResource(plural string) schema.GroupVersionResource {
+	return schema.GroupVersionResource{Group: "aiops.prophet.io", Version: "v1alpha1", Resource: plural}
+}
+
+var resources = map[string]resource{
+	"healthchecks": {
+		gvr:        prophetResource("healthchecks"),
+		kind:       "HealthCheck",
+		namespaced: true,
+		columns: []column{
+			{"TARGET", joined("/", "spec.targetRef.kind", "spec.targetRef.name")},
+			{"HEALTHY", field("status.healthy")},
+			{"FAILURES", field("status.failureCount")},
+			{"LAST CHECK", age("status.lastCheckTime")},
+		},
+	},
+	"diagnostics": {
+		gvr:        prophetResource("diagnosticremediations"),
+		kind:       "DiagnosticRemediation",
+		namespaced: true,
+		columns: []column{
+			{"TARGET", joined("/", "spec.target.kind", "spec.target.name")},
+			{"PHASE", field("status.phase")},
+			{"ISSUES", count("status.issues")},
+			{"REMEDIATIONS", field("status.remediationCount")},
+			{"LAST DIAGNOSED", age("status.lastDiagnosed")},
+		},
+	},
+	"incidents": {
+		gvr:        prophetResource("incidents"),
+		kind:       "Incident",
+		namespaced: true,
+		columns: []column{
+			{"TARGET", joined("/", "spec.target.kind", "spec.target.name")},
+			{"PHASE", field("status.phase")},
+			{"SEVERITY", field("status.severity")},
+			{"SIGNALS", count("status.signals")},
+			{"LAST SEEN", age("status.lastSeen")},
+		},
+	},
+	"budgetguards": {
+		gvr:  prophetResource("budgetguards"),
+		kind: "BudgetGuard",
+		columns: []column{
+			{"SCOPE", field("spec.scope")},
+			{"SPEND", field("status.currentSpend")},
+			{"BUDGET", joined(" ", "spec.budget.amount", "spec.budget.currency")},
+			{"EXCEEDED", field("status.exceeded")},
+		},
+	},
+	"costalerts": {
+		gvr:        prophetResource("costalerts"),
+		kind:       "CostAlert",
+		namespaced: true,
+		columns: []column{
+			{"SCOPE", field("spec.scope")},
+			{"CURRENT COST", field("status.currentCost")},
+			{"TRIGGERED", field("status.triggered")},
+			{"SILENCED", field("status.silenced")},
+		},
+	},
+}
+
+var aliases = map[string]string{
+	"healthcheck": "healthchecks", "hc": "healthchecks",
+	"diagnostic": "diagnostics", "diagnosticremediation": "diagnostics", "diagnosticremediations": "diagnostics", "dr": "diagnostics",
+	"incident": "incidents", "inc": "incidents",
+	"budgetguard": "budgetguards", "bg": "budgetguards",
+	"costalert": "costalerts", "ca": "costalerts",
+}
+
+// lookupResource resolves a resource name or alias
+func lookupResource(name string) (resource, error) {
+	name = strings.ToLower(name)
+	if alias, ok := aliases[name]; ok {
+		name = alias
+	}
+	r, ok := resources[name]
+	if !ok {
+		return resource{}, fmt.Errorf("unknown resource %q", name)
+	}
+	return r, nil
+}
+
+// field prints a nested field, or "-" if it isn't set
+func field(path string) func(obj *unstructured.Unstructured) string {
+	return func(obj *unstructured.Unstructured) string {
+		value, found, err := unstructured.NestedFieldNoCopy(obj.Object, strings.Split(path, ".")...)
+		if !found || err != nil {
+			return "-"
+		}
+		return fmt.Sprint(value)
+	}
+}
+
+// joined prints several fields separated by sep
+func joined(sep string, paths ...string) func(obj *unstructured.Unstructured) string {
+	return func(obj *unstructured.Unstructured) string {
+		values := make([]string, 0, len(paths))
+		for _, path := range paths {
+			values = append(values, field(path)(obj))
+		}
+		return strings.Join(values, sep)
+	}
+}
+
+// count prints the length of a list field
+func count(path string) func(obj *unstructured.Unstructured) string {
+	return func(obj *unstructured.Unstructured) string {
+		items, _, _ := unstructured.NestedSlice(obj.Object, strings.Split(path, ".")...)
+		return fmt.Sprint(len(items))
+	}
+}
+
+// age prints how long ago a timestamp field was
+func age(path string) func(obj *unstructured.Unstructured) string {
+	return func(obj *unstructured.Unstructured) string {
+		value, found, _ := unstructured.NestedString(obj.Object, strings.Split(path, ".")...)
+		if !found {
+			return "-"
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return value
+		}
+		return time.Since(t).Round(time.Second).String() + " ago"
+	}
+}